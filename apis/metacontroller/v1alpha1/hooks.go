@@ -27,6 +27,91 @@ type Hook struct {
 
 	// Inline invocation to arrive at desired state
 	Inline *Inline `json:"inline,omitempty"`
+
+	// Jsonnet evaluated within metac to arrive at desired state
+	Jsonnet *Jsonnet `json:"jsonnet,omitempty"`
+
+	// Exec runs a local command to arrive at desired state
+	Exec *Exec `json:"exec,omitempty"`
+
+	// Wasm executes a WebAssembly module within metac to
+	// arrive at desired state
+	Wasm *Wasm `json:"wasm,omitempty"`
+
+	// Record the requests & responses of this hook. These are
+	// recorded only if metac is started with a record sink
+	// i.e. a directory or an in-memory buffer.
+	Record *bool `json:"record,omitempty"`
+}
+
+// Wasm refers to the WebAssembly module that gets executed
+// within metac to arrive at the desired state.
+//
+// Only one of ConfigMapKeyRef, Path or URL may be set.
+type Wasm struct {
+	// Key of a ConfigMap that holds the module as binary data.
+	// Key defaults to hook.wasm
+	ConfigMapKeyRef *ObjectKeyReference `json:"configMapKeyRef,omitempty"`
+
+	// Path of the module in metac's file system
+	Path *string `json:"path,omitempty"`
+
+	// URL from where the module is downloaded
+	URL *string `json:"url,omitempty"`
+
+	// Maximum memory available to the module in units of 64KiB
+	// pages. Defaults to 256 i.e. 16MiB.
+	MaxMemoryPages *int32 `json:"maxMemoryPages,omitempty"`
+
+	// Maximum time the module is allowed to run. Defaults
+	// to 10s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// Exec refers to the local command that gets executed to arrive
+// at the desired state. The hook request is written as JSON to
+// the command's stdin & the hook response is read as JSON from
+// its stdout.
+type Exec struct {
+	// Command to be executed. This is looked up in PATH if
+	// it does not contain a path separator.
+	Command string `json:"command"`
+
+	// Arguments to the command
+	Args []string `json:"args,omitempty"`
+
+	// Environment variables set for the command in addition
+	// to the ones set for metac
+	Env []ExecEnvVar `json:"env,omitempty"`
+
+	// Maximum time the command is allowed to run. Defaults
+	// to 10s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// ExecEnvVar is an environment variable set for an exec hook
+type ExecEnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// Jsonnet refers to the jsonnet snippet that gets evaluated
+// within metac to arrive at the desired state. The hook request
+// is available to the snippet as the top level argument named
+// 'request'.
+//
+// Only one of its fields may be set.
+type Jsonnet struct {
+	// Jsonnet snippet
+	Snippet *string `json:"snippet,omitempty"`
+
+	// Key of a ConfigMap that holds the jsonnet snippet. Key
+	// defaults to hook.jsonnet
+	ConfigMapKeyRef *ObjectKeyReference `json:"configMapKeyRef,omitempty"`
+
+	// Maximum time the snippet is allowed to evaluate. Defaults
+	// to 10s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
 }
 
 // Webhook refers to the logic that gets invoked as
@@ -37,6 +122,143 @@ type Webhook struct {
 
 	Path    *string           `json:"path,omitempty"`
 	Service *ServiceReference `json:"service,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle used to verify the
+	// webhook's server certificate. System trust roots are
+	// used if neither this nor CABundleFrom is set.
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// CABundleFrom refers to a Secret or ConfigMap key that
+	// holds the PEM encoded CA bundle
+	CABundleFrom *WebhookCABundleSource `json:"caBundleFrom,omitempty"`
+
+	// ClientCertificate refers to the certificate & key that
+	// are presented to the webhook i.e. for mutual TLS
+	ClientCertificate *WebhookClientCertificate `json:"clientCertificate,omitempty"`
+
+	// ServerName overrides the server name used to verify
+	// the webhook's server certificate
+	ServerName *string `json:"serverName,omitempty"`
+
+	// Retry lets the webhook be retried within the same sync
+	// if the invocation fails with a transient error. Webhook
+	// is invoked only once if this is not set.
+	Retry *WebhookRetry `json:"retry,omitempty"`
+
+	// Gzip compresses the request body sent to the webhook &
+	// sets the Content-Encoding header to gzip. Webhook should
+	// support gzip encoded requests if this is set. Gzip
+	// encoded responses are supported irrespective of this.
+	Gzip *bool `json:"gzip,omitempty"`
+
+	// Auth sets the credentials against every request so that
+	// the webhook can verify that the caller is metac
+	Auth *WebhookAuth `json:"auth,omitempty"`
+}
+
+// WebhookAuth determines how metac authenticates itself to a
+// webhook. Any combination of its fields may be set.
+type WebhookAuth struct {
+	// Header is a static header whose value is read from a
+	// Secret
+	Header *WebhookAuthHeader `json:"header,omitempty"`
+
+	// ServiceAccountToken sends metac's projected service account
+	// token as a bearer token in the Authorization header
+	ServiceAccountToken *WebhookAuthServiceAccountToken `json:"serviceAccountToken,omitempty"`
+
+	// HMAC signs the request body with a shared key
+	HMAC *WebhookAuthHMAC `json:"hmac,omitempty"`
+}
+
+// WebhookAuthHeader is a header whose value is read from a
+// Secret
+type WebhookAuthHeader struct {
+	// Name of the header. Defaults to Authorization
+	Name string `json:"name,omitempty"`
+
+	// Prefix is prepended to the value e.g. 'Bearer'. A space
+	// separates the prefix from the value.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Key of a Secret that holds the header value. Key defaults
+	// to token
+	SecretKeyRef *ObjectKeyReference `json:"secretKeyRef"`
+}
+
+// WebhookAuthServiceAccountToken refers to the projected service
+// account token of metac. The token file is set via metac's
+// webhook-token-path flag & is read again periodically to pick up
+// the rotated token.
+//
+// NOTE:
+//	Token that metac uses against kube api server is never sent.
+// The projected token should have an audience that is dedicated to
+// webhooks.
+type WebhookAuthServiceAccountToken struct{}
+
+// WebhookAuthHMAC signs the request body with a shared key using
+// HMAC-SHA256. Signature is sent as 'sha256=<hex encoded digest>'.
+type WebhookAuthHMAC struct {
+	// Name of the header that holds the signature. Defaults to
+	// X-Metac-Signature
+	Header string `json:"header,omitempty"`
+
+	// Key of a Secret that holds the shared key. Key defaults
+	// to key
+	SecretKeyRef *ObjectKeyReference `json:"secretKeyRef"`
+}
+
+// WebhookRetry is the retry & backoff policy used to invoke
+// a webhook
+type WebhookRetry struct {
+	// Maximum number of invocations including the first one.
+	// Defaults to 3.
+	MaxAttempts *int32 `json:"maxAttempts,omitempty"`
+
+	// Wait duration before the first retry. This is doubled
+	// after every retry. Defaults to 500ms.
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"`
+
+	// Maximum wait duration between retries. Defaults to 10s.
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+
+	// HTTP status codes that are retried. Defaults to 429,
+	// 502, 503 & 504. Connection errors are always retried.
+	RetryableStatusCodes []int32 `json:"retryableStatusCodes,omitempty"`
+}
+
+// WebhookCABundleSource refers to the source of a CA bundle.
+// Only one of its fields may be set.
+type WebhookCABundleSource struct {
+	// Key of a Secret that holds the CA bundle. Key defaults
+	// to ca.crt
+	SecretKeyRef *ObjectKeyReference `json:"secretKeyRef,omitempty"`
+
+	// Key of a ConfigMap that holds the CA bundle. Key
+	// defaults to ca.crt
+	ConfigMapKeyRef *ObjectKeyReference `json:"configMapKeyRef,omitempty"`
+}
+
+// ObjectKeyReference refers to a key of a Secret or ConfigMap
+type ObjectKeyReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+}
+
+// WebhookClientCertificate refers to the client certificate &
+// key presented to a webhook
+type WebhookClientCertificate struct {
+	// Secret of type kubernetes.io/tls that holds the PEM
+	// encoded certificate & key as tls.crt & tls.key
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+}
+
+// SecretReference refers to a Secret
+type SecretReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
 // Inline refers to the logic that gets invoked as inline
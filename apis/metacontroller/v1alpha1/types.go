@@ -93,8 +93,25 @@ const (
 
 	// ChildUpdateRollingInPlace implies update the existing child resource
 	ChildUpdateRollingInPlace ChildUpdateMethod = "RollingInPlace"
+
+	// ChildUpdateServerSideApply implies create & update the child
+	// resource via kubernetes server side apply
+	ChildUpdateServerSideApply ChildUpdateMethod = "ServerSideApply"
 )
 
+// ServerSideApplyOptions tunes the server side apply of child
+// resources
+type ServerSideApplyOptions struct {
+	// FieldManager is the name of the manager that owns the fields
+	// applied to the child resources. This defaults to a name that
+	// is unique to the controller.
+	FieldManager string `json:"fieldManager,omitempty"`
+
+	// ForceConflicts takes over the ownership of the applied fields
+	// from other managers if set to true. This defaults to true.
+	ForceConflicts *bool `json:"forceConflicts,omitempty"`
+}
+
 type CompositeControllerChildResourceRule struct {
 	ResourceRule   `json:",inline"`
 	UpdateStrategy *CompositeControllerChildUpdateStrategy `json:"updateStrategy,omitempty"`
@@ -103,6 +120,9 @@ type CompositeControllerChildResourceRule struct {
 type CompositeControllerChildUpdateStrategy struct {
 	Method       ChildUpdateMethod       `json:"method,omitempty"`
 	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+
+	// ServerSideApply is used by ServerSideApply method only
+	ServerSideApply *ServerSideApplyOptions `json:"serverSideApply,omitempty"`
 }
 
 type ChildUpdateStatusChecks struct {
@@ -126,6 +146,10 @@ type CompositeControllerHooks struct {
 	Sync     *Hook `json:"sync,omitempty"`
 	Finalize *Hook `json:"finalize,omitempty"`
 
+	// Customize hook returns the resources related to a parent.
+	// These are sent as 'related' in sync & finalize requests.
+	Customize *Hook `json:"customize,omitempty"`
+
 	PreUpdateChild  *Hook `json:"preUpdateChild,omitempty"`
 	PostUpdateChild *Hook `json:"postUpdateChild,omitempty"`
 }
@@ -216,11 +240,18 @@ type DecoratorControllerAttachmentRule struct {
 
 type DecoratorControllerAttachmentUpdateStrategy struct {
 	Method ChildUpdateMethod `json:"method,omitempty"`
+
+	// ServerSideApply is used by ServerSideApply method only
+	ServerSideApply *ServerSideApplyOptions `json:"serverSideApply,omitempty"`
 }
 
 type DecoratorControllerHooks struct {
 	Sync     *Hook `json:"sync,omitempty"`
 	Finalize *Hook `json:"finalize,omitempty"`
+
+	// Customize hook returns the resources related to a parent.
+	// These are sent as 'related' in sync & finalize requests.
+	Customize *Hook `json:"customize,omitempty"`
 }
 
 type DecoratorControllerStatus struct {
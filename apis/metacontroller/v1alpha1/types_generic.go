@@ -121,6 +121,64 @@ type GenericControllerSpec struct {
 	// NOTE:
 	//	This is optional
 	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// SyncHookCache enables caching of sync hook responses. Sync
+	// hook is not invoked if its request is unchanged since its
+	// last successful invocation. The cached response is used
+	// instead.
+	//
+	// NOTE:
+	//	This is optional
+	SyncHookCache *SyncHookCache `json:"syncHookCache,omitempty"`
+
+	// InformerScope restricts the watch & attachments that are listed
+	// & watched from the API server. This limits the memory used by
+	// metac as well as the load on the API server to the resources
+	// that are actually selected.
+	//
+	// NOTE:
+	//	This is optional. Scope set against the watch or an attachment
+	// overrides this scope.
+	InformerScope *InformerScope `json:"informerScope,omitempty"`
+}
+
+// InformerScope restricts the resources that are listed & watched
+// by the informers of a controller
+//
+// NOTE:
+//	Resources outside the scope are not visible to the controller.
+// Hence attachments should not be created outside the scope of the
+// corresponding attachment informer.
+type InformerScope struct {
+	// Namespaces to list & watch the resources from. Resources from
+	// all namespaces are watched if this is empty. This is ignored
+	// for cluster scoped resources.
+	//
+	// NOTE:
+	//	If metac is started with watch-namespaces flag, these namespaces
+	// must be a subset of the flag's namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// LabelSelector is evaluated by the API server while listing &
+	// watching the resources.
+	//
+	// NOTE:
+	//	This is unlike the label selector of a watch or attachment
+	// that is evaluated by metac against the resources that are
+	// already cached.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// SyncHookCache represents the tunables of sync hook response
+// cache
+type SyncHookCache struct {
+	// TTLSeconds is the time in seconds after which a cached
+	// response expires. Sync hook is invoked on the next sync
+	// after expiry even if the request is unchanged.
+	//
+	// NOTE:
+	//	This is optional. Defaults to 300 seconds.
+	TTLSeconds *int32 `json:"ttlSeconds,omitempty"`
 }
 
 // GenericControllerHooks holds the sync as well as finalize hooks
@@ -130,6 +188,10 @@ type GenericControllerHooks struct {
 
 	// Hook that gets invoked during delete reconciliation
 	Finalize *Hook `json:"finalize,omitempty"`
+
+	// Hook that returns the resources related to a watch. These
+	// are sent as 'related' in sync & finalize requests.
+	Customize *Hook `json:"customize,omitempty"`
 }
 
 // GenericControllerResource represent a resource that is understood
@@ -174,6 +236,14 @@ type GenericControllerResource struct {
 	// of labels, annotations, name, namespace, target object,
 	// path & slice values.
 	AdvancedSelector *ResourceSelector `json:"advancedSelector,omitempty"`
+
+	// InformerScope restricts this resource to the ones that are
+	// listed & watched from the API server. This overrides the
+	// informer scope set at the spec.
+	//
+	// NOTE:
+	//	This is optional
+	InformerScope *InformerScope `json:"informerScope,omitempty"`
 }
 
 // GenericControllerAttachment represents a resources that takes
@@ -202,6 +272,32 @@ type GenericControllerAttachmentUpdateStrategy struct {
 	// does a plain override of the observed instance from desired
 	// instance.
 	Patch *bool `json:"patch,omitempty"`
+
+	// MaxUnavailable is the maximum number of attachments of this
+	// kind that can be unavailable during a rolling update. An
+	// attachment is unavailable if it is not observed yet, is
+	// pending deletion or fails the status checks. This defaults
+	// to 1 i.e. attachments are updated one at a time.
+	//
+	// NOTE:
+	//	This is used by RollingInPlace & RollingRecreate methods
+	// only
+	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+
+	// StatusChecks decide if an attachment is available. A rolling
+	// update waits for the updated attachments to be available
+	// before updating any more attachments.
+	//
+	// NOTE:
+	//	This is used by RollingInPlace & RollingRecreate methods
+	// only
+	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+
+	// ServerSideApply tunes the server side apply of attachments
+	//
+	// NOTE:
+	//	This is used by ServerSideApply method only
+	ServerSideApply *ServerSideApplyOptions `json:"serverSideApply,omitempty"`
 }
 
 // GenericControllerStatusPhase represents various execution states
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +genclient:noStatus
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=mctl
+// MapController maps each selected input object i.e. an object
+// that is not owned by the parent to zero or more output objects
+// i.e. children of the parent.
+type MapController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec MapControllerSpec `json:"spec"`
+	// +optional
+	Status MapControllerStatus `json:"status,omitempty"`
+}
+
+// MapControllerSpec is the specifications of MapController
+type MapControllerSpec struct {
+	// ParentResource owns the output objects. Its duck typed
+	// 'spec.selector' field selects the input objects.
+	ParentResource MapControllerParentResourceRule `json:"parentResource"`
+
+	// InputResources are the resources whose objects are mapped
+	// to outputs. These objects are not owned by the parent.
+	InputResources []MapControllerInputResourceRule `json:"inputResources"`
+
+	// OutputResources are the resources of the objects returned
+	// by the map hook. These objects are owned by the parent.
+	OutputResources []MapControllerOutputResourceRule `json:"outputResources,omitempty"`
+
+	Hooks *MapControllerHooks `json:"hooks,omitempty"`
+
+	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+}
+
+// MapControllerParentResourceRule identifies the parent resource
+type MapControllerParentResourceRule struct {
+	ResourceRule `json:",inline"`
+}
+
+// MapControllerInputResourceRule identifies an input resource
+type MapControllerInputResourceRule struct {
+	ResourceRule `json:",inline"`
+}
+
+// MapControllerOutputResourceRule identifies an output resource
+type MapControllerOutputResourceRule struct {
+	ResourceRule   `json:",inline"`
+	UpdateStrategy *MapControllerOutputUpdateStrategy `json:"updateStrategy,omitempty"`
+}
+
+// MapControllerOutputUpdateStrategy determines how the observed
+// outputs are updated to their desired state
+type MapControllerOutputUpdateStrategy struct {
+	Method ChildUpdateMethod `json:"method,omitempty"`
+}
+
+// MapControllerHooks are the hooks supported by MapController
+type MapControllerHooks struct {
+	// Map hook returns the desired outputs of a single input
+	Map *Hook `json:"map,omitempty"`
+
+	// Tombstone hook returns the outputs to be kept when their
+	// input is gone. All such outputs are deleted if this hook
+	// is not set.
+	Tombstone *Hook `json:"tombstone,omitempty"`
+}
+
+// MapControllerStatus is the status of MapController
+type MapControllerStatus struct {
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// +kubebuilder:object:root=true
+// MapControllerList is a list of MapController
+type MapControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []MapController `json:"items"`
+}
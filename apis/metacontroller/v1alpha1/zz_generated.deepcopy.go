@@ -132,6 +132,11 @@ func (in *CompositeControllerChildResourceRule) DeepCopy() *CompositeControllerC
 func (in *CompositeControllerChildUpdateStrategy) DeepCopyInto(out *CompositeControllerChildUpdateStrategy) {
 	*out = *in
 	in.StatusChecks.DeepCopyInto(&out.StatusChecks)
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApplyOptions)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -158,6 +163,11 @@ func (in *CompositeControllerHooks) DeepCopyInto(out *CompositeControllerHooks)
 		*out = new(Hook)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Customize != nil {
+		in, out := &in.Customize, &out.Customize
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PreUpdateChild != nil {
 		in, out := &in.PreUpdateChild, &out.PreUpdateChild
 		*out = new(Hook)
@@ -435,7 +445,7 @@ func (in *DecoratorControllerAttachmentRule) DeepCopyInto(out *DecoratorControll
 	if in.UpdateStrategy != nil {
 		in, out := &in.UpdateStrategy, &out.UpdateStrategy
 		*out = new(DecoratorControllerAttachmentUpdateStrategy)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
@@ -453,6 +463,11 @@ func (in *DecoratorControllerAttachmentRule) DeepCopy() *DecoratorControllerAtta
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DecoratorControllerAttachmentUpdateStrategy) DeepCopyInto(out *DecoratorControllerAttachmentUpdateStrategy) {
 	*out = *in
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApplyOptions)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -479,6 +494,11 @@ func (in *DecoratorControllerHooks) DeepCopyInto(out *DecoratorControllerHooks)
 		*out = new(Hook)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Customize != nil {
+		in, out := &in.Customize, &out.Customize
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -608,6 +628,53 @@ func (in *DecoratorControllerStatus) DeepCopy() *DecoratorControllerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Exec) DeepCopyInto(out *Exec) {
+	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]ExecEnvVar, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Exec.
+func (in *Exec) DeepCopy() *Exec {
+	if in == nil {
+		return nil
+	}
+	out := new(Exec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecEnvVar) DeepCopyInto(out *ExecEnvVar) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecEnvVar.
+func (in *ExecEnvVar) DeepCopy() *ExecEnvVar {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecEnvVar)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GenericController) DeepCopyInto(out *GenericController) {
 	*out = *in
@@ -666,6 +733,17 @@ func (in *GenericControllerAttachmentUpdateStrategy) DeepCopyInto(out *GenericCo
 		*out = new(bool)
 		**out = **in
 	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(int32)
+		**out = **in
+	}
+	in.StatusChecks.DeepCopyInto(&out.StatusChecks)
+	if in.ServerSideApply != nil {
+		in, out := &in.ServerSideApply, &out.ServerSideApply
+		*out = new(ServerSideApplyOptions)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -722,6 +800,11 @@ func (in *GenericControllerHooks) DeepCopyInto(out *GenericControllerHooks) {
 		*out = new(Hook)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Customize != nil {
+		in, out := &in.Customize, &out.Customize
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -792,6 +875,11 @@ func (in *GenericControllerResource) DeepCopyInto(out *GenericControllerResource
 		*out = new(ResourceSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.InformerScope != nil {
+		in, out := &in.InformerScope, &out.InformerScope
+		*out = new(InformerScope)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -848,6 +936,16 @@ func (in *GenericControllerSpec) DeepCopyInto(out *GenericControllerSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.SyncHookCache != nil {
+		in, out := &in.SyncHookCache, &out.SyncHookCache
+		*out = new(SyncHookCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InformerScope != nil {
+		in, out := &in.InformerScope, &out.InformerScope
+		*out = new(InformerScope)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -897,6 +995,26 @@ func (in *Hook) DeepCopyInto(out *Hook) {
 		*out = new(Inline)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Jsonnet != nil {
+		in, out := &in.Jsonnet, &out.Jsonnet
+		*out = new(Jsonnet)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(Exec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Wasm != nil {
+		in, out := &in.Wasm, &out.Wasm
+		*out = new(Wasm)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Record != nil {
+		in, out := &in.Record, &out.Record
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
@@ -910,6 +1028,32 @@ func (in *Hook) DeepCopy() *Hook {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InformerScope) DeepCopyInto(out *InformerScope) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InformerScope.
+func (in *InformerScope) DeepCopy() *InformerScope {
+	if in == nil {
+		return nil
+	}
+	out := new(InformerScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Inline) DeepCopyInto(out *Inline) {
 	*out = *in
@@ -931,6 +1075,251 @@ func (in *Inline) DeepCopy() *Inline {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Jsonnet) DeepCopyInto(out *Jsonnet) {
+	*out = *in
+	if in.Snippet != nil {
+		in, out := &in.Snippet, &out.Snippet
+		*out = new(string)
+		**out = **in
+	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ObjectKeyReference)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Jsonnet.
+func (in *Jsonnet) DeepCopy() *Jsonnet {
+	if in == nil {
+		return nil
+	}
+	out := new(Jsonnet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapController) DeepCopyInto(out *MapController) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapController.
+func (in *MapController) DeepCopy() *MapController {
+	if in == nil {
+		return nil
+	}
+	out := new(MapController)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MapController) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerHooks) DeepCopyInto(out *MapControllerHooks) {
+	*out = *in
+	if in.Map != nil {
+		in, out := &in.Map, &out.Map
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tombstone != nil {
+		in, out := &in.Tombstone, &out.Tombstone
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerHooks.
+func (in *MapControllerHooks) DeepCopy() *MapControllerHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerInputResourceRule) DeepCopyInto(out *MapControllerInputResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerInputResourceRule.
+func (in *MapControllerInputResourceRule) DeepCopy() *MapControllerInputResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerInputResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerList) DeepCopyInto(out *MapControllerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MapController, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerList.
+func (in *MapControllerList) DeepCopy() *MapControllerList {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MapControllerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerOutputResourceRule) DeepCopyInto(out *MapControllerOutputResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(MapControllerOutputUpdateStrategy)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerOutputResourceRule.
+func (in *MapControllerOutputResourceRule) DeepCopy() *MapControllerOutputResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerOutputResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerOutputUpdateStrategy) DeepCopyInto(out *MapControllerOutputUpdateStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerOutputUpdateStrategy.
+func (in *MapControllerOutputUpdateStrategy) DeepCopy() *MapControllerOutputUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerOutputUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerParentResourceRule) DeepCopyInto(out *MapControllerParentResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerParentResourceRule.
+func (in *MapControllerParentResourceRule) DeepCopy() *MapControllerParentResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerParentResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerSpec) DeepCopyInto(out *MapControllerSpec) {
+	*out = *in
+	out.ParentResource = in.ParentResource
+	if in.InputResources != nil {
+		in, out := &in.InputResources, &out.InputResources
+		*out = make([]MapControllerInputResourceRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.OutputResources != nil {
+		in, out := &in.OutputResources, &out.OutputResources
+		*out = make([]MapControllerOutputResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(MapControllerHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncPeriodSeconds != nil {
+		in, out := &in.ResyncPeriodSeconds, &out.ResyncPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerSpec.
+func (in *MapControllerSpec) DeepCopy() *MapControllerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MapControllerStatus) DeepCopyInto(out *MapControllerStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MapControllerStatus.
+func (in *MapControllerStatus) DeepCopy() *MapControllerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MapControllerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in NameSelector) DeepCopyInto(out *NameSelector) {
 	{
@@ -951,6 +1340,22 @@ func (in NameSelector) DeepCopy() NameSelector {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectKeyReference) DeepCopyInto(out *ObjectKeyReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectKeyReference.
+func (in *ObjectKeyReference) DeepCopy() *ObjectKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReferenceSelectorRequirement) DeepCopyInto(out *ReferenceSelectorRequirement) {
 	*out = *in
@@ -1010,6 +1415,22 @@ func (in *ResourceSelector) DeepCopy() *ResourceSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SelectorTerm) DeepCopyInto(out *SelectorTerm) {
 	*out = *in
@@ -1100,6 +1521,27 @@ func (in *SelectorTerm) DeepCopy() *SelectorTerm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerSideApplyOptions) DeepCopyInto(out *ServerSideApplyOptions) {
+	*out = *in
+	if in.ForceConflicts != nil {
+		in, out := &in.ForceConflicts, &out.ForceConflicts
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerSideApplyOptions.
+func (in *ServerSideApplyOptions) DeepCopy() *ServerSideApplyOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ServerSideApplyOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceReference) DeepCopyInto(out *ServiceReference) {
 	*out = *in
@@ -1173,6 +1615,68 @@ func (in *StatusConditionCheck) DeepCopy() *StatusConditionCheck {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncHookCache) DeepCopyInto(out *SyncHookCache) {
+	*out = *in
+	if in.TTLSeconds != nil {
+		in, out := &in.TTLSeconds, &out.TTLSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncHookCache.
+func (in *SyncHookCache) DeepCopy() *SyncHookCache {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncHookCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Wasm) DeepCopyInto(out *Wasm) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ObjectKeyReference)
+		**out = **in
+	}
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = new(string)
+		**out = **in
+	}
+	if in.URL != nil {
+		in, out := &in.URL, &out.URL
+		*out = new(string)
+		**out = **in
+	}
+	if in.MaxMemoryPages != nil {
+		in, out := &in.MaxMemoryPages, &out.MaxMemoryPages
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Wasm.
+func (in *Wasm) DeepCopy() *Wasm {
+	if in == nil {
+		return nil
+	}
+	out := new(Wasm)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Webhook) DeepCopyInto(out *Webhook) {
 	*out = *in
@@ -1196,6 +1700,41 @@ func (in *Webhook) DeepCopyInto(out *Webhook) {
 		*out = new(ServiceReference)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.CABundleFrom != nil {
+		in, out := &in.CABundleFrom, &out.CABundleFrom
+		*out = new(WebhookCABundleSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientCertificate != nil {
+		in, out := &in.ClientCertificate, &out.ClientCertificate
+		*out = new(WebhookClientCertificate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServerName != nil {
+		in, out := &in.ServerName, &out.ServerName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(WebhookRetry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Gzip != nil {
+		in, out := &in.Gzip, &out.Gzip
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(WebhookAuth)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1208,3 +1747,175 @@ func (in *Webhook) DeepCopy() *Webhook {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAuth) DeepCopyInto(out *WebhookAuth) {
+	*out = *in
+	if in.Header != nil {
+		in, out := &in.Header, &out.Header
+		*out = new(WebhookAuthHeader)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccountToken != nil {
+		in, out := &in.ServiceAccountToken, &out.ServiceAccountToken
+		*out = new(WebhookAuthServiceAccountToken)
+		**out = **in
+	}
+	if in.HMAC != nil {
+		in, out := &in.HMAC, &out.HMAC
+		*out = new(WebhookAuthHMAC)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAuth.
+func (in *WebhookAuth) DeepCopy() *WebhookAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAuthHMAC) DeepCopyInto(out *WebhookAuthHMAC) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(ObjectKeyReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAuthHMAC.
+func (in *WebhookAuthHMAC) DeepCopy() *WebhookAuthHMAC {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAuthHMAC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAuthHeader) DeepCopyInto(out *WebhookAuthHeader) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(ObjectKeyReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAuthHeader.
+func (in *WebhookAuthHeader) DeepCopy() *WebhookAuthHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAuthHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookAuthServiceAccountToken) DeepCopyInto(out *WebhookAuthServiceAccountToken) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookAuthServiceAccountToken.
+func (in *WebhookAuthServiceAccountToken) DeepCopy() *WebhookAuthServiceAccountToken {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookAuthServiceAccountToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookCABundleSource) DeepCopyInto(out *WebhookCABundleSource) {
+	*out = *in
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(ObjectKeyReference)
+		**out = **in
+	}
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ObjectKeyReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookCABundleSource.
+func (in *WebhookCABundleSource) DeepCopy() *WebhookCABundleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookCABundleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookClientCertificate) DeepCopyInto(out *WebhookClientCertificate) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookClientCertificate.
+func (in *WebhookClientCertificate) DeepCopy() *WebhookClientCertificate {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookClientCertificate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookRetry) DeepCopyInto(out *WebhookRetry) {
+	*out = *in
+	if in.MaxAttempts != nil {
+		in, out := &in.MaxAttempts, &out.MaxAttempts
+		*out = new(int32)
+		**out = **in
+	}
+	if in.InitialBackoff != nil {
+		in, out := &in.InitialBackoff, &out.InitialBackoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxBackoff != nil {
+		in, out := &in.MaxBackoff, &out.MaxBackoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RetryableStatusCodes != nil {
+		in, out := &in.RetryableStatusCodes, &out.RetryableStatusCodes
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookRetry.
+func (in *WebhookRetry) DeepCopy() *WebhookRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookRetry)
+	in.DeepCopyInto(out)
+	return out
+}
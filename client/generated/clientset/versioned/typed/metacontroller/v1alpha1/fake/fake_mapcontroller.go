@@ -0,0 +1,120 @@
+/*
+Copyright 2019 The MayaData Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+	v1alpha1 "openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+// FakeMapControllers implements MapControllerInterface
+type FakeMapControllers struct {
+	Fake *FakeMetacontrollerV1alpha1
+}
+
+var mapcontrollersResource = schema.GroupVersionResource{Group: "metacontroller", Version: "v1alpha1", Resource: "mapcontrollers"}
+
+var mapcontrollersKind = schema.GroupVersionKind{Group: "metacontroller", Version: "v1alpha1", Kind: "MapController"}
+
+// Get takes name of the mapController, and returns the corresponding mapController object, and an error if there is any.
+func (c *FakeMapControllers) Get(name string, options v1.GetOptions) (result *v1alpha1.MapController, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(mapcontrollersResource, name), &v1alpha1.MapController{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MapController), err
+}
+
+// List takes label and field selectors, and returns the list of MapControllers that match those selectors.
+func (c *FakeMapControllers) List(opts v1.ListOptions) (result *v1alpha1.MapControllerList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(mapcontrollersResource, mapcontrollersKind, opts), &v1alpha1.MapControllerList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.MapControllerList{ListMeta: obj.(*v1alpha1.MapControllerList).ListMeta}
+	for _, item := range obj.(*v1alpha1.MapControllerList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested mapControllers.
+func (c *FakeMapControllers) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(mapcontrollersResource, opts))
+}
+
+// Create takes the representation of a mapController and creates it.  Returns the server's representation of the mapController, and an error, if there is any.
+func (c *FakeMapControllers) Create(mapController *v1alpha1.MapController) (result *v1alpha1.MapController, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(mapcontrollersResource, mapController), &v1alpha1.MapController{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MapController), err
+}
+
+// Update takes the representation of a mapController and updates it. Returns the server's representation of the mapController, and an error, if there is any.
+func (c *FakeMapControllers) Update(mapController *v1alpha1.MapController) (result *v1alpha1.MapController, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(mapcontrollersResource, mapController), &v1alpha1.MapController{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MapController), err
+}
+
+// Delete takes name of the mapController and deletes it. Returns an error if one occurs.
+func (c *FakeMapControllers) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteAction(mapcontrollersResource, name), &v1alpha1.MapController{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeMapControllers) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(mapcontrollersResource, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.MapControllerList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched mapController.
+func (c *FakeMapControllers) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.MapController, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(mapcontrollersResource, name, pt, data, subresources...), &v1alpha1.MapController{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MapController), err
+}
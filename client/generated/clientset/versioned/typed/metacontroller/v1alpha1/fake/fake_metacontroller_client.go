@@ -44,6 +44,10 @@ func (c *FakeMetacontrollerV1alpha1) GenericControllers(namespace string) v1alph
 	return &FakeGenericControllers{c, namespace}
 }
 
+func (c *FakeMetacontrollerV1alpha1) MapControllers() v1alpha1.MapControllerInterface {
+	return &FakeMapControllers{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeMetacontrollerV1alpha1) RESTClient() rest.Interface {
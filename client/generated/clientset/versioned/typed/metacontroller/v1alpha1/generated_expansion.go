@@ -25,3 +25,5 @@ type ControllerRevisionExpansion interface{}
 type DecoratorControllerExpansion interface{}
 
 type GenericControllerExpansion interface{}
+
+type MapControllerExpansion interface{}
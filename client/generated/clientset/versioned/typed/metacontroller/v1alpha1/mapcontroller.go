@@ -0,0 +1,164 @@
+/*
+Copyright 2019 The MayaData Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+	v1alpha1 "openebs.io/metac/apis/metacontroller/v1alpha1"
+	scheme "openebs.io/metac/client/generated/clientset/versioned/scheme"
+)
+
+// MapControllersGetter has a method to return a MapControllerInterface.
+// A group's client should implement this interface.
+type MapControllersGetter interface {
+	MapControllers() MapControllerInterface
+}
+
+// MapControllerInterface has methods to work with MapController resources.
+type MapControllerInterface interface {
+	Create(*v1alpha1.MapController) (*v1alpha1.MapController, error)
+	Update(*v1alpha1.MapController) (*v1alpha1.MapController, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.MapController, error)
+	List(opts v1.ListOptions) (*v1alpha1.MapControllerList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.MapController, err error)
+	MapControllerExpansion
+}
+
+// mapControllers implements MapControllerInterface
+type mapControllers struct {
+	client rest.Interface
+}
+
+// newMapControllers returns a MapControllers
+func newMapControllers(c *MetacontrollerV1alpha1Client) *mapControllers {
+	return &mapControllers{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the mapController, and returns the corresponding mapController object, and an error if there is any.
+func (c *mapControllers) Get(name string, options v1.GetOptions) (result *v1alpha1.MapController, err error) {
+	result = &v1alpha1.MapController{}
+	err = c.client.Get().
+		Resource("mapcontrollers").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of MapControllers that match those selectors.
+func (c *mapControllers) List(opts v1.ListOptions) (result *v1alpha1.MapControllerList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.MapControllerList{}
+	err = c.client.Get().
+		Resource("mapcontrollers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested mapControllers.
+func (c *mapControllers) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("mapcontrollers").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a mapController and creates it.  Returns the server's representation of the mapController, and an error, if there is any.
+func (c *mapControllers) Create(mapController *v1alpha1.MapController) (result *v1alpha1.MapController, err error) {
+	result = &v1alpha1.MapController{}
+	err = c.client.Post().
+		Resource("mapcontrollers").
+		Body(mapController).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a mapController and updates it. Returns the server's representation of the mapController, and an error, if there is any.
+func (c *mapControllers) Update(mapController *v1alpha1.MapController) (result *v1alpha1.MapController, err error) {
+	result = &v1alpha1.MapController{}
+	err = c.client.Put().
+		Resource("mapcontrollers").
+		Name(mapController.Name).
+		Body(mapController).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the mapController and deletes it. Returns an error if one occurs.
+func (c *mapControllers) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("mapcontrollers").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *mapControllers) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("mapcontrollers").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched mapController.
+func (c *mapControllers) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.MapController, err error) {
+	result = &v1alpha1.MapController{}
+	err = c.client.Patch(pt).
+		Resource("mapcontrollers").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -30,6 +30,7 @@ type MetacontrollerV1alpha1Interface interface {
 	ControllerRevisionsGetter
 	DecoratorControllersGetter
 	GenericControllersGetter
+	MapControllersGetter
 }
 
 // MetacontrollerV1alpha1Client is used to interact with features provided by the metacontroller group.
@@ -53,6 +54,10 @@ func (c *MetacontrollerV1alpha1Client) GenericControllers(namespace string) Gene
 	return newGenericControllers(c, namespace)
 }
 
+func (c *MetacontrollerV1alpha1Client) MapControllers() MapControllerInterface {
+	return newMapControllers(c)
+}
+
 // NewForConfig creates a new MetacontrollerV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*MetacontrollerV1alpha1Client, error) {
 	config := *c
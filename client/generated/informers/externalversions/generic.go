@@ -61,6 +61,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Metacontroller().V1alpha1().DecoratorControllers().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("genericcontrollers"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Metacontroller().V1alpha1().GenericControllers().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("mapcontrollers"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Metacontroller().V1alpha1().MapControllers().Informer()}, nil
 
 	}
 
@@ -32,6 +32,8 @@ type Interface interface {
 	DecoratorControllers() DecoratorControllerInformer
 	// GenericControllers returns a GenericControllerInformer.
 	GenericControllers() GenericControllerInformer
+	// MapControllers returns a MapControllerInformer.
+	MapControllers() MapControllerInformer
 }
 
 type version struct {
@@ -64,3 +66,8 @@ func (v *version) DecoratorControllers() DecoratorControllerInformer {
 func (v *version) GenericControllers() GenericControllerInformer {
 	return &genericControllerInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// MapControllers returns a MapControllerInformer.
+func (v *version) MapControllers() MapControllerInformer {
+	return &mapControllerInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
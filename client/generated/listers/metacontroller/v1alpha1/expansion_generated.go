@@ -41,3 +41,7 @@ type GenericControllerListerExpansion interface{}
 // GenericControllerNamespaceListerExpansion allows custom methods to be added to
 // GenericControllerNamespaceLister.
 type GenericControllerNamespaceListerExpansion interface{}
+
+// MapControllerListerExpansion allows custom methods to be added to
+// MapControllerLister.
+type MapControllerListerExpansion interface{}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The MayaData Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	v1alpha1 "openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+// MapControllerLister helps list MapControllers.
+type MapControllerLister interface {
+	// List lists all MapControllers in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.MapController, err error)
+	// Get retrieves the MapController from the index for a given name.
+	Get(name string) (*v1alpha1.MapController, error)
+	MapControllerListerExpansion
+}
+
+// mapControllerLister implements the MapControllerLister interface.
+type mapControllerLister struct {
+	indexer cache.Indexer
+}
+
+// NewMapControllerLister returns a new MapControllerLister.
+func NewMapControllerLister(indexer cache.Indexer) MapControllerLister {
+	return &mapControllerLister{indexer: indexer}
+}
+
+// List lists all MapControllers in the indexer.
+func (s *mapControllerLister) List(selector labels.Selector) (ret []*v1alpha1.MapController, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.MapController))
+	})
+	return ret, err
+}
+
+// Get retrieves the MapController from the index for a given name.
+func (s *mapControllerLister) Get(name string) (*v1alpha1.MapController, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("mapcontroller"), name)
+	}
+	return obj.(*v1alpha1.MapController), nil
+}
@@ -53,6 +53,66 @@ func (mc MetacConfigs) ListGenericControllers() ([]*v1alpha1.GenericController,
 	return gctls, nil
 }
 
+// ListCompositeControllers returns all CompositeController configs
+func (mc MetacConfigs) ListCompositeControllers() ([]*v1alpha1.CompositeController, error) {
+	var cctls []*v1alpha1.CompositeController
+	for _, u := range mc {
+		if u.GetKind() != "CompositeController" {
+			continue
+		}
+		raw, err := u.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		cctl := v1alpha1.CompositeController{}
+		if err := json.Unmarshal(raw, &cctl); err != nil {
+			return nil, err
+		}
+		cctls = append(cctls, &cctl)
+	}
+	return cctls, nil
+}
+
+// ListDecoratorControllers returns all DecoratorController configs
+func (mc MetacConfigs) ListDecoratorControllers() ([]*v1alpha1.DecoratorController, error) {
+	var dctls []*v1alpha1.DecoratorController
+	for _, u := range mc {
+		if u.GetKind() != "DecoratorController" {
+			continue
+		}
+		raw, err := u.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		dctl := v1alpha1.DecoratorController{}
+		if err := json.Unmarshal(raw, &dctl); err != nil {
+			return nil, err
+		}
+		dctls = append(dctls, &dctl)
+	}
+	return dctls, nil
+}
+
+// ListMapControllers returns all MapController configs
+func (mc MetacConfigs) ListMapControllers() ([]*v1alpha1.MapController, error) {
+	var mctls []*v1alpha1.MapController
+	for _, u := range mc {
+		if u.GetKind() != "MapController" {
+			continue
+		}
+		raw, err := u.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		mctl := v1alpha1.MapController{}
+		if err := json.Unmarshal(raw, &mctl); err != nil {
+			return nil, err
+		}
+		mctls = append(mctls, &mctl)
+	}
+	return mctls, nil
+}
+
 // Config is the path to metac's Config files
 type Config struct {
 	Path string
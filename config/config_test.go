@@ -30,8 +30,8 @@ func TestConfigLoad(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Expected no error: Got %v", err)
 	}
-	if len(mConfigs) != 3 {
-		t.Fatalf("Expected metac config count 3: Got %d", len(mConfigs))
+	if len(mConfigs) != 4 {
+		t.Fatalf("Expected metac config count 4: Got %d", len(mConfigs))
 	}
 	gctls, err := mConfigs.ListGenericControllers()
 	if err != nil {
@@ -40,6 +40,39 @@ func TestConfigLoad(t *testing.T) {
 	if len(gctls) != 1 {
 		t.Fatalf("Expected gctl count 1: Got %d", len(gctls))
 	}
+	cctls, err := mConfigs.ListCompositeControllers()
+	if err != nil {
+		t.Fatalf("Expected no error while listing cctls: Got %v", err)
+	}
+	if len(cctls) != 1 {
+		t.Fatalf("Expected cctl count 1: Got %d", len(cctls))
+	}
+	if cctls[0].Spec.ParentResource.Resource != "bluegreendeployments" {
+		t.Fatalf(
+			"Expected cctl parent 'bluegreendeployments': Got %q",
+			cctls[0].Spec.ParentResource.Resource,
+		)
+	}
+	dctls, err := mConfigs.ListDecoratorControllers()
+	if err != nil {
+		t.Fatalf("Expected no error while listing dctls: Got %v", err)
+	}
+	if len(dctls) != 1 {
+		t.Fatalf("Expected dctl count 1: Got %d", len(dctls))
+	}
+	if len(dctls[0].Spec.Attachments) != 1 {
+		t.Fatalf("Expected dctl attachment count 1: Got %d", len(dctls[0].Spec.Attachments))
+	}
+	mctls, err := mConfigs.ListMapControllers()
+	if err != nil {
+		t.Fatalf("Expected no error while listing mctls: Got %v", err)
+	}
+	if len(mctls) != 1 {
+		t.Fatalf("Expected mctl count 1: Got %d", len(mctls))
+	}
+	if len(mctls[0].Spec.InputResources) != 1 {
+		t.Fatalf("Expected mctl input count 1: Got %d", len(mctls[0].Spec.InputResources))
+	}
 }
 
 func TestMetacConfigsListGeneric(t *testing.T) {
@@ -0,0 +1,368 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	dynamicobject "openebs.io/metac/dynamic/object"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+// ApplyWaveAnnotationKey is the annotation key used to set the
+// wave of a resource. Resources are applied in the ascending order
+// of their waves & deleted in the reverse order. Resources without
+// this annotation belong to wave 0.
+const ApplyWaveAnnotationKey string = "metac.openebs.io/apply-wave"
+
+// applyPhase decides the order in which resources are applied
+type applyPhase struct {
+	// wave is set via the resource's wave annotation
+	wave int
+
+	// rank is set via the built-in rules. Resources that other
+	// resources depend on have a lower rank.
+	rank int
+}
+
+// String implements Stringer interface
+func (p applyPhase) String() string {
+	return fmt.Sprintf("wave %d rank %d", p.wave, p.rank)
+}
+
+func (p applyPhase) less(other applyPhase) bool {
+	if p.wave != other.wave {
+		return p.wave < other.wave
+	}
+	return p.rank < other.rank
+}
+
+// builtInRank returns the rank of the provided api version & kind.
+// Namespaces & CustomResourceDefinitions are applied before other
+// resources of the same wave.
+func builtInRank(apiVersion, kind string) int {
+	group, _ := ParseAPIVersionToGroupVersion(apiVersion)
+	switch {
+	case group == "" && kind == "Namespace":
+		return 0
+	case group == "apiextensions.k8s.io" && kind == "CustomResourceDefinition":
+		return 0
+	default:
+		return 1
+	}
+}
+
+// applyPhaseOf returns the apply phase of the provided resource
+func applyPhaseOf(obj *unstructured.Unstructured) (applyPhase, error) {
+	phase := applyPhase{
+		rank: builtInRank(obj.GetAPIVersion(), obj.GetKind()),
+	}
+	value, found := obj.GetAnnotations()[ApplyWaveAnnotationKey]
+	if !found {
+		return phase, nil
+	}
+	wave, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return phase, errors.Wrapf(
+			err,
+			"Invalid annotation %s: %s",
+			ApplyWaveAnnotationKey,
+			DescObjectAsKey(obj),
+		)
+	}
+	phase.wave = wave
+	return phase, nil
+}
+
+// splitByApplyPhase splits the provided registry by the apply
+// phase of its resources. It returns the phases in ascending
+// order.
+func splitByApplyPhase(
+	registry AnyUnstructRegistry,
+) (map[applyPhase]AnyUnstructRegistry, []applyPhase, error) {
+	var errs []error
+	split := map[applyPhase]AnyUnstructRegistry{}
+	for verkind, objects := range registry {
+		for name, obj := range objects {
+			if obj == nil {
+				continue
+			}
+			phase, err := applyPhaseOf(obj)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if split[phase] == nil {
+				split[phase] = AnyUnstructRegistry{}
+			}
+			if split[phase][verkind] == nil {
+				split[phase][verkind] = map[string]*unstructured.Unstructured{}
+			}
+			split[phase][verkind][name] = obj
+		}
+	}
+	if len(errs) != 0 {
+		return nil, nil, utilerrors.NewAggregate(errs)
+	}
+	var phases []applyPhase
+	for phase := range split {
+		phases = append(phases, phase)
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		return phases[i].less(phases[j])
+	})
+	return split, phases, nil
+}
+
+// checkApplied returns an error if the provided resource is not
+// yet applied i.e. is not ready to be depended upon
+//
+// NOTE:
+//	A resource is applied if it is observed, is not pending deletion
+// & its status conditions if any report it to be ready.
+// CustomResourceDefinitions need to be established & Namespaces
+// need to be active.
+func checkApplied(observed *unstructured.Unstructured) error {
+	if observed == nil {
+		return errors.Errorf("not observed yet")
+	}
+	if observed.GetDeletionTimestamp() != nil {
+		return errors.Errorf("pending deletion")
+	}
+	content := observed.UnstructuredContent()
+	if builtInRank(observed.GetAPIVersion(), observed.GetKind()) == 0 {
+		if observed.GetKind() == "Namespace" {
+			phase := k8s.GetNestedString(content, "status", "phase")
+			if phase != "" && phase != "Active" {
+				return errors.Errorf("phase is %q (want \"Active\")", phase)
+			}
+			return nil
+		}
+		cond := dynamicobject.GetStatusCondition(content, "Established")
+		if cond == nil || cond.Status != "True" {
+			return errors.Errorf("not established yet")
+		}
+		return nil
+	}
+	cond := dynamicobject.GetStatusCondition(content, "Ready")
+	if cond != nil && cond.Status != "True" {
+		return errors.Errorf("%q condition status is %q (want \"True\")", "Ready", cond.Status)
+	}
+	return nil
+}
+
+// isApplyOrdered returns true if the observed & desired resources
+// span more than one apply phase
+func (m *ClusterStatesController) isApplyOrdered() (bool, error) {
+	phases := map[applyPhase]bool{}
+	for _, registry := range []AnyUnstructRegistry{m.Observed, m.Desired} {
+		split, _, err := splitByApplyPhase(registry)
+		if err != nil {
+			return false, err
+		}
+		for phase := range split {
+			phases[phase] = true
+		}
+	}
+	return len(phases) > 1, nil
+}
+
+// wait records the provided reason due to which applying the
+// remaining resources is deferred
+func (m *ClusterStatesController) wait(reason string) {
+	glog.V(4).Infof("Will wait: %s: %s", reason, m)
+	m.waiting = append(m.waiting, reason)
+}
+
+// Waiting returns the reason due to which Apply deferred applying
+// some of the resources. It returns an empty string if all the
+// resources were applied.
+//
+// NOTE:
+//	Apply needs to be invoked again, once the resources applied
+// so far are observed & ready, to apply the remaining resources.
+func (m *ClusterStatesController) Waiting() string {
+	return strings.Join(m.waiting, "; ")
+}
+
+// isDeleteCandidate returns true if the provided observed resource
+// will be deleted since it is no longer desired
+func (m *ClusterStatesController) isDeleteCandidate(
+	verkind string,
+	name string,
+	obj *unstructured.Unstructured,
+) bool {
+	if m.Desired[verkind][name] != nil {
+		return false
+	}
+	if m.DeleteAny != nil && *m.DeleteAny {
+		return true
+	}
+	return obj.GetAnnotations()[AttachmentCreateAnnotationKey] ==
+		string(m.Watch.GetUID())
+}
+
+// deleteInOrder deletes the resources that are no longer desired
+// in the descending order of their apply phases. Resources of a
+// phase are deleted only after the resources of later phases are
+// gone.
+func (m *ClusterStatesController) deleteInOrder() error {
+	split, phases, err := splitByApplyPhase(m.Observed)
+	if err != nil {
+		return err
+	}
+	// phases with resources to be deleted in descending order
+	var deletePhases []applyPhase
+	candidates := map[applyPhase][]string{}
+	for i := len(phases) - 1; i >= 0; i-- {
+		phase := phases[i]
+		for verkind, objects := range split[phase] {
+			for name, obj := range objects {
+				if m.isDeleteCandidate(verkind, name, obj) {
+					candidates[phase] = append(candidates[phase], DescObjectAsKey(obj))
+				}
+			}
+		}
+		if len(candidates[phase]) != 0 {
+			deletePhases = append(deletePhases, phase)
+		}
+	}
+	if len(deletePhases) == 0 {
+		return nil
+	}
+	phase := deletePhases[0]
+	phaseCtrl := &ClusterStatesController{
+		ClusterStatesControllerBase: m.ClusterStatesControllerBase,
+		DynamicClientSet:            m.DynamicClientSet,
+		Observed:                    split[phase],
+		Desired:                     m.Desired,
+	}
+	phaseCtrl.initDeleter()
+	if len(phaseCtrl.errs) != 0 {
+		return utilerrors.NewAggregate(phaseCtrl.errs)
+	}
+	err = phaseCtrl.DeleteFn()
+	if len(deletePhases) > 1 {
+		// resources of earlier phases are deleted once these
+		// resources are gone
+		sort.Strings(candidates[phase])
+		m.wait(fmt.Sprintf(
+			"Deleting %s before %s: %s",
+			phase,
+			deletePhases[1],
+			strings.Join(candidates[phase], ", "),
+		))
+	}
+	return err
+}
+
+// createOrUpdateInOrder creates or updates the desired resources
+// in the ascending order of their apply phases. Resources of a
+// phase are applied only after the resources of earlier phases
+// are observed to be ready.
+func (m *ClusterStatesController) createOrUpdateInOrder() error {
+	split, phases, err := splitByApplyPhase(m.Desired)
+	if err != nil {
+		return err
+	}
+	for i, phase := range phases {
+		phaseCtrl := &ClusterStatesController{
+			ClusterStatesControllerBase: m.ClusterStatesControllerBase,
+			DynamicClientSet:            m.DynamicClientSet,
+			Observed:                    m.Observed,
+			Desired:                     split[phase],
+		}
+		phaseCtrl.initCreateUpdater()
+		if len(phaseCtrl.errs) != 0 {
+			if i == 0 {
+				return utilerrors.NewAggregate(phaseCtrl.errs)
+			}
+			// resources of this phase may be defined by the
+			// custom resource definitions of earlier phases
+			m.wait(fmt.Sprintf(
+				"Discovering resources of %s: %s",
+				phase,
+				utilerrors.NewAggregate(phaseCtrl.errs),
+			))
+			return nil
+		}
+		err := phaseCtrl.CreateOrUpdateFn()
+		if err != nil {
+			return err
+		}
+		if i == len(phases)-1 {
+			return nil
+		}
+		// check if resources of this phase are ready to be
+		// depended upon by the resources of next phase
+		var pending []string
+		for verkind, objects := range split[phase] {
+			for name, obj := range objects {
+				if err := checkApplied(m.Observed[verkind][name]); err != nil {
+					pending = append(
+						pending,
+						fmt.Sprintf("%s is %v", DescObjectAsKey(obj), err),
+					)
+				}
+			}
+		}
+		if len(pending) != 0 {
+			sort.Strings(pending)
+			m.wait(fmt.Sprintf(
+				"Applying %s before %s: %s",
+				phase,
+				phases[i+1],
+				strings.Join(pending, ", "),
+			))
+			return nil
+		}
+	}
+	return nil
+}
+
+// applyInOrder applies the resources in the order of their apply
+// phases
+//
+// NOTE:
+//	Explicit updates & explicit deletes are not ordered
+func (m *ClusterStatesController) applyInOrder() error {
+	if m.ExplicitDeleteFn == nil {
+		m.initExplicitDeleter()
+	}
+	if m.ExplicitUpdateFn == nil {
+		m.initExplicitUpdater()
+	}
+	if len(m.errs) != 0 {
+		return utilerrors.NewAggregate(m.errs)
+	}
+	m.errs = append(
+		m.errs,
+		m.deleteInOrder(),
+		m.createOrUpdateInOrder(),
+		m.ExplicitUpdateFn(),
+		m.ExplicitDeleteFn(),
+	)
+	return utilerrors.NewAggregate(m.errs)
+}
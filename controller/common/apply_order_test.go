@@ -0,0 +1,477 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	"openebs.io/metac/third_party/kubernetes"
+)
+
+func newApplyOrderTestObj(apiVersion, kind, name, wave string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	if wave != "" {
+		obj.SetAnnotations(map[string]string{ApplyWaveAnnotationKey: wave})
+	}
+	return obj
+}
+
+func TestSplitByApplyPhase(t *testing.T) {
+	var tests = map[string]struct {
+		objs         []*unstructured.Unstructured
+		expectPhases string
+		isErr        bool
+	}{
+		"no objects": {
+			expectPhases: "[]",
+		},
+		"same phase": {
+			objs: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+				newApplyOrderTestObj("apps/v1", "Deployment", "deploy", ""),
+			},
+			expectPhases: "[wave 0 rank 1]",
+		},
+		"namespace & crd first": {
+			objs: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+				newApplyOrderTestObj("v1", "Namespace", "ns", ""),
+				newApplyOrderTestObj("apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "crd", ""),
+			},
+			expectPhases: "[wave 0 rank 0 wave 0 rank 1]",
+		},
+		"waves win over built in rules": {
+			objs: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", "-1"),
+				newApplyOrderTestObj("v1", "Namespace", "ns", ""),
+				newApplyOrderTestObj("example.io/v1", "Foo", "foo", "2"),
+			},
+			expectPhases: "[wave -1 rank 1 wave 0 rank 0 wave 2 rank 1]",
+		},
+		"invalid wave": {
+			objs: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", "first"),
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			split, phases, err := splitByApplyPhase(MakeAnyUnstructRegistry(mock.objs))
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if fmt.Sprint(phases) != mock.expectPhases {
+				t.Fatalf("Expected phases %s got %v", mock.expectPhases, phases)
+			}
+			var count int
+			for _, phase := range phases {
+				count += split[phase].Len()
+			}
+			if count != len(mock.objs) {
+				t.Fatalf("Expected %d objects got %d", len(mock.objs), count)
+			}
+		})
+	}
+}
+
+func TestIsApplyOrdered(t *testing.T) {
+	var tests = map[string]struct {
+		observed  []*unstructured.Unstructured
+		desired   []*unstructured.Unstructured
+		isOrdered bool
+	}{
+		"single phase": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+			},
+			desired: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+				newApplyOrderTestObj("v1", "Secret", "secret", "0"),
+			},
+		},
+		"desired with namespace": {
+			desired: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "Namespace", "ns", ""),
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+			},
+			isOrdered: true,
+		},
+		"observed in another wave": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "old", "1"),
+			},
+			desired: []*unstructured.Unstructured{
+				newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+			},
+			isOrdered: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ctrl := &ClusterStatesController{
+				Observed: MakeAnyUnstructRegistry(mock.observed),
+				Desired:  MakeAnyUnstructRegistry(mock.desired),
+			}
+			got, err := ctrl.isApplyOrdered()
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if got != mock.isOrdered {
+				t.Fatalf("Expected ordered %t got %t", mock.isOrdered, got)
+			}
+		})
+	}
+}
+
+func TestCheckApplied(t *testing.T) {
+	withStatus := func(obj *unstructured.Unstructured, status map[string]interface{}) *unstructured.Unstructured {
+		obj.Object["status"] = status
+		return obj
+	}
+	condition := func(ctype, status string) map[string]interface{} {
+		return map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": ctype, "status": status},
+			},
+		}
+	}
+	crd := func() *unstructured.Unstructured {
+		return newApplyOrderTestObj("apiextensions.k8s.io/v1", "CustomResourceDefinition", "crd", "")
+	}
+	var tests = map[string]struct {
+		observed  *unstructured.Unstructured
+		isApplied bool
+	}{
+		"not observed": {},
+		"without status": {
+			observed:  newApplyOrderTestObj("v1", "ConfigMap", "cm", ""),
+			isApplied: true,
+		},
+		"not ready": {
+			observed: withStatus(
+				newApplyOrderTestObj("example.io/v1", "Foo", "foo", ""),
+				condition("Ready", "False"),
+			),
+		},
+		"ready": {
+			observed: withStatus(
+				newApplyOrderTestObj("example.io/v1", "Foo", "foo", ""),
+				condition("Ready", "True"),
+			),
+			isApplied: true,
+		},
+		"crd not established": {
+			observed: crd(),
+		},
+		"crd established": {
+			observed:  withStatus(crd(), condition("Established", "True")),
+			isApplied: true,
+		},
+		"namespace terminating": {
+			observed: withStatus(
+				newApplyOrderTestObj("v1", "Namespace", "ns", ""),
+				map[string]interface{}{"phase": "Terminating"},
+			),
+		},
+		"namespace active": {
+			observed: withStatus(
+				newApplyOrderTestObj("v1", "Namespace", "ns", ""),
+				map[string]interface{}{"phase": "Active"},
+			),
+			isApplied: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			err := checkApplied(mock.observed)
+			if mock.isApplied && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !mock.isApplied && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+		})
+	}
+}
+
+// applyOrderTestServer is a fake API server that records the
+// requests made against it
+type applyOrderTestServer struct {
+	mutex    sync.Mutex
+	requests []string
+}
+
+func (s *applyOrderTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodDelete {
+		w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Success"}`))
+		return
+	}
+	// created & updated objects are echoed back
+	body, _ := ioutil.ReadAll(r.Body)
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+	}
+	w.Write(body)
+}
+
+// newApplyOrderTestController returns a controller whose requests
+// are served by the provided server. Namespaces & config maps are
+// the only discovered resources. The returned function stops the
+// server.
+func newApplyOrderTestController(
+	t *testing.T,
+	srv *applyOrderTestServer,
+	observed []*unstructured.Unstructured,
+	desired []*unstructured.Unstructured,
+) (*ClusterStatesController, func()) {
+	httpSrv := httptest.NewServer(srv)
+
+	discovery := newTestDiscovery(&metav1.APIResourceList{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "namespaces", Kind: "Namespace"},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		},
+	})
+	stop := func() {
+		discovery.Stop()
+		httpSrv.Close()
+	}
+	clientset, err := dynamicclientset.New(&rest.Config{Host: httpSrv.URL}, discovery)
+	if err != nil {
+		stop()
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	watch := &unstructured.Unstructured{}
+	watch.SetAPIVersion("example.io/v1")
+	watch.SetKind("Watch")
+	watch.SetName("watch")
+	watch.SetUID(types.UID("watch-uid"))
+
+	return &ClusterStatesController{
+		ClusterStatesControllerBase: ClusterStatesControllerBase{
+			GetChildUpdateStrategyByGK: func(group, kind string) v1alpha1.ChildUpdateMethod {
+				// observed resources are left as is
+				return v1alpha1.ChildUpdateOnDelete
+			},
+			Watch:        watch,
+			IsWatchOwner: kubernetes.BoolPtr(false),
+		},
+		DynamicClientSet: clientset,
+		Observed:         MakeAnyUnstructRegistry(observed),
+		Desired:          MakeAnyUnstructRegistry(desired),
+	}, stop
+}
+
+// newApplyOrderTestObserved returns the provided object as if it
+// was created by the test controller's watch
+func newApplyOrderTestObserved(
+	obj *unstructured.Unstructured,
+	namespacePhase string,
+) *unstructured.Unstructured {
+	observed := obj.DeepCopy()
+	ann := observed.GetAnnotations()
+	if ann == nil {
+		ann = map[string]string{}
+	}
+	ann[AttachmentCreateAnnotationKey] = "watch-uid"
+	observed.SetAnnotations(ann)
+	if namespacePhase != "" {
+		observed.Object["status"] = map[string]interface{}{
+			"phase": namespacePhase,
+		}
+	}
+	return observed
+}
+
+func TestCreateOrUpdateInOrder(t *testing.T) {
+	ns := newApplyOrderTestObj("v1", "Namespace", "ns", "")
+	cm := newApplyOrderTestObj("v1", "ConfigMap", "cm", "")
+	cm.SetNamespace("ns")
+	foo := newApplyOrderTestObj("example.io/v1", "Foo", "foo", "")
+	foo.SetNamespace("ns")
+	earlyFoo := newApplyOrderTestObj("example.io/v1", "Foo", "foo", "-1")
+	earlyFoo.SetNamespace("ns")
+
+	var tests = map[string]struct {
+		observed       []*unstructured.Unstructured
+		desired        []*unstructured.Unstructured
+		expectRequests []string
+		expectWaiting  string
+		isErr          bool
+	}{
+		"first sync creates namespace only": {
+			desired:        []*unstructured.Unstructured{ns, cm},
+			expectRequests: []string{"POST /api/v1/namespaces"},
+			expectWaiting:  "Applying wave 0 rank 0 before wave 0 rank 1: v1:Namespace:ns is not observed yet",
+		},
+		"terminating namespace blocks its objects": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Terminating"),
+			},
+			desired:       []*unstructured.Unstructured{ns, cm},
+			expectWaiting: "Applying wave 0 rank 0 before wave 0 rank 1",
+		},
+		"active namespace lets its objects be created": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Active"),
+			},
+			desired:        []*unstructured.Unstructured{ns, cm},
+			expectRequests: []string{"POST /api/v1/namespaces/ns/configmaps"},
+		},
+		"undiscovered resources of later phase wait": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Active"),
+			},
+			desired:       []*unstructured.Unstructured{ns, foo},
+			expectWaiting: "Discovering resources of wave 0 rank 1",
+		},
+		"undiscovered resources of first phase fail": {
+			desired: []*unstructured.Unstructured{earlyFoo, cm},
+			isErr:   true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			srv := &applyOrderTestServer{}
+			ctrl, stop := newApplyOrderTestController(t, srv, mock.observed, mock.desired)
+			defer stop()
+			err := ctrl.createOrUpdateInOrder()
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !reflect.DeepEqual(srv.requests, mock.expectRequests) {
+				t.Fatalf(
+					"Expected requests %v got %v", mock.expectRequests, srv.requests,
+				)
+			}
+			got := ctrl.Waiting()
+			if mock.expectWaiting == "" && got != "" {
+				t.Fatalf("Expected no wait got %q", got)
+			}
+			if !strings.HasPrefix(got, mock.expectWaiting) {
+				t.Fatalf("Expected wait %q got %q", mock.expectWaiting, got)
+			}
+		})
+	}
+}
+
+func TestDeleteInOrder(t *testing.T) {
+	ns := newApplyOrderTestObj("v1", "Namespace", "ns", "")
+	cm := newApplyOrderTestObj("v1", "ConfigMap", "cm", "")
+	cm.SetNamespace("ns")
+
+	var tests = map[string]struct {
+		observed       []*unstructured.Unstructured
+		desired        []*unstructured.Unstructured
+		expectRequests []string
+		expectWaiting  string
+	}{
+		"objects are deleted before their namespace": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Active"),
+				newApplyOrderTestObserved(cm, ""),
+			},
+			expectRequests: []string{"DELETE /api/v1/namespaces/ns/configmaps/cm"},
+			expectWaiting:  "Deleting wave 0 rank 1 before wave 0 rank 0: v1:ConfigMap:ns:cm",
+		},
+		"namespace is deleted once its objects are gone": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Active"),
+			},
+			expectRequests: []string{"DELETE /api/v1/namespaces/ns"},
+		},
+		"objects not created by watch don't block": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Active"),
+				cm,
+			},
+			expectRequests: []string{"DELETE /api/v1/namespaces/ns"},
+		},
+		"desired objects are not deleted": {
+			observed: []*unstructured.Unstructured{
+				newApplyOrderTestObserved(ns, "Active"),
+				newApplyOrderTestObserved(cm, ""),
+			},
+			desired:        []*unstructured.Unstructured{ns},
+			expectRequests: []string{"DELETE /api/v1/namespaces/ns/configmaps/cm"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			srv := &applyOrderTestServer{}
+			ctrl, stop := newApplyOrderTestController(t, srv, mock.observed, mock.desired)
+			defer stop()
+			err := ctrl.deleteInOrder()
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !reflect.DeepEqual(srv.requests, mock.expectRequests) {
+				t.Fatalf(
+					"Expected requests %v got %v", mock.expectRequests, srv.requests,
+				)
+			}
+			got := ctrl.Waiting()
+			if got != mock.expectWaiting {
+				t.Fatalf("Expected wait %q got %q", mock.expectWaiting, got)
+			}
+		})
+	}
+}
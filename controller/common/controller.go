@@ -30,6 +30,7 @@ import (
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	dynamicapply "openebs.io/metac/dynamic/apply"
 	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	"openebs.io/metac/metrics"
 	"openebs.io/metac/third_party/kubernetes"
 )
 
@@ -62,6 +63,22 @@ type ClusterStatesControllerBase struct {
 	// versus the default 3-way merge during the update operations
 	IsPatchByGK func(group, kind string) bool
 
+	// GetRollingUpdateStrategyByGK fetches the rolling update
+	// strategy of resources whose update strategy is one of the
+	// rolling methods. Resources are updated one at a time without
+	// any status checks if this is not set.
+	GetRollingUpdateStrategyByGK func(group, kind string) RollingUpdateStrategy
+
+	// RecordRollout if set is invoked with the progress of every
+	// rolling update
+	RecordRollout func(RolloutStatus)
+
+	// GetServerSideApplyOptionsByGK fetches the server side apply
+	// options of resources whose update strategy is ServerSideApply.
+	// Resources are applied by the "metac" field manager with
+	// conflicts forced if this is not set.
+	GetServerSideApplyOptionsByGK func(group, kind string) ServerSideApplyOptions
+
 	// Another resource that is being watched to arrive at some
 	// desired state. A watch might be related to this resource
 	// under operation. For example, a watch might be owner of
@@ -93,6 +110,10 @@ type ClusterStatesControllerBase struct {
 	// update the resource even if this resource is pending
 	// deletion
 	UpdateDuringPendingDelete *bool
+
+	// MetricsController is the controller against which the
+	// create, update & delete operations are recorded
+	MetricsController metrics.Controller
 }
 
 // ClusterStatesController **applies** resources in Kubernetes cluster.
@@ -134,6 +155,10 @@ type ClusterStatesController struct {
 
 	// error as value
 	errs []error
+
+	// reasons due to which applying some of the resources is
+	// deferred
+	waiting []string
 }
 
 // String implements Stringer interface
@@ -375,7 +400,18 @@ func (m *ClusterStatesController) initIfNil() {
 
 // Apply executes create, delete or update operations against
 // the child resources set against this manager instance
+//
+// NOTE:
+//	Resources are applied in the order of their apply phases if
+// they span more than one phase. Refer to ApplyWaveAnnotationKey.
 func (m *ClusterStatesController) Apply() error {
+	isOrdered, err := m.isApplyOrdered()
+	if err != nil {
+		return err
+	}
+	if isOrdered {
+		return m.applyInOrder()
+	}
 	m.initIfNil()
 	if len(m.errs) != 0 {
 		return utilerrors.NewAggregate(m.errs)
@@ -436,6 +472,17 @@ func (e ResourceStatesController) String() string {
 	return strings.Join(strs, " ")
 }
 
+// recordOperation records the outcome of the provided operation
+// executed against this controller's resource type
+func (e ResourceStatesController) recordOperation(op metrics.Operation, err error) {
+	metrics.RecordOperation(
+		e.MetricsController,
+		op,
+		e.DynamicClient.APIVersion+"/"+e.DynamicClient.Kind,
+		err,
+	)
+}
+
 // IsUpdateDuringPendingDelete returns true if update is allowed
 // even if the targeted resource is pending deletion
 func (e ResourceStatesController) IsUpdateDuringPendingDelete() bool {
@@ -453,12 +500,25 @@ func (e *ResourceStatesController) update(
 	observed *unstructured.Unstructured,
 	desired *unstructured.Unstructured,
 ) (bool, error) {
-	// use either desired namespace or watch namespace to update
-	ns := desired.GetNamespace()
-	if ns == "" {
-		ns = e.Watch.GetNamespace()
+	mergedObj, method, err := e.merge(observed, desired)
+	if err != nil || mergedObj == nil {
+		return false, err
+	}
+	err = e.applyMerged(observed, desired, mergedObj, method)
+	if err != nil {
+		return false, err
 	}
+	// this resulted in an actual update
+	return true, nil
+}
 
+// merge returns the result of merging the desired state into the
+// observed state along with the update method to be followed. The
+// merged state is nil if no update is allowed or required.
+func (e *ResourceStatesController) merge(
+	observed *unstructured.Unstructured,
+	desired *unstructured.Unstructured,
+) (*unstructured.Unstructured, v1alpha1.ChildUpdateMethod, error) {
 	// Leave it alone if it's pending deletion && updating during
 	// pending deletion is not enabled
 	if observed.GetDeletionTimestamp() != nil && !e.IsUpdateDuringPendingDelete() {
@@ -467,7 +527,7 @@ func (e *ResourceStatesController) update(
 			DescObjectAsKey(desired),
 			e,
 		)
-		return false, nil
+		return nil, "", nil
 	}
 
 	// if controller has rights to update any attachments
@@ -496,7 +556,7 @@ func (e *ResourceStatesController) update(
 			updateAny,
 			e,
 		)
-		return false, nil
+		return nil, "", nil
 	}
 
 	// Check the update strategy for this child kind
@@ -516,7 +576,25 @@ func (e *ResourceStatesController) update(
 			method,
 			e,
 		)
-		return false, nil
+		return nil, "", nil
+	}
+
+	if method == v1alpha1.ChildUpdateServerSideApply {
+		// server side apply replaces the 3-way merge
+		config, err := e.serverSideApplyConfig(observed, desired)
+		if err != nil {
+			return nil, "", err
+		}
+		_, hasLastApplied := observedAnns[string(e.Watch.GetUID())+GCTLLastAppliedAnnotationKeySuffix]
+		if IsServerSideApplied(observed, config) && !hasLastApplied {
+			glog.V(7).Infof(
+				"Won't update %s: Nothing changed: %s",
+				DescObjectAsKey(desired),
+				e,
+			)
+			return nil, "", nil
+		}
+		return config, method, nil
 	}
 
 	// 3-way merge
@@ -556,7 +634,7 @@ func (e *ResourceStatesController) update(
 			lastAppliedKey,
 		)
 		if err != nil {
-			return false, err
+			return nil, "", err
 		}
 	}
 
@@ -565,14 +643,14 @@ func (e *ResourceStatesController) update(
 	// invoke 3-way merge
 	mergedObj, err := a.Merge(observed, desired)
 	if err != nil {
-		return false, err
+		return nil, "", err
 	}
 
 	// Proceed for update, only if above merge resulted in any
 	// differences between observed state vs. desired state
 	isDiff, err := a.HasMergeDiff()
 	if err != nil {
-		return false, err
+		return nil, "", err
 	}
 	if !isDiff {
 		glog.V(7).Infof(
@@ -580,16 +658,35 @@ func (e *ResourceStatesController) update(
 			DescObjectAsKey(desired),
 			e,
 		)
-		return false, nil
+		return nil, "", nil
 	}
 	glog.V(6).Infof(
 		"Will update %s since observed != desired: %s",
 		DescObjectAsKey(desired),
 		e,
 	)
+	return mergedObj, method, nil
+}
+
+// applyMerged updates the observed state to the provided merged
+// state as per the provided update method
+func (e *ResourceStatesController) applyMerged(
+	observed *unstructured.Unstructured,
+	desired *unstructured.Unstructured,
+	mergedObj *unstructured.Unstructured,
+	method v1alpha1.ChildUpdateMethod,
+) error {
+	// use either desired namespace or watch namespace to update
+	ns := desired.GetNamespace()
+	if ns == "" {
+		ns = e.Watch.GetNamespace()
+	}
 
 	// Act based on the update strategy for this child kind.
 	switch method {
+	case v1alpha1.ChildUpdateServerSideApply:
+		// merged state is the configuration to be applied
+		return e.serverSideApply(observed, mergedObj)
 	case v1alpha1.ChildUpdateRecreate, v1alpha1.ChildUpdateRollingRecreate:
 		// Delete the object (now) and recreate it (on the next sync).
 		glog.V(4).Infof(
@@ -609,8 +706,9 @@ func (e *ResourceStatesController) update(
 				PropagationPolicy: &propagation,
 			},
 		)
+		e.recordOperation(metrics.OperationDelete, err)
 		if err != nil {
-			return false, err
+			return err
 		}
 
 		glog.Infof(
@@ -641,8 +739,9 @@ func (e *ResourceStatesController) update(
 			mergedObj,
 			metav1.UpdateOptions{},
 		)
+		e.recordOperation(metrics.OperationUpdate, err)
 		if err != nil {
-			return false, err
+			return err
 		}
 
 		glog.V(6).Infof(
@@ -651,15 +750,14 @@ func (e *ResourceStatesController) update(
 			e,
 		)
 	default:
-		return false, errors.Errorf(
+		return errors.Errorf(
 			"Invalid update strategy %s: %s: %s",
 			method,
 			DescObjectAsKey(desired),
 			e,
 		)
 	}
-	// this resulted in an actual update
-	return true, nil
+	return nil
 }
 
 // Create creates the desired resource in the kubernetes cluster
@@ -677,6 +775,19 @@ func (e *ResourceStatesController) create(desired *unstructured.Unstructured) er
 		e,
 	)
 
+	method := e.GetChildUpdateStrategyByGK(
+		e.DynamicClient.Group,
+		e.DynamicClient.Kind,
+	)
+	if method == v1alpha1.ChildUpdateServerSideApply {
+		// server side apply does not need the last applied state
+		config, err := e.serverSideApplyConfig(nil, desired)
+		if err != nil {
+			return err
+		}
+		return e.serverSideApply(nil, config)
+	}
+
 	// The controller i.e. sync hook should return a partial attachment
 	// containing only the fields it cares about. We save this partial
 	// attachment so we can do a 3-way merge upon update, in the style
@@ -720,6 +831,7 @@ func (e *ResourceStatesController) create(desired *unstructured.Unstructured) er
 			desired,
 			metav1.CreateOptions{},
 		)
+	e.recordOperation(metrics.OperationCreate, err)
 	if err != nil {
 		return err
 	}
@@ -734,6 +846,14 @@ func (e *ResourceStatesController) create(desired *unstructured.Unstructured) er
 
 // CreateOrUpdate will create or update the resources
 func (e *ResourceStatesController) CreateOrUpdate() error {
+	// resources with rolling update strategies are rolled
+	method := e.GetChildUpdateStrategyByGK(
+		e.DynamicClient.Group,
+		e.DynamicClient.Kind,
+	)
+	if IsRollingUpdate(method) {
+		return e.rollingCreateOrUpdate(method)
+	}
 	var errs []error
 	// map **desired** with its exact **observed**
 	// state to execute either an update or create operation
@@ -866,6 +986,7 @@ func (e *ResourceStatesController) Delete() error {
 					// try delete of other resources that are no longer desired
 					continue
 				}
+				e.recordOperation(metrics.OperationDelete, err)
 				errs = append(
 					errs,
 					errors.Wrapf(
@@ -878,6 +999,7 @@ func (e *ResourceStatesController) Delete() error {
 				// try delete of other resources that are no longer desired
 				continue
 			}
+			e.recordOperation(metrics.OperationDelete, nil)
 			glog.Infof(
 				"Deleted %s: %s",
 				DescObjectAsKey(obj),
@@ -942,6 +1064,7 @@ func (e *ResourceStatesController) ExplicitDelete() error {
 				// try explicit delete of other listed resources
 				continue
 			}
+			e.recordOperation(metrics.OperationDelete, err)
 			errs = append(
 				errs,
 				errors.Wrapf(
@@ -954,6 +1077,7 @@ func (e *ResourceStatesController) ExplicitDelete() error {
 			// try explicit delete of other listed resources
 			continue
 		}
+		e.recordOperation(metrics.OperationDelete, nil)
 		glog.Infof(
 			"Explicitly deleted %s: %s",
 			DescObjectAsKey(obj),
@@ -0,0 +1,487 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customize
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+)
+
+// defaultInformerSyncTimeout is the maximum duration to wait
+// for the informer of a related resource to sync
+const defaultInformerSyncTimeout = 30 * time.Second
+
+// RelatedResourceRule selects the resources that are related
+// to a parent. Related resources need not be owned by the
+// parent.
+type RelatedResourceRule struct {
+	v1alpha1.ResourceRule `json:",inline"`
+
+	// Selects the related resources by their labels. All the
+	// resources are selected if this is not set.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// Namespace to select the related resources from. Defaults
+	// to the parent's namespace if parent is namespaced. Other
+	// namespaces are allowed if the controller's hook scope allows
+	// these.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Names of the related resources to select
+	Names []string `json:"names,omitempty"`
+}
+
+// HookResponse is the expected format of the JSON response
+// from the customize hook
+type HookResponse struct {
+	RelatedResources []*RelatedResourceRule `json:"relatedResources"`
+}
+
+// InvokeFn invokes the customize hook for the provided parent
+type InvokeFn func(parent *unstructured.Unstructured) (*HookResponse, error)
+
+// relatedRule is the evaluated form of RelatedResourceRule
+type relatedRule struct {
+	apiVersion string
+	resource   string
+
+	// namespace to select from; empty implies all namespaces
+	namespace string
+
+	// true if the related resource is namespace scoped
+	isNamespaced bool
+
+	names    map[string]bool
+	selector labels.Selector
+}
+
+// matches returns true if the provided object is selected by
+// this rule
+func (r *relatedRule) matches(obj *unstructured.Unstructured) bool {
+	if r.isNamespaced && r.namespace != "" && obj.GetNamespace() != r.namespace {
+		return false
+	}
+	if len(r.names) != 0 && !r.names[obj.GetName()] {
+		return false
+	}
+	return r.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// parentRules are the related rules of a parent as per its
+// customize hook response
+type parentRules struct {
+	// latest parent that was synced
+	parent *unstructured.Unstructured
+
+	// parent version the customize hook was invoked for
+	version string
+
+	rules []*relatedRule
+}
+
+// Manager invokes the customize hook of a controller & fetches
+// the related resources of its parents from informers
+//
+// NOTE:
+//	Customize hook response is cached per parent till the
+// parent's generation changes. Hence the related resources
+// should depend only on the parent's spec. Response is cached
+// till the parent's resourceVersion changes if the parent has
+// no generation e.g. ConfigMap or Secret.
+type Manager struct {
+	// name of the controller used for logging
+	Name string
+
+	// scope of the controller that decides the namespaces
+	// related resources may be selected from
+	scope common.HookScope
+
+	invoke          InvokeFn
+	clientset       *dynamicclientset.Clientset
+	informerFactory *dynamicinformer.SharedInformerFactory
+
+	// enqueues the provided parent for a sync
+	enqueueParent func(obj interface{})
+
+	// maximum duration to wait for informers to sync
+	informerSyncTimeout time.Duration
+
+	// guards the fields below
+	mutex     sync.Mutex
+	informers common.ResourceInformerRegistrar
+	parents   map[types.UID]*parentRules
+	isStopped bool
+}
+
+// NewManager returns a new instance of Manager
+func NewManager(
+	name string,
+	scope common.HookScope,
+	invoke InvokeFn,
+	clientset *dynamicclientset.Clientset,
+	informerFactory *dynamicinformer.SharedInformerFactory,
+	enqueueParent func(obj interface{}),
+) *Manager {
+	return &Manager{
+		Name:                name,
+		scope:               scope,
+		invoke:              invoke,
+		clientset:           clientset,
+		informerFactory:     informerFactory,
+		enqueueParent:       enqueueParent,
+		informerSyncTimeout: defaultInformerSyncTimeout,
+		informers:           make(common.ResourceInformerRegistrar),
+		parents:             make(map[types.UID]*parentRules),
+	}
+}
+
+// String implements Stringer interface
+func (m *Manager) String() string {
+	return fmt.Sprintf("Customize manager for %s", m.Name)
+}
+
+// Stop stops receiving events of related resources & releases
+// their informers
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, informer := range m.informers {
+		informer.Informer().RemoveEventHandlers()
+		informer.Close()
+	}
+	m.informers = make(common.ResourceInformerRegistrar)
+	m.parents = make(map[types.UID]*parentRules)
+	m.isStopped = true
+}
+
+// ForgetParent removes the cached customize hook response of
+// the provided parent. This is invoked when the parent is
+// deleted.
+func (m *Manager) ForgetParent(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	parent, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.parents, parent.GetUID())
+}
+
+// buildRule evaluates the provided rule against the provided
+// parent
+func (m *Manager) buildRule(
+	parent *unstructured.Unstructured,
+	rule *RelatedResourceRule,
+) (*relatedRule, error) {
+	if rule == nil || rule.APIVersion == "" || rule.Resource == "" {
+		return nil, errors.Errorf(
+			"Invalid related resource: Specify 'APIVersion' & 'Resource': %+v",
+			rule,
+		)
+	}
+	namespace, err := m.namespaceOf(parent, rule)
+	if err != nil {
+		return nil, err
+	}
+	client, err := m.clientset.GetClientForAPIVersionAndResource(
+		rule.APIVersion,
+		rule.Resource,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Invalid related resource %s.%s",
+			rule.Resource,
+			rule.APIVersion,
+		)
+	}
+	selector := labels.Everything()
+	if rule.LabelSelector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(rule.LabelSelector)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"Invalid label selector of related resource %s.%s",
+				rule.Resource,
+				rule.APIVersion,
+			)
+		}
+	}
+	var names map[string]bool
+	if len(rule.Names) != 0 {
+		names = make(map[string]bool)
+		for _, name := range rule.Names {
+			names[name] = true
+		}
+	}
+	return &relatedRule{
+		apiVersion:   rule.APIVersion,
+		resource:     rule.Resource,
+		namespace:    namespace,
+		isNamespaced: client.Namespaced,
+		names:        names,
+		selector:     selector,
+	}, nil
+}
+
+// namespaceOf returns the namespace the provided rule selects
+// the related resources from. Empty namespace implies all the
+// namespaces.
+func (m *Manager) namespaceOf(
+	parent *unstructured.Unstructured,
+	rule *RelatedResourceRule,
+) (string, error) {
+	if rule.Namespace == "" || rule.Namespace == parent.GetNamespace() {
+		// related resources of a namespaced parent default
+		// to parent's namespace
+		return parent.GetNamespace(), nil
+	}
+	err := m.scope.ValidateRef(rule.Resource, rule.Namespace)
+	if err != nil {
+		return "", errors.Wrapf(
+			err,
+			"Invalid related resource %s.%s",
+			rule.Resource,
+			rule.APIVersion,
+		)
+	}
+	return rule.Namespace, nil
+}
+
+// versionOf returns the version of the provided parent that
+// decides if its cached customize hook response is still valid
+func versionOf(parent *unstructured.Unstructured) string {
+	if parent.GetGeneration() != 0 {
+		// parent has a spec
+		return fmt.Sprintf("generation/%d", parent.GetGeneration())
+	}
+	// parent has no spec e.g. ConfigMap or Secret
+	return "resourceVersion/" + parent.GetResourceVersion()
+}
+
+// getRules returns the related rules of the provided parent.
+// Customize hook is invoked if the rules are not cached or if
+// the parent's version has changed.
+func (m *Manager) getRules(parent *unstructured.Unstructured) ([]*relatedRule, error) {
+	version := versionOf(parent)
+	m.mutex.Lock()
+	cached, found := m.parents[parent.GetUID()]
+	if found && cached.version == version {
+		// related events will enqueue the latest parent
+		cached.parent = parent
+		m.mutex.Unlock()
+		return cached.rules, nil
+	}
+	m.mutex.Unlock()
+
+	resp, err := m.invoke(parent)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Customize hook failed")
+	}
+	var rules []*relatedRule
+	if resp != nil {
+		for _, r := range resp.RelatedResources {
+			rule, err := m.buildRule(parent, r)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.parents[parent.GetUID()] = &parentRules{
+		parent:  parent,
+		version: version,
+		rules:   rules,
+	}
+	return rules, nil
+}
+
+// getInformer returns the informer of the provided related
+// resource. Informer is created if it does not exist.
+func (m *Manager) getInformer(
+	apiVersion string,
+	resource string,
+) (*dynamicinformer.ResourceInformer, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.isStopped {
+		return nil, errors.Errorf("%s: Stopped", m)
+	}
+	if informer := m.informers.Get(apiVersion, resource); informer != nil {
+		return informer, nil
+	}
+	informer, err := m.informerFactory.GetOrCreate(apiVersion, resource)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Can't create informer for related resource %s.%s",
+			resource,
+			apiVersion,
+		)
+	}
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			m.onRelatedEvent(apiVersion, resource, obj)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			m.onRelatedEvent(apiVersion, resource, old, cur)
+		},
+		DeleteFunc: func(obj interface{}) {
+			m.onRelatedEvent(apiVersion, resource, obj)
+		},
+	})
+	m.informers.Set(apiVersion, resource, informer)
+	glog.V(4).Infof(
+		"%s: Added informer for related resource %s.%s",
+		m,
+		resource,
+		apiVersion,
+	)
+	return informer, nil
+}
+
+// onRelatedEvent enqueues the parents that select any of the
+// provided related objects
+func (m *Manager) onRelatedEvent(apiVersion, resource string, objs ...interface{}) {
+	var related []*unstructured.Unstructured
+	for _, obj := range objs {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			related = append(related, u)
+		}
+	}
+
+	var toEnqueue []*unstructured.Unstructured
+	m.mutex.Lock()
+	for _, pr := range m.parents {
+		if isSelected(pr.rules, apiVersion, resource, related) {
+			toEnqueue = append(toEnqueue, pr.parent)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, parent := range toEnqueue {
+		glog.V(6).Infof(
+			"%s: Will enqueue %s/%s due to related %s.%s event",
+			m,
+			parent.GetNamespace(),
+			parent.GetName(),
+			resource,
+			apiVersion,
+		)
+		m.enqueueParent(parent)
+	}
+}
+
+// isSelected returns true if any of the provided rules selects
+// any of the provided objects
+func isSelected(
+	rules []*relatedRule,
+	apiVersion string,
+	resource string,
+	objs []*unstructured.Unstructured,
+) bool {
+	for _, rule := range rules {
+		if rule.apiVersion != apiVersion || rule.resource != resource {
+			continue
+		}
+		for _, obj := range objs {
+			if rule.matches(obj) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetRelatedObjects returns the related objects of the provided
+// parent as per its customize hook response
+func (m *Manager) GetRelatedObjects(
+	parent *unstructured.Unstructured,
+) (common.AnyUnstructRegistry, error) {
+	rules, err := m.getRules(parent)
+	if err != nil {
+		return nil, err
+	}
+	related := make(common.AnyUnstructRegistry)
+	for _, rule := range rules {
+		informer, err := m.getInformer(rule.apiVersion, rule.resource)
+		if err != nil {
+			return nil, err
+		}
+		err = wait.PollImmediate(
+			100*time.Millisecond,
+			m.informerSyncTimeout,
+			func() (bool, error) {
+				return informer.Informer().HasSynced(), nil
+			},
+		)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"Informer for related resource %s.%s is not synced",
+				rule.resource,
+				rule.apiVersion,
+			)
+		}
+		var objs []*unstructured.Unstructured
+		if rule.isNamespaced && rule.namespace != "" {
+			objs, err = informer.Lister().ListNamespace(rule.namespace, rule.selector)
+		} else {
+			objs, err = informer.Lister().List(rule.selector)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"Can't list related resource %s.%s",
+				rule.resource,
+				rule.apiVersion,
+			)
+		}
+		for _, obj := range objs {
+			if rule.matches(obj) {
+				related.InsertByReference(parent, obj)
+			}
+		}
+	}
+	return related, nil
+}
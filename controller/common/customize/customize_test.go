@@ -0,0 +1,342 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package customize
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+)
+
+func newObj(namespace, name string, lbls map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(lbls)
+	return obj
+}
+
+func TestIsSelected(t *testing.T) {
+	var tests = map[string]struct {
+		rule       *relatedRule
+		apiVersion string
+		resource   string
+		obj        *unstructured.Unstructured
+		isSelected bool
+	}{
+		"select everything": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "configmaps",
+				selector:   labels.Everything(),
+			},
+			apiVersion: "v1",
+			resource:   "configmaps",
+			obj:        newObj("ns", "cm", nil),
+			isSelected: true,
+		},
+		"different resource": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "configmaps",
+				selector:   labels.Everything(),
+			},
+			apiVersion: "v1",
+			resource:   "secrets",
+			obj:        newObj("ns", "cm", nil),
+		},
+		"different namespace": {
+			rule: &relatedRule{
+				apiVersion:   "v1",
+				resource:     "configmaps",
+				namespace:    "ns",
+				isNamespaced: true,
+				selector:     labels.Everything(),
+			},
+			apiVersion: "v1",
+			resource:   "configmaps",
+			obj:        newObj("other", "cm", nil),
+		},
+		"namespace is ignored for cluster scoped resource": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "namespaces",
+				namespace:  "ns",
+				selector:   labels.Everything(),
+			},
+			apiVersion: "v1",
+			resource:   "namespaces",
+			obj:        newObj("", "other", nil),
+			isSelected: true,
+		},
+		"matching name": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "configmaps",
+				names:      map[string]bool{"cm": true},
+				selector:   labels.Everything(),
+			},
+			apiVersion: "v1",
+			resource:   "configmaps",
+			obj:        newObj("ns", "cm", nil),
+			isSelected: true,
+		},
+		"non matching name": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "configmaps",
+				names:      map[string]bool{"cm": true},
+				selector:   labels.Everything(),
+			},
+			apiVersion: "v1",
+			resource:   "configmaps",
+			obj:        newObj("ns", "other", nil),
+		},
+		"matching labels": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "configmaps",
+				selector:   labels.SelectorFromSet(labels.Set{"app": "cool"}),
+			},
+			apiVersion: "v1",
+			resource:   "configmaps",
+			obj:        newObj("ns", "cm", map[string]string{"app": "cool"}),
+			isSelected: true,
+		},
+		"non matching labels": {
+			rule: &relatedRule{
+				apiVersion: "v1",
+				resource:   "configmaps",
+				selector:   labels.SelectorFromSet(labels.Set{"app": "cool"}),
+			},
+			apiVersion: "v1",
+			resource:   "configmaps",
+			obj:        newObj("ns", "cm", map[string]string{"app": "other"}),
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := isSelected(
+				[]*relatedRule{mock.rule},
+				mock.apiVersion,
+				mock.resource,
+				[]*unstructured.Unstructured{mock.obj},
+			)
+			if got != mock.isSelected {
+				t.Fatalf("Expected selected %t got %t", mock.isSelected, got)
+			}
+		})
+	}
+}
+
+func TestBuildRuleInvalid(t *testing.T) {
+	var tests = map[string]struct {
+		parent *unstructured.Unstructured
+		rule   *RelatedResourceRule
+	}{
+		"nil rule": {
+			parent: newObj("ns", "parent", nil),
+		},
+		"missing resource": {
+			parent: newObj("ns", "parent", nil),
+			rule: &RelatedResourceRule{
+				ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1"},
+			},
+		},
+		"namespace not allowed by scope": {
+			parent: newObj("ns", "parent", nil),
+			rule: &RelatedResourceRule{
+				ResourceRule: v1alpha1.ResourceRule{
+					APIVersion: "v1",
+					Resource:   "configmaps",
+				},
+				Namespace: "other",
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			m := &Manager{Name: "test", scope: common.HookScope{Namespace: "ns"}}
+			_, err := m.buildRule(mock.parent, mock.rule)
+			if err == nil {
+				t.Fatalf("Expected error got none")
+			}
+		})
+	}
+}
+
+func TestNamespaceOf(t *testing.T) {
+	var tests = map[string]struct {
+		scope      common.HookScope
+		namespaces []string
+		parent     *unstructured.Unstructured
+		namespace  string
+		expect     string
+		isErr      bool
+	}{
+		"namespaced parent defaults to its namespace": {
+			scope:  common.HookScope{Namespace: "ns"},
+			parent: newObj("ns", "parent", nil),
+			expect: "ns",
+		},
+		"cluster scoped parent defaults to all namespaces": {
+			parent: newObj("", "parent", nil),
+			expect: "",
+		},
+		"parent namespace": {
+			scope:     common.HookScope{Namespace: "ctl"},
+			parent:    newObj("ns", "parent", nil),
+			namespace: "ns",
+			expect:    "ns",
+		},
+		"other namespace of cluster scoped controller": {
+			parent:    newObj("ns", "parent", nil),
+			namespace: "other",
+			expect:    "other",
+		},
+		"other namespace of local controller": {
+			scope:     common.LocalHookScope,
+			parent:    newObj("ns", "parent", nil),
+			namespace: "other",
+			expect:    "other",
+		},
+		"controller namespace of namespaced controller": {
+			scope:     common.HookScope{Namespace: "ctl"},
+			parent:    newObj("ns", "parent", nil),
+			namespace: "ctl",
+			expect:    "ctl",
+		},
+		"allowed namespace of namespaced controller": {
+			scope:      common.HookScope{Namespace: "ctl"},
+			namespaces: []string{"shared"},
+			parent:     newObj("ns", "parent", nil),
+			namespace:  "shared",
+			expect:     "shared",
+		},
+		"other namespace of namespaced controller": {
+			scope:      common.HookScope{Namespace: "ctl"},
+			namespaces: []string{"shared"},
+			parent:     newObj("ns", "parent", nil),
+			namespace:  "other",
+			isErr:      true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			common.SetHookNamespaces(mock.namespaces)
+			defer common.SetHookNamespaces(nil)
+
+			m := &Manager{Name: "test", scope: mock.scope}
+			got, err := m.namespaceOf(mock.parent, &RelatedResourceRule{
+				ResourceRule: v1alpha1.ResourceRule{
+					APIVersion: "v1",
+					Resource:   "configmaps",
+				},
+				Namespace: mock.namespace,
+			})
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !mock.isErr && got != mock.expect {
+				t.Fatalf("Expected namespace %q got %q", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestGetRulesIsCachedPerGeneration(t *testing.T) {
+	var invoked int
+	m := NewManager(
+		"test",
+		common.HookScope{},
+		func(parent *unstructured.Unstructured) (*HookResponse, error) {
+			invoked++
+			return &HookResponse{}, nil
+		},
+		nil,
+		nil,
+		func(obj interface{}) {},
+	)
+
+	parent := newObj("ns", "parent", nil)
+	parent.SetUID(types.UID("uid-1"))
+	parent.SetGeneration(1)
+
+	for _, generation := range []int64{1, 1, 2, 2} {
+		parent.SetGeneration(generation)
+		_, err := m.getRules(parent)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	if invoked != 2 {
+		t.Fatalf("Expected customize hook invocations 2 got %d", invoked)
+	}
+
+	m.ForgetParent(parent)
+	_, err := m.getRules(parent)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if invoked != 3 {
+		t.Fatalf("Expected customize hook invocations 3 got %d", invoked)
+	}
+}
+
+func TestGetRulesIsCachedPerResourceVersion(t *testing.T) {
+	var invoked int
+	m := NewManager(
+		"test",
+		common.HookScope{},
+		func(parent *unstructured.Unstructured) (*HookResponse, error) {
+			invoked++
+			return &HookResponse{}, nil
+		},
+		nil,
+		nil,
+		func(obj interface{}) {},
+	)
+
+	// ConfigMap has no generation
+	parent := newObj("ns", "parent", nil)
+	parent.SetUID(types.UID("uid-1"))
+
+	for _, version := range []string{"1", "1", "2", "3", "3"} {
+		parent.SetResourceVersion(version)
+		_, err := m.getRules(parent)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	if invoked != 3 {
+		t.Fatalf("Expected customize hook invocations 3 got %d", invoked)
+	}
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+const (
+	// hookDataTTL is the duration for which the data fetched from
+	// Secrets, ConfigMaps, URLs & files is used before fetching it
+	// again
+	hookDataTTL = 1 * time.Minute
+
+	// maxHookDataSize is the maximum size of the data that is
+	// downloaded from a URL or read from a file
+	maxHookDataSize = 32 << 20
+)
+
+// hookData is the data fetched from a Secret, ConfigMap, URL or
+// file
+type hookData struct {
+	data      map[string][]byte
+	fetchedAt time.Time
+}
+
+// hookDataCall is an in-flight fetch of a Secret, ConfigMap, URL or
+// file. Callers that need the same data wait for this fetch
+// instead of fetching it again.
+type hookDataCall struct {
+	wg   sync.WaitGroup
+	data map[string][]byte
+	err  error
+}
+
+// hookDataStore fetches & caches the Secrets, ConfigMaps, URLs & files
+// referred to by hooks e.g. webhook TLS settings
+type hookDataStore struct {
+	mutex      sync.Mutex
+	client     kubernetes.Interface
+	httpClient *http.Client
+	ttl        time.Duration
+	cache      map[string]hookData
+
+	// in-flight fetches anchored by cache key
+	calls map[string]*hookDataCall
+
+	// used to mock current time in unit tests
+	now func() time.Time
+}
+
+// hookDataCache is used by all the hooks to fetch the
+// referred Secrets, ConfigMaps, URLs & files
+var hookDataCache = &hookDataStore{
+	httpClient: &http.Client{Timeout: 30 * time.Second},
+	ttl:        hookDataTTL,
+	cache:      make(map[string]hookData),
+	now:        time.Now,
+}
+
+// SetHookKubeClient sets the client used to fetch the Secrets
+// & ConfigMaps referred to by hooks
+//
+// NOTE:
+//	Metac servers set this client during their start
+func SetHookKubeClient(client kubernetes.Interface) {
+	hookDataCache.mutex.Lock()
+	defer hookDataCache.mutex.Unlock()
+
+	hookDataCache.client = client
+	hookDataCache.cache = make(map[string]hookData)
+}
+
+// fetch returns the cached data of the provided key if it has
+// not expired. Otherwise data is fetched via the provided function
+// & is cached.
+//
+// NOTE:
+//	Fetch is done without holding the lock so that a slow API
+// server or URL does not block the hooks that need other data.
+// Concurrent fetches of the same key wait for the first one.
+func (s *hookDataStore) fetch(
+	key string,
+	fetchFn func() (map[string][]byte, error),
+) (map[string][]byte, error) {
+	s.mutex.Lock()
+	if cached, found := s.cache[key]; found &&
+		s.now().Sub(cached.fetchedAt) < s.ttl {
+		s.mutex.Unlock()
+		return cached.data, nil
+	}
+	if call, found := s.calls[key]; found {
+		s.mutex.Unlock()
+		call.wg.Wait()
+		return call.data, call.err
+	}
+	if s.calls == nil {
+		s.calls = make(map[string]*hookDataCall)
+	}
+	call := &hookDataCall{}
+	call.wg.Add(1)
+	s.calls[key] = call
+	s.mutex.Unlock()
+
+	call.data, call.err = fetchFn()
+
+	s.mutex.Lock()
+	delete(s.calls, key)
+	if call.err == nil {
+		s.evictExpired()
+		s.cache[key] = hookData{data: call.data, fetchedAt: s.now()}
+	}
+	s.mutex.Unlock()
+	call.wg.Done()
+
+	return call.data, call.err
+}
+
+// evictExpired removes the expired data from the cache. This
+// keeps the cache from growing with data that is no longer
+// referred to by any hook.
+//
+// NOTE:
+//	This must be called with the lock held
+func (s *hookDataStore) evictExpired() {
+	now := s.now()
+	for key, cached := range s.cache {
+		if now.Sub(cached.fetchedAt) >= s.ttl {
+			delete(s.cache, key)
+		}
+	}
+}
+
+// get returns the data of the Secret or ConfigMap with the
+// provided namespace & name
+func (s *hookDataStore) get(
+	kind string,
+	namespace string,
+	name string,
+) (map[string][]byte, error) {
+	if kind != "Secret" && kind != "ConfigMap" {
+		return nil, errors.Errorf("Unsupported kind %q", kind)
+	}
+	s.mutex.Lock()
+	client := s.client
+	s.mutex.Unlock()
+
+	key := fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+	return s.fetch(key, func() (map[string][]byte, error) {
+		if client == nil {
+			return nil, errors.Errorf(
+				"Can't fetch %s %s/%s: Nil kubernetes client",
+				kind,
+				namespace,
+				name,
+			)
+		}
+		if kind == "Secret" {
+			secret, err := client.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "Can't fetch %s %s/%s", kind, namespace, name)
+			}
+			return secret.Data, nil
+		}
+		cm, err := client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't fetch %s %s/%s", kind, namespace, name)
+		}
+		data := make(map[string][]byte)
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+		return data, nil
+	})
+}
+
+// getURL returns the content downloaded from the provided URL
+func (s *hookDataStore) getURL(url string) ([]byte, error) {
+	data, err := s.fetch("URL/"+url, func() (map[string][]byte, error) {
+		body, err := s.download(url)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{url: body}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data[url], nil
+}
+
+// download returns the content of the provided URL
+func (s *hookDataStore) download(url string) ([]byte, error) {
+	resp, err := s.httpClient.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't download %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf(
+			"Can't download %s: Response status is not OK: Got %d",
+			url,
+			resp.StatusCode,
+		)
+	}
+	body, err := readHookData(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't download %s", url)
+	}
+	return body, nil
+}
+
+// getFile returns the content of the file at the provided path
+func (s *hookDataStore) getFile(path string) ([]byte, error) {
+	data, err := s.fetch("File/"+path, func() (map[string][]byte, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't read %s", path)
+		}
+		defer file.Close()
+		body, err := readHookData(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't read %s", path)
+		}
+		return map[string][]byte{path: body}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data[path], nil
+}
+
+// readHookData reads the provided reader till its end. It returns
+// error if the read content is larger than maxHookDataSize.
+func readHookData(r io.Reader) ([]byte, error) {
+	// read one more byte than the limit to detect large content
+	body, err := ioutil.ReadAll(io.LimitReader(r, maxHookDataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxHookDataSize {
+		return nil, errors.Errorf(
+			"Content is larger than %d bytes", maxHookDataSize,
+		)
+	}
+	return body, nil
+}
+
+// getKey returns the value of the provided key of the Secret
+// or ConfigMap. Provided default key is used if the reference
+// does not specify a key.
+func (s *hookDataStore) getKey(
+	kind string,
+	ref *v1alpha1.ObjectKeyReference,
+	defaultKey string,
+) ([]byte, error) {
+	if ref.Namespace == "" || ref.Name == "" {
+		return nil, errors.Errorf(
+			"Invalid %s reference: Specify 'Namespace' & 'Name': %+v",
+			kind,
+			ref,
+		)
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultKey
+	}
+	data, err := s.get(kind, ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	value, found := data[key]
+	if !found || len(value) == 0 {
+		return nil, errors.Errorf(
+			"Key %q not found in %s %s/%s",
+			key,
+			kind,
+			ref.Namespace,
+			ref.Name,
+		)
+	}
+	return value, nil
+}
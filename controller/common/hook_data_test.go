@@ -0,0 +1,258 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+func TestHookDataStoreCache(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "ca"},
+			Data:       map[string][]byte{"ca.crt": []byte("old")},
+		},
+	)
+	now := time.Now()
+	store := &hookDataStore{
+		client: client,
+		ttl:    time.Minute,
+		cache:  make(map[string]hookData),
+		now:    func() time.Time { return now },
+	}
+	ref := &v1alpha1.ObjectKeyReference{Namespace: "metac", Name: "ca"}
+
+	got, err := store.getKey("Secret", ref, "ca.crt")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if string(got) != "old" {
+		t.Fatalf("Expected %q got %q", "old", got)
+	}
+
+	_, err = client.CoreV1().Secrets("metac").Update(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "ca"},
+		Data:       map[string][]byte{"ca.crt": []byte("new")},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	// cached data is used within ttl
+	got, _ = store.getKey("Secret", ref, "ca.crt")
+	if string(got) != "old" {
+		t.Fatalf("Expected cached %q got %q", "old", got)
+	}
+
+	// data is fetched again after ttl
+	now = now.Add(2 * time.Minute)
+	got, _ = store.getKey("Secret", ref, "ca.crt")
+	if string(got) != "new" {
+		t.Fatalf("Expected refreshed %q got %q", "new", got)
+	}
+}
+
+func TestHookDataStoreGetURL(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Write([]byte("module"))
+		}),
+	)
+	defer srv.Close()
+
+	now := time.Now()
+	store := &hookDataStore{
+		httpClient: srv.Client(),
+		ttl:        time.Minute,
+		cache:      make(map[string]hookData),
+		now:        func() time.Time { return now },
+	}
+	for _, advance := range []time.Duration{0, 30 * time.Second, time.Minute} {
+		now = now.Add(advance)
+		got, err := store.getURL(srv.URL)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		if string(got) != "module" {
+			t.Fatalf("Expected %q got %q", "module", got)
+		}
+	}
+	// second call is served from cache
+	if calls != 2 {
+		t.Fatalf("Expected 2 downloads got %d", calls)
+	}
+}
+
+func TestHookDataStoreGetFile(t *testing.T) {
+	file, err := ioutil.TempFile("", "hook-*.wasm")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("module")
+	file.Close()
+
+	now := time.Now()
+	store := &hookDataStore{
+		ttl:   time.Minute,
+		cache: make(map[string]hookData),
+		now:   func() time.Time { return now },
+	}
+	got, err := store.getFile(file.Name())
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if string(got) != "module" {
+		t.Fatalf("Expected %q got %q", "module", got)
+	}
+
+	// file is not read again till its cache expires
+	err = ioutil.WriteFile(file.Name(), []byte("changed"), 0644)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	got, _ = store.getFile(file.Name())
+	if string(got) != "module" {
+		t.Fatalf("Expected cached %q got %q", "module", got)
+	}
+	now = now.Add(time.Minute)
+	got, _ = store.getFile(file.Name())
+	if string(got) != "changed" {
+		t.Fatalf("Expected %q got %q", "changed", got)
+	}
+
+	// file larger than the limit is not read
+	err = os.Truncate(file.Name(), maxHookDataSize+1)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	now = now.Add(time.Minute)
+	_, err = store.getFile(file.Name())
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
+
+func TestHookDataStoreGetURLConcurrent(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				atomic.AddInt32(&calls, 1)
+				<-release
+			}
+			w.Write([]byte("module"))
+		}),
+	)
+	defer srv.Close()
+
+	now := time.Now()
+	store := &hookDataStore{
+		httpClient: srv.Client(),
+		ttl:        time.Minute,
+		cache:      make(map[string]hookData),
+		now:        func() time.Time { return now },
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.getURL(srv.URL + "/slow")
+			errs <- err
+		}()
+	}
+
+	// other URLs are not blocked by the slow download
+	done := make(chan error)
+	go func() {
+		_, err := store.getURL(srv.URL + "/fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected download of other URL to not wait for slow download")
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected 1 download of slow URL got %d", calls)
+	}
+}
+
+func TestHookDataStoreEvictsExpired(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("module"))
+		}),
+	)
+	defer srv.Close()
+
+	now := time.Now()
+	store := &hookDataStore{
+		httpClient: srv.Client(),
+		ttl:        time.Minute,
+		cache:      make(map[string]hookData),
+		now:        func() time.Time { return now },
+	}
+	for _, path := range []string{"/a", "/b"} {
+		_, err := store.getURL(srv.URL + path)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	now = now.Add(2 * time.Minute)
+	_, err := store.getURL(srv.URL + "/c")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if len(store.cache) != 1 {
+		t.Fatalf("Expected 1 cached entry got %d", len(store.cache))
+	}
+	if _, found := store.cache["URL/"+srv.URL+"/c"]; !found {
+		t.Fatalf("Expected latest entry to be cached")
+	}
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/hooks"
+)
+
+// ConditionTypeHookError is the status condition that is set
+// against the watch or parent when its hook responds with a
+// structured error
+const ConditionTypeHookError = "HookError"
+
+// RequeueAfterSyncError adds the provided key back to the queue
+// after a failed sync. Structured hook errors decide if & when
+// the key is requeued. Other errors are requeued with rate
+// limited backoff.
+func RequeueAfterSyncError(
+	queue workqueue.RateLimitingInterface,
+	key interface{},
+	err error,
+) {
+	hookErr, ok := hooks.AsError(err)
+	if !ok {
+		queue.AddRateLimited(key)
+		return
+	}
+	if !hookErr.IsRetryable() {
+		// sync happens again only when the object changes or
+		// informers resync
+		glog.V(4).Infof("Will not requeue %q: %s", key, hookErr)
+		queue.Forget(key)
+		return
+	}
+	if delay := hookErr.RetryAfter(); delay > 0 {
+		glog.V(4).Infof("Will requeue %q after %s: %s", key, delay, hookErr)
+		queue.Forget(key)
+		queue.AddAfter(key, delay)
+		return
+	}
+	queue.AddRateLimited(key)
+}
+
+// NewHookErrorCondition returns the status condition that
+// corresponds to the provided hook error
+func NewHookErrorCondition(hookErr *hooks.Error) *dynamicobject.StatusCondition {
+	return &dynamicobject.StatusCondition{
+		Type:    ConditionTypeHookError,
+		Status:  "True",
+		Reason:  hookErr.Reason,
+		Message: hookErr.Message,
+	}
+}
+
+// UpdateHookErrorCondition sets the hook error condition against
+// the provided object if the provided error is a structured hook
+// error. It is a no-op for other errors, if the provided object
+// already has this condition or if the object's resource does not
+// have a status sub resource.
+//
+// NOTE:
+//	Condition is not set for resources without status sub resource
+// since their updates would change their generation & hence would
+// be synced again immediately.
+func UpdateHookErrorCondition(
+	client *dynamicclientset.ResourceClient,
+	obj *unstructured.Unstructured,
+	err error,
+) error {
+	hookErr, ok := hooks.AsError(err)
+	if !ok {
+		return nil
+	}
+	cond := NewHookErrorCondition(hookErr)
+	old := dynamicobject.GetStatusCondition(
+		obj.UnstructuredContent(),
+		ConditionTypeHookError,
+	)
+	if old != nil && *old == *cond {
+		// Nothing to do.
+		return nil
+	}
+	if !client.HasSubresource("status") {
+		glog.V(4).Infof(
+			"Will not set hook error condition: No status sub resource: %s",
+			DescObjectAsKey(obj),
+		)
+		return nil
+	}
+	_, updateErr := client.Namespace(obj.GetNamespace()).AtomicStatusUpdate(
+		obj,
+		func(current *unstructured.Unstructured) bool {
+			old := dynamicobject.GetStatusCondition(
+				current.UnstructuredContent(),
+				ConditionTypeHookError,
+			)
+			if old != nil && *old == *cond {
+				// Nothing to do.
+				return false
+			}
+			dynamicobject.SetStatusCondition(current.UnstructuredContent(), cond)
+			return true
+		},
+	)
+	return updateErr
+}
+
+// IsHookErrorConditionUpdate returns true if the provided old &
+// current states of an object differ only in their hook error
+// condition.
+//
+// NOTE:
+//	Controllers ignore such updates since these are made by the
+// controllers themselves after a failed hook. Failed hooks are
+// retried as per their errors instead of being invoked again
+// immediately.
+func IsHookErrorConditionUpdate(old, cur interface{}) bool {
+	oldObj, ok := old.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	curObj, ok := cur.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	oldCond := dynamicobject.GetStatusCondition(
+		oldObj.UnstructuredContent(),
+		ConditionTypeHookError,
+	)
+	curCond := dynamicobject.GetStatusCondition(
+		curObj.UnstructuredContent(),
+		ConditionTypeHookError,
+	)
+	if curCond == nil || (oldCond != nil && *oldCond == *curCond) {
+		return false
+	}
+	return reflect.DeepEqual(
+		withoutHookErrorCondition(oldObj),
+		withoutHookErrorCondition(curObj),
+	)
+}
+
+// withoutHookErrorCondition returns a copy of the provided object
+// without its hook error condition & the fields that change with
+// every update
+func withoutHookErrorCondition(obj *unstructured.Unstructured) map[string]interface{} {
+	copied := runtime.DeepCopyJSON(obj.UnstructuredContent())
+	unstructured.RemoveNestedField(copied, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(copied, "metadata", "managedFields")
+
+	conditions, found, _ := unstructured.NestedSlice(copied, "status", "conditions")
+	if !found {
+		return copied
+	}
+	var retained []interface{}
+	for _, item := range conditions {
+		cond, ok := item.(map[string]interface{})
+		if ok && cond["type"] == ConditionTypeHookError {
+			continue
+		}
+		retained = append(retained, item)
+	}
+	if len(retained) != 0 {
+		unstructured.SetNestedSlice(copied, retained, "status", "conditions")
+		return copied
+	}
+	unstructured.RemoveNestedField(copied, "status", "conditions")
+	if status, _, _ := unstructured.NestedMap(copied, "status"); len(status) == 0 {
+		unstructured.RemoveNestedField(copied, "status")
+	}
+	return copied
+}
+
+// ResolveHookErrorCondition marks the hook error condition if
+// any as resolved i.e. sets its status to False. It returns
+// the provided status if there is nothing to resolve. Otherwise
+// it returns a resolved copy of the provided status.
+//
+// NOTE:
+//	This is invoked after a successful hook invocation
+func ResolveHookErrorCondition(status map[string]interface{}) map[string]interface{} {
+	if status == nil {
+		return nil
+	}
+	cond := dynamicobject.GetStatusCondition(
+		map[string]interface{}{"status": status},
+		ConditionTypeHookError,
+	)
+	if cond == nil || cond.Status != "True" {
+		return status
+	}
+	resolved := runtime.DeepCopyJSON(status)
+	dynamicobject.SetCondition(resolved, &dynamicobject.StatusCondition{
+		Type:   ConditionTypeHookError,
+		Status: "False",
+	})
+	return resolved
+}
@@ -0,0 +1,457 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	"openebs.io/metac/hooks"
+)
+
+// fakeQueue records the requeue calls made against it
+type fakeQueue struct {
+	workqueue.RateLimitingInterface
+
+	rateLimited int
+	forgotten   int
+	after       time.Duration
+}
+
+func (q *fakeQueue) AddRateLimited(item interface{}) {
+	q.rateLimited++
+}
+
+func (q *fakeQueue) Forget(item interface{}) {
+	q.forgotten++
+}
+
+func (q *fakeQueue) AddAfter(item interface{}, duration time.Duration) {
+	q.after = duration
+}
+
+func TestRequeueAfterSyncError(t *testing.T) {
+	notRetryable := false
+	var tests = map[string]struct {
+		err               error
+		expectRateLimited int
+		expectForgotten   int
+		expectAfter       time.Duration
+	}{
+		"unstructured error": {
+			err:               errors.New("oops"),
+			expectRateLimited: 1,
+		},
+		"hook error": {
+			err: errors.Wrapf(
+				&hooks.Error{Reason: "Busy"},
+				"Sync hook failed",
+			),
+			expectRateLimited: 1,
+		},
+		"hook error with retry after": {
+			err: errors.Wrapf(
+				&hooks.Error{Reason: "Busy", RetryAfterSeconds: 1.5},
+				"Sync hook failed",
+			),
+			expectForgotten: 1,
+			expectAfter:     1500 * time.Millisecond,
+		},
+		"non retryable hook error": {
+			err: errors.Wrapf(
+				&hooks.Error{
+					Reason:            "InvalidSpec",
+					Retryable:         &notRetryable,
+					RetryAfterSeconds: 10,
+				},
+				"Sync hook failed",
+			),
+			expectForgotten: 1,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			q := &fakeQueue{}
+			RequeueAfterSyncError(q, "ns/name", mock.err)
+			if q.rateLimited != mock.expectRateLimited {
+				t.Fatalf(
+					"Expected rate limited %d got %d",
+					mock.expectRateLimited,
+					q.rateLimited,
+				)
+			}
+			if q.forgotten != mock.expectForgotten {
+				t.Fatalf(
+					"Expected forgotten %d got %d",
+					mock.expectForgotten,
+					q.forgotten,
+				)
+			}
+			if q.after != mock.expectAfter {
+				t.Fatalf("Expected after %s got %s", mock.expectAfter, q.after)
+			}
+		})
+	}
+}
+
+func TestResolveHookErrorCondition(t *testing.T) {
+	var tests = map[string]struct {
+		status       map[string]interface{}
+		expectStatus map[string]interface{}
+	}{
+		"nil status": {},
+		"no conditions": {
+			status:       map[string]interface{}{"phase": "Ready"},
+			expectStatus: map[string]interface{}{"phase": "Ready"},
+		},
+		"hook error condition": {
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":    "HookError",
+						"status":  "True",
+						"reason":  "Busy",
+						"message": "Try later",
+					},
+					map[string]interface{}{
+						"type":   "Ready",
+						"status": "True",
+					},
+				},
+			},
+			expectStatus: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   "HookError",
+						"status": "False",
+					},
+					map[string]interface{}{
+						"type":   "Ready",
+						"status": "True",
+					},
+				},
+			},
+		},
+		"resolved hook error condition": {
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   "HookError",
+						"status": "False",
+					},
+				},
+			},
+			expectStatus: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   "HookError",
+						"status": "False",
+					},
+				},
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			var original map[string]interface{}
+			if mock.status != nil {
+				original = runtime.DeepCopyJSON(mock.status)
+			}
+			got := ResolveHookErrorCondition(mock.status)
+			if !reflect.DeepEqual(got, mock.expectStatus) {
+				t.Fatalf("Expected status %v got %v", mock.expectStatus, got)
+			}
+			if !reflect.DeepEqual(mock.status, original) {
+				t.Fatalf("Expected provided status to be unchanged got %v", mock.status)
+			}
+		})
+	}
+}
+
+// statusRecordingResourceOperation serves the provided object &
+// records the updates made against it
+type statusRecordingResourceOperation struct {
+	NoopResourceOperation
+	obj *unstructured.Unstructured
+
+	gets          int
+	statusUpdates []*unstructured.Unstructured
+}
+
+func (r *statusRecordingResourceOperation) Get(name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.gets++
+	return r.obj.DeepCopy(), nil
+}
+
+func (r *statusRecordingResourceOperation) UpdateStatus(obj *unstructured.Unstructured, options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	r.statusUpdates = append(r.statusUpdates, obj)
+	return obj, nil
+}
+
+// newTestDiscovery returns a started discovery of the provided
+// resources that has synced
+func newTestDiscovery(
+	resources ...*metav1.APIResourceList,
+) *dynamicdiscovery.APIResourceDiscovery {
+	discovery := dynamicdiscovery.NewAPIResourceDiscoverer(
+		&fakediscovery.FakeDiscovery{
+			Fake: &clienttesting.Fake{Resources: resources},
+		},
+	)
+	discovery.Start(time.Hour)
+	for !discovery.HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return discovery
+}
+
+// newTestAPIResource returns the discovered API resource of
+// config maps with or without status sub resource
+//
+// NOTE:
+//	Resource is discovered as cluster scoped to let the tests use
+// a client without namespaced operations
+func newTestAPIResource(t *testing.T, hasStatus bool) *dynamicdiscovery.APIResource {
+	resources := []metav1.APIResource{
+		{Name: "configmaps", Kind: "ConfigMap"},
+	}
+	if hasStatus {
+		resources = append(resources, metav1.APIResource{
+			Name: "configmaps/status", Kind: "ConfigMap",
+		})
+	}
+	discovery := newTestDiscovery(
+		&metav1.APIResourceList{GroupVersion: "v1", APIResources: resources},
+	)
+	defer discovery.Stop()
+	resource := discovery.GetAPIForAPIVersionAndResource("v1", "configmaps")
+	if resource == nil {
+		t.Fatalf("Expected discovered config maps got none")
+	}
+	return resource
+}
+
+func TestUpdateHookErrorCondition(t *testing.T) {
+	newObj := func(cond map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      "test",
+					"namespace": "ns",
+				},
+			},
+		}
+		if cond != nil {
+			obj.Object["status"] = map[string]interface{}{
+				"conditions": []interface{}{cond},
+			}
+		}
+		return obj
+	}
+	hookErr := errors.Wrapf(
+		&hooks.Error{Reason: "InvalidSpec", Message: "Bad replicas"},
+		"Sync hook failed",
+	)
+	var tests = map[string]struct {
+		obj                 *unstructured.Unstructured
+		err                 error
+		hasStatus           bool
+		expectGets          int
+		expectStatusUpdates int
+	}{
+		"not a hook error": {
+			obj:       newObj(nil),
+			err:       errors.New("oops"),
+			hasStatus: true,
+		},
+		"no status sub resource": {
+			obj: newObj(nil),
+			err: hookErr,
+		},
+		"unchanged condition": {
+			obj: newObj(map[string]interface{}{
+				"type":    "HookError",
+				"status":  "True",
+				"reason":  "InvalidSpec",
+				"message": "Bad replicas",
+			}),
+			err:       hookErr,
+			hasStatus: true,
+		},
+		"new condition": {
+			obj:                 newObj(nil),
+			err:                 hookErr,
+			hasStatus:           true,
+			expectGets:          1,
+			expectStatusUpdates: 1,
+		},
+		"changed condition": {
+			obj: newObj(map[string]interface{}{
+				"type":   "HookError",
+				"status": "False",
+			}),
+			err:                 hookErr,
+			hasStatus:           true,
+			expectGets:          1,
+			expectStatusUpdates: 1,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ops := &statusRecordingResourceOperation{obj: mock.obj}
+			client := &dynamicclientset.ResourceClient{
+				ResourceInterface: ops,
+				APIResource:       newTestAPIResource(t, mock.hasStatus),
+			}
+			err := UpdateHookErrorCondition(client, mock.obj, mock.err)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if ops.gets != mock.expectGets {
+				t.Fatalf("Expected gets %d got %d", mock.expectGets, ops.gets)
+			}
+			if len(ops.statusUpdates) != mock.expectStatusUpdates {
+				t.Fatalf(
+					"Expected status updates %d got %d",
+					mock.expectStatusUpdates,
+					len(ops.statusUpdates),
+				)
+			}
+			if mock.expectStatusUpdates == 0 {
+				return
+			}
+			got := ops.statusUpdates[0]
+			if !IsHookErrorConditionUpdate(mock.obj, got) {
+				t.Fatalf("Expected hook error condition update got %v", got)
+			}
+		})
+	}
+}
+
+func TestIsHookErrorConditionUpdate(t *testing.T) {
+	newObj := func(rv string, status map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "test.io/v1",
+				"kind":       "Watch",
+				"metadata": map[string]interface{}{
+					"name":            "test",
+					"resourceVersion": rv,
+				},
+				"spec": map[string]interface{}{
+					"replicas": int64(1),
+				},
+			},
+		}
+		if status != nil {
+			obj.Object["status"] = status
+		}
+		return obj
+	}
+	hookErrCond := map[string]interface{}{
+		"type":   "HookError",
+		"status": "True",
+		"reason": "InvalidSpec",
+	}
+	readyCond := map[string]interface{}{
+		"type":   "Ready",
+		"status": "True",
+	}
+	var tests = map[string]struct {
+		old    interface{}
+		cur    interface{}
+		expect bool
+	}{
+		"hook error condition is set": {
+			old:    newObj("1", nil),
+			cur:    newObj("2", map[string]interface{}{"conditions": []interface{}{hookErrCond}}),
+			expect: true,
+		},
+		"hook error condition is set along with other conditions": {
+			old: newObj("1", map[string]interface{}{
+				"phase":      "Ready",
+				"conditions": []interface{}{readyCond},
+			}),
+			cur: newObj("2", map[string]interface{}{
+				"phase":      "Ready",
+				"conditions": []interface{}{readyCond, hookErrCond},
+			}),
+			expect: true,
+		},
+		"hook error condition is resolved": {
+			old: newObj("1", map[string]interface{}{"conditions": []interface{}{hookErrCond}}),
+			cur: newObj("2", map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "HookError", "status": "False"},
+				},
+			}),
+			expect: true,
+		},
+		"resync": {
+			old: newObj("1", map[string]interface{}{"conditions": []interface{}{hookErrCond}}),
+			cur: newObj("1", map[string]interface{}{"conditions": []interface{}{hookErrCond}}),
+		},
+		"other status is changed": {
+			old: newObj("1", map[string]interface{}{"phase": "Pending"}),
+			cur: newObj("2", map[string]interface{}{
+				"phase":      "Ready",
+				"conditions": []interface{}{hookErrCond},
+			}),
+		},
+		"spec is changed": {
+			old: newObj("1", nil),
+			cur: func() *unstructured.Unstructured {
+				obj := newObj("2", map[string]interface{}{"conditions": []interface{}{hookErrCond}})
+				obj.Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+				return obj
+			}(),
+		},
+		"tombstone": {
+			old: newObj("1", nil),
+			cur: "ns/test",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := IsHookErrorConditionUpdate(mock.old, mock.cur)
+			if got != mock.expect {
+				t.Fatalf("Expected %t got %t", mock.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/recorder"
+	"openebs.io/metac/metrics"
+)
+
+// redactedValue replaces the values of Secrets in hook records
+const redactedValue = "REDACTED"
+
+// hookRecorder records hook invocations if set
+var hookRecorder struct {
+	mutex    sync.RWMutex
+	recorder *recorder.Recorder
+}
+
+// SetHookRecorder sets the recorder used to record hook
+// invocations. Recording is disabled if nil.
+//
+// NOTE:
+//	Metac binary sets this recorder from its flags
+func SetHookRecorder(r *recorder.Recorder) {
+	hookRecorder.mutex.Lock()
+	defer hookRecorder.mutex.Unlock()
+	hookRecorder.recorder = r
+}
+
+// RecordHook records the latency & outcome of the provided hook
+// invocation that was started at the provided time. Request &
+// response are recorded as well if recording is enabled for this
+// hook.
+func RecordHook(
+	ctl metrics.Controller,
+	schema *v1alpha1.Hook,
+	start time.Time,
+	request, response interface{},
+	err error,
+) {
+	metrics.RecordHook(ctl, DescHook(schema), start, err)
+
+	hookRecorder.mutex.RLock()
+	r := hookRecorder.recorder
+	hookRecorder.mutex.RUnlock()
+
+	optIn := schema != nil && schema.Record != nil && *schema.Record
+	if !r.IsEnabled(optIn) {
+		return
+	}
+	record, recErr := newHookRecord(ctl, schema, start, request, response, err)
+	if recErr == nil {
+		recErr = r.Write(record)
+	}
+	if recErr != nil {
+		// recording must never interrupt reconciliation
+		glog.V(4).Infof(
+			"Failed to record hook %s: %s: %v",
+			DescHook(schema),
+			ctl.Name,
+			recErr,
+		)
+	}
+}
+
+// newHookRecord returns a record of the provided hook invocation.
+// Data of Secrets found in the request & response is redacted.
+func newHookRecord(
+	ctl metrics.Controller,
+	schema *v1alpha1.Hook,
+	start time.Time,
+	request, response interface{},
+	err error,
+) (*recorder.Record, error) {
+	record := &recorder.Record{
+		ControllerKind: string(ctl.Kind),
+		ControllerName: ctl.Name,
+		Hook:           DescHook(schema),
+		StartTime:      start,
+		EndTime:        time.Now(),
+		Result:         "success",
+	}
+	reqBody, marshalErr := marshalRedacted(request)
+	if marshalErr != nil {
+		return nil, errors.Wrapf(marshalErr, "Can't marshal hook request")
+	}
+	record.Request = reqBody
+	if err != nil {
+		record.Result = "error"
+		record.Error = err.Error()
+		return record, nil
+	}
+	respBody, marshalErr := marshalRedacted(response)
+	if marshalErr != nil {
+		return nil, errors.Wrapf(marshalErr, "Can't marshal hook response")
+	}
+	record.Response = respBody
+	return record, nil
+}
+
+// marshalRedacted returns the JSON of the provided value after
+// redacting the data of all Secrets found in it. Secrets may be
+// found anywhere e.g. as attachments, children or related objects.
+func marshalRedacted(val interface{}) (json.RawMessage, error) {
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(raw, []byte(`"Secret"`)) {
+		// nothing to redact
+		return raw, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	// numbers are kept as is
+	decoder.UseNumber()
+	var generic interface{}
+	err = decoder.Decode(&generic)
+	if err != nil {
+		return nil, err
+	}
+	if !redactSecrets(generic) {
+		return raw, nil
+	}
+	return json.Marshal(generic)
+}
+
+// redactSecrets redacts the Secrets found in the provided value.
+// It returns true if any Secret was redacted.
+func redactSecrets(val interface{}) bool {
+	var isRedacted bool
+	switch v := val.(type) {
+	case map[string]interface{}:
+		if v["apiVersion"] == "v1" && v["kind"] == "Secret" {
+			redactSecret(v)
+			isRedacted = true
+		}
+		for _, field := range v {
+			if redactSecrets(field) {
+				isRedacted = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if redactSecrets(item) {
+				isRedacted = true
+			}
+		}
+	}
+	return isRedacted
+}
+
+// redactSecret replaces the data of the provided Secret
+func redactSecret(secret map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := secret[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key := range data {
+			data[key] = redactedValue
+		}
+	}
+	// last applied state of a Secret holds its data as well
+	metadata, ok := secret["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key := range annotations {
+		if strings.Contains(key, "last-applied") {
+			annotations[key] = redactedValue
+		}
+	}
+}
+
+// ReplayHook returns a function that invokes the provided hook
+// with recorded requests. This is meant to replay recorded hook
+// traffic against webhook, jsonnet, exec & wasm hooks. Hooks
+// are replayed in local scope since replay is run by the operator.
+func ReplayHook(schema *v1alpha1.Hook) recorder.InvokeFn {
+	return func(request json.RawMessage) (json.RawMessage, error) {
+		var response json.RawMessage
+		err := InvokeHook(LocalHookScope, schema, request, &response)
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/recorder"
+	"openebs.io/metac/metrics"
+)
+
+func TestRecordHook(t *testing.T) {
+	record := true
+	snippet := `function(request) request`
+	var tests = map[string]struct {
+		recordAll   bool
+		schema      *v1alpha1.Hook
+		err         error
+		expectCount int
+		expectError string
+	}{
+		"hook not opted in": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+			},
+			expectCount: 0,
+		},
+		"hook opted in": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+				Record:  &record,
+			},
+			expectCount: 1,
+		},
+		"record all": {
+			recordAll: true,
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+			},
+			expectCount: 1,
+		},
+		"failed hook": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+				Record:  &record,
+			},
+			err:         errors.Errorf("oops"),
+			expectCount: 1,
+			expectError: "oops",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ring, _ := recorder.NewRingSink(5)
+			SetHookRecorder(&recorder.Recorder{
+				RecordAll: mock.recordAll,
+				Sinks:     []recorder.Sink{ring},
+			})
+			defer SetHookRecorder(nil)
+
+			RecordHook(
+				metrics.Controller{Kind: metrics.KindGenericController, Name: "ns/test"},
+				mock.schema,
+				time.Now(),
+				map[string]string{"phase": "Ready"},
+				map[string]string{"phase": "Done"},
+				mock.err,
+			)
+			got := ring.List()
+			if len(got) != mock.expectCount {
+				t.Fatalf("Expected %d records got %d", mock.expectCount, len(got))
+			}
+			if mock.expectCount == 0 {
+				return
+			}
+			if string(got[0].Request) != `{"phase":"Ready"}` {
+				t.Fatalf("Expected recorded request got %s", got[0].Request)
+			}
+			if got[0].Error != mock.expectError {
+				t.Fatalf("Expected error %q got %q", mock.expectError, got[0].Error)
+			}
+			if mock.expectError == "" && string(got[0].Response) != `{"phase":"Done"}` {
+				t.Fatalf("Expected recorded response got %s", got[0].Response)
+			}
+		})
+	}
+}
+
+func TestReplayHook(t *testing.T) {
+	snippet := `function(request) { phase: request.phase }`
+	schema := &v1alpha1.Hook{
+		Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+	}
+	results := recorder.Replay(
+		[]*recorder.Record{
+			{
+				Request:  []byte(`{"phase":"Ready"}`),
+				Response: []byte(`{"phase":"Ready"}`),
+			},
+			{
+				Request:  []byte(`{"phase":"Ready"}`),
+				Response: []byte(`{"phase":"Done"}`),
+			},
+		},
+		ReplayHook(schema),
+	)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results got %d", len(results))
+	}
+	if !results[0].IsMatch() {
+		t.Fatalf("Expected first result to match: %s", results[0].Diff)
+	}
+	if results[1].IsMatch() {
+		t.Fatalf("Expected second result to not match")
+	}
+}
+
+// TestReplayHookGolden replays the hook records found in testdata
+// against a jsonnet hook
+func TestReplayHookGolden(t *testing.T) {
+	var tests = map[string]struct {
+		snippet      string
+		expectDiffs int
+	}{
+		"same logic": {
+			snippet: `function(request) {
+				local spec = request.watch.spec,
+				status: {
+					replicas: if std.objectHas(spec, "replicas") then spec.replicas else 1,
+				},
+			}`,
+		},
+		"changed default": {
+			snippet: `function(request) {
+				local spec = request.watch.spec,
+				status: {
+					replicas: if std.objectHas(spec, "replicas") then spec.replicas else 2,
+				},
+			}`,
+			expectDiffs: 1,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			snippet := mock.snippet
+			results, err := recorder.ReplayDir(
+				"testdata/hook-records",
+				ReplayHook(&v1alpha1.Hook{
+					Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+				}),
+			)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if len(results) != 2 {
+				t.Fatalf("Expected 2 results got %d", len(results))
+			}
+			var diffs int
+			for _, result := range results {
+				if !result.IsMatch() {
+					diffs++
+				}
+			}
+			if diffs != mock.expectDiffs {
+				t.Fatalf("Expected %d diffs got %d: %+v", mock.expectDiffs, diffs, results)
+			}
+		})
+	}
+}
+
+func TestRecordHookRedactsSecrets(t *testing.T) {
+	ring, _ := recorder.NewRingSink(5)
+	SetHookRecorder(&recorder.Recorder{
+		RecordAll: true,
+		Sinks:     []recorder.Sink{ring},
+	})
+	defer SetHookRecorder(nil)
+
+	secret := func() map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name": "creds",
+				"annotations": map[string]interface{}{
+					"metac.openebs.io/last-applied-configuration": `{"data":{"password":"c2VjcmV0"}}`,
+					"owner": "team-a",
+				},
+			},
+			"data": map[string]interface{}{
+				"password": "c2VjcmV0",
+			},
+			"stringData": map[string]interface{}{
+				"token": "secret",
+			},
+		}
+	}
+	request := map[string]interface{}{
+		"attachments": []interface{}{
+			secret(),
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"data": map[string]interface{}{
+					"config": "not-secret",
+				},
+			},
+		},
+		"related": map[string]interface{}{
+			"v1/Secret": map[string]interface{}{
+				"ns/creds": secret(),
+			},
+		},
+		"replicas": 12345678901234,
+	}
+	RecordHook(
+		metrics.Controller{Kind: metrics.KindGenericController, Name: "ns/test"},
+		&v1alpha1.Hook{},
+		time.Now(),
+		request,
+		map[string]interface{}{"attachments": []interface{}{secret()}},
+		nil,
+	)
+	got := ring.List()
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 record got %d", len(got))
+	}
+	for _, raw := range []string{string(got[0].Request), string(got[0].Response)} {
+		for _, leaked := range []string{"c2VjcmV0", `"secret"`} {
+			if strings.Contains(raw, leaked) {
+				t.Fatalf("Expected secret data to be redacted got %s", raw)
+			}
+		}
+	}
+	for _, expect := range []string{"not-secret", "team-a", "12345678901234", redactedValue} {
+		if !strings.Contains(string(got[0].Request), expect) {
+			t.Fatalf("Expected %q in recorded request got %s", expect, got[0].Request)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+// hookNamespaces are the namespaces whose Secrets & ConfigMaps
+// may be referred by the hooks of namespaced controllers in
+// addition to the controller's own namespace
+var hookNamespaces struct {
+	mutex      sync.RWMutex
+	namespaces []string
+}
+
+// SetHookNamespaces lets the hooks of namespaced controllers refer
+// to Secrets & ConfigMaps of the provided namespaces in addition
+// to the controller's own namespace.
+//
+// NOTE:
+//	Metac binary sets these namespaces from its flags
+func SetHookNamespaces(namespaces []string) {
+	hookNamespaces.mutex.Lock()
+	defer hookNamespaces.mutex.Unlock()
+	hookNamespaces.namespaces = namespaces
+}
+
+func getHookNamespaces() []string {
+	hookNamespaces.mutex.RLock()
+	defer hookNamespaces.mutex.RUnlock()
+	return hookNamespaces.namespaces
+}
+
+// HookScope describes the controller that invokes a hook. It
+// decides what the controller's hooks are allowed to do.
+//
+// NOTE:
+//	Controllers loaded from metac's config files are trusted
+// like metac binary itself. Controllers set as custom resources
+// can be created by anyone who can create these resources & hence
+// are restricted.
+type HookScope struct {
+	// Local is true if the controller was loaded from metac's
+	// config files
+	Local bool
+
+	// Namespace of the controller. This is empty for cluster
+	// scoped controllers. Hooks of a namespaced controller may
+	// refer to Secrets & ConfigMaps of this namespace & of the
+	// namespaces set via SetHookNamespaces only.
+	Namespace string
+}
+
+// LocalHookScope is the scope of controllers loaded from metac's
+// config files
+var LocalHookScope = HookScope{Local: true}
+
+// String implements Stringer interface
+func (s HookScope) String() string {
+	if s.Local {
+		return "local"
+	}
+	if s.Namespace == "" {
+		return "cluster"
+	}
+	return "namespace " + s.Namespace
+}
+
+// validate returns error if the provided hook is not allowed
+// in this scope
+func (s HookScope) validate(schema *v1alpha1.Hook) error {
+	if schema.Exec != nil && !s.Local {
+		// exec runs any command within metac's container that
+		// has metac's privileges
+		return errors.Errorf(
+			"Invalid hook: Exec is allowed only for controllers loaded from config files: Scope %s: %s",
+			s,
+			DescHook(schema),
+		)
+	}
+	if schema.Wasm != nil && schema.Wasm.Path != nil && !s.Local {
+		// path can refer to any file within metac's container
+		return errors.Errorf(
+			"Invalid wasm module: Path is allowed only for controllers loaded from config files: Scope %s: %s",
+			s,
+			DescHook(schema),
+		)
+	}
+	if schema.Wasm != nil && schema.Wasm.ConfigMapKeyRef != nil {
+		err := s.ValidateRef("ConfigMap", schema.Wasm.ConfigMapKeyRef.Namespace)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid wasm module")
+		}
+	}
+	if schema.Jsonnet != nil && schema.Jsonnet.ConfigMapKeyRef != nil {
+		err := s.ValidateRef("ConfigMap", schema.Jsonnet.ConfigMapKeyRef.Namespace)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid jsonnet snippet")
+		}
+	}
+	if schema.Webhook != nil && schema.Webhook.CABundleFrom != nil {
+		from := schema.Webhook.CABundleFrom
+		if from.SecretKeyRef != nil {
+			err := s.ValidateRef("Secret", from.SecretKeyRef.Namespace)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid ca bundle")
+			}
+		}
+		if from.ConfigMapKeyRef != nil {
+			err := s.ValidateRef("ConfigMap", from.ConfigMapKeyRef.Namespace)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid ca bundle")
+			}
+		}
+	}
+	if schema.Webhook != nil && schema.Webhook.ClientCertificate != nil &&
+		schema.Webhook.ClientCertificate.SecretRef != nil {
+		err := s.ValidateRef(
+			"Secret", schema.Webhook.ClientCertificate.SecretRef.Namespace,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid client certificate")
+		}
+	}
+	if schema.Webhook != nil && schema.Webhook.Auth != nil {
+		auth := schema.Webhook.Auth
+		if auth.Header != nil && auth.Header.SecretKeyRef != nil {
+			err := s.ValidateRef("Secret", auth.Header.SecretKeyRef.Namespace)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid auth header")
+			}
+		}
+		if auth.HMAC != nil && auth.HMAC.SecretKeyRef != nil {
+			err := s.ValidateRef("Secret", auth.HMAC.SecretKeyRef.Namespace)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid auth hmac")
+			}
+		}
+		if auth.ServiceAccountToken != nil && !s.Local && s.Namespace != "" {
+			// token is sent to the webhook url set by the controller &
+			// may be replayed by this url against other webhooks that
+			// trust the token's audience
+			return errors.Errorf(
+				"Invalid auth: Service account token is not allowed for namespaced controllers: Scope %s: %s",
+				s,
+				DescHook(schema),
+			)
+		}
+	}
+	return nil
+}
+
+// ValidateRef returns error if an object of the provided kind &
+// namespace may not be referred in this scope e.g. a Secret that
+// is read by a hook or a related resource that is sent to a hook
+//
+// NOTE:
+//	Metac reads these objects with its own privileges. A namespaced
+// controller should not be able to read objects from namespaces
+// that its creator has no access to.
+func (s HookScope) ValidateRef(kind, namespace string) error {
+	if s.Local || s.Namespace == "" || namespace == s.Namespace {
+		return nil
+	}
+	for _, allowed := range getHookNamespaces() {
+		if namespace == allowed {
+			return nil
+		}
+	}
+	return errors.Errorf(
+		"%s of namespace %q can't be referred: Scope %s",
+		kind,
+		namespace,
+		s,
+	)
+}
+
+// ValidateHooks returns error if any of the provided hooks is
+// not allowed in the provided scope. Nil hooks are ignored.
+func ValidateHooks(scope HookScope, schemas ...*v1alpha1.Hook) error {
+	for _, schema := range schemas {
+		if schema == nil {
+			continue
+		}
+		err := scope.validate(schema)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
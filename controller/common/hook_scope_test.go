@@ -0,0 +1,317 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+func TestValidateHooks(t *testing.T) {
+	snippet := `function(request) request`
+	exec := &v1alpha1.Hook{
+		Exec: &v1alpha1.Exec{Command: "cat"},
+	}
+	jsonnet := &v1alpha1.Hook{
+		Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+	}
+	path := "/hooks/hook.wasm"
+	wasmPath := &v1alpha1.Hook{
+		Wasm: &v1alpha1.Wasm{Path: &path},
+	}
+	var tests = map[string]struct {
+		scope HookScope
+		hooks []*v1alpha1.Hook
+		isErr bool
+	}{
+		"no hooks": {
+			scope: HookScope{Namespace: "ns"},
+			hooks: []*v1alpha1.Hook{nil},
+		},
+		"jsonnet in namespace scope": {
+			scope: HookScope{Namespace: "ns"},
+			hooks: []*v1alpha1.Hook{jsonnet},
+		},
+		"exec in local scope": {
+			scope: LocalHookScope,
+			hooks: []*v1alpha1.Hook{jsonnet, exec},
+		},
+		"exec in namespace scope": {
+			scope: HookScope{Namespace: "ns"},
+			hooks: []*v1alpha1.Hook{jsonnet, exec},
+			isErr: true,
+		},
+		"exec in cluster scope": {
+			scope: HookScope{},
+			hooks: []*v1alpha1.Hook{nil, exec},
+			isErr: true,
+		},
+		"wasm path in local scope": {
+			scope: LocalHookScope,
+			hooks: []*v1alpha1.Hook{wasmPath},
+		},
+		"wasm path in namespace scope": {
+			scope: HookScope{Namespace: "ns"},
+			hooks: []*v1alpha1.Hook{wasmPath},
+			isErr: true,
+		},
+		"wasm path in cluster scope": {
+			scope: HookScope{},
+			hooks: []*v1alpha1.Hook{wasmPath},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			err := ValidateHooks(mock.scope, mock.hooks...)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+		})
+	}
+}
+
+func TestHookScopeValidateAuthRefs(t *testing.T) {
+	url := "https://hook.ns/sync"
+	withAuth := func(auth *v1alpha1.WebhookAuth) *v1alpha1.Hook {
+		return &v1alpha1.Hook{
+			Webhook: &v1alpha1.Webhook{URL: &url, Auth: auth},
+		}
+	}
+	headerOf := func(namespace string) *v1alpha1.Hook {
+		return withAuth(&v1alpha1.WebhookAuth{
+			Header: &v1alpha1.WebhookAuthHeader{
+				SecretKeyRef: &v1alpha1.ObjectKeyReference{
+					Namespace: namespace,
+					Name:      "token",
+				},
+			},
+		})
+	}
+	hmacOf := func(namespace string) *v1alpha1.Hook {
+		return withAuth(&v1alpha1.WebhookAuth{
+			HMAC: &v1alpha1.WebhookAuthHMAC{
+				SecretKeyRef: &v1alpha1.ObjectKeyReference{
+					Namespace: namespace,
+					Name:      "hmac",
+				},
+			},
+		})
+	}
+	saToken := withAuth(&v1alpha1.WebhookAuth{
+		ServiceAccountToken: &v1alpha1.WebhookAuthServiceAccountToken{},
+	})
+	var tests = map[string]struct {
+		scope      HookScope
+		namespaces []string
+		hook       *v1alpha1.Hook
+		isErr      bool
+	}{
+		"header of own namespace": {
+			scope: HookScope{Namespace: "ns"},
+			hook:  headerOf("ns"),
+		},
+		"header of other namespace": {
+			scope: HookScope{Namespace: "ns"},
+			hook:  headerOf("kube-system"),
+			isErr: true,
+		},
+		"header of allowed namespace": {
+			scope:      HookScope{Namespace: "ns"},
+			namespaces: []string{"metac", "shared"},
+			hook:       headerOf("shared"),
+		},
+		"hmac of other namespace": {
+			scope: HookScope{Namespace: "ns"},
+			hook:  hmacOf("kube-system"),
+			isErr: true,
+		},
+		"hmac of other namespace not in allowed namespaces": {
+			scope:      HookScope{Namespace: "ns"},
+			namespaces: []string{"shared"},
+			hook:       hmacOf("kube-system"),
+			isErr:      true,
+		},
+		"hmac of other namespace in local scope": {
+			scope: HookScope{Local: true, Namespace: "ns"},
+			hook:  hmacOf("kube-system"),
+		},
+		"hmac of any namespace in cluster scope": {
+			scope: HookScope{},
+			hook:  hmacOf("kube-system"),
+		},
+		"service account token in local scope": {
+			scope: LocalHookScope,
+			hook:  saToken,
+		},
+		"service account token in cluster scope": {
+			scope: HookScope{},
+			hook:  saToken,
+		},
+		"service account token in namespace scope": {
+			scope: HookScope{Namespace: "ns"},
+			hook:  saToken,
+			isErr: true,
+		},
+		"service account token in namespace scope with allowed namespaces": {
+			scope:      HookScope{Namespace: "ns"},
+			namespaces: []string{"ns", "metac"},
+			hook:       saToken,
+			isErr:      true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			SetHookNamespaces(mock.namespaces)
+			defer SetHookNamespaces(nil)
+			err := ValidateHooks(mock.scope, mock.hook)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+		})
+	}
+}
+
+func TestHookScopeValidateTLSRefs(t *testing.T) {
+	url := "https://hook.ns/sync"
+	var tests = map[string]struct {
+		webhook *v1alpha1.Webhook
+		isErr   bool
+	}{
+		"ca bundle secret of own namespace": {
+			webhook: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					SecretKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "ns",
+						Name:      "ca",
+					},
+				},
+			},
+		},
+		"ca bundle secret of other namespace": {
+			webhook: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					SecretKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "kube-system",
+						Name:      "ca",
+					},
+				},
+			},
+			isErr: true,
+		},
+		"ca bundle config map of other namespace": {
+			webhook: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "kube-system",
+						Name:      "ca",
+					},
+				},
+			},
+			isErr: true,
+		},
+		"client certificate of own namespace": {
+			webhook: &v1alpha1.Webhook{
+				ClientCertificate: &v1alpha1.WebhookClientCertificate{
+					SecretRef: &v1alpha1.SecretReference{
+						Namespace: "ns",
+						Name:      "tls",
+					},
+				},
+			},
+		},
+		"client certificate of other namespace": {
+			webhook: &v1alpha1.Webhook{
+				ClientCertificate: &v1alpha1.WebhookClientCertificate{
+					SecretRef: &v1alpha1.SecretReference{
+						Namespace: "kube-system",
+						Name:      "tls",
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			mock.webhook.URL = &url
+			err := ValidateHooks(
+				HookScope{Namespace: "ns"},
+				&v1alpha1.Hook{Webhook: mock.webhook},
+			)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+		})
+	}
+}
+
+func TestHookScopeValidateConfigMapRefs(t *testing.T) {
+	var tests = map[string]struct {
+		scope HookScope
+		ref   *v1alpha1.ObjectKeyReference
+		isErr bool
+	}{
+		"config map of own namespace": {
+			scope: HookScope{Namespace: "ns"},
+			ref:   &v1alpha1.ObjectKeyReference{Namespace: "ns", Name: "hook"},
+		},
+		"config map of other namespace": {
+			scope: HookScope{Namespace: "ns"},
+			ref:   &v1alpha1.ObjectKeyReference{Namespace: "kube-system", Name: "hook"},
+			isErr: true,
+		},
+		"config map of other namespace in cluster scope": {
+			ref: &v1alpha1.ObjectKeyReference{Namespace: "kube-system", Name: "hook"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			hooks := map[string]*v1alpha1.Hook{
+				"wasm":    {Wasm: &v1alpha1.Wasm{ConfigMapKeyRef: mock.ref}},
+				"jsonnet": {Jsonnet: &v1alpha1.Jsonnet{ConfigMapKeyRef: mock.ref}},
+			}
+			for kind, hook := range hooks {
+				err := ValidateHooks(mock.scope, hook)
+				if mock.isErr && err == nil {
+					t.Fatalf("Expected error got none: %s hook", kind)
+				}
+				if !mock.isErr && err != nil {
+					t.Fatalf("Expected no error got [%+v]: %s hook", err, kind)
+				}
+			}
+		})
+	}
+}
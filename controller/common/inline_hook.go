@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// InlineHookRegistry holds the inline hook functions anchored by
+// their names. It is safe to add & get these functions concurrently.
+//
+// NOTE:
+//	Every controller kind has its own registry since the signature
+// of its inline hook functions is specific to this kind. Hence the
+// functions are stored as interface{} & are expected to be asserted
+// to the kind's function type by the caller.
+type InlineHookRegistry struct {
+	mutex sync.RWMutex
+	funcs map[string]interface{}
+}
+
+// NewInlineHookRegistry returns a new instance of inline hook
+// registry
+func NewInlineHookRegistry() *InlineHookRegistry {
+	return &InlineHookRegistry{
+		funcs: map[string]interface{}{},
+	}
+}
+
+// Add adds the provided function against the provided name. An
+// existing function with the same name is replaced. A nil function
+// removes the existing one.
+func (r *InlineHookRegistry) Add(funcName string, fn interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if fn == nil ||
+		(reflect.ValueOf(fn).Kind() == reflect.Func && reflect.ValueOf(fn).IsNil()) {
+		delete(r.funcs, funcName)
+		return
+	}
+	r.funcs[funcName] = fn
+}
+
+// Get returns the function added against the provided name
+func (r *InlineHookRegistry) Get(funcName string) (interface{}, error) {
+	if funcName == "" {
+		return nil, errors.Errorf("Inline invoker function name can't be empty")
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	fn := r.funcs[funcName]
+	if fn == nil {
+		return nil, errors.Errorf("Inline hook function not found for %s", funcName)
+	}
+	return fn, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+type testInlineFn func() string
+
+func TestInlineHookRegistryGet(t *testing.T) {
+	r := NewInlineHookRegistry()
+	r.Add("test/hello", testInlineFn(func() string { return "hello" }))
+	r.Add("test/removed", testInlineFn(func() string { return "removed" }))
+	r.Add("test/removed", testInlineFn(nil))
+
+	var tests = map[string]struct {
+		funcName string
+		expect   string
+		isErr    bool
+	}{
+		"added function": {
+			funcName: "test/hello",
+			expect:   "hello",
+		},
+		"empty name": {
+			isErr: true,
+		},
+		"not added function": {
+			funcName: "test/none",
+			isErr:    true,
+		},
+		"function removed via nil": {
+			funcName: "test/removed",
+			isErr:    true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			fn, err := r.Get(mock.funcName)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if got := fn.(testInlineFn)(); got != mock.expect {
+				t.Fatalf("Expected %q got %q", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestInlineHookRegistryConcurrent(t *testing.T) {
+	r := NewInlineHookRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		funcName := fmt.Sprintf("test/fn-%d", i)
+		go func() {
+			defer wg.Done()
+			r.Add(funcName, testInlineFn(func() string { return funcName }))
+		}()
+		go func() {
+			defer wg.Done()
+			// function may or may not be added yet
+			r.Get(funcName)
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 10; i++ {
+		funcName := fmt.Sprintf("test/fn-%d", i)
+		fn, err := r.Get(funcName)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		if got := fn.(testInlineFn)(); got != funcName {
+			t.Fatalf("Expected %q got %q", funcName, got)
+		}
+	}
+}
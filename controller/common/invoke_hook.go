@@ -18,43 +18,177 @@ package common
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/pkg/errors"
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/hooks"
+	"openebs.io/metac/hooks/exec"
+	"openebs.io/metac/hooks/jsonnet"
+	"openebs.io/metac/hooks/wasm"
 	"openebs.io/metac/hooks/webhook"
+	"openebs.io/metac/metrics"
 )
 
-// InvokeHook invokes the given hook with the given request
-func InvokeHook(schema *v1alpha1.Hook, request, response interface{}) error {
-	i, err := hooks.NewInvoker(WithHookSchema(schema))
+const (
+	// defaultJsonnetHookKey is the key used to lookup the jsonnet
+	// snippet from a ConfigMap if no key was specified
+	defaultJsonnetHookKey = "hook.jsonnet"
+
+	// defaultWasmHookKey is the key used to lookup the wasm
+	// module from a ConfigMap if no key was specified
+	defaultWasmHookKey = "hook.wasm"
+
+	// defaultWasmMaxMemoryPages is the default maximum memory
+	// available to a wasm module i.e. 16MiB
+	defaultWasmMaxMemoryPages = 256
+
+	// maxWasmMemoryPages is the maximum memory that can be set
+	// for a wasm module of a local hook i.e. 4GiB which is the
+	// maximum addressable memory
+	maxWasmMemoryPages = 65536
+
+	// maxScopedWasmMemoryPages is the maximum memory that can be
+	// set for a wasm module of a hook that is set via a controller
+	// resource i.e. 64MiB
+	maxScopedWasmMemoryPages = 1024
+)
+
+// InvokeHook invokes the given hook with the given request on
+// behalf of a controller of the given scope
+func InvokeHook(
+	scope HookScope,
+	schema *v1alpha1.Hook,
+	request, response interface{},
+) error {
+	i, err := hooks.NewInvoker(WithHookSchema(scope, schema))
 	if err != nil {
 		return err
 	}
 	return i.Invoke(request, response)
 }
 
+// InvokeHookWithMetrics invokes the given hook & records its
+// latency & outcome against the given controller
+func InvokeHookWithMetrics(
+	ctl metrics.Controller,
+	scope HookScope,
+	schema *v1alpha1.Hook,
+	request, response interface{},
+) error {
+	start := time.Now()
+	err := InvokeHook(scope, schema, request, response)
+	RecordHook(ctl, schema, start, request, response, err)
+	return err
+}
+
+// DescHook returns a short description of the given hook that
+// is suitable to be used as a metrics label
+func DescHook(schema *v1alpha1.Hook) string {
+	if schema == nil {
+		return ""
+	}
+	if schema.Inline != nil && schema.Inline.FuncName != nil {
+		return "inline:" + *schema.Inline.FuncName
+	}
+	if schema.Jsonnet != nil {
+		if ref := schema.Jsonnet.ConfigMapKeyRef; ref != nil {
+			return "jsonnet:" + ref.Namespace + "/" + ref.Name
+		}
+		return "jsonnet"
+	}
+	if schema.Exec != nil {
+		return "exec:" + schema.Exec.Command
+	}
+	if schema.Wasm != nil {
+		return "wasm:" + wasmNameFromSchema(schema.Wasm)
+	}
+	if schema.Webhook != nil {
+		caller := &webhook.Invoker{}
+		err := SetWebhookURLFromSchema(schema.Webhook)(caller)
+		if err == nil {
+			return caller.URL
+		}
+		return "webhook"
+	}
+	return "unknown"
+}
+
 // WithHookSchema sets the hook invoker instance with appropriate
-// invoke function based on the provided schema
+// invoke function based on the provided schema. Hooks that are
+// not allowed in the provided scope are rejected.
 //
 // NOTE:
 //	This logic is expected to have multiple **if conditions** to support
 // different hook types e.g. webhook, inline hook, etc
-func WithHookSchema(schema *v1alpha1.Hook) hooks.InvokerOption {
+func WithHookSchema(scope HookScope, schema *v1alpha1.Hook) hooks.InvokerOption {
 	return func(invoker *hooks.Invoker) error {
-		// webhook is the only commonly supported hook for
-		// all meta controllers
+		var count int
+		for _, isSet := range []bool{
+			schema.Webhook != nil,
+			schema.Jsonnet != nil,
+			schema.Exec != nil,
+			schema.Wasm != nil,
+		} {
+			if isSet {
+				count++
+			}
+		}
+		if count > 1 {
+			return errors.Errorf(
+				"Invalid hook: Specify only one of 'Webhook', 'Jsonnet', 'Exec' or 'Wasm': %v",
+				schema,
+			)
+		}
+		err := scope.validate(schema)
+		if err != nil {
+			return err
+		}
+		if schema.Wasm != nil {
+			// wasm module is executed within metac
+			wi, err := wasm.NewInvoker(
+				SetWasmFromSchema(scope, schema.Wasm),
+			)
+			if err != nil {
+				return err
+			}
+			invoker.InvokeFn = wi.Invoke
+			return nil
+		}
+		if schema.Exec != nil {
+			// exec runs a local command
+			ei, err := exec.NewInvoker(
+				SetExecFromSchema(schema.Exec),
+			)
+			if err != nil {
+				return err
+			}
+			invoker.InvokeFn = ei.Invoke
+			return nil
+		}
+		if schema.Jsonnet != nil {
+			// jsonnet is evaluated within metac
+			ji, err := jsonnet.NewInvoker(
+				SetJsonnetSnippetFromSchema(schema.Jsonnet),
+			)
+			if err != nil {
+				return err
+			}
+			invoker.InvokeFn = ji.Invoke
+			return nil
+		}
+		// webhook, jsonnet, exec & wasm are the commonly
+		// supported hooks for all meta controllers
 		if schema.Webhook == nil {
 			return errors.Errorf("Unsupported hook %v", schema)
 		}
 		// Since this is webhook set the webhook call func
-		// create a new instance of webhook invoker
-		whi, err := webhook.NewInvoker(
-			// set various webhook options
-			SetWebhookURLFromSchema(schema.Webhook),
-			SetWebhookTimeoutFromSchemaOrDefault(schema.Webhook),
-		)
+		// from the pooled webhook invoker
+		//
+		// NOTE:
+		//	Pooled invoker reuses its connections across syncs
+		whi, err := webhookInvokers.get(schema.Webhook)
 		if err != nil {
 			return err
 		}
@@ -131,3 +265,267 @@ func SetWebhookTimeoutFromSchemaOrDefault(schema *v1alpha1.Webhook) webhook.Invo
 		return nil
 	}
 }
+
+// SetWebhookRetryFromSchema evaluates webhook retry policy and
+// sets the evaluated policy against WebhookCaller instance
+func SetWebhookRetryFromSchema(schema *v1alpha1.Webhook) webhook.InvokerOption {
+	return func(caller *webhook.Invoker) error {
+		if schema.Retry == nil {
+			// webhook is invoked only once
+			return nil
+		}
+		// defaults
+		policy := &webhook.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+			RetryableStatusCodes: map[int]bool{
+				http.StatusTooManyRequests:    true,
+				http.StatusBadGateway:         true,
+				http.StatusServiceUnavailable: true,
+				http.StatusGatewayTimeout:     true,
+			},
+		}
+		retry := schema.Retry
+		if retry.MaxAttempts != nil {
+			if *retry.MaxAttempts < 1 {
+				return errors.Errorf(
+					"Invalid webhook retry: Max attempts must be >= 1: %v",
+					schema,
+				)
+			}
+			policy.MaxAttempts = int(*retry.MaxAttempts)
+		}
+		if retry.InitialBackoff != nil {
+			if retry.InitialBackoff.Duration <= 0 {
+				return errors.Errorf(
+					"Invalid webhook retry: Initial backoff must be > 0: %v",
+					schema,
+				)
+			}
+			policy.InitialBackoff = retry.InitialBackoff.Duration
+		}
+		if retry.MaxBackoff != nil {
+			if retry.MaxBackoff.Duration <= 0 {
+				return errors.Errorf(
+					"Invalid webhook retry: Max backoff must be > 0: %v",
+					schema,
+				)
+			}
+			policy.MaxBackoff = retry.MaxBackoff.Duration
+		}
+		if policy.InitialBackoff > policy.MaxBackoff {
+			return errors.Errorf(
+				"Invalid webhook retry: Initial backoff %s must be <= max backoff %s: %v",
+				policy.InitialBackoff,
+				policy.MaxBackoff,
+				schema,
+			)
+		}
+		if len(retry.RetryableStatusCodes) != 0 {
+			policy.RetryableStatusCodes = map[int]bool{}
+			for _, code := range retry.RetryableStatusCodes {
+				if code < 100 || code > 599 || code == http.StatusOK {
+					return errors.Errorf(
+						"Invalid webhook retry: Invalid status code %d: %v",
+						code,
+						schema,
+					)
+				}
+				policy.RetryableStatusCodes[int(code)] = true
+			}
+		}
+		caller.Retry = policy
+		return nil
+	}
+}
+
+// SetWebhookGzipFromSchema sets the request compression of the
+// provided webhook against WebhookCaller instance
+func SetWebhookGzipFromSchema(schema *v1alpha1.Webhook) webhook.InvokerOption {
+	return func(caller *webhook.Invoker) error {
+		caller.Gzip = schema.Gzip != nil && *schema.Gzip
+		return nil
+	}
+}
+
+// SetJsonnetSnippetFromSchema evaluates the jsonnet snippet either
+// inline or from the referred ConfigMap & sets it against the
+// jsonnet Invoker instance along with its timeout
+func SetJsonnetSnippetFromSchema(schema *v1alpha1.Jsonnet) jsonnet.InvokerOption {
+	return func(caller *jsonnet.Invoker) error {
+		if schema.Snippet != nil && schema.ConfigMapKeyRef != nil {
+			return errors.Errorf(
+				"Invalid jsonnet hook: Specify either 'Snippet' or 'ConfigMapKeyRef': %v",
+				schema,
+			)
+		}
+		caller.Timeout = 10 * time.Second
+		if schema.Timeout != nil {
+			if schema.Timeout.Duration <= 0 {
+				return errors.Errorf(
+					"Invalid jsonnet hook timeout: Must be > 0: %v",
+					schema,
+				)
+			}
+			caller.Timeout = schema.Timeout.Duration
+		}
+		if schema.Snippet != nil {
+			caller.Name = "inline"
+			caller.Snippet = *schema.Snippet
+			return nil
+		}
+		ref := schema.ConfigMapKeyRef
+		if ref == nil {
+			return errors.Errorf(
+				"Invalid jsonnet hook: Specify 'Snippet' or 'ConfigMapKeyRef': %v",
+				schema,
+			)
+		}
+		snippet, err := hookDataCache.getKey(
+			"ConfigMap",
+			ref,
+			defaultJsonnetHookKey,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid jsonnet hook: %v", schema)
+		}
+		key := ref.Key
+		if key == "" {
+			key = defaultJsonnetHookKey
+		}
+		caller.Name = ref.Namespace + "/" + ref.Name + "/" + key
+		caller.Snippet = string(snippet)
+		return nil
+	}
+}
+
+// SetExecFromSchema evaluates the command, arguments, environment
+// & timeout of the provided exec hook and sets them against the
+// exec Invoker instance
+func SetExecFromSchema(schema *v1alpha1.Exec) exec.InvokerOption {
+	return func(caller *exec.Invoker) error {
+		if schema.Command == "" {
+			return errors.Errorf(
+				"Invalid exec hook: Specify 'Command': %v",
+				schema,
+			)
+		}
+		caller.Command = schema.Command
+		caller.Args = schema.Args
+		for _, env := range schema.Env {
+			if env.Name == "" {
+				return errors.Errorf(
+					"Invalid exec hook: Specify env 'Name': %v",
+					schema,
+				)
+			}
+			caller.Env = append(caller.Env, env.Name+"="+env.Value)
+		}
+		if schema.Timeout == nil {
+			// Defaults to 10 Seconds similar to webhook
+			caller.Timeout = 10 * time.Second
+			return nil
+		}
+		if schema.Timeout.Duration <= 0 {
+			return errors.Errorf(
+				"Invalid exec hook timeout: Must be > 0: %v",
+				schema,
+			)
+		}
+		caller.Timeout = schema.Timeout.Duration
+		return nil
+	}
+}
+
+// wasmNameFromSchema returns the source of the provided wasm
+// module as its name
+func wasmNameFromSchema(schema *v1alpha1.Wasm) string {
+	switch {
+	case schema.ConfigMapKeyRef != nil:
+		ref := schema.ConfigMapKeyRef
+		key := ref.Key
+		if key == "" {
+			key = defaultWasmHookKey
+		}
+		return ref.Namespace + "/" + ref.Name + "/" + key
+	case schema.Path != nil:
+		return *schema.Path
+	case schema.URL != nil:
+		return *schema.URL
+	default:
+		return ""
+	}
+}
+
+// SetWasmFromSchema loads the wasm module either from the referred
+// ConfigMap, path or URL & sets it against the wasm Invoker
+// instance along with its limits. Limits are bounded as per the
+// provided scope.
+func SetWasmFromSchema(scope HookScope, schema *v1alpha1.Wasm) wasm.InvokerOption {
+	return func(caller *wasm.Invoker) error {
+		var count int
+		for _, isSet := range []bool{
+			schema.ConfigMapKeyRef != nil,
+			schema.Path != nil,
+			schema.URL != nil,
+		} {
+			if isSet {
+				count++
+			}
+		}
+		if count != 1 {
+			return errors.Errorf(
+				"Invalid wasm hook: Specify one of 'ConfigMapKeyRef', 'Path' or 'URL': %v",
+				schema,
+			)
+		}
+		var module []byte
+		var err error
+		switch {
+		case schema.ConfigMapKeyRef != nil:
+			module, err = hookDataCache.getKey(
+				"ConfigMap",
+				schema.ConfigMapKeyRef,
+				defaultWasmHookKey,
+			)
+		case schema.Path != nil:
+			module, err = hookDataCache.getFile(*schema.Path)
+		case schema.URL != nil:
+			module, err = hookDataCache.getURL(*schema.URL)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Invalid wasm hook: Can't load module: %v", schema)
+		}
+		caller.Name = wasmNameFromSchema(schema)
+		caller.Module = module
+
+		caller.MaxMemoryPages = defaultWasmMaxMemoryPages
+		if schema.MaxMemoryPages != nil {
+			maxPages := int32(maxScopedWasmMemoryPages)
+			if scope.Local {
+				maxPages = maxWasmMemoryPages
+			}
+			if *schema.MaxMemoryPages < 1 || *schema.MaxMemoryPages > maxPages {
+				return errors.Errorf(
+					"Invalid wasm hook: Max memory pages must be between 1 & %d: %v",
+					maxPages,
+					schema,
+				)
+			}
+			caller.MaxMemoryPages = int(*schema.MaxMemoryPages)
+		}
+
+		caller.Timeout = 10 * time.Second
+		if schema.Timeout != nil {
+			if schema.Timeout.Duration <= 0 {
+				return errors.Errorf(
+					"Invalid wasm hook timeout: Must be > 0: %v",
+					schema,
+				)
+			}
+			caller.Timeout = schema.Timeout.Duration
+		}
+		return nil
+	}
+}
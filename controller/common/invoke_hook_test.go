@@ -0,0 +1,478 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/wasm"
+	"openebs.io/metac/hooks/webhook"
+	"openebs.io/metac/third_party/kubernetes"
+)
+
+func TestSetWebhookRetryFromSchema(t *testing.T) {
+	var tests = map[string]struct {
+		retry             *v1alpha1.WebhookRetry
+		isErr             bool
+		isNilPolicy       bool
+		expectMaxAttempts int
+		expectRetryable   int
+	}{
+		"no retry": {
+			isNilPolicy: true,
+		},
+		"defaults": {
+			retry:             &v1alpha1.WebhookRetry{},
+			expectMaxAttempts: 3,
+			expectRetryable:   503,
+		},
+		"custom": {
+			retry: &v1alpha1.WebhookRetry{
+				MaxAttempts:          kubernetes.Int32Ptr(5),
+				RetryableStatusCodes: []int32{500},
+			},
+			expectMaxAttempts: 5,
+			expectRetryable:   500,
+		},
+		"zero max attempts": {
+			retry: &v1alpha1.WebhookRetry{
+				MaxAttempts: kubernetes.Int32Ptr(0),
+			},
+			isErr: true,
+		},
+		"initial backoff greater than max backoff": {
+			retry: &v1alpha1.WebhookRetry{
+				InitialBackoff: &metav1.Duration{Duration: 5 * time.Second},
+				MaxBackoff:     &metav1.Duration{Duration: 1 * time.Second},
+			},
+			isErr: true,
+		},
+		"invalid status code": {
+			retry: &v1alpha1.WebhookRetry{
+				RetryableStatusCodes: []int32{200},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker := &webhook.Invoker{}
+			err := SetWebhookRetryFromSchema(
+				&v1alpha1.Webhook{Retry: mock.retry},
+			)(invoker)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if mock.isNilPolicy {
+				if invoker.Retry != nil {
+					t.Fatalf("Expected nil retry policy got %+v", invoker.Retry)
+				}
+				return
+			}
+			if invoker.Retry.MaxAttempts != mock.expectMaxAttempts {
+				t.Fatalf(
+					"Expected max attempts %d got %d",
+					mock.expectMaxAttempts,
+					invoker.Retry.MaxAttempts,
+				)
+			}
+			if !invoker.Retry.RetryableStatusCodes[mock.expectRetryable] {
+				t.Fatalf(
+					"Expected status %d to be retryable got %v",
+					mock.expectRetryable,
+					invoker.Retry.RetryableStatusCodes,
+				)
+			}
+		})
+	}
+}
+
+func TestInvokeJsonnetHook(t *testing.T) {
+	snippet := `function(request) { status: { phase: request.phase } }`
+	runaway := `function(request)
+		local f(n) = if n == 0 then 0 else f(n - 1) + f(n - 1);
+		{ status: { phase: f(40) } }`
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "hooks"},
+			Data:       map[string]string{"hook.jsonnet": snippet},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "hooks"},
+			Data:       map[string]string{"hook.jsonnet": snippet},
+		},
+	)
+	SetHookKubeClient(client)
+	defer SetHookKubeClient(nil)
+
+	var tests = map[string]struct {
+		schema *v1alpha1.Hook
+		isErr  bool
+	}{
+		"inline snippet": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+			},
+		},
+		"inline snippet with timeout": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{
+					Snippet: &snippet,
+					Timeout: &metav1.Duration{Duration: 5 * time.Second},
+				},
+			},
+		},
+		"inline snippet with invalid timeout": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{
+					Snippet: &snippet,
+					Timeout: &metav1.Duration{},
+				},
+			},
+			isErr: true,
+		},
+		"runaway snippet": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{
+					Snippet: &runaway,
+					Timeout: &metav1.Duration{Duration: 100 * time.Millisecond},
+				},
+			},
+			isErr: true,
+		},
+		"snippet from configmap with default key": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "hooks",
+					},
+				},
+			},
+		},
+		"snippet from missing configmap key": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "hooks",
+						Key:       "sync.jsonnet",
+					},
+				},
+			},
+			isErr: true,
+		},
+		"snippet from configmap of other namespace": {
+			schema: &v1alpha1.Hook{
+				Jsonnet: &v1alpha1.Jsonnet{
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "other",
+						Name:      "hooks",
+					},
+				},
+			},
+			isErr: true,
+		},
+		"both webhook & jsonnet": {
+			schema: &v1alpha1.Hook{
+				Webhook: &v1alpha1.Webhook{URL: &snippet},
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			var resp map[string]map[string]string
+			err := InvokeHook(
+				HookScope{Namespace: "metac"},
+				mock.schema,
+				map[string]string{"phase": "Ready"},
+				&resp,
+			)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !mock.isErr && resp["status"]["phase"] != "Ready" {
+				t.Fatalf("Expected phase %q got %v", "Ready", resp)
+			}
+		})
+	}
+}
+
+func TestInvokeExecHook(t *testing.T) {
+	snippet := `function(request) request`
+	exec := &v1alpha1.Exec{
+		Command: "sh",
+		Args:    []string{"-c", `printf '{"phase": "%s"}' "$PHASE"`},
+		Env:     []v1alpha1.ExecEnvVar{{Name: "PHASE", Value: "Ready"}},
+	}
+	var tests = map[string]struct {
+		scope  *HookScope
+		schema *v1alpha1.Hook
+		isErr  bool
+	}{
+		"exec": {
+			schema: &v1alpha1.Hook{
+				Exec: exec,
+			},
+		},
+		"exec of namespaced custom resource controller": {
+			scope: &HookScope{Namespace: "ns"},
+			schema: &v1alpha1.Hook{
+				Exec: exec,
+			},
+			isErr: true,
+		},
+		"exec of cluster scoped custom resource controller": {
+			scope: &HookScope{},
+			schema: &v1alpha1.Hook{
+				Exec: exec,
+			},
+			isErr: true,
+		},
+		"exec without command": {
+			schema: &v1alpha1.Hook{
+				Exec: &v1alpha1.Exec{},
+			},
+			isErr: true,
+		},
+		"exec with invalid timeout": {
+			schema: &v1alpha1.Hook{
+				Exec: &v1alpha1.Exec{
+					Command: "cat",
+					Timeout: &metav1.Duration{},
+				},
+			},
+			isErr: true,
+		},
+		"both exec & jsonnet": {
+			schema: &v1alpha1.Hook{
+				Exec:    &v1alpha1.Exec{Command: "cat"},
+				Jsonnet: &v1alpha1.Jsonnet{Snippet: &snippet},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			scope := LocalHookScope
+			if mock.scope != nil {
+				scope = *mock.scope
+			}
+			var resp map[string]string
+			err := InvokeHook(scope, mock.schema, map[string]string{}, &resp)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !mock.isErr && resp["phase"] != "Ready" {
+				t.Fatalf("Expected phase %q got %v", "Ready", resp)
+			}
+		})
+	}
+}
+
+func TestSetWasmFromSchema(t *testing.T) {
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/hook.wasm" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte("from-url"))
+		}),
+	)
+	defer srv.Close()
+
+	file, err := ioutil.TempFile("", "hook-*.wasm")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("from-path")
+	file.Close()
+
+	largeFile, err := ioutil.TempFile("", "hook-*.wasm")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer os.Remove(largeFile.Name())
+	largeFile.Truncate(maxHookDataSize + 1)
+	largeFile.Close()
+
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "hooks"},
+			BinaryData: map[string][]byte{"hook.wasm": []byte("from-configmap")},
+		},
+	)
+	SetHookKubeClient(client)
+	defer SetHookKubeClient(nil)
+
+	var tests = map[string]struct {
+		scope          HookScope
+		schema         *v1alpha1.Wasm
+		isErr          bool
+		expectModule   string
+		expectMaxPages int
+	}{
+		"from configmap with default key": {
+			scope: HookScope{Namespace: "metac"},
+			schema: &v1alpha1.Wasm{
+				ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+					Namespace: "metac",
+					Name:      "hooks",
+				},
+			},
+			expectModule:   "from-configmap",
+			expectMaxPages: 256,
+		},
+		"from path with max memory pages": {
+			scope: LocalHookScope,
+			schema: &v1alpha1.Wasm{
+				Path:           kubernetes.StringPtr(file.Name()),
+				MaxMemoryPages: kubernetes.Int32Ptr(16),
+			},
+			expectModule:   "from-path",
+			expectMaxPages: 16,
+		},
+		"from large path": {
+			scope: LocalHookScope,
+			schema: &v1alpha1.Wasm{
+				Path: kubernetes.StringPtr(largeFile.Name()),
+			},
+			isErr: true,
+		},
+		"from url": {
+			scope: HookScope{},
+			schema: &v1alpha1.Wasm{
+				URL: kubernetes.StringPtr(srv.URL + "/hook.wasm"),
+			},
+			expectModule:   "from-url",
+			expectMaxPages: 256,
+		},
+		"from missing url": {
+			scope: HookScope{},
+			schema: &v1alpha1.Wasm{
+				URL: kubernetes.StringPtr(srv.URL + "/none.wasm"),
+			},
+			isErr: true,
+		},
+		"no source": {
+			scope:  LocalHookScope,
+			schema: &v1alpha1.Wasm{},
+			isErr:  true,
+		},
+		"path & url": {
+			scope: LocalHookScope,
+			schema: &v1alpha1.Wasm{
+				Path: kubernetes.StringPtr(file.Name()),
+				URL:  kubernetes.StringPtr(srv.URL + "/hook.wasm"),
+			},
+			isErr: true,
+		},
+		"invalid max memory pages": {
+			scope: LocalHookScope,
+			schema: &v1alpha1.Wasm{
+				Path:           kubernetes.StringPtr(file.Name()),
+				MaxMemoryPages: kubernetes.Int32Ptr(0),
+			},
+			isErr: true,
+		},
+		"local max memory pages": {
+			scope: LocalHookScope,
+			schema: &v1alpha1.Wasm{
+				Path:           kubernetes.StringPtr(file.Name()),
+				MaxMemoryPages: kubernetes.Int32Ptr(65536),
+			},
+			expectModule:   "from-path",
+			expectMaxPages: 65536,
+		},
+		"cluster max memory pages": {
+			scope: HookScope{},
+			schema: &v1alpha1.Wasm{
+				URL:            kubernetes.StringPtr(srv.URL + "/hook.wasm"),
+				MaxMemoryPages: kubernetes.Int32Ptr(1024),
+			},
+			expectModule:   "from-url",
+			expectMaxPages: 1024,
+		},
+		"cluster max memory pages above limit": {
+			scope: HookScope{},
+			schema: &v1alpha1.Wasm{
+				URL:            kubernetes.StringPtr(srv.URL + "/hook.wasm"),
+				MaxMemoryPages: kubernetes.Int32Ptr(1025),
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker := &wasm.Invoker{}
+			err := SetWasmFromSchema(mock.scope, mock.schema)(invoker)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if string(invoker.Module) != mock.expectModule {
+				t.Fatalf("Expected module %q got %q", mock.expectModule, invoker.Module)
+			}
+			if invoker.MaxMemoryPages != mock.expectMaxPages {
+				t.Fatalf(
+					"Expected max memory pages %d got %d",
+					mock.expectMaxPages,
+					invoker.MaxMemoryPages,
+				)
+			}
+		})
+	}
+}
@@ -300,11 +300,33 @@ type ChildUpdateStrategyGetter interface {
 	Get(apiGroup, kind string) v1alpha1.ChildUpdateMethod
 }
 
+// ChildUpdateHooks provides the abstraction to invoke hooks
+// before & after an individual child is updated
+type ChildUpdateHooks interface {
+	// PreUpdate is invoked before updating the observed child to
+	// its desired state. Child is not updated if this returns false.
+	PreUpdate(
+		method v1alpha1.ChildUpdateMethod,
+		observed, desired *unstructured.Unstructured,
+	) (bool, error)
+
+	// PostUpdate is invoked after the observed child was updated
+	// to its desired state
+	PostUpdate(
+		method v1alpha1.ChildUpdateMethod,
+		observed, desired *unstructured.Unstructured,
+	) error
+}
+
 // ManageChildren ensures the relevant children objects of the
 // given parent are in sync
+//
+// NOTE:
+//	hooks is optional & can be nil
 func ManageChildren(
 	dynClient *dynamicclientset.Clientset,
 	updateStrategy ChildUpdateStrategyGetter,
+	hooks ChildUpdateHooks,
 	parent *unstructured.Unstructured,
 	observedChildren, desiredChildren AnyUnstructRegistry,
 ) error {
@@ -337,6 +359,7 @@ func ManageChildren(
 		if err := updateChildren(
 			client,
 			updateStrategy,
+			hooks,
 			parent,
 			observedChildren[key],
 			objects,
@@ -383,15 +406,33 @@ func deleteChildren(
 func updateChildren(
 	client *dynamicclientset.ResourceClient,
 	updateStrategy ChildUpdateStrategyGetter,
+	hooks ChildUpdateHooks,
 	parent *unstructured.Unstructured,
 	observed, desired map[string]*unstructured.Unstructured,
 ) error {
 	var errs []error
+	// server side apply replaces both the 3-way merge & create
+	isServerSideApply :=
+		updateStrategy.Get(client.Group, client.Kind) == v1alpha1.ChildUpdateServerSideApply
 	for name, obj := range desired {
 		ns := obj.GetNamespace()
 		if ns == "" {
 			ns = parent.GetNamespace()
 		}
+		if isServerSideApply {
+			err := serverSideApplyChild(
+				client,
+				updateStrategy,
+				hooks,
+				parent,
+				observed[name],
+				obj,
+			)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
 		if oldObj := observed[name]; oldObj != nil {
 			// Update
 			a := Apply{}
@@ -418,7 +459,26 @@ func updateChildren(
 			}
 
 			// Check the update strategy for this child kind.
-			switch method := updateStrategy.Get(client.Group, client.Kind); method {
+			method := updateStrategy.Get(client.Group, client.Kind)
+			if hooks != nil &&
+				method != v1alpha1.ChildUpdateOnDelete && method != "" {
+				// Let the pre update hook decide if this child
+				// can be updated now
+				proceed, err := hooks.PreUpdate(method, oldObj, newObj)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if !proceed {
+					glog.Infof(
+						"%v: not updating %v (blocked by pre update hook)",
+						describeObject(parent),
+						describeObject(obj),
+					)
+					continue
+				}
+			}
+			switch method {
 			case v1alpha1.ChildUpdateOnDelete, "":
 				// This means we don't try to update anything unless it gets deleted
 				// by someone else (we won't delete it ourselves).
@@ -462,6 +522,13 @@ func updateChildren(
 				)
 				continue
 			}
+			if hooks != nil {
+				// Let the post update hook know this child was updated
+				if err := hooks.PostUpdate(method, oldObj, newObj); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
 		} else {
 			// Create
 			glog.Infof("%v: creating %v", describeObject(parent), describeObject(obj))
@@ -0,0 +1,92 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// StartRetrier retries the start logic of config based meta
+// controllers till all their controllers are started
+type StartRetrier struct {
+	// Caller that is starting its controllers
+	Caller fmt.Stringer
+
+	// Maximum time to wait for the start logic to succeed
+	WaitTimeout time.Duration
+
+	// Interval between retries
+	WaitInterval time.Duration
+
+	// When true the start logic is retried indefinitely
+	// i.e. even after the timeout
+	RetryIndefinitely *bool
+}
+
+// Run polls the condition until it's true, with the configured
+// interval and timeout.
+//
+// The condition function returns a bool indicating whether it
+// is satisfied, as well as an error which should be non-nil if
+// and only if the function was unable to determine whether or
+// not the condition is satisfied (for example if the check
+// involves calling a remote server and the request failed).
+func (r StartRetrier) Run(condition func() (bool, error)) error {
+	// mark the start time
+	start := time.Now()
+	for {
+		// check the condition
+		done, err := condition()
+		if err == nil && done {
+			// returning nil implies the condition has completed
+			return nil
+		}
+		if time.Since(start) > r.WaitTimeout &&
+			(r.RetryIndefinitely == nil || !*r.RetryIndefinitely) {
+			return errors.Errorf(
+				"Condition timed out after %s: %+v: %s",
+				r.WaitTimeout,
+				err,
+				r.Caller,
+			)
+		}
+		if err != nil {
+			// condition resulted in error
+			// keep trying until timeout
+			glog.V(7).Infof(
+				"Condition failed: Will retry after %s: %+v: %s",
+				r.WaitInterval,
+				err,
+				r.Caller,
+			)
+		} else {
+			// condition did not pass
+			// keep trying until timeout
+			glog.V(7).Infof(
+				"Waiting for condition to succeed: Will retry after %s: %s",
+				r.WaitInterval,
+				r.Caller,
+			)
+		}
+		// wait & then continue retrying
+		time.Sleep(r.WaitInterval)
+	}
+}
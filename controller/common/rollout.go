@@ -0,0 +1,407 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicobject "openebs.io/metac/dynamic/object"
+)
+
+const (
+	// ConditionTypeAttachmentsUpdated is the status condition that
+	// reports the progress of rolling updates of the attachments
+	ConditionTypeAttachmentsUpdated = "AttachmentsUpdated"
+
+	// RolloutReasonWaiting is used when a rolling update waits for
+	// the attachments to be available
+	RolloutReasonWaiting = "RolloutWaiting"
+
+	// RolloutReasonProgressing is used when a rolling update has
+	// updated one or more attachments
+	RolloutReasonProgressing = "RolloutProgressing"
+
+	// RolloutReasonComplete is used when all the attachments are
+	// updated & available
+	RolloutReasonComplete = "RolloutComplete"
+)
+
+// IsRollingUpdate returns true if the provided update method
+// updates resources in a rolling manner
+func IsRollingUpdate(method v1alpha1.ChildUpdateMethod) bool {
+	switch method {
+	case v1alpha1.ChildUpdateRollingInPlace, v1alpha1.ChildUpdateRollingRecreate:
+		return true
+	default:
+		return false
+	}
+}
+
+// RollingUpdateStrategy tunes the rolling update of resources
+// belonging to a single api group & kind
+type RollingUpdateStrategy struct {
+	// MaxUnavailable is the maximum number of resources that can
+	// be unavailable during the rolling update
+	MaxUnavailable int
+
+	// StatusChecks decide if a resource is available
+	StatusChecks *v1alpha1.ChildUpdateStatusChecks
+}
+
+// CheckStatusConditions verifies the provided resource's status
+// conditions against the provided checks. It returns an error if
+// any of these checks fail.
+func CheckStatusConditions(
+	checks *v1alpha1.ChildUpdateStatusChecks,
+	obj *unstructured.Unstructured,
+) error {
+	if checks == nil {
+		// Nothing to check.
+		return nil
+	}
+	for _, condCheck := range checks.Conditions {
+		cond := dynamicobject.GetStatusCondition(obj.UnstructuredContent(), condCheck.Type)
+		if cond == nil {
+			return errors.Errorf(
+				"required condition type missing: %q", condCheck.Type,
+			)
+		}
+		if condCheck.Status != nil && cond.Status != *condCheck.Status {
+			return errors.Errorf(
+				"%q condition status is %q (want %q)",
+				condCheck.Type,
+				cond.Status,
+				*condCheck.Status,
+			)
+		}
+		if condCheck.Reason != nil && cond.Reason != *condCheck.Reason {
+			return errors.Errorf(
+				"%q condition reason is %q (want %q)",
+				condCheck.Type,
+				cond.Reason,
+				*condCheck.Reason,
+			)
+		}
+	}
+	return nil
+}
+
+// checkAvailable returns an error if the provided observed
+// resource is not available as per the provided update method
+// & status checks
+func checkAvailable(
+	method v1alpha1.ChildUpdateMethod,
+	checks *v1alpha1.ChildUpdateStatusChecks,
+	observed *unstructured.Unstructured,
+) error {
+	if observed.GetDeletionTimestamp() != nil {
+		return errors.Errorf("pending deletion")
+	}
+	// For RollingInPlace, status should reflect the latest spec.
+	// ObservedGeneration is ignored if the resource does not
+	// support it.
+	if method == v1alpha1.ChildUpdateRollingInPlace {
+		observedGen := dynamicobject.GetObservedGeneration(observed.UnstructuredContent())
+		if observedGen > 0 && observedGen < observed.GetGeneration() {
+			return errors.Errorf("latest spec is not observed yet")
+		}
+	}
+	if err := CheckStatusConditions(checks, observed); err != nil {
+		return errors.Wrapf(err, "failed status check")
+	}
+	return nil
+}
+
+// RolloutStatus reports the progress of the rolling update of
+// resources belonging to a single api version & kind
+type RolloutStatus struct {
+	APIVersion string
+	Kind       string
+
+	// Desired is the number of desired resources
+	Desired int
+
+	// Pending is the number of resources that still need to be
+	// updated after this rollout step
+	Pending int
+
+	// Unavailable is the number of resources that were not
+	// available at the start of this rollout step
+	Unavailable int
+
+	// Updated holds the names of the resources updated in this
+	// rollout step
+	Updated []string
+
+	// Waiting explains the first unavailable resource. This is
+	// empty if all the resources are available.
+	Waiting string
+}
+
+// IsComplete returns true if all the resources are updated &
+// available
+func (s RolloutStatus) IsComplete() bool {
+	return s.Pending == 0 && s.Unavailable == 0 && len(s.Updated) == 0
+}
+
+// String implements Stringer interface
+func (s RolloutStatus) String() string {
+	msg := fmt.Sprintf(
+		"%s %s: %d desired, %d pending, %d unavailable",
+		s.APIVersion,
+		s.Kind,
+		s.Desired,
+		s.Pending,
+		s.Unavailable,
+	)
+	if len(s.Updated) != 0 {
+		msg += fmt.Sprintf(": updating %s", strings.Join(s.Updated, ", "))
+	}
+	if s.Waiting != "" {
+		msg += fmt.Sprintf(": waiting: %s", s.Waiting)
+	}
+	return msg
+}
+
+// NewRolloutCondition returns the status condition that reports
+// the provided rollouts
+func NewRolloutCondition(rollouts []RolloutStatus) *dynamicobject.StatusCondition {
+	// rollouts are reported in a stable order
+	sorted := append([]RolloutStatus(nil), rollouts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].APIVersion+"/"+sorted[i].Kind <
+			sorted[j].APIVersion+"/"+sorted[j].Kind
+	})
+	cond := &dynamicobject.StatusCondition{
+		Type:   ConditionTypeAttachmentsUpdated,
+		Status: "True",
+		Reason: RolloutReasonComplete,
+	}
+	var msgs []string
+	for _, r := range sorted {
+		msgs = append(msgs, r.String())
+		if r.IsComplete() {
+			continue
+		}
+		cond.Status = "False"
+		if len(r.Updated) != 0 {
+			cond.Reason = RolloutReasonProgressing
+		} else if cond.Reason != RolloutReasonProgressing {
+			cond.Reason = RolloutReasonWaiting
+		}
+	}
+	cond.Message = strings.Join(msgs, "; ")
+	return cond
+}
+
+// rollingUpdate is an update that is due as part of a rolling
+// update
+type rollingUpdate struct {
+	observed *unstructured.Unstructured
+	desired  *unstructured.Unstructured
+	merged   *unstructured.Unstructured
+
+	// isUnavailable is true if the observed resource is not
+	// available. Updating such a resource does not reduce the
+	// number of available resources.
+	isUnavailable bool
+}
+
+// getRollingUpdateStrategy returns the rolling update strategy
+// of this controller's resource type
+func (e *ResourceStatesController) getRollingUpdateStrategy() RollingUpdateStrategy {
+	var strategy RollingUpdateStrategy
+	if e.GetRollingUpdateStrategyByGK != nil {
+		strategy = e.GetRollingUpdateStrategyByGK(
+			e.DynamicClient.Group,
+			e.DynamicClient.Kind,
+		)
+	}
+	if strategy.MaxUnavailable < 1 {
+		// resources are updated one at a time by default
+		strategy.MaxUnavailable = 1
+	}
+	return strategy
+}
+
+// rollingCreateOrUpdate creates the desired resources that are not
+// observed & updates the observed resources in a rolling manner.
+//
+// NOTE:
+//	Observed resources are updated in the order of their names.
+// An update is done only if the number of unavailable resources
+// stays within the strategy's MaxUnavailable. Resources that are
+// not available already are updated irrespective of this limit.
+//
+// NOTE:
+//	Creates are never held back. A created resource is however
+// counted as unavailable till it is observed & passes the status
+// checks.
+func (e *ResourceStatesController) rollingCreateOrUpdate(
+	method v1alpha1.ChildUpdateMethod,
+) error {
+	var errs []error
+	strategy := e.getRollingUpdateStrategy()
+	rollout := RolloutStatus{
+		APIVersion: e.DynamicClient.APIVersion,
+		Kind:       e.DynamicClient.Kind,
+		Desired:    len(e.Desired),
+	}
+	var names []string
+	for name := range e.Desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pending []rollingUpdate
+	for _, name := range names {
+		dObj := e.Desired[name]
+		oObj := e.Observed[name]
+		if oObj == nil {
+			// try create since object is not observed in cluster
+			rollout.Unavailable++
+			if rollout.Waiting == "" {
+				rollout.Waiting = fmt.Sprintf("%s is not observed yet", name)
+			}
+			err := e.create(dObj)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		isUnavailable := false
+		if err := checkAvailable(method, strategy.StatusChecks, oObj); err != nil {
+			isUnavailable = true
+			rollout.Unavailable++
+			if rollout.Waiting == "" {
+				rollout.Waiting = fmt.Sprintf("%s is unavailable: %v", name, err)
+			}
+		}
+		mergedObj, _, err := e.merge(oObj, dObj)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if mergedObj == nil {
+			// nothing to update
+			continue
+		}
+		pending = append(pending, rollingUpdate{
+			observed:      oObj,
+			desired:       dObj,
+			merged:        mergedObj,
+			isUnavailable: isUnavailable,
+		})
+	}
+
+	budget := strategy.MaxUnavailable - rollout.Unavailable
+	for _, p := range pending {
+		if !p.isUnavailable {
+			if budget <= 0 {
+				rollout.Pending++
+				continue
+			}
+			budget--
+		}
+		err := e.applyMerged(p.observed, p.desired, p.merged, method)
+		if err != nil {
+			rollout.Pending++
+			errs = append(errs, err)
+			continue
+		}
+		rollout.Updated = append(rollout.Updated, p.desired.GetName())
+	}
+	glog.V(4).Infof("Rolling update: %s: %s", rollout, e)
+	if e.RecordRollout != nil {
+		e.RecordRollout(rollout)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// UpdateRolloutCondition sets the rollout condition corresponding
+// to the provided rollouts against the provided object. It is a
+// no-op if there are no rollouts or if the condition is unchanged.
+func UpdateRolloutCondition(
+	client *dynamicclientset.ResourceClient,
+	obj *unstructured.Unstructured,
+	rollouts []RolloutStatus,
+) error {
+	if len(rollouts) == 0 {
+		return nil
+	}
+	cond := NewRolloutCondition(rollouts)
+	_, err := client.Namespace(obj.GetNamespace()).AtomicStatusUpdate(
+		obj,
+		func(current *unstructured.Unstructured) bool {
+			old := dynamicobject.GetStatusCondition(
+				current.UnstructuredContent(),
+				ConditionTypeAttachmentsUpdated,
+			)
+			if old != nil && *old == *cond {
+				// Nothing to do.
+				return false
+			}
+			dynamicobject.SetStatusCondition(current.UnstructuredContent(), cond)
+			return true
+		},
+	)
+	return err
+}
+
+// RetainRolloutCondition copies the rollout condition if any from
+// the observed status to the provided status if the latter does
+// not have one. It returns the provided status if there is nothing
+// to retain. Otherwise it returns an updated copy of the provided
+// status.
+//
+// NOTE:
+//	This lets the rollout progress survive the status returned
+// by sync hooks that are unaware of this condition
+func RetainRolloutCondition(
+	observed map[string]interface{},
+	status map[string]interface{},
+) map[string]interface{} {
+	cond := dynamicobject.GetStatusCondition(
+		map[string]interface{}{"status": observed},
+		ConditionTypeAttachmentsUpdated,
+	)
+	if cond == nil {
+		return status
+	}
+	if dynamicobject.GetStatusCondition(
+		map[string]interface{}{"status": status},
+		ConditionTypeAttachmentsUpdated,
+	) != nil {
+		return status
+	}
+	retained := make(map[string]interface{})
+	if status != nil {
+		retained = runtime.DeepCopyJSON(status)
+	}
+	dynamicobject.SetCondition(retained, cond)
+	return retained
+}
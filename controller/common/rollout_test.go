@@ -0,0 +1,349 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/third_party/kubernetes"
+)
+
+// recordingResourceOperation records the names of the resources
+// that are created, updated or deleted
+type recordingResourceOperation struct {
+	NoopResourceOperation
+	names []string
+}
+
+func (r *recordingResourceOperation) Create(obj *unstructured.Unstructured, options metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.names = append(r.names, "create:"+obj.GetName())
+	return obj, nil
+}
+func (r *recordingResourceOperation) Update(obj *unstructured.Unstructured, options metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.names = append(r.names, "update:"+obj.GetName())
+	return obj, nil
+}
+func (r *recordingResourceOperation) Delete(name string, options *metav1.DeleteOptions, subresources ...string) error {
+	r.names = append(r.names, "delete:"+name)
+	return nil
+}
+
+func newRolloutTestObj(name, spec string, ready bool) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name": name,
+				"annotations": map[string]interface{}{
+					AttachmentCreateAnnotationKey: "test-watch-uid",
+				},
+			},
+			"spec": spec,
+		},
+	}
+	if ready {
+		obj.Object["status"] = map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+		}
+	}
+	return obj
+}
+
+func TestRollingCreateOrUpdate(t *testing.T) {
+	readyCheck := &v1alpha1.ChildUpdateStatusChecks{
+		Conditions: []v1alpha1.StatusConditionCheck{
+			{Type: "Ready", Status: kubernetes.StringPtr("True")},
+		},
+	}
+	var tests = map[string]struct {
+		method         v1alpha1.ChildUpdateMethod
+		maxUnavailable int
+		observed       []*unstructured.Unstructured
+		desired        []*unstructured.Unstructured
+		expectOps      []string
+		expectPending  int
+		expectUnavail  int
+	}{
+		"one at a time": {
+			method: v1alpha1.ChildUpdateRollingInPlace,
+			observed: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "old", true),
+				newRolloutTestObj("b", "old", true),
+				newRolloutTestObj("c", "old", true),
+			},
+			desired: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "new", false),
+				newRolloutTestObj("c", "new", false),
+			},
+			expectOps:     []string{"update:a"},
+			expectPending: 2,
+		},
+		"max unavailable of two": {
+			method:         v1alpha1.ChildUpdateRollingRecreate,
+			maxUnavailable: 2,
+			observed: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "old", true),
+				newRolloutTestObj("b", "old", true),
+				newRolloutTestObj("c", "old", true),
+			},
+			desired: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "new", false),
+				newRolloutTestObj("c", "new", false),
+			},
+			expectOps:     []string{"delete:a", "delete:b"},
+			expectPending: 1,
+		},
+		"wait for unavailable": {
+			method: v1alpha1.ChildUpdateRollingInPlace,
+			observed: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "old", true),
+			},
+			desired: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "new", false),
+			},
+			expectPending: 1,
+			expectUnavail: 1,
+		},
+		"wait for create": {
+			method: v1alpha1.ChildUpdateRollingInPlace,
+			observed: []*unstructured.Unstructured{
+				newRolloutTestObj("b", "old", true),
+			},
+			desired: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "new", false),
+			},
+			expectOps:     []string{"create:a"},
+			expectPending: 1,
+			expectUnavail: 1,
+		},
+		"unavailable ones are updated first": {
+			method: v1alpha1.ChildUpdateRollingInPlace,
+			observed: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "old", true),
+				newRolloutTestObj("b", "old", false),
+			},
+			desired: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "new", false),
+			},
+			expectOps:     []string{"update:b"},
+			expectPending: 1,
+			expectUnavail: 1,
+		},
+		"complete": {
+			method: v1alpha1.ChildUpdateRollingInPlace,
+			observed: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", true),
+				newRolloutTestObj("b", "new", true),
+			},
+			desired: []*unstructured.Unstructured{
+				newRolloutTestObj("a", "new", false),
+				newRolloutTestObj("b", "new", false),
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ops := &recordingResourceOperation{}
+			var rollouts []RolloutStatus
+			ctrl := &ResourceStatesController{
+				ClusterStatesControllerBase: ClusterStatesControllerBase{
+					GetChildUpdateStrategyByGK: func(group, kind string) v1alpha1.ChildUpdateMethod {
+						return mock.method
+					},
+					IsPatchByGK: func(group, kind string) bool {
+						return false
+					},
+					GetRollingUpdateStrategyByGK: func(group, kind string) RollingUpdateStrategy {
+						return RollingUpdateStrategy{
+							MaxUnavailable: mock.maxUnavailable,
+							StatusChecks:   readyCheck,
+						}
+					},
+					RecordRollout: func(rollout RolloutStatus) {
+						rollouts = append(rollouts, rollout)
+					},
+					Watch: &unstructured.Unstructured{
+						Object: map[string]interface{}{
+							"metadata": map[string]interface{}{
+								"uid": "test-watch-uid",
+							},
+						},
+					},
+				},
+				DynamicClient: &dynamicclientset.ResourceClient{
+					ResourceInterface: ops,
+					APIResource:       &dynamicdiscovery.APIResource{},
+				},
+				Observed: map[string]*unstructured.Unstructured{},
+				Desired:  map[string]*unstructured.Unstructured{},
+			}
+			for _, obj := range mock.observed {
+				ctrl.Observed[obj.GetName()] = obj
+			}
+			for _, obj := range mock.desired {
+				ctrl.Desired[obj.GetName()] = obj
+			}
+			err := ctrl.CreateOrUpdate()
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			sort.Strings(ops.names)
+			if fmt.Sprint(ops.names) != fmt.Sprint(mock.expectOps) {
+				t.Fatalf("Expected ops %v got %v", mock.expectOps, ops.names)
+			}
+			if len(rollouts) != 1 {
+				t.Fatalf("Expected 1 rollout got %d", len(rollouts))
+			}
+			if rollouts[0].Pending != mock.expectPending {
+				t.Fatalf("Expected pending %d got %d", mock.expectPending, rollouts[0].Pending)
+			}
+			if rollouts[0].Unavailable != mock.expectUnavail {
+				t.Fatalf("Expected unavailable %d got %d", mock.expectUnavail, rollouts[0].Unavailable)
+			}
+		})
+	}
+}
+
+func TestNewRolloutCondition(t *testing.T) {
+	var tests = map[string]struct {
+		rollouts     []RolloutStatus
+		expectStatus string
+		expectReason string
+	}{
+		"complete": {
+			rollouts: []RolloutStatus{
+				{Kind: "Deployment", Desired: 2},
+				{Kind: "Service", Desired: 1},
+			},
+			expectStatus: "True",
+			expectReason: RolloutReasonComplete,
+		},
+		"waiting": {
+			rollouts: []RolloutStatus{
+				{Kind: "Deployment", Desired: 2, Pending: 1, Unavailable: 1},
+				{Kind: "Service", Desired: 1},
+			},
+			expectStatus: "False",
+			expectReason: RolloutReasonWaiting,
+		},
+		"progressing wins over waiting": {
+			rollouts: []RolloutStatus{
+				{Kind: "Deployment", Desired: 2, Pending: 1, Updated: []string{"a"}},
+				{Kind: "Service", Desired: 1, Pending: 1, Unavailable: 1},
+			},
+			expectStatus: "False",
+			expectReason: RolloutReasonProgressing,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := NewRolloutCondition(mock.rollouts)
+			if got.Status != mock.expectStatus {
+				t.Fatalf("Expected status %q got %q", mock.expectStatus, got.Status)
+			}
+			if got.Reason != mock.expectReason {
+				t.Fatalf("Expected reason %q got %q", mock.expectReason, got.Reason)
+			}
+		})
+	}
+}
+
+func TestRetainRolloutCondition(t *testing.T) {
+	observed := map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"type":   ConditionTypeAttachmentsUpdated,
+				"status": "False",
+				"reason": RolloutReasonWaiting,
+			},
+		},
+	}
+	var tests = map[string]struct {
+		observed     map[string]interface{}
+		status       map[string]interface{}
+		expectReason string
+	}{
+		"nothing to retain": {
+			status: map[string]interface{}{"phase": "Online"},
+		},
+		"retain in nil status": {
+			observed:     observed,
+			expectReason: RolloutReasonWaiting,
+		},
+		"retain in hook status": {
+			observed:     observed,
+			status:       map[string]interface{}{"phase": "Online"},
+			expectReason: RolloutReasonWaiting,
+		},
+		"hook status wins": {
+			observed: observed,
+			status: map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":   ConditionTypeAttachmentsUpdated,
+						"status": "True",
+						"reason": RolloutReasonComplete,
+					},
+				},
+			},
+			expectReason: RolloutReasonComplete,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := RetainRolloutCondition(mock.observed, mock.status)
+			cond := dynamicobject.GetStatusCondition(
+				map[string]interface{}{"status": got},
+				ConditionTypeAttachmentsUpdated,
+			)
+			if mock.expectReason == "" {
+				if cond != nil {
+					t.Fatalf("Expected no condition got %+v", cond)
+				}
+				return
+			}
+			if cond == nil || cond.Reason != mock.expectReason {
+				t.Fatalf("Expected reason %q got %+v", mock.expectReason, cond)
+			}
+			if mock.status != nil && mock.status["phase"] != nil && got["phase"] != "Online" {
+				t.Fatalf("Expected phase to be retained got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selector
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+// IndexableRequirement is an equality requirement of a select term.
+// A target matching this requirement has the reference's value at
+// the requirement's target path. Hence targets matching this select
+// term can be looked up from an index of targets by this path.
+type IndexableRequirement struct {
+	// TargetFields is the nested field path of the target
+	TargetFields []string
+
+	// Operator decides the reference value
+	Operator v1alpha1.ReferenceSelectorOperator
+
+	// ReferenceFields is the nested field path of the reference.
+	// This is used by Equals operator only.
+	ReferenceFields []string
+}
+
+// ReferenceValue returns the value that a matching target has at
+// the target path
+func (r IndexableRequirement) ReferenceValue(
+	reference *unstructured.Unstructured,
+) (string, error) {
+	if reference == nil || reference.Object == nil {
+		return "", errors.Errorf("Can't get reference value: Nil reference")
+	}
+	switch r.Operator {
+	case v1alpha1.ReferenceSelectorOpEqualsUID:
+		return string(reference.GetUID()), nil
+	case v1alpha1.ReferenceSelectorOpEqualsName:
+		return reference.GetName(), nil
+	case v1alpha1.ReferenceSelectorOpEqualsNamespace:
+		return reference.GetNamespace(), nil
+	default:
+		value, _, err := unstructured.NestedString(
+			reference.Object,
+			r.ReferenceFields...,
+		)
+		if err != nil {
+			return "", errors.Wrapf(
+				err,
+				"Can't get reference value at path %q",
+				strings.Join(r.ReferenceFields, "."),
+			)
+		}
+		return value, nil
+	}
+}
+
+// isNamespace returns true if this requirement is against the
+// namespace of the target
+func (r IndexableRequirement) isNamespace() bool {
+	return len(r.TargetFields) == 2 &&
+		r.TargetFields[0] == "metadata" &&
+		r.TargetFields[1] == "namespace"
+}
+
+// IndexableRequirements returns one equality requirement for each
+// of the provided select terms. Targets matching any of these terms
+// are a subset of the targets matching any of these requirements.
+//
+// It returns false if there are no terms or if any of the terms has
+// no equality requirement against the reference. All targets are
+// candidates in such cases.
+//
+// NOTE:
+//	A requirement against the target's namespace is chosen only if
+// the select term has no other equality requirement, since namespaces
+// are usually less selective.
+func IndexableRequirements(
+	terms []*v1alpha1.SelectorTerm,
+) ([]IndexableRequirement, bool) {
+	if len(terms) == 0 {
+		return nil, false
+	}
+	var requirements []IndexableRequirement
+	for _, term := range terms {
+		if term == nil {
+			// nil terms never match
+			continue
+		}
+		var chosen *IndexableRequirement
+		for _, r := range termRequirements(*term) {
+			r := r
+			if chosen == nil || (chosen.isNamespace() && !r.isNamespace()) {
+				chosen = &r
+			}
+		}
+		if chosen == nil {
+			return nil, false
+		}
+		requirements = append(requirements, *chosen)
+	}
+	return requirements, true
+}
+
+// termRequirements returns the equality requirements of the provided
+// select term
+func termRequirements(term v1alpha1.SelectorTerm) []IndexableRequirement {
+	var requirements []IndexableRequirement
+	for _, path := range term.MatchReference {
+		if path == "" {
+			continue
+		}
+		fields := splitNestedPath(path)
+		requirements = append(requirements, IndexableRequirement{
+			TargetFields:    fields,
+			Operator:        v1alpha1.ReferenceSelectorOpEquals,
+			ReferenceFields: fields,
+		})
+	}
+	for _, exp := range term.MatchReferenceExpressions {
+		if exp.Key == "" {
+			continue
+		}
+		r := IndexableRequirement{
+			TargetFields: splitNestedPath(exp.Key),
+			Operator:     exp.Operator,
+		}
+		switch exp.Operator {
+		case v1alpha1.ReferenceSelectorOpEquals,
+			v1alpha1.ReferenceSelectorOperator(""):
+			r.Operator = v1alpha1.ReferenceSelectorOpEquals
+			r.ReferenceFields = r.TargetFields
+			if exp.RefKey != "" {
+				r.ReferenceFields = splitNestedPath(exp.RefKey)
+			}
+		case v1alpha1.ReferenceSelectorOpEqualsUID,
+			v1alpha1.ReferenceSelectorOpEqualsName,
+			v1alpha1.ReferenceSelectorOpEqualsNamespace:
+			if exp.RefKey != "" {
+				// this is invalid & fails during match
+				continue
+			}
+		default:
+			continue
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements
+}
+
+// splitNestedPath splits the provided dot separated path into its
+// fields. A '\' escapes a dot that is part of a field.
+func splitNestedPath(nestedpath string) []string {
+	var restored []string
+	sanitised := strings.ReplaceAll(nestedpath, `\.`, "-@@-")
+	for _, sfield := range strings.Split(sanitised, ".") {
+		restored = append(
+			restored,
+			strings.ReplaceAll(sfield, "-@@-", "."),
+		)
+	}
+	return restored
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selector
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+func TestIndexableRequirements(t *testing.T) {
+	var tests = map[string]struct {
+		terms        []*v1alpha1.SelectorTerm
+		isIndexable  bool
+		expectFields []string
+	}{
+		"no terms": {
+			isIndexable: false,
+		},
+		"term without reference requirements": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchLabels: map[string]string{"app": "test"}},
+			},
+			isIndexable: false,
+		},
+		"term with not equals only": {
+			terms: []*v1alpha1.SelectorTerm{
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:      "spec.name",
+							Operator: v1alpha1.ReferenceSelectorOpNotEquals,
+						},
+					},
+				},
+			},
+			isIndexable: false,
+		},
+		"match reference": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchReference: []string{`metadata.labels.app\.io/name`}},
+			},
+			isIndexable:  true,
+			expectFields: []string{"metadata/labels/app.io/name"},
+		},
+		"namespace is chosen last": {
+			terms: []*v1alpha1.SelectorTerm{
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:      "metadata.namespace",
+							Operator: v1alpha1.ReferenceSelectorOpEqualsNamespace,
+						},
+						{
+							Key:      "spec.watchUID",
+							Operator: v1alpha1.ReferenceSelectorOpEqualsUID,
+						},
+					},
+				},
+			},
+			isIndexable:  true,
+			expectFields: []string{"spec/watchUID"},
+		},
+		"one term per requirement": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchReference: []string{"spec.a"}},
+				nil,
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:    "spec.b",
+							RefKey: "spec.c",
+						},
+					},
+				},
+			},
+			isIndexable:  true,
+			expectFields: []string{"spec/a", "spec/b"},
+		},
+		"any term not indexable": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchReference: []string{"spec.a"}},
+				{MatchFields: map[string]string{"spec.b": "b"}},
+			},
+			isIndexable: false,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got, ok := IndexableRequirements(mock.terms)
+			if ok != mock.isIndexable {
+				t.Fatalf("Expected indexable %t got %t", mock.isIndexable, ok)
+			}
+			if len(got) != len(mock.expectFields) {
+				t.Fatalf("Expected %d requirements got %d", len(mock.expectFields), len(got))
+			}
+			for i, r := range got {
+				if strings.Join(r.TargetFields, "/") != mock.expectFields[i] {
+					t.Fatalf(
+						"Expected fields %q at %d got %q",
+						mock.expectFields[i],
+						i,
+						strings.Join(r.TargetFields, "/"),
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexableRequirementReferenceValue(t *testing.T) {
+	reference := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"namespace": "ns",
+				"name":      "watch",
+				"uid":       "watch-uid",
+			},
+			"spec": map[string]interface{}{
+				"c":     "c-value",
+				"count": int64(1),
+			},
+		},
+	}
+	var tests = map[string]struct {
+		requirement IndexableRequirement
+		expect      string
+		isErr       bool
+	}{
+		"equals uid": {
+			requirement: IndexableRequirement{
+				Operator: v1alpha1.ReferenceSelectorOpEqualsUID,
+			},
+			expect: "watch-uid",
+		},
+		"equals name": {
+			requirement: IndexableRequirement{
+				Operator: v1alpha1.ReferenceSelectorOpEqualsName,
+			},
+			expect: "watch",
+		},
+		"equals namespace": {
+			requirement: IndexableRequirement{
+				Operator: v1alpha1.ReferenceSelectorOpEqualsNamespace,
+			},
+			expect: "ns",
+		},
+		"equals reference path": {
+			requirement: IndexableRequirement{
+				Operator:        v1alpha1.ReferenceSelectorOpEquals,
+				ReferenceFields: []string{"spec", "c"},
+			},
+			expect: "c-value",
+		},
+		"missing reference path": {
+			requirement: IndexableRequirement{
+				Operator:        v1alpha1.ReferenceSelectorOpEquals,
+				ReferenceFields: []string{"spec", "d"},
+			},
+			expect: "",
+		},
+		"non string reference path": {
+			requirement: IndexableRequirement{
+				Operator:        v1alpha1.ReferenceSelectorOpEquals,
+				ReferenceFields: []string{"spec", "count"},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got, err := mock.requirement.ReferenceValue(reference)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if got != mock.expect {
+				t.Fatalf("Expected %q got %q", mock.expect, got)
+			}
+		})
+	}
+}
@@ -77,17 +77,7 @@ func NewReferenceSelector(config ReferenceSelectorConfig) *ReferenceSelection {
 }
 
 func (s *ReferenceSelection) pathToFields(nestedpath string) []string {
-	var restored []string
-	// '\' is the escape character for .
-	sanitised := strings.ReplaceAll(nestedpath, `\.`, "-@@-")
-	sfields := strings.Split(sanitised, ".")
-	for _, sfield := range sfields {
-		restored = append(
-			restored,
-			strings.ReplaceAll(sfield, "-@@-", "."),
-		)
-	}
-	return restored
+	return splitNestedPath(nestedpath)
 }
 
 func (s *ReferenceSelection) addTargetExpressionFromPath(nestedpath string) {
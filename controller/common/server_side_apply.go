@@ -0,0 +1,742 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicapply "openebs.io/metac/dynamic/apply"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	"openebs.io/metac/metrics"
+)
+
+const (
+	// ServerSideAppliedHashAnnotationKey is the annotation key used
+	// to hold the hash of the configuration that was applied last
+	// via server side apply
+	//
+	// NOTE:
+	//	This lets metac skip server side apply calls that would not
+	// change anything
+	ServerSideAppliedHashAnnotationKey string = "metac.openebs.io/server-side-applied-hash"
+
+	// defaultFieldManager is the field manager used if none is set
+	defaultFieldManager string = "metac"
+
+	// maxFieldManagerLength is the maximum length of a field manager
+	// name that is accepted by kubernetes
+	maxFieldManagerLength int = 128
+
+	// beforeFirstApplyManager is the field manager set by kubernetes
+	// to own the fields of a resource without managed fields at its
+	// first server side apply
+	beforeFirstApplyManager string = "before-first-apply"
+)
+
+// updateFieldManagers own the fields that were set by metac's
+// 3-way merges
+//
+// NOTE:
+//	Kubernetes derives the field manager of an update that does
+// not set one from the client's user agent
+var updateFieldManagers = []string{
+	strings.Split(rest.DefaultKubernetesUserAgent(), "/")[0],
+	beforeFirstApplyManager,
+}
+
+// ServerSideApplyOptions tunes the server side apply of resources
+type ServerSideApplyOptions struct {
+	// FieldManager owns the applied fields
+	FieldManager string
+
+	// Force takes over the ownership of applied fields from other
+	// field managers
+	Force bool
+}
+
+// MakeFieldManager returns the field manager name corresponding to
+// the provided controller kind & name parts
+func MakeFieldManager(controllerKind string, names ...string) string {
+	parts := append(
+		[]string{defaultFieldManager, strings.ToLower(controllerKind)},
+		names...,
+	)
+	manager := strings.Join(parts, "/")
+	if len(manager) > maxFieldManagerLength {
+		manager = manager[:maxFieldManagerLength]
+	}
+	return manager
+}
+
+// MakeServerSideApplyOptions returns the server side apply options
+// corresponding to the provided API options. The provided field
+// manager is used if API options do not set one.
+//
+// NOTE:
+//	Conflicts are forced by default since a controller is expected
+// to own the fields it applies
+func MakeServerSideApplyOptions(
+	opts *v1alpha1.ServerSideApplyOptions,
+	fieldManager string,
+) ServerSideApplyOptions {
+	ssa := ServerSideApplyOptions{
+		FieldManager: fieldManager,
+		Force:        true,
+	}
+	if opts != nil {
+		if opts.FieldManager != "" {
+			ssa.FieldManager = opts.FieldManager
+		}
+		if opts.ForceConflicts != nil {
+			ssa.Force = *opts.ForceConflicts
+		}
+	}
+	if ssa.FieldManager == "" {
+		ssa.FieldManager = defaultFieldManager
+	}
+	return ssa
+}
+
+// ServerSideApplyOptionsGetter provides the abstraction to figure out
+// the server side apply options of resources
+type ServerSideApplyOptionsGetter interface {
+	GetServerSideApplyOptions(apiGroup, kind string) ServerSideApplyOptions
+}
+
+// NewServerSideApplyConfig returns the configuration to be sent
+// via server side apply corresponding to the provided desired
+// state. The returned configuration is annotated with its own hash.
+//
+// NOTE:
+//	Provided last applied annotations are removed from the returned
+// configuration since server side apply does not need them
+func NewServerSideApplyConfig(
+	desired *unstructured.Unstructured,
+	lastAppliedKeys ...string,
+) (*unstructured.Unstructured, error) {
+	config := desired.DeepCopy()
+	anns := config.GetAnnotations()
+	for _, key := range append(lastAppliedKeys, ServerSideAppliedHashAnnotationKey) {
+		delete(anns, key)
+	}
+	// hash is computed without the hash annotation
+	config.SetAnnotations(anns)
+	raw, err := json.Marshal(config.UnstructuredContent())
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Can't make server side apply config: %s",
+			DescObjectAsKey(desired),
+		)
+	}
+	sum := sha256.Sum256(raw)
+	if anns == nil {
+		anns = make(map[string]string)
+	}
+	anns[ServerSideAppliedHashAnnotationKey] = hex.EncodeToString(sum[:])
+	config.SetAnnotations(anns)
+	return config, nil
+}
+
+// IsServerSideApplied returns true if the provided configuration
+// was applied last & the observed state still has all of its
+// fields. In other words applying this configuration again would
+// not change anything.
+func IsServerSideApplied(
+	observed *unstructured.Unstructured,
+	config *unstructured.Unstructured,
+) bool {
+	if observed == nil ||
+		observed.GetAnnotations()[ServerSideAppliedHashAnnotationKey] !=
+			config.GetAnnotations()[ServerSideAppliedHashAnnotationKey] {
+		return false
+	}
+	return isSubset(config.UnstructuredContent(), observed.UnstructuredContent())
+}
+
+// isSubset returns true if all the fields of the provided subset
+// are found in the provided superset with the same values
+//
+// NOTE:
+//	Every item of a subset list needs to be a subset of some item
+// of the superset list. Superset lists may have more items e.g.
+// the ones owned by other field managers.
+func isSubset(subset, superset interface{}) bool {
+	switch sub := subset.(type) {
+	case map[string]interface{}:
+		super, ok := superset.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range sub {
+			superValue, found := super[key]
+			if !found || !isSubset(value, superValue) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		super, ok := superset.([]interface{})
+		if !ok || len(sub) > len(super) {
+			return false
+		}
+		for _, item := range sub {
+			var found bool
+			for _, superItem := range super {
+				if isSubset(item, superItem) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	default:
+		// numbers may be decoded as different types
+		subJSON, subErr := json.Marshal(subset)
+		superJSON, superErr := json.Marshal(superset)
+		if subErr != nil || superErr != nil {
+			return reflect.DeepEqual(subset, superset)
+		}
+		return string(subJSON) == string(superJSON)
+	}
+}
+
+// ServerSideApply creates or updates the resource corresponding to
+// the provided configuration via kubernetes server side apply
+func ServerSideApply(
+	client *dynamicclientset.ResourceClient,
+	config *unstructured.Unstructured,
+	opts ServerSideApplyOptions,
+) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(config.UnstructuredContent())
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Can't marshal server side apply config: %s",
+			DescObjectAsKey(config),
+		)
+	}
+	force := opts.Force
+	return client.Namespace(config.GetNamespace()).Patch(
+		config.GetName(),
+		types.ApplyPatchType,
+		data,
+		metav1.PatchOptions{
+			FieldManager: opts.FieldManager,
+			Force:        &force,
+		},
+	)
+}
+
+// mergeFieldSets adds the fields of the provided source field set
+// to the provided destination field set. Field sets are in the
+// FieldsV1 format found in managed fields.
+func mergeFieldSets(dest, src map[string]interface{}) {
+	for key, srcValue := range src {
+		destValue, found := dest[key]
+		if !found {
+			dest[key] = runtime.DeepCopyJSONValue(srcValue)
+			continue
+		}
+		destSet, isDestSet := destValue.(map[string]interface{})
+		srcSet, isSrcSet := srcValue.(map[string]interface{})
+		if isDestSet && isSrcSet {
+			mergeFieldSets(destSet, srcSet)
+		}
+	}
+}
+
+// UpgradeManagedFields hands over the fields owned by the provided
+// update managers to the provided apply manager. It returns true if
+// the managed fields of the provided resource were changed.
+//
+// NOTE:
+//	Fields that were set via updates are not owned by the apply
+// manager. Hence these fields would never be removed by server
+// side apply even if they are dropped from the applied config.
+// This is similar to how kubectl upgrades resources that were
+// applied at client side.
+func UpgradeManagedFields(
+	obj *unstructured.Unstructured,
+	applyManager string,
+	updateManagers ...string,
+) (bool, error) {
+	entries, found, err := unstructured.NestedSlice(
+		obj.UnstructuredContent(), "metadata", "managedFields",
+	)
+	if err != nil {
+		return false, errors.Wrapf(
+			err,
+			"Can't upgrade managed fields: %s",
+			DescObjectAsKey(obj),
+		)
+	}
+	if !found {
+		return false, nil
+	}
+	isUpdateManager := map[string]bool{}
+	for _, manager := range updateManagers {
+		isUpdateManager[manager] = true
+	}
+
+	var upgraded []interface{}
+	var updateFieldSets []map[string]interface{}
+	var apply map[string]interface{}
+	for _, item := range entries {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			upgraded = append(upgraded, item)
+			continue
+		}
+		manager, _ := entry["manager"].(string)
+		operation, _ := entry["operation"].(string)
+		subresource, _ := entry["subresource"].(string)
+		if subresource != "" {
+			// fields of sub resources e.g. status are not applied
+			upgraded = append(upgraded, entry)
+			continue
+		}
+		if operation == string(metav1.ManagedFieldsOperationUpdate) &&
+			isUpdateManager[manager] {
+			fieldSet, _ := entry["fieldsV1"].(map[string]interface{})
+			updateFieldSets = append(updateFieldSets, fieldSet)
+			continue
+		}
+		if operation == string(metav1.ManagedFieldsOperationApply) &&
+			manager == applyManager {
+			apply = entry
+		}
+		upgraded = append(upgraded, entry)
+	}
+	if len(updateFieldSets) == 0 {
+		return false, nil
+	}
+	if apply == nil {
+		apply = map[string]interface{}{
+			"manager":    applyManager,
+			"operation":  string(metav1.ManagedFieldsOperationApply),
+			"apiVersion": obj.GetAPIVersion(),
+			"fieldsType": "FieldsV1",
+			"fieldsV1":   map[string]interface{}{},
+		}
+		upgraded = append(upgraded, apply)
+	}
+	applyFieldSet, ok := apply["fieldsV1"].(map[string]interface{})
+	if !ok {
+		applyFieldSet = map[string]interface{}{}
+		apply["fieldsV1"] = applyFieldSet
+	}
+	for _, fieldSet := range updateFieldSets {
+		mergeFieldSets(applyFieldSet, fieldSet)
+	}
+	err = unstructured.SetNestedSlice(
+		obj.UnstructuredContent(), upgraded, "metadata", "managedFields",
+	)
+	if err != nil {
+		return false, errors.Wrapf(
+			err,
+			"Can't upgrade managed fields: %s",
+			DescObjectAsKey(obj),
+		)
+	}
+	return true, nil
+}
+
+// MigrateToServerSideApply migrates the provided resource that was
+// updated via 3-way merges earlier to server side apply. Fields set
+// by these merges are handed over to the provided field manager &
+// the provided last applied annotation is removed. It is a no-op if
+// the observed resource does not have this annotation.
+//
+// NOTE:
+//	Applied state is the resource returned by the server side apply.
+// Its managed fields are upgraded before the annotation is removed
+// so that a failed upgrade is retried at the next sync.
+func MigrateToServerSideApply(
+	client *dynamicclientset.ResourceClient,
+	observed *unstructured.Unstructured,
+	applied *unstructured.Unstructured,
+	fieldManager string,
+	lastAppliedKey string,
+) error {
+	if _, found := observed.GetAnnotations()[lastAppliedKey]; !found {
+		return nil
+	}
+	if applied != nil {
+		upgraded := applied.DeepCopy()
+		isUpgraded, err := UpgradeManagedFields(
+			upgraded,
+			fieldManager,
+			updateFieldManagers...,
+		)
+		if err != nil {
+			return err
+		}
+		if isUpgraded {
+			err = patchManagedFields(client, upgraded)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return RemoveAnnotations(client, observed, lastAppliedKey)
+}
+
+// patchManagedFields replaces the managed fields of the resource
+// with the ones of the provided resource. Patch fails if the
+// resource was changed since the provided resource was read.
+func patchManagedFields(
+	client *dynamicclientset.ResourceClient,
+	obj *unstructured.Unstructured,
+) error {
+	managedFields, _, _ := unstructured.NestedSlice(
+		obj.UnstructuredContent(), "metadata", "managedFields",
+	)
+	data, err := json.Marshal([]map[string]interface{}{
+		{
+			"op":    "test",
+			"path":  "/metadata/resourceVersion",
+			"value": obj.GetResourceVersion(),
+		},
+		{
+			"op":    "replace",
+			"path":  "/metadata/managedFields",
+			"value": managedFields,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Namespace(obj.GetNamespace()).Patch(
+		obj.GetName(),
+		types.JSONPatchType,
+		data,
+		metav1.PatchOptions{},
+	)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"Failed to upgrade managed fields: %s",
+			DescObjectAsKey(obj),
+		)
+	}
+	glog.V(4).Infof("Upgraded managed fields: %s", DescObjectAsKey(obj))
+	return nil
+}
+
+// RemoveAnnotations removes the provided annotations from the
+// provided resource. It is a no-op if the resource has none of
+// these annotations.
+//
+// NOTE:
+//	This is used to migrate resources that were updated via 3-way
+// merges to server side apply. Server side apply can't remove these
+// annotations since they are owned by other field managers.
+func RemoveAnnotations(
+	client *dynamicclientset.ResourceClient,
+	obj *unstructured.Unstructured,
+	keys ...string,
+) error {
+	remove := map[string]interface{}{}
+	for _, key := range keys {
+		if _, found := obj.GetAnnotations()[key]; found {
+			// null removes the annotation via merge patch
+			remove[key] = nil
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": remove,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = client.Namespace(obj.GetNamespace()).Patch(
+		obj.GetName(),
+		types.MergePatchType,
+		data,
+		metav1.PatchOptions{},
+	)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"Failed to remove annotations %v: %s",
+			keys,
+			DescObjectAsKey(obj),
+		)
+	}
+	glog.V(4).Infof(
+		"Removed annotations %v: %s",
+		keys,
+		DescObjectAsKey(obj),
+	)
+	return nil
+}
+
+// getServerSideApplyOptions returns the server side apply options
+// of this controller's resource type
+func (e *ResourceStatesController) getServerSideApplyOptions() ServerSideApplyOptions {
+	if e.GetServerSideApplyOptionsByGK == nil {
+		return MakeServerSideApplyOptions(nil, "")
+	}
+	return e.GetServerSideApplyOptionsByGK(
+		e.DynamicClient.Group,
+		e.DynamicClient.Kind,
+	)
+}
+
+// serverSideApplyConfig returns the configuration to be server side
+// applied corresponding to the provided desired state. Observed state
+// is nil if the resource is yet to be created.
+//
+// NOTE:
+//	Annotations & owner reference set by metac are part of the
+// configuration. Server side apply would otherwise remove these
+// fields once they are owned by this controller's field manager.
+func (e *ResourceStatesController) serverSideApplyConfig(
+	observed *unstructured.Unstructured,
+	desired *unstructured.Unstructured,
+) (*unstructured.Unstructured, error) {
+	config := desired.DeepCopy()
+	if config.GetNamespace() == "" && e.DynamicClient.Namespaced {
+		config.SetNamespace(e.Watch.GetNamespace())
+	}
+	watchUID := string(e.Watch.GetUID())
+	anns := config.GetAnnotations()
+	if anns == nil {
+		anns = make(map[string]string)
+	}
+	createdBy := watchUID
+	if observed != nil {
+		createdBy = observed.GetAnnotations()[AttachmentCreateAnnotationKey]
+	}
+	if createdBy != "" {
+		anns[AttachmentCreateAnnotationKey] = createdBy
+	}
+	if createdBy != watchUID {
+		// set who is responsible for updating a resource that
+		// was not created due to this watch
+		anns[watchUID+AttachmentUpdateAnnotationKeySuffix] =
+			DescObjectAsSanitisedKey(e.Watch)
+	}
+	config.SetAnnotations(anns)
+
+	// watch is set as the owner if it was the owner at creation
+	isOwner := observed == nil && e.IsWatchOwner != nil && *e.IsWatchOwner
+	if observed != nil {
+		for _, ref := range observed.GetOwnerReferences() {
+			if ref.UID == e.Watch.GetUID() {
+				isOwner = true
+				break
+			}
+		}
+	}
+	if isOwner {
+		ownerRefs := config.GetOwnerReferences()
+		var found bool
+		for _, ref := range ownerRefs {
+			if ref.UID == e.Watch.GetUID() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ownerRefs = append(ownerRefs, *MakeOwnerRef(e.Watch))
+			config.SetOwnerReferences(ownerRefs)
+		}
+	}
+	return NewServerSideApplyConfig(
+		config,
+		watchUID+GCTLLastAppliedAnnotationKeySuffix,
+	)
+}
+
+// serverSideApply creates or updates the provided observed state
+// to the provided configuration via server side apply. Observed
+// state is nil if the resource is yet to be created.
+//
+// NOTE:
+//	Resources that were updated via 3-way merges earlier are
+// migrated once they are server side applied.
+func (e *ResourceStatesController) serverSideApply(
+	observed *unstructured.Unstructured,
+	config *unstructured.Unstructured,
+) error {
+	op := metrics.OperationUpdate
+	if observed == nil {
+		op = metrics.OperationCreate
+	}
+	glog.V(6).Infof(
+		"Server side applying %s: %s",
+		DescObjectAsKey(config),
+		e,
+	)
+	opts := e.getServerSideApplyOptions()
+	applied, err := ServerSideApply(e.DynamicClient, config, opts)
+	e.recordOperation(op, err)
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof(
+		"Server side applied %s: %s",
+		DescObjectAsKey(config),
+		e,
+	)
+	if observed == nil {
+		return nil
+	}
+	return MigrateToServerSideApply(
+		e.DynamicClient,
+		observed,
+		applied,
+		opts.FieldManager,
+		string(e.Watch.GetUID())+GCTLLastAppliedAnnotationKeySuffix,
+	)
+}
+
+// serverSideApplyChild creates or updates the provided observed child
+// to its desired state via server side apply. Observed child is nil
+// if the child is yet to be created.
+//
+// NOTE:
+//	Parent is always set as the controller of the child. Child that
+// was updated via 3-way merges earlier is migrated once it is server
+// side applied.
+func serverSideApplyChild(
+	client *dynamicclientset.ResourceClient,
+	updateStrategy ChildUpdateStrategyGetter,
+	hooks ChildUpdateHooks,
+	parent *unstructured.Unstructured,
+	observed, desired *unstructured.Unstructured,
+) error {
+	config := desired.DeepCopy()
+	if config.GetNamespace() == "" && client.Namespaced {
+		config.SetNamespace(parent.GetNamespace())
+	}
+	// We always claim everything we apply.
+	ownerRefs := config.GetOwnerReferences()
+	var isOwned bool
+	for _, ref := range ownerRefs {
+		if ref.UID == parent.GetUID() {
+			isOwned = true
+			break
+		}
+	}
+	if !isOwned {
+		ownerRefs = append(ownerRefs, *MakeOwnerRef(parent))
+		config.SetOwnerReferences(ownerRefs)
+	}
+	config, err := NewServerSideApplyConfig(
+		config,
+		dynamicapply.LastAppliedAnnotationKey,
+	)
+	if err != nil {
+		return err
+	}
+
+	method := v1alpha1.ChildUpdateServerSideApply
+	if observed != nil {
+		_, hasLastApplied :=
+			observed.GetAnnotations()[dynamicapply.LastAppliedAnnotationKey]
+		if IsServerSideApplied(observed, config) && !hasLastApplied {
+			// Nothing changed.
+			return nil
+		}
+		// Leave it alone if it's pending deletion.
+		if observed.GetDeletionTimestamp() != nil {
+			glog.Infof(
+				"%v: not updating %v (pending deletion)",
+				describeObject(parent),
+				describeObject(observed),
+			)
+			return nil
+		}
+		if hooks != nil {
+			// Let the pre update hook decide if this child
+			// can be updated now
+			proceed, err := hooks.PreUpdate(method, observed, config)
+			if err != nil {
+				return err
+			}
+			if !proceed {
+				glog.Infof(
+					"%v: not updating %v (blocked by pre update hook)",
+					describeObject(parent),
+					describeObject(observed),
+				)
+				return nil
+			}
+		}
+	}
+
+	opts := MakeServerSideApplyOptions(nil, "")
+	if getter, ok := updateStrategy.(ServerSideApplyOptionsGetter); ok {
+		opts = getter.GetServerSideApplyOptions(client.Group, client.Kind)
+	}
+	glog.Infof(
+		"%v: server side applying %v as %q",
+		describeObject(parent),
+		describeObject(config),
+		opts.FieldManager,
+	)
+	applied, err := ServerSideApply(client, config, opts)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"Can't server side apply %v",
+			describeObject(config),
+		)
+	}
+	if observed == nil {
+		return nil
+	}
+	err = MigrateToServerSideApply(
+		client,
+		observed,
+		applied,
+		opts.FieldManager,
+		dynamicapply.LastAppliedAnnotationKey,
+	)
+	if err != nil {
+		return err
+	}
+	if hooks != nil {
+		// Let the post update hook know this child was updated
+		return hooks.PostUpdate(method, observed, config)
+	}
+	return nil
+}
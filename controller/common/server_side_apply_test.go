@@ -0,0 +1,639 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	"openebs.io/metac/third_party/kubernetes"
+)
+
+// patchRecordingResourceOperation records the patches that are
+// sent to the server
+type patchRecordingResourceOperation struct {
+	NoopResourceOperation
+	patches []string
+	applied []*unstructured.Unstructured
+	options []metav1.PatchOptions
+
+	// data of the JSON patches
+	jsonPatches [][]byte
+
+	// managed fields set against the applied resources
+	managedFields []interface{}
+}
+
+func (r *patchRecordingResourceOperation) Patch(name string, pt types.PatchType, data []byte, options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	r.patches = append(r.patches, fmt.Sprintf("%s:%s", pt, name))
+	r.options = append(r.options, options)
+	if pt == types.ApplyPatchType {
+		obj := &unstructured.Unstructured{}
+		if err := json.Unmarshal(data, &obj.Object); err != nil {
+			return nil, err
+		}
+		r.applied = append(r.applied, obj)
+		if r.managedFields != nil {
+			applied := obj.DeepCopy()
+			unstructured.SetNestedSlice(
+				applied.Object,
+				runtime.DeepCopyJSONValue(r.managedFields).([]interface{}),
+				"metadata", "managedFields",
+			)
+			return applied, nil
+		}
+		return obj, nil
+	}
+	if pt == types.JSONPatchType {
+		r.jsonPatches = append(r.jsonPatches, data)
+	}
+	return nil, nil
+}
+
+func TestMakeServerSideApplyOptions(t *testing.T) {
+	var tests = map[string]struct {
+		opts          *v1alpha1.ServerSideApplyOptions
+		fieldManager  string
+		expectManager string
+		expectForce   bool
+	}{
+		"nil options": {
+			fieldManager:  "metac/compositecontroller/test",
+			expectManager: "metac/compositecontroller/test",
+			expectForce:   true,
+		},
+		"no default field manager": {
+			expectManager: "metac",
+			expectForce:   true,
+		},
+		"field manager & force are set": {
+			opts: &v1alpha1.ServerSideApplyOptions{
+				FieldManager:   "my-manager",
+				ForceConflicts: kubernetes.BoolPtr(false),
+			},
+			fieldManager:  "metac/compositecontroller/test",
+			expectManager: "my-manager",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := MakeServerSideApplyOptions(mock.opts, mock.fieldManager)
+			if got.FieldManager != mock.expectManager {
+				t.Fatalf("Expected field manager %q got %q", mock.expectManager, got.FieldManager)
+			}
+			if got.Force != mock.expectForce {
+				t.Fatalf("Expected force %t got %t", mock.expectForce, got.Force)
+			}
+		})
+	}
+}
+
+func TestMakeFieldManager(t *testing.T) {
+	got := MakeFieldManager("GenericController", "ns", "test")
+	if got != "metac/genericcontroller/ns/test" {
+		t.Fatalf("Expected %q got %q", "metac/genericcontroller/ns/test", got)
+	}
+	long := MakeFieldManager("GenericController", string(make([]byte, 200)))
+	if len(long) != maxFieldManagerLength {
+		t.Fatalf("Expected length %d got %d", maxFieldManagerLength, len(long))
+	}
+}
+
+func TestIsServerSideApplied(t *testing.T) {
+	desired := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name": "test",
+				"annotations": map[string]interface{}{
+					"metac.openebs.io/last-applied-configuration": "{}",
+				},
+			},
+			"data": map[string]interface{}{
+				"key": "value",
+			},
+			"items": []interface{}{
+				map[string]interface{}{"name": "a", "replicas": int64(1)},
+			},
+		},
+	}
+	config, err := NewServerSideApplyConfig(
+		desired,
+		"metac.openebs.io/last-applied-configuration",
+	)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if _, found := config.GetAnnotations()["metac.openebs.io/last-applied-configuration"]; found {
+		t.Fatalf("Expected last applied annotation to be removed got %v", config.GetAnnotations())
+	}
+	hash := config.GetAnnotations()[ServerSideAppliedHashAnnotationKey]
+	if hash == "" {
+		t.Fatalf("Expected hash annotation got none")
+	}
+	again, err := NewServerSideApplyConfig(config)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if again.GetAnnotations()[ServerSideAppliedHashAnnotationKey] != hash {
+		t.Fatalf("Expected hash to be stable got %q want %q",
+			again.GetAnnotations()[ServerSideAppliedHashAnnotationKey], hash)
+	}
+
+	withServerFields := func(obj *unstructured.Unstructured) *unstructured.Unstructured {
+		obj = obj.DeepCopy()
+		obj.SetUID("uid-1")
+		obj.Object["items"] = []interface{}{
+			map[string]interface{}{"name": "b"},
+			// numbers may be decoded as float
+			map[string]interface{}{"name": "a", "replicas": float64(1), "extra": "x"},
+		}
+		return obj
+	}
+	var tests = map[string]struct {
+		observed  *unstructured.Unstructured
+		isApplied bool
+	}{
+		"not observed": {},
+		"observed without hash": {
+			observed: withServerFields(desired),
+		},
+		"observed with server fields": {
+			observed:  withServerFields(config),
+			isApplied: true,
+		},
+		"observed field was changed": {
+			observed: func() *unstructured.Unstructured {
+				obj := withServerFields(config)
+				obj.Object["data"] = map[string]interface{}{"key": "changed"}
+				return obj
+			}(),
+		},
+		"observed list item was removed": {
+			observed: func() *unstructured.Unstructured {
+				obj := withServerFields(config)
+				obj.Object["items"] = []interface{}{
+					map[string]interface{}{"name": "b"},
+				}
+				return obj
+			}(),
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := IsServerSideApplied(mock.observed, config)
+			if got != mock.isApplied {
+				t.Fatalf("Expected applied %t got %t", mock.isApplied, got)
+			}
+		})
+	}
+}
+
+func TestResourceStatesControllerServerSideApply(t *testing.T) {
+	watch := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "test.io/v1",
+			"kind":       "Watch",
+			"metadata": map[string]interface{}{
+				"name":      "watch",
+				"namespace": "ns",
+				"uid":       "test-watch-uid",
+			},
+		},
+	}
+	lastAppliedKey := "test-watch-uid" + GCTLLastAppliedAnnotationKeySuffix
+	desired := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name": "test",
+				},
+				"data": map[string]interface{}{
+					"key": "value",
+				},
+			},
+		}
+	}
+	var tests = map[string]struct {
+		observed      func(ctrl *ResourceStatesController) *unstructured.Unstructured
+		managedFields []interface{}
+		expectPatches []string
+	}{
+		"create": {
+			expectPatches: []string{"application/apply-patch+yaml:test"},
+		},
+		"migrate fields set via 3-way merge": {
+			observed: func(ctrl *ResourceStatesController) *unstructured.Unstructured {
+				obj := desired()
+				obj.SetNamespace("ns")
+				obj.SetAnnotations(map[string]string{
+					AttachmentCreateAnnotationKey: "test-watch-uid",
+					lastAppliedKey:                "{}",
+				})
+				return obj
+			},
+			managedFields: []interface{}{
+				map[string]interface{}{
+					"manager":   updateFieldManagers[0],
+					"operation": "Update",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:key": map[string]interface{}{},
+						},
+					},
+				},
+			},
+			expectPatches: []string{
+				"application/apply-patch+yaml:test",
+				"application/json-patch+json:test",
+				"application/merge-patch+json:test",
+			},
+		},
+		"migrate from 3-way merge": {
+			observed: func(ctrl *ResourceStatesController) *unstructured.Unstructured {
+				obj := desired()
+				obj.SetNamespace("ns")
+				obj.SetAnnotations(map[string]string{
+					AttachmentCreateAnnotationKey: "test-watch-uid",
+					lastAppliedKey:                "{}",
+				})
+				return obj
+			},
+			expectPatches: []string{
+				"application/apply-patch+yaml:test",
+				"application/merge-patch+json:test",
+			},
+		},
+		"already applied": {
+			observed: func(ctrl *ResourceStatesController) *unstructured.Unstructured {
+				config, err := ctrl.serverSideApplyConfig(nil, desired())
+				if err != nil {
+					t.Fatalf("Expected no error got [%+v]", err)
+				}
+				return config
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ops := &patchRecordingResourceOperation{
+				managedFields: mock.managedFields,
+			}
+			ctrl := &ResourceStatesController{
+				ClusterStatesControllerBase: ClusterStatesControllerBase{
+					GetChildUpdateStrategyByGK: func(group, kind string) v1alpha1.ChildUpdateMethod {
+						return v1alpha1.ChildUpdateServerSideApply
+					},
+					IsPatchByGK: func(group, kind string) bool {
+						return false
+					},
+					GetServerSideApplyOptionsByGK: func(group, kind string) ServerSideApplyOptions {
+						return MakeServerSideApplyOptions(nil, "metac/genericcontroller/ns/test")
+					},
+					Watch:        watch,
+					IsWatchOwner: kubernetes.BoolPtr(true),
+				},
+				DynamicClient: &dynamicclientset.ResourceClient{
+					ResourceInterface: ops,
+					APIResource:       &dynamicdiscovery.APIResource{},
+				},
+				Observed: map[string]*unstructured.Unstructured{},
+				Desired: map[string]*unstructured.Unstructured{
+					"test": desired(),
+				},
+			}
+			if mock.observed != nil {
+				ctrl.Observed["test"] = mock.observed(ctrl)
+			}
+			err := ctrl.CreateOrUpdate()
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if fmt.Sprint(ops.patches) != fmt.Sprint(mock.expectPatches) {
+				t.Fatalf("Expected patches %v got %v", mock.expectPatches, ops.patches)
+			}
+			if len(ops.applied) == 0 {
+				return
+			}
+			opts := ops.options[0]
+			if opts.FieldManager != "metac/genericcontroller/ns/test" ||
+				opts.Force == nil || !*opts.Force {
+				t.Fatalf("Expected forced apply by field manager got %+v", opts)
+			}
+			applied := ops.applied[0]
+			anns := applied.GetAnnotations()
+			if anns[AttachmentCreateAnnotationKey] != "test-watch-uid" {
+				t.Fatalf("Expected created annotation got %v", anns)
+			}
+			if _, found := anns[lastAppliedKey]; found {
+				t.Fatalf("Expected no last applied annotation got %v", anns)
+			}
+			if mock.observed == nil && len(applied.GetOwnerReferences()) != 1 {
+				t.Fatalf("Expected watch as owner got %v", applied.GetOwnerReferences())
+			}
+		})
+	}
+}
+
+func TestUpgradeManagedFields(t *testing.T) {
+	var tests = map[string]struct {
+		managedFields []interface{}
+		isUpgraded    bool
+		expect        []interface{}
+	}{
+		"no managed fields": {},
+		"no update manager": {
+			managedFields: []interface{}{
+				map[string]interface{}{
+					"manager":   "kubectl",
+					"operation": "Update",
+					"fieldsV1":  map[string]interface{}{"f:data": map[string]interface{}{}},
+				},
+			},
+		},
+		"update manager is handed over to new apply manager": {
+			managedFields: []interface{}{
+				map[string]interface{}{
+					"manager":   "metac",
+					"operation": "Update",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:old": map[string]interface{}{},
+						},
+					},
+				},
+				map[string]interface{}{
+					"manager":   "kubectl",
+					"operation": "Update",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:other": map[string]interface{}{},
+						},
+					},
+				},
+			},
+			isUpgraded: true,
+			expect: []interface{}{
+				map[string]interface{}{
+					"manager":   "kubectl",
+					"operation": "Update",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:other": map[string]interface{}{},
+						},
+					},
+				},
+				map[string]interface{}{
+					"manager":    "metac/test",
+					"operation":  "Apply",
+					"apiVersion": "v1",
+					"fieldsType": "FieldsV1",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:old": map[string]interface{}{},
+						},
+					},
+				},
+			},
+		},
+		"update managers are merged into existing apply manager": {
+			managedFields: []interface{}{
+				map[string]interface{}{
+					"manager":   "metac/test",
+					"operation": "Apply",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:new": map[string]interface{}{},
+						},
+					},
+				},
+				map[string]interface{}{
+					"manager":   "metac",
+					"operation": "Update",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:old": map[string]interface{}{},
+						},
+					},
+				},
+				map[string]interface{}{
+					"manager":   "before-first-apply",
+					"operation": "Update",
+					"fieldsV1": map[string]interface{}{
+						"f:metadata": map[string]interface{}{
+							"f:labels": map[string]interface{}{
+								"f:app": map[string]interface{}{},
+							},
+						},
+					},
+				},
+			},
+			isUpgraded: true,
+			expect: []interface{}{
+				map[string]interface{}{
+					"manager":   "metac/test",
+					"operation": "Apply",
+					"fieldsV1": map[string]interface{}{
+						"f:data": map[string]interface{}{
+							"f:new": map[string]interface{}{},
+							"f:old": map[string]interface{}{},
+						},
+						"f:metadata": map[string]interface{}{
+							"f:labels": map[string]interface{}{
+								"f:app": map[string]interface{}{},
+							},
+						},
+					},
+				},
+			},
+		},
+		"sub resource of update manager is retained": {
+			managedFields: []interface{}{
+				map[string]interface{}{
+					"manager":     "metac",
+					"operation":   "Update",
+					"subresource": "status",
+					"fieldsV1":    map[string]interface{}{"f:status": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata": map[string]interface{}{
+						"name": "test",
+					},
+				},
+			}
+			if mock.managedFields != nil {
+				unstructured.SetNestedSlice(
+					obj.Object, mock.managedFields, "metadata", "managedFields",
+				)
+			}
+			isUpgraded, err := UpgradeManagedFields(
+				obj, "metac/test", "metac", "before-first-apply",
+			)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if isUpgraded != mock.isUpgraded {
+				t.Fatalf("Expected upgraded %t got %t", mock.isUpgraded, isUpgraded)
+			}
+			if !mock.isUpgraded {
+				return
+			}
+			got, _, _ := unstructured.NestedSlice(obj.Object, "metadata", "managedFields")
+			if !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected managed fields\n%v\ngot\n%v", mock.expect, got)
+			}
+		})
+	}
+}
+
+// TestMigrateToServerSideApplyOwnsRemovedField verifies if a field
+// that was set via 3-way merge & is removed from the desired state
+// after the migration is owned by the apply manager only. Server
+// side apply removes such a field once it is not applied.
+func TestMigrateToServerSideApplyOwnsRemovedField(t *testing.T) {
+	lastAppliedKey := "uid" + GCTLLastAppliedAnnotationKeySuffix
+	observed := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":            "test",
+				"namespace":       "ns",
+				"resourceVersion": "10",
+				"annotations": map[string]interface{}{
+					lastAppliedKey: `{"data":{"key":"value","removed":"value"}}`,
+				},
+			},
+			"data": map[string]interface{}{
+				"key":     "value",
+				"removed": "value",
+			},
+		},
+	}
+	// removed field is not part of the applied config & hence
+	// is owned by the update manager only
+	applied := observed.DeepCopy()
+	unstructured.SetNestedSlice(
+		applied.Object,
+		[]interface{}{
+			map[string]interface{}{
+				"manager":   updateFieldManagers[0],
+				"operation": "Update",
+				"fieldsV1": map[string]interface{}{
+					"f:data": map[string]interface{}{
+						"f:key":     map[string]interface{}{},
+						"f:removed": map[string]interface{}{},
+					},
+				},
+			},
+			map[string]interface{}{
+				"manager":   "metac/test",
+				"operation": "Apply",
+				"fieldsV1": map[string]interface{}{
+					"f:data": map[string]interface{}{
+						"f:key": map[string]interface{}{},
+					},
+				},
+			},
+		},
+		"metadata", "managedFields",
+	)
+
+	ops := &patchRecordingResourceOperation{}
+	client := &dynamicclientset.ResourceClient{
+		ResourceInterface: ops,
+		APIResource:       &dynamicdiscovery.APIResource{},
+	}
+	err := MigrateToServerSideApply(client, observed, applied, "metac/test", lastAppliedKey)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	expectPatches := []string{
+		"application/json-patch+json:test",
+		"application/merge-patch+json:test",
+	}
+	if fmt.Sprint(ops.patches) != fmt.Sprint(expectPatches) {
+		t.Fatalf("Expected patches %v got %v", expectPatches, ops.patches)
+	}
+
+	var patch []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}
+	err = json.Unmarshal(ops.jsonPatches[0], &patch)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if len(patch) != 2 || patch[0].Op != "test" || patch[0].Value != "10" {
+		t.Fatalf("Expected patch to test resource version got %+v", patch)
+	}
+	entries, ok := patch[1].Value.([]interface{})
+	if !ok {
+		t.Fatalf("Expected managed fields got %+v", patch[1])
+	}
+	var owners []string
+	for _, item := range entries {
+		entry := item.(map[string]interface{})
+		_, found, _ := unstructured.NestedMap(entry, "fieldsV1", "f:data", "f:removed")
+		if found {
+			owners = append(owners, entry["manager"].(string))
+		}
+	}
+	if fmt.Sprint(owners) != "[metac/test]" {
+		t.Fatalf("Expected removed field to be owned by [metac/test] got %v", owners)
+	}
+
+	// nothing to migrate once the annotation is removed
+	ops.patches = nil
+	err = MigrateToServerSideApply(client, applied, applied, "metac/test", "unknown")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if len(ops.patches) != 0 {
+		t.Fatalf("Expected no patches got %v", ops.patches)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/webhook"
+)
+
+const (
+	// defaultAuthHeaderKey is the key used to lookup the header
+	// value from a Secret if no key was specified
+	defaultAuthHeaderKey = "token"
+
+	// defaultHMACKey is the key used to lookup the HMAC key
+	// from a Secret if no key was specified
+	defaultHMACKey = "key"
+)
+
+// webhookTokenPath is the path of the projected service account
+// token that is sent to webhooks
+var webhookTokenPath struct {
+	mutex sync.RWMutex
+	path  string
+}
+
+// SetWebhookTokenPath sets the path of the projected service
+// account token that is sent to webhooks whose auth is set to
+// service account token. Such webhooks fail if this is not set.
+//
+// NOTE:
+//	Metac binary sets this path from its flags
+func SetWebhookTokenPath(path string) {
+	webhookTokenPath.mutex.Lock()
+	defer webhookTokenPath.mutex.Unlock()
+	webhookTokenPath.path = path
+}
+
+func getWebhookTokenPath() string {
+	webhookTokenPath.mutex.RLock()
+	defer webhookTokenPath.mutex.RUnlock()
+	return webhookTokenPath.path
+}
+
+// authenticatorsFromSchema returns the authenticators based on
+// the auth settings of the provided webhook
+//
+// NOTE:
+//	Secrets are fetched per request via the hook data cache. This
+// picks up rotated Secrets once the cached ones expire.
+func (s *hookDataStore) authenticatorsFromSchema(
+	schema *v1alpha1.Webhook,
+) ([]webhook.Authenticator, error) {
+	auth := schema.Auth
+	if auth == nil {
+		return nil, nil
+	}
+	var authenticators []webhook.Authenticator
+	isAuthorizationSet := false
+	if auth.Header != nil {
+		ref := auth.Header.SecretKeyRef
+		if ref == nil || ref.Namespace == "" || ref.Name == "" {
+			return nil, errors.Errorf(
+				"Invalid auth header: Specify secret 'Namespace' & 'Name'",
+			)
+		}
+		name := auth.Header.Name
+		if name == "" {
+			name = "Authorization"
+		}
+		isAuthorizationSet = http.CanonicalHeaderKey(name) == "Authorization"
+		authenticators = append(authenticators, &webhook.HeaderAuth{
+			Name:   name,
+			Prefix: auth.Header.Prefix,
+			Value: func() ([]byte, error) {
+				return s.getKey("Secret", ref, defaultAuthHeaderKey)
+			},
+		})
+	}
+	if auth.ServiceAccountToken != nil {
+		if isAuthorizationSet {
+			return nil, errors.Errorf(
+				"Invalid auth: Both header & service account token set Authorization header",
+			)
+		}
+		path := getWebhookTokenPath()
+		if path == "" {
+			return nil, errors.Errorf(
+				"Invalid auth: Service account token is not available: Metac is not started with webhook-token-path",
+			)
+		}
+		authenticators = append(authenticators, webhook.NewTokenFileAuth(path))
+	}
+	if auth.HMAC != nil {
+		ref := auth.HMAC.SecretKeyRef
+		if ref == nil || ref.Namespace == "" || ref.Name == "" {
+			return nil, errors.Errorf(
+				"Invalid auth hmac: Specify secret 'Namespace' & 'Name'",
+			)
+		}
+		header := auth.HMAC.Header
+		if header == "" {
+			header = webhook.DefaultSignatureHeader
+		}
+		authenticators = append(authenticators, &webhook.HMACAuth{
+			Header: header,
+			Key: func() ([]byte, error) {
+				return s.getKey("Secret", ref, defaultHMACKey)
+			},
+		})
+	}
+	return authenticators, nil
+}
+
+// SetWebhookAuthFromSchema evaluates the auth settings of the
+// provided webhook & sets the evaluated authenticators against
+// the webhook Invoker instance
+func SetWebhookAuthFromSchema(schema *v1alpha1.Webhook) webhook.InvokerOption {
+	return func(caller *webhook.Invoker) error {
+		authenticators, err := hookDataCache.authenticatorsFromSchema(schema)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid webhook auth: %v", schema)
+		}
+		caller.Auth = authenticators
+		return nil
+	}
+}
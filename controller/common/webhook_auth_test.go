@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/webhook"
+)
+
+func TestWebhookAuthFromSchema(t *testing.T) {
+	tokenFile, err := ioutil.TempFile("", "token")
+	if err != nil {
+		t.Fatalf("Can't create token file: %v", err)
+	}
+	defer os.Remove(tokenFile.Name())
+	tokenFile.WriteString("sa-token\n")
+	tokenFile.Close()
+	SetWebhookTokenPath(tokenFile.Name())
+	defer SetWebhookTokenPath("")
+
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "hook-auth"},
+			Data: map[string][]byte{
+				"token":  []byte("static-token"),
+				"key":    []byte("shared"),
+				"apikey": []byte("my-key"),
+			},
+		},
+	)
+	SetHookKubeClient(client)
+	defer SetHookKubeClient(nil)
+
+	body := []byte(`{"hello":"world"}`)
+	var tests = map[string]struct {
+		schema        *v1alpha1.Webhook
+		isErr         bool
+		isAuthErr     bool
+		expectHeaders map[string]string
+	}{
+		"no auth settings": {
+			schema:        &v1alpha1.Webhook{},
+			expectHeaders: map[string]string{"Authorization": ""},
+		},
+		"header from secret with default name & key": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					Header: &v1alpha1.WebhookAuthHeader{
+						Prefix: "Bearer",
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+							Name:      "hook-auth",
+						},
+					},
+				},
+			},
+			expectHeaders: map[string]string{"Authorization": "Bearer static-token"},
+		},
+		"custom header from secret with key": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					Header: &v1alpha1.WebhookAuthHeader{
+						Name: "X-Api-Key",
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+							Name:      "hook-auth",
+							Key:       "apikey",
+						},
+					},
+				},
+			},
+			expectHeaders: map[string]string{"X-Api-Key": "my-key"},
+		},
+		"service account token": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					ServiceAccountToken: &v1alpha1.WebhookAuthServiceAccountToken{},
+				},
+			},
+			expectHeaders: map[string]string{"Authorization": "Bearer sa-token"},
+		},
+		"hmac with default header & key": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					HMAC: &v1alpha1.WebhookAuthHMAC{
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+							Name:      "hook-auth",
+						},
+					},
+				},
+			},
+			expectHeaders: map[string]string{
+				webhook.DefaultSignatureHeader: webhook.Sign([]byte("shared"), body),
+			},
+		},
+		"service account token & hmac": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					ServiceAccountToken: &v1alpha1.WebhookAuthServiceAccountToken{},
+					HMAC: &v1alpha1.WebhookAuthHMAC{
+						Header: "X-Signature",
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+							Name:      "hook-auth",
+						},
+					},
+				},
+			},
+			expectHeaders: map[string]string{
+				"Authorization": "Bearer sa-token",
+				"X-Signature":   webhook.Sign([]byte("shared"), body),
+			},
+		},
+		"header & service account token set authorization": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					Header: &v1alpha1.WebhookAuthHeader{
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+							Name:      "hook-auth",
+						},
+					},
+					ServiceAccountToken: &v1alpha1.WebhookAuthServiceAccountToken{},
+				},
+			},
+			isErr: true,
+		},
+		"header without secret ref": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					Header: &v1alpha1.WebhookAuthHeader{Name: "X-Api-Key"},
+				},
+			},
+			isErr: true,
+		},
+		"hmac without secret name": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					HMAC: &v1alpha1.WebhookAuthHMAC{
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+						},
+					},
+				},
+			},
+			isErr: true,
+		},
+		"header from missing key": {
+			schema: &v1alpha1.Webhook{
+				Auth: &v1alpha1.WebhookAuth{
+					Header: &v1alpha1.WebhookAuthHeader{
+						SecretKeyRef: &v1alpha1.ObjectKeyReference{
+							Namespace: "metac",
+							Name:      "hook-auth",
+							Key:       "junk",
+						},
+					},
+				},
+			},
+			isAuthErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker := &webhook.Invoker{}
+			err := SetWebhookAuthFromSchema(mock.schema)(invoker)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			req, _ := http.NewRequest(http.MethodPost, "http://hook", nil)
+			for _, auth := range invoker.Auth {
+				err = auth.Authenticate(req, body)
+				if err != nil {
+					break
+				}
+			}
+			if mock.isAuthErr && err == nil {
+				t.Fatalf("Expected auth error got none")
+			}
+			if !mock.isAuthErr && err != nil {
+				t.Fatalf("Expected no auth error got [%+v]", err)
+			}
+			for header, expect := range mock.expectHeaders {
+				if got := req.Header.Get(header); got != expect {
+					t.Fatalf("Expected header %s=%q got %q", header, expect, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookAuthFromSchemaWithoutTokenPath(t *testing.T) {
+	invoker := &webhook.Invoker{}
+	err := SetWebhookAuthFromSchema(&v1alpha1.Webhook{
+		Auth: &v1alpha1.WebhookAuth{
+			ServiceAccountToken: &v1alpha1.WebhookAuthServiceAccountToken{},
+		},
+	})(invoker)
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/webhook"
+)
+
+// webhookInvokerIdleTTL is the duration after which an unused
+// webhook invoker is removed from the pool
+const webhookInvokerIdleTTL = 10 * time.Minute
+
+// pooledWebhookInvoker is a webhook invoker along with its
+// book keeping details
+type pooledWebhookInvoker struct {
+	invoker *webhook.Invoker
+
+	// transport owned by this invoker. This is nil if the
+	// invoker uses the pool's shared transport.
+	transport *http.Transport
+
+	// true if the invoker's TLS settings are fetched from
+	// Secrets or ConfigMaps & hence need to be refreshed
+	isRefreshable bool
+
+	createdAt  time.Time
+	lastUsedAt time.Time
+}
+
+// close closes the idle connections of this invoker's
+// transport
+func (p *pooledWebhookInvoker) close() {
+	if p.transport != nil {
+		p.transport.CloseIdleConnections()
+	}
+}
+
+// webhookInvokerPool caches webhook invokers per webhook schema
+// so that their connections are reused across invocations
+type webhookInvokerPool struct {
+	mutex sync.Mutex
+
+	// config used to build the transports
+	config webhook.TransportConfig
+
+	// transport shared by the webhooks that use default TLS
+	// settings
+	transport *http.Transport
+
+	// invokers mapped by their webhook schema
+	invokers map[string]*pooledWebhookInvoker
+
+	// incremented whenever the pool is reset; invokers built
+	// from an older config are not added to the pool
+	generation int64
+
+	// duration after which an invoker with TLS settings
+	// fetched from Secrets or ConfigMaps is built again
+	ttl time.Duration
+
+	// duration after which an unused invoker is removed
+	idleTTL   time.Duration
+	lastSweep time.Time
+
+	// used to mock current time in unit tests
+	now func() time.Time
+}
+
+// newWebhookInvokerPool returns a new instance of pool that
+// builds its transports as per the provided config
+func newWebhookInvokerPool(config webhook.TransportConfig) *webhookInvokerPool {
+	return &webhookInvokerPool{
+		config:    config,
+		transport: webhook.NewTransport(config, nil),
+		invokers:  make(map[string]*pooledWebhookInvoker),
+		ttl:       hookDataTTL,
+		idleTTL:   webhookInvokerIdleTTL,
+		now:       time.Now,
+	}
+}
+
+// webhookInvokers is the pool used by all the webhook hooks
+var webhookInvokers = newWebhookInvokerPool(webhook.DefaultTransportConfig)
+
+// SetWebhookTransportConfig sets the connection pool settings
+// used to invoke webhooks. Invokers built earlier are discarded.
+//
+// NOTE:
+//	Metac binary sets this config from its flags
+func SetWebhookTransportConfig(config webhook.TransportConfig) {
+	webhookInvokers.mutex.Lock()
+	defer webhookInvokers.mutex.Unlock()
+	for _, pooled := range webhookInvokers.invokers {
+		pooled.close()
+	}
+	webhookInvokers.transport.CloseIdleConnections()
+	webhookInvokers.config = config
+	webhookInvokers.transport = webhook.NewTransport(config, nil)
+	webhookInvokers.invokers = make(map[string]*pooledWebhookInvoker)
+	webhookInvokers.generation++
+}
+
+// isExpired returns true if the provided invoker needs to be
+// built again
+func (p *webhookInvokerPool) isExpired(pooled *pooledWebhookInvoker) bool {
+	return pooled.isRefreshable && p.now().Sub(pooled.createdAt) >= p.ttl
+}
+
+// sweep removes the invokers that were not used recently
+//
+// NOTE:
+//	This is invoked with the lock held
+func (p *webhookInvokerPool) sweep() {
+	now := p.now()
+	if now.Sub(p.lastSweep) < p.idleTTL {
+		return
+	}
+	p.lastSweep = now
+	for key, pooled := range p.invokers {
+		if now.Sub(pooled.lastUsedAt) >= p.idleTTL {
+			pooled.close()
+			delete(p.invokers, key)
+		}
+	}
+}
+
+// get returns the webhook invoker corresponding to the provided
+// webhook schema. Invoker is built if it is not available in
+// this pool.
+func (p *webhookInvokerPool) get(schema *v1alpha1.Webhook) (*webhook.Invoker, error) {
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid webhook: %v", schema)
+	}
+	key := string(raw)
+
+	p.mutex.Lock()
+	p.sweep()
+	if pooled, found := p.invokers[key]; found {
+		if !p.isExpired(pooled) {
+			pooled.lastUsedAt = p.now()
+			p.mutex.Unlock()
+			return pooled.invoker, nil
+		}
+		pooled.close()
+		delete(p.invokers, key)
+	}
+	config := p.config
+	transport := p.transport
+	generation := p.generation
+	p.mutex.Unlock()
+
+	// NOTE:
+	//	Invoker is built without holding the lock since building
+	// it may fetch Secrets & ConfigMaps. This lets the hooks that
+	// are already pooled to be invoked meanwhile.
+	pooled := &pooledWebhookInvoker{
+		isRefreshable: schema.CABundleFrom != nil || schema.ClientCertificate != nil,
+		createdAt:     p.now(),
+		lastUsedAt:    p.now(),
+	}
+	invoker, err := webhook.NewInvoker(
+		// set various webhook options
+		SetWebhookURLFromSchema(schema),
+		SetWebhookTimeoutFromSchemaOrDefault(schema),
+		SetWebhookTLSFromSchema(schema),
+		SetWebhookRetryFromSchema(schema),
+		SetWebhookGzipFromSchema(schema),
+		SetWebhookAuthFromSchema(schema),
+		// client is set at the end since it depends on the
+		// TLS settings
+		setWebhookClient(pooled, config, transport),
+	)
+	if err != nil {
+		return nil, err
+	}
+	pooled.invoker = invoker
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if existing, found := p.invokers[key]; found && !p.isExpired(existing) {
+		// invoker was built & pooled by a concurrent get
+		pooled.close()
+		existing.lastUsedAt = p.now()
+		return existing.invoker, nil
+	}
+	if generation != p.generation {
+		// pool was reset while this invoker was being built
+		return invoker, nil
+	}
+	p.invokers[key] = pooled
+	glog.V(4).Infof("Added to webhook pool: %s", invoker)
+	return invoker, nil
+}
+
+// setWebhookClient sets a http client that reuses its connections
+// against the webhook invoker
+func setWebhookClient(
+	pooled *pooledWebhookInvoker,
+	config webhook.TransportConfig,
+	transport *http.Transport,
+) webhook.InvokerOption {
+	return func(caller *webhook.Invoker) error {
+		if caller.TLSConfig != nil {
+			// transport with custom TLS settings can't be shared
+			pooled.transport = webhook.NewTransport(config, caller.TLSConfig)
+			transport = pooled.transport
+		}
+		caller.Client = &http.Client{
+			Timeout:   caller.Timeout,
+			Transport: transport,
+		}
+		return nil
+	}
+}
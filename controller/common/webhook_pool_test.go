@@ -0,0 +1,222 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/webhook"
+)
+
+func TestWebhookInvokerPoolGet(t *testing.T) {
+	srv, caBundle := newTestTLSServer()
+	defer srv.Close()
+	url := srv.URL
+	otherURL := srv.URL + "/other"
+	timeout := metav1.Duration{Duration: -1 * time.Second}
+
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "ca"},
+			Data:       map[string][]byte{"ca.crt": caBundle},
+		},
+	)
+	SetHookKubeClient(client)
+	defer SetHookKubeClient(nil)
+
+	caBundleFrom := &v1alpha1.WebhookCABundleSource{
+		SecretKeyRef: &v1alpha1.ObjectKeyReference{
+			Namespace: "metac",
+			Name:      "ca",
+		},
+	}
+	var tests = map[string]struct {
+		first         *v1alpha1.Webhook
+		second        *v1alpha1.Webhook
+		elapsed       time.Duration
+		isErr         bool
+		isSameInvoker bool
+	}{
+		"same schema": {
+			first:         &v1alpha1.Webhook{URL: &url},
+			second:        &v1alpha1.Webhook{URL: &url},
+			elapsed:       10 * time.Minute,
+			isSameInvoker: true,
+		},
+		"different schema": {
+			first:  &v1alpha1.Webhook{URL: &url},
+			second: &v1alpha1.Webhook{URL: &otherURL},
+		},
+		"same schema with ca bundle from secret": {
+			first:         &v1alpha1.Webhook{URL: &url, CABundleFrom: caBundleFrom},
+			second:        &v1alpha1.Webhook{URL: &url, CABundleFrom: caBundleFrom},
+			elapsed:       30 * time.Second,
+			isSameInvoker: true,
+		},
+		"expired schema with ca bundle from secret": {
+			first:   &v1alpha1.Webhook{URL: &url, CABundleFrom: caBundleFrom},
+			second:  &v1alpha1.Webhook{URL: &url, CABundleFrom: caBundleFrom},
+			elapsed: 2 * time.Minute,
+		},
+		"invalid schema": {
+			first:  &v1alpha1.Webhook{URL: &url},
+			second: &v1alpha1.Webhook{URL: &url, Timeout: &timeout},
+			isErr:  true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			pool := newWebhookInvokerPool(webhook.DefaultTransportConfig)
+			pool.idleTTL = time.Hour
+			pool.now = func() time.Time { return now }
+
+			first, err := pool.get(mock.first)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			now = now.Add(mock.elapsed)
+			got, err := pool.get(mock.second)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				if len(pool.invokers) != 1 {
+					t.Fatalf("Expected 1 pooled invoker got %d", len(pool.invokers))
+				}
+				return
+			}
+			if mock.isSameInvoker != (got == first) {
+				t.Fatalf(
+					"Expected same invoker %t got %t",
+					mock.isSameInvoker,
+					got == first,
+				)
+			}
+		})
+	}
+}
+
+func TestWebhookInvokerPoolSweep(t *testing.T) {
+	url := "http://localhost/hook"
+	otherURL := "http://localhost/other"
+
+	now := time.Now()
+	pool := newWebhookInvokerPool(webhook.DefaultTransportConfig)
+	pool.idleTTL = 10 * time.Minute
+	pool.now = func() time.Time { return now }
+
+	for _, u := range []*string{&url, &otherURL} {
+		_, err := pool.get(&v1alpha1.Webhook{URL: u})
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	now = now.Add(6 * time.Minute)
+	_, err := pool.get(&v1alpha1.Webhook{URL: &url})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	now = now.Add(6 * time.Minute)
+	_, err = pool.get(&v1alpha1.Webhook{URL: &url})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if len(pool.invokers) != 1 {
+		t.Fatalf("Expected 1 pooled invoker got %d", len(pool.invokers))
+	}
+}
+
+func TestWebhookInvokerPoolGetConcurrent(t *testing.T) {
+	url := "http://localhost/hook"
+
+	pool := newWebhookInvokerPool(webhook.DefaultTransportConfig)
+	invokers := make([]*webhook.Invoker, 10)
+	var wg sync.WaitGroup
+	for i := range invokers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			invoker, err := pool.get(&v1alpha1.Webhook{URL: &url})
+			if err != nil {
+				t.Errorf("Expected no error got [%+v]", err)
+			}
+			invokers[i] = invoker
+		}(i)
+	}
+	wg.Wait()
+
+	if len(pool.invokers) != 1 {
+		t.Fatalf("Expected 1 pooled invoker got %d", len(pool.invokers))
+	}
+	for _, invoker := range invokers[1:] {
+		if invoker != invokers[0] {
+			t.Fatalf("Expected concurrent gets to return the pooled invoker")
+		}
+	}
+}
+
+func TestInvokeWebhookHookReusesConnections(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status": "ok"}`)
+		}),
+	)
+	srv.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	url := srv.URL
+
+	schema := &v1alpha1.Hook{
+		Webhook: &v1alpha1.Webhook{URL: &url},
+	}
+	for count := 0; count < 5; count++ {
+		var resp map[string]string
+		err := InvokeHook(HookScope{}, schema, map[string]string{}, &resp)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		if resp["status"] != "ok" {
+			t.Fatalf("Expected status %q got %v", "ok", resp)
+		}
+	}
+	if conns != 1 {
+		t.Fatalf("Expected 1 connection got %d", conns)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/webhook"
+)
+
+// defaultCABundleKey is the key used to lookup the CA bundle
+// from a Secret or ConfigMap if no key was specified
+const defaultCABundleKey = "ca.crt"
+
+// caBundleFromSchema returns the CA bundle set in the provided
+// webhook either inline or via a reference
+func (s *hookDataStore) caBundleFromSchema(schema *v1alpha1.Webhook) ([]byte, error) {
+	if len(schema.CABundle) != 0 {
+		return schema.CABundle, nil
+	}
+	src := schema.CABundleFrom
+	if src == nil {
+		return nil, nil
+	}
+	if src.SecretKeyRef != nil && src.ConfigMapKeyRef != nil {
+		return nil, errors.Errorf(
+			"Invalid webhook CA bundle: Specify either 'SecretKeyRef' or 'ConfigMapKeyRef'",
+		)
+	}
+	if src.SecretKeyRef != nil {
+		return s.getKey("Secret", src.SecretKeyRef, defaultCABundleKey)
+	}
+	if src.ConfigMapKeyRef != nil {
+		return s.getKey("ConfigMap", src.ConfigMapKeyRef, defaultCABundleKey)
+	}
+	return nil, errors.Errorf(
+		"Invalid webhook CA bundle: Specify 'SecretKeyRef' or 'ConfigMapKeyRef'",
+	)
+}
+
+// clientCertFromSchema returns the client certificate set in
+// the provided webhook
+func (s *hookDataStore) clientCertFromSchema(
+	schema *v1alpha1.Webhook,
+) (*tls.Certificate, error) {
+	if schema.ClientCertificate == nil {
+		return nil, nil
+	}
+	ref := schema.ClientCertificate.SecretRef
+	if ref == nil || ref.Namespace == "" || ref.Name == "" {
+		return nil, errors.Errorf(
+			"Invalid webhook client certificate: Specify secret 'Namespace' & 'Name'",
+		)
+	}
+	data, err := s.get("Secret", ref.Namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(
+		data[corev1.TLSCertKey],
+		data[corev1.TLSPrivateKeyKey],
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Invalid webhook client certificate in Secret %s/%s",
+			ref.Namespace,
+			ref.Name,
+		)
+	}
+	return &cert, nil
+}
+
+// tlsConfigFromSchema returns the TLS config based on the
+// provided webhook. It returns nil if no TLS settings are
+// specified in the webhook.
+func (s *hookDataStore) tlsConfigFromSchema(
+	schema *v1alpha1.Webhook,
+) (*tls.Config, error) {
+	if len(schema.CABundle) == 0 &&
+		schema.CABundleFrom == nil &&
+		schema.ClientCertificate == nil &&
+		schema.ServerName == nil {
+		// default TLS settings are used
+		return nil, nil
+	}
+	config := &tls.Config{}
+	caBundle, err := s.caBundleFromSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(caBundle) != 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.Errorf(
+				"Invalid webhook CA bundle: No PEM encoded certificates found",
+			)
+		}
+		config.RootCAs = pool
+	}
+	cert, err := s.clientCertFromSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	if schema.ServerName != nil {
+		config.ServerName = *schema.ServerName
+	}
+	return config, nil
+}
+
+// SetWebhookTLSFromSchema evaluates the TLS settings of the
+// provided webhook & sets the evaluated TLS config against the
+// webhook Invoker instance
+func SetWebhookTLSFromSchema(schema *v1alpha1.Webhook) webhook.InvokerOption {
+	return func(caller *webhook.Invoker) error {
+		config, err := hookDataCache.tlsConfigFromSchema(schema)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid webhook TLS: %v", schema)
+		}
+		caller.TLSConfig = config
+		return nil
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/hooks/webhook"
+)
+
+func newTestTLSServer() (*httptest.Server, []byte) {
+	srv := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status": "ok"}`)
+		}),
+	)
+	caBundle := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	})
+	return srv, caBundle
+}
+
+func TestWebhookTLSInvoke(t *testing.T) {
+	srv, caBundle := newTestTLSServer()
+	defer srv.Close()
+	url := srv.URL
+
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "ca"},
+			Data:       map[string][]byte{"ca.crt": caBundle},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "metac", Name: "ca"},
+			Data:       map[string]string{"bundle": string(caBundle)},
+		},
+	)
+	SetHookKubeClient(client)
+	defer SetHookKubeClient(nil)
+
+	var tests = map[string]struct {
+		schema  *v1alpha1.Webhook
+		isErr   bool
+		isTLSOk bool
+	}{
+		"no tls settings": {
+			schema: &v1alpha1.Webhook{},
+			// server certificate is not trusted
+			isTLSOk: false,
+		},
+		"inline ca bundle": {
+			schema:  &v1alpha1.Webhook{CABundle: caBundle},
+			isTLSOk: true,
+		},
+		"ca bundle from secret with default key": {
+			schema: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					SecretKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "ca",
+					},
+				},
+			},
+			isTLSOk: true,
+		},
+		"ca bundle from configmap with key": {
+			schema: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "ca",
+						Key:       "bundle",
+					},
+				},
+			},
+			isTLSOk: true,
+		},
+		"ca bundle from secret & configmap": {
+			schema: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					SecretKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "ca",
+					},
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "ca",
+					},
+				},
+			},
+			isErr: true,
+		},
+		"ca bundle from missing key": {
+			schema: &v1alpha1.Webhook{
+				CABundleFrom: &v1alpha1.WebhookCABundleSource{
+					ConfigMapKeyRef: &v1alpha1.ObjectKeyReference{
+						Namespace: "metac",
+						Name:      "ca",
+					},
+				},
+			},
+			isErr: true,
+		},
+		"invalid inline ca bundle": {
+			schema: &v1alpha1.Webhook{CABundle: []byte("junk")},
+			isErr:  true,
+		},
+		"client certificate from missing secret": {
+			schema: &v1alpha1.Webhook{
+				CABundle: caBundle,
+				ClientCertificate: &v1alpha1.WebhookClientCertificate{
+					SecretRef: &v1alpha1.SecretReference{
+						Namespace: "metac",
+						Name:      "client-cert",
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker, err := webhook.NewInvoker(
+				SetWebhookURLFromSchema(&v1alpha1.Webhook{URL: &url}),
+				SetWebhookTimeoutFromSchemaOrDefault(mock.schema),
+				SetWebhookTLSFromSchema(mock.schema),
+			)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			var resp map[string]interface{}
+			err = invoker.Invoke(map[string]string{}, &resp)
+			if mock.isTLSOk && err != nil {
+				t.Fatalf("Expected no invoke error got [%+v]", err)
+			}
+			if !mock.isTLSOk && err == nil {
+				t.Fatalf("Expected invoke error got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+)
+
+// ChildUpdateHookRequest is the object sent as JSON to the
+// preUpdateChild & postUpdateChild hooks
+type ChildUpdateHookRequest struct {
+	Controller *v1alpha1.CompositeController `json:"controller"`
+	Parent     *unstructured.Unstructured    `json:"parent"`
+
+	// Child is the observed state of the child that
+	// gets updated
+	Child *unstructured.Unstructured `json:"child"`
+
+	// Desired is the state the child gets updated to
+	Desired *unstructured.Unstructured `json:"desired"`
+
+	// UpdateMethod is the method used to update the child
+	UpdateMethod v1alpha1.ChildUpdateMethod `json:"updateMethod"`
+}
+
+// String implements Stringer interface
+func (r *ChildUpdateHookRequest) String() string {
+	if r.Child == nil {
+		return "ChildUpdateHookRequest"
+	}
+	return fmt.Sprintf(
+		"ChildUpdateHookRequest %s/%s of %s",
+		r.Child.GetNamespace(), r.Child.GetName(), r.Child.GroupVersionKind(),
+	)
+}
+
+// PreUpdateChildHookResponse is the expected format of the JSON
+// response from the preUpdateChild hook
+type PreUpdateChildHookResponse struct {
+	// SkipUpdate when set to true blocks the update of
+	// the child
+	SkipUpdate bool `json:"skipUpdate"`
+
+	// Message explains why the update was blocked
+	Message string `json:"message"`
+
+	// ResyncAfterSeconds re-evaluates the parent after this
+	// delay; useful to re-check a blocked update
+	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
+}
+
+// PostUpdateChildHookResponse is the expected format of the JSON
+// response from the postUpdateChild hook
+type PostUpdateChildHookResponse struct {
+	// ResyncAfterSeconds re-evaluates the parent after this
+	// delay
+	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
+}
+
+// childUpdateHookState is the last known result of child update
+// hooks against a particular child
+type childUpdateHookState struct {
+	// message set by preUpdateChild hook when this child's
+	// update was blocked
+	blockedMessage string
+
+	// request that needs to be re-sent to postUpdateChild hook
+	// since its earlier invocation failed
+	pendingPostUpdate *ChildUpdateHookRequest
+}
+
+// childUpdateHookStore holds the child update hook states of
+// all the parents managed by a composite controller
+type childUpdateHookStore struct {
+	// guards states since parents are reconciled by
+	// multiple workers
+	mutex sync.Mutex
+
+	// child update hook states anchored by parent key
+	// & then by child key
+	states map[string]map[string]*childUpdateHookState
+}
+
+// newChildUpdateHookStore returns a new instance of childUpdateHookStore
+func newChildUpdateHookStore() *childUpdateHookStore {
+	return &childUpdateHookStore{
+		states: make(map[string]map[string]*childUpdateHookState),
+	}
+}
+
+// parentKeyFromObj returns the key of the provided parent. This
+// has the same format as the key used to queue the parent.
+func parentKeyFromObj(parent *unstructured.Unstructured) string {
+	if parent.GetNamespace() == "" {
+		return parent.GetName()
+	}
+	return parent.GetNamespace() + "/" + parent.GetName()
+}
+
+// childUpdateHookKey returns the key to refer to a child
+// irrespective of its version
+func childUpdateHookKey(apiGroup, kind, name string) string {
+	return fmt.Sprintf("%s/%s", claimMapKey(apiGroup, kind), name)
+}
+
+// childUpdateHookKeyFromObj returns the key to refer to the
+// provided child irrespective of its version
+func childUpdateHookKeyFromObj(child *unstructured.Unstructured) string {
+	apiGroup, _ := common.ParseAPIVersionToGroupVersion(child.GetAPIVersion())
+	return childUpdateHookKey(apiGroup, child.GetKind(), child.GetName())
+}
+
+// update mutates the state of the child via the provided function.
+// State is removed if it no longer holds any information.
+func (s *childUpdateHookStore) update(
+	parentKey string,
+	childKey string,
+	fn func(*childUpdateHookState),
+) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	children := s.states[parentKey]
+	if children == nil {
+		children = make(map[string]*childUpdateHookState)
+		s.states[parentKey] = children
+	}
+	state := children[childKey]
+	if state == nil {
+		state = &childUpdateHookState{}
+		children[childKey] = state
+	}
+	fn(state)
+	if state.blockedMessage == "" && state.pendingPostUpdate == nil {
+		delete(children, childKey)
+	}
+	if len(children) == 0 {
+		delete(s.states, parentKey)
+	}
+}
+
+// get returns a copy of the state of the child
+func (s *childUpdateHookStore) get(
+	parentKey string,
+	childKey string,
+) (childUpdateHookState, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	state := s.states[parentKey][childKey]
+	if state == nil {
+		return childUpdateHookState{}, false
+	}
+	return *state, true
+}
+
+// listPendingPostUpdates returns the requests that need to be
+// re-sent to postUpdateChild hook for the given parent
+func (s *childUpdateHookStore) listPendingPostUpdates(
+	parentKey string,
+) map[string]*ChildUpdateHookRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pending := make(map[string]*ChildUpdateHookRequest)
+	for key, state := range s.states[parentKey] {
+		if state.pendingPostUpdate != nil {
+			pending[key] = state.pendingPostUpdate
+		}
+	}
+	return pending
+}
+
+// clearBlocked removes all the blocked messages of the given
+// parent. This is done before every reconciliation of children
+// since preUpdateChild hook gets re-evaluated.
+func (s *childUpdateHookStore) clearBlocked(parentKey string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for key, state := range s.states[parentKey] {
+		state.blockedMessage = ""
+		if state.pendingPostUpdate == nil {
+			delete(s.states[parentKey], key)
+		}
+	}
+	if len(s.states[parentKey]) == 0 {
+		delete(s.states, parentKey)
+	}
+}
+
+// forget removes all the states of the given parent
+func (s *childUpdateHookStore) forget(parentKey string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.states, parentKey)
+}
+
+// childUpdateHookExecutor invokes preUpdateChild & postUpdateChild
+// hooks for the children of a single parent. It implements
+// common.ChildUpdateHooks.
+type childUpdateHookExecutor struct {
+	Controller *v1alpha1.CompositeController
+	Scope      common.HookScope
+	Parent     *unstructured.Unstructured
+	Store      *childUpdateHookStore
+
+	// least resync delay requested by hooks
+	ResyncAfterSeconds float64
+}
+
+// String implements Stringer interface
+func (e *childUpdateHookExecutor) String() string {
+	if e.Parent == nil {
+		return "ChildUpdateHookExecutor"
+	}
+	return fmt.Sprintf(
+		"ChildUpdateHookExecutor %s/%s of %s",
+		e.Parent.GetNamespace(), e.Parent.GetName(), e.Parent.GroupVersionKind(),
+	)
+}
+
+// requestResync remembers the least resync delay
+func (e *childUpdateHookExecutor) requestResync(seconds float64) {
+	if seconds <= 0 {
+		return
+	}
+	if e.ResyncAfterSeconds == 0 || seconds < e.ResyncAfterSeconds {
+		e.ResyncAfterSeconds = seconds
+	}
+}
+
+// PreUpdate invokes the preUpdateChild hook if set. It returns
+// false if the hook blocked the update of this child.
+func (e *childUpdateHookExecutor) PreUpdate(
+	method v1alpha1.ChildUpdateMethod,
+	observed, desired *unstructured.Unstructured,
+) (bool, error) {
+	hook := e.Controller.Spec.Hooks.PreUpdateChild
+	if hook == nil {
+		return true, nil
+	}
+	req := &ChildUpdateHookRequest{
+		Controller:   e.Controller,
+		Parent:       e.Parent,
+		Child:        observed,
+		Desired:      desired,
+		UpdateMethod: method,
+	}
+	var resp PreUpdateChildHookResponse
+	err := common.InvokeHookWithMetrics(
+		metricsControllerOf(e.Controller), e.Scope, hook, req, &resp,
+	)
+	if err != nil {
+		return false, errors.Wrapf(err, "%s: PreUpdateChild hook failed for %s", e, req)
+	}
+	e.requestResync(resp.ResyncAfterSeconds)
+	if !resp.SkipUpdate {
+		return true, nil
+	}
+	message := resp.Message
+	if message == "" {
+		message = "blocked by preUpdateChild hook"
+	}
+	e.Store.update(
+		parentKeyFromObj(e.Parent),
+		childUpdateHookKeyFromObj(observed),
+		func(state *childUpdateHookState) {
+			state.blockedMessage = message
+		},
+	)
+	return false, nil
+}
+
+// PostUpdate invokes the postUpdateChild hook if set. A failed
+// invocation is remembered & is retried during the next
+// reconciliation of this parent.
+func (e *childUpdateHookExecutor) PostUpdate(
+	method v1alpha1.ChildUpdateMethod,
+	observed, desired *unstructured.Unstructured,
+) error {
+	if e.Controller.Spec.Hooks.PostUpdateChild == nil {
+		return nil
+	}
+	req := &ChildUpdateHookRequest{
+		Controller:   e.Controller,
+		Parent:       e.Parent,
+		Child:        observed,
+		Desired:      desired,
+		UpdateMethod: method,
+	}
+	return e.invokePostUpdate(childUpdateHookKeyFromObj(observed), req)
+}
+
+// invokePostUpdate sends the provided request to postUpdateChild
+// hook & records the result against the child
+func (e *childUpdateHookExecutor) invokePostUpdate(
+	childKey string,
+	req *ChildUpdateHookRequest,
+) error {
+	var resp PostUpdateChildHookResponse
+	err := common.InvokeHookWithMetrics(
+		metricsControllerOf(e.Controller),
+		e.Scope,
+		e.Controller.Spec.Hooks.PostUpdateChild,
+		req,
+		&resp,
+	)
+	e.Store.update(
+		parentKeyFromObj(e.Parent),
+		childKey,
+		func(state *childUpdateHookState) {
+			if err != nil {
+				state.pendingPostUpdate = req
+			} else {
+				state.pendingPostUpdate = nil
+			}
+		},
+	)
+	if err != nil {
+		return errors.Wrapf(err, "%s: PostUpdateChild hook failed for %s", e, req)
+	}
+	e.requestResync(resp.ResyncAfterSeconds)
+	return nil
+}
+
+// RetryPendingPostUpdates re-sends the earlier failed requests
+// to postUpdateChild hook
+func (e *childUpdateHookExecutor) RetryPendingPostUpdates() error {
+	pending := e.Store.listPendingPostUpdates(parentKeyFromObj(e.Parent))
+	if len(pending) == 0 {
+		return nil
+	}
+	if e.Controller.Spec.Hooks.PostUpdateChild == nil {
+		// hook was removed from the controller; nothing to retry
+		e.Store.forget(parentKeyFromObj(e.Parent))
+		return nil
+	}
+	var lastErr error
+	for key, req := range pending {
+		if req.Parent.GetUID() != e.Parent.GetUID() {
+			// parent was re-created; hence this is stale
+			e.Store.update(
+				parentKeyFromObj(e.Parent),
+				key,
+				func(state *childUpdateHookState) {
+					state.pendingPostUpdate = nil
+				},
+			)
+			continue
+		}
+		glog.V(4).Infof("%s: Will retry PostUpdateChild hook for %s", e, req)
+		// use the latest parent & controller
+		req.Parent = e.Parent
+		req.Controller = e.Controller
+		if err := e.invokePostUpdate(key, req); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// hasChildUpdateHooks returns true if any of the child update
+// hooks are set
+func hasChildUpdateHooks(api *v1alpha1.CompositeController) bool {
+	return api.Spec.Hooks != nil &&
+		(api.Spec.Hooks.PreUpdateChild != nil ||
+			api.Spec.Hooks.PostUpdateChild != nil)
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+func newTestHookServer(status int, body string) *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}),
+	)
+}
+
+func newTestChildUpdateHookObjects() (parent, child *unstructured.Unstructured) {
+	parent = &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "test.io/v1",
+			"kind":       "Parent",
+			"metadata": map[string]interface{}{
+				"name":      "my-parent",
+				"namespace": "default",
+				"uid":       "p-uid-1",
+			},
+		},
+	}
+	child = &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "my-child",
+				"namespace": "default",
+			},
+		},
+	}
+	return parent, child
+}
+
+func TestChildUpdateHookExecutorPreUpdate(t *testing.T) {
+	var tests = map[string]struct {
+		status        int
+		body          string
+		isNilHook     bool
+		expectProceed bool
+		expectBlocked string
+		expectResync  float64
+		isErr         bool
+	}{
+		"nil hook": {
+			isNilHook:     true,
+			expectProceed: true,
+		},
+		"empty response": {
+			status:        http.StatusOK,
+			body:          `{}`,
+			expectProceed: true,
+		},
+		"skip update": {
+			status:        http.StatusOK,
+			body:          `{"skipUpdate": true, "message": "draining", "resyncAfterSeconds": 5}`,
+			expectProceed: false,
+			expectBlocked: "draining",
+			expectResync:  5,
+		},
+		"skip update without message": {
+			status:        http.StatusOK,
+			body:          `{"skipUpdate": true}`,
+			expectProceed: false,
+			expectBlocked: "blocked by preUpdateChild hook",
+		},
+		"hook error": {
+			status: http.StatusInternalServerError,
+			body:   `oops`,
+			isErr:  true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			srv := newTestHookServer(mock.status, mock.body)
+			defer srv.Close()
+
+			api := &v1alpha1.CompositeController{
+				Spec: v1alpha1.CompositeControllerSpec{
+					Hooks: &v1alpha1.CompositeControllerHooks{},
+				},
+			}
+			if !mock.isNilHook {
+				api.Spec.Hooks.PreUpdateChild = &v1alpha1.Hook{
+					Webhook: &v1alpha1.Webhook{URL: k8s.StringPtr(srv.URL)},
+				}
+			}
+			parent, child := newTestChildUpdateHookObjects()
+			store := newChildUpdateHookStore()
+			e := &childUpdateHookExecutor{
+				Controller: api,
+				Parent:     parent,
+				Store:      store,
+			}
+			proceed, err := e.PreUpdate(v1alpha1.ChildUpdateRollingInPlace, child, child)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if proceed != mock.expectProceed {
+				t.Fatalf("Expected proceed %t got %t", mock.expectProceed, proceed)
+			}
+			if e.ResyncAfterSeconds != mock.expectResync {
+				t.Fatalf(
+					"Expected resync %f got %f",
+					mock.expectResync,
+					e.ResyncAfterSeconds,
+				)
+			}
+			state, _ := store.get(
+				parentKeyFromObj(parent),
+				childUpdateHookKey("apps", "Deployment", "my-child"),
+			)
+			if state.blockedMessage != mock.expectBlocked {
+				t.Fatalf(
+					"Expected blocked message %q got %q",
+					mock.expectBlocked,
+					state.blockedMessage,
+				)
+			}
+		})
+	}
+}
+
+func TestChildUpdateHookExecutorPostUpdateRetry(t *testing.T) {
+	status := http.StatusInternalServerError
+	srv := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(`{}`))
+		}),
+	)
+	defer srv.Close()
+
+	api := &v1alpha1.CompositeController{
+		Spec: v1alpha1.CompositeControllerSpec{
+			Hooks: &v1alpha1.CompositeControllerHooks{
+				PostUpdateChild: &v1alpha1.Hook{
+					Webhook: &v1alpha1.Webhook{URL: k8s.StringPtr(srv.URL)},
+				},
+			},
+		},
+	}
+	parent, child := newTestChildUpdateHookObjects()
+	store := newChildUpdateHookStore()
+	e := &childUpdateHookExecutor{
+		Controller: api,
+		Parent:     parent,
+		Store:      store,
+	}
+	parentKey := parentKeyFromObj(parent)
+	childKey := childUpdateHookKey("apps", "Deployment", "my-child")
+
+	err := e.PostUpdate(v1alpha1.ChildUpdateRollingInPlace, child, child)
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+	state, found := store.get(parentKey, childKey)
+	if !found || state.pendingPostUpdate == nil {
+		t.Fatalf("Expected pending post update got none")
+	}
+
+	// blocked messages are cleared without touching pending
+	// post updates
+	store.clearBlocked(parentKey)
+	if _, found := store.get(parentKey, childKey); !found {
+		t.Fatalf("Expected pending post update to survive clear blocked")
+	}
+
+	// hook recovers
+	status = http.StatusOK
+	err = e.RetryPendingPostUpdates()
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if _, found := store.get(parentKey, childKey); found {
+		t.Fatalf("Expected no state after successful retry")
+	}
+}
+
+func TestShouldContinueRollingWithChildUpdateHooks(t *testing.T) {
+	parent, _ := newTestChildUpdateHookObjects()
+	pc := &parentController{
+		updateStrategy: childUpdateStrategies{
+			updateStrategyMap: updateStrategyMap{
+				claimMapKey("apps", "Deployment"): &v1alpha1.CompositeControllerChildUpdateStrategy{
+					Method: v1alpha1.ChildUpdateRollingInPlace,
+				},
+			},
+		},
+		childUpdateHooks: newChildUpdateHookStore(),
+	}
+	latest := &parentRevision{
+		parent: parent,
+		revision: &v1alpha1.ControllerRevision{
+			Children: []v1alpha1.ControllerRevisionChildren{
+				{
+					APIGroup: "apps",
+					Kind:     "Deployment",
+					Names:    []string{"my-child"},
+				},
+			},
+		},
+	}
+	pc.childUpdateHooks.update(
+		parentKeyFromObj(parent),
+		childUpdateHookKey("apps", "Deployment", "my-child"),
+		func(state *childUpdateHookState) {
+			state.blockedMessage = "draining"
+		},
+	)
+	err := pc.shouldContinueRolling(latest, nil)
+	if err == nil {
+		t.Fatalf("Expected rollout to pause got none")
+	}
+	want := "child Deployment my-child update is blocked: draining"
+	if err.Error() != want {
+		t.Fatalf("Expected error %q got %q", want, err.Error())
+	}
+}
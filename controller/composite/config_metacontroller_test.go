@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+func TestConfigMetacontrollerValidateConfigs(t *testing.T) {
+	var tests = map[string]struct {
+		configs []*v1alpha1.CompositeController
+		isErr   bool
+	}{
+		"no configs": {},
+		"unique configs": {
+			configs: []*v1alpha1.CompositeController{
+				{ObjectMeta: metav1.ObjectMeta{Name: "one"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "two"}},
+			},
+		},
+		"duplicate configs": {
+			configs: []*v1alpha1.CompositeController{
+				{ObjectMeta: metav1.ObjectMeta{Name: "one"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "one"}},
+			},
+			isErr: true,
+		},
+		"in place update strategy": {
+			configs: []*v1alpha1.CompositeController{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "one"},
+					Spec: v1alpha1.CompositeControllerSpec{
+						ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+							{
+								ResourceRule: v1alpha1.ResourceRule{
+									APIVersion: "v1",
+									Resource:   "pods",
+								},
+								UpdateStrategy: &v1alpha1.CompositeControllerChildUpdateStrategy{
+									Method: v1alpha1.ChildUpdateInPlace,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"rolling update strategy": {
+			configs: []*v1alpha1.CompositeController{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "one"},
+					Spec: v1alpha1.CompositeControllerSpec{
+						ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+							{
+								ResourceRule: v1alpha1.ResourceRule{
+									APIVersion: "v1",
+									Resource:   "pods",
+								},
+								UpdateStrategy: &v1alpha1.CompositeControllerChildUpdateStrategy{
+									Method: v1alpha1.ChildUpdateRollingRecreate,
+								},
+							},
+						},
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ctl := &ConfigMetacontroller{
+				Configs: mock.configs,
+			}
+			ctl.validateConfigs()
+			if mock.isErr && ctl.err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && ctl.err != nil {
+				t.Fatalf("Expected no error got [%+v]", ctl.err)
+			}
+		})
+	}
+}
@@ -39,11 +39,13 @@ import (
 	mcclientset "openebs.io/metac/client/generated/clientset/versioned"
 	mclisters "openebs.io/metac/client/generated/listers/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/customize"
 	"openebs.io/metac/controller/common/finalizer"
 	dynamicclientset "openebs.io/metac/dynamic/clientset"
 	dynamiccontrollerref "openebs.io/metac/dynamic/controllerref"
 	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
 	dynamicinformer "openebs.io/metac/dynamic/informer"
+	"openebs.io/metac/metrics"
 	k8s "openebs.io/metac/third_party/kubernetes"
 )
 
@@ -64,10 +66,30 @@ type parentController struct {
 	stopCh, doneCh chan struct{}
 	queue          workqueue.RateLimitingInterface
 
-	updateStrategy updateStrategyMap
+	updateStrategy childUpdateStrategies
 	childInformers common.ResourceInformerRegistrar
 
 	finalizer *finalizer.Finalizer
+
+	// last known results of preUpdateChild & postUpdateChild
+	// hooks against the children
+	childUpdateHooks *childUpdateHookStore
+
+	// fetches the resources related to a parent if customize
+	// hook is set
+	customize *customize.Manager
+
+	// decides what the hooks of this controller are allowed to do
+	hookScope common.HookScope
+}
+
+// metricsControllerOf returns the provided composite controller
+// as a metrics label
+func metricsControllerOf(api *v1alpha1.CompositeController) metrics.Controller {
+	return metrics.Controller{
+		Kind: metrics.KindCompositeController,
+		Name: api.Name,
+	}
 }
 
 func newParentController(
@@ -77,7 +99,24 @@ func newParentController(
 	mcClient mcclientset.Interface,
 	revisionLister mclisters.ControllerRevisionLister,
 	api *v1alpha1.CompositeController,
+	hookScope common.HookScope,
 ) (pc *parentController, newErr error) {
+	// reject the hooks that are not allowed in this scope
+	if api.Spec.Hooks != nil {
+		err := common.ValidateHooks(
+			hookScope,
+			api.Spec.Hooks.Sync,
+			api.Spec.Hooks.Finalize,
+			api.Spec.Hooks.Customize,
+			api.Spec.Hooks.PreUpdateChild,
+			api.Spec.Hooks.PostUpdateChild,
+		)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "Invalid hooks: CompositeController %s", api.Name,
+			)
+		}
+	}
 	// Make a dynamic client for the parent resource.
 	parentClient, err := dynClientSet.GetClientForAPIVersionAndResource(
 		api.Spec.ParentResource.APIVersion,
@@ -88,10 +127,14 @@ func newParentController(
 	}
 	parentResource := parentClient.APIResource
 
-	updateStrategy, err := makeUpdateStrategyMap(resources, api)
+	strategies, err := makeUpdateStrategyMap(resources, api)
 	if err != nil {
 		return nil, err
 	}
+	updateStrategy := childUpdateStrategies{
+		updateStrategyMap: strategies,
+		fieldManager:      common.MakeFieldManager("CompositeController", api.GetName()),
+	}
 
 	// Create informer for the parent resource.
 	parentInformer, err := informerFactory.GetOrCreate(
@@ -148,12 +191,33 @@ func newParentController(
 		updateStrategy: updateStrategy,
 		queue: workqueue.NewNamedRateLimitingQueue(
 			workqueue.DefaultControllerRateLimiter(),
-			"CompositeController-"+api.Name,
+			metrics.QueueName(metricsControllerOf(api)),
 		),
 		finalizer: &finalizer.Finalizer{
 			Name:    "metac.openebs.io/compositecontroller-" + api.Name,
 			Enabled: api.Spec.Hooks.Finalize != nil,
 		},
+		childUpdateHooks: newChildUpdateHookStore(),
+		hookScope:        hookScope,
+	}
+
+	if api.Spec.Hooks != nil && api.Spec.Hooks.Customize != nil {
+		if api.Spec.Hooks.Customize.Inline != nil {
+			return nil, errors.Errorf(
+				"CompositeController %s: inline customize hook is not supported",
+				pc,
+			)
+		}
+		// related resources are fetched via informers that
+		// are created on demand
+		pc.customize = customize.NewManager(
+			"CompositeController "+pc.String(),
+			hookScope,
+			pc.callCustomizeHook,
+			dynClientSet,
+			informerFactory,
+			pc.enqueueParentObject,
+		)
 	}
 
 	return pc, nil
@@ -182,7 +246,7 @@ func (pc *parentController) Start(workerCount int) {
 	parentHandlers := cache.ResourceEventHandlerFuncs{
 		AddFunc:    pc.enqueueParentObject,
 		UpdateFunc: pc.updateParentObject,
-		DeleteFunc: pc.enqueueParentObject,
+		DeleteFunc: pc.deleteParentObject,
 	}
 	if pc.api.Spec.ResyncPeriodSeconds != nil {
 		// Use a custom resync period if requested. This only applies to the parent.
@@ -268,6 +332,10 @@ func (pc *parentController) Stop() {
 	// wait till done channel is closed
 	<-pc.doneCh
 
+	// Release the informers of related resources if any
+	if pc.customize != nil {
+		pc.customize.Stop()
+	}
 	// Remove event handlers and close informers (i.e. decrement the counter)
 	// for all child resources.
 	for _, informer := range pc.childInformers {
@@ -296,7 +364,9 @@ func (pc *parentController) processNextWorkItem() bool {
 	}
 
 	defer pc.queue.Done(key)
+	start := time.Now()
 	err := pc.sync(key.(string))
+	metrics.RecordSync(metricsControllerOf(pc.api), start, err)
 	if err != nil {
 		utilruntime.HandleError(errors.Wrapf(
 			err,
@@ -305,7 +375,10 @@ func (pc *parentController) processNextWorkItem() bool {
 		// reconcile failed; add this once more
 		// default rate limit should hopefully avoid
 		// a hot sync loop
-		pc.queue.AddRateLimited(key)
+		//
+		// NOTE:
+		//	structured hook errors may defer or skip the requeue
+		common.RequeueAfterSyncError(pc.queue, key, err)
 		return true
 	}
 
@@ -336,6 +409,15 @@ func (pc *parentController) enqueueParentObjectAfter(obj interface{}, delay time
 	pc.queue.AddAfter(key, delay)
 }
 
+// deleteParentObject enqueues the deleted parent after
+// forgetting its related resources
+func (pc *parentController) deleteParentObject(obj interface{}) {
+	if pc.customize != nil {
+		pc.customize.ForgetParent(obj)
+	}
+	pc.enqueueParentObject(obj)
+}
+
 func (pc *parentController) updateParentObject(old, cur interface{}) {
 	// We used to ignore our own status updates, but we don't anymore.
 	// It's sometimes necessary for a hook to see its own status updates
@@ -344,6 +426,12 @@ func (pc *parentController) updateParentObject(old, cur interface{}) {
 	// different status (e.g. you have some incrementing counter).
 	// Doing that is an anti-pattern anyway because status generation should be
 	// idempotent if nothing meaningful has actually changed in the system.
+	//
+	// Hook error condition set by this controller is ignored though. A
+	// failed hook is retried as per its error.
+	if common.IsHookErrorConditionUpdate(old, cur) {
+		return
+	}
 	pc.enqueueParentObject(cur)
 }
 
@@ -553,6 +641,8 @@ func (pc *parentController) sync(key string) error {
 			"CompositeController %s: parent %s/%s has been deleted",
 			pc, namespace, name,
 		)
+		// forget the child update hook results of this parent
+		pc.childUpdateHooks.forget(key)
 		return nil
 	}
 	if err != nil {
@@ -586,11 +676,59 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 		return err
 	}
 
+	// Child update hooks are invoked only if they are set
+	var childUpdateHooks common.ChildUpdateHooks
+	var childUpdateHookExec *childUpdateHookExecutor
+	if hasChildUpdateHooks(pc.api) {
+		childUpdateHookExec = &childUpdateHookExecutor{
+			Controller: pc.api,
+			Scope:      pc.hookScope,
+			Parent:     parent,
+			Store:      pc.childUpdateHooks,
+		}
+		childUpdateHooks = childUpdateHookExec
+		// Retry postUpdateChild hook for children whose earlier
+		// invocations failed. Rolling update is paused till these
+		// succeed.
+		if err := childUpdateHookExec.RetryPendingPostUpdates(); err != nil {
+			glog.Warningf(
+				"CompositeController %s: %+v",
+				pc,
+				err,
+			)
+		}
+	}
+
+	// List the resources related to this parent if any
+	var relatedObjects common.AnyUnstructRegistry
+	if pc.customize != nil {
+		relatedObjects, err = pc.customize.GetRelatedObjects(parent)
+		if err != nil {
+			return errors.Wrapf(
+				err,
+				"CompositeController %s: can't get related objects for %s/%s",
+				pc,
+				parent.GetNamespace(),
+				parent.GetName(),
+			)
+		}
+	}
+
 	// Reconcile ControllerRevisions belonging to this parent.
 	// Call the sync hook for each revision, then compute the overall status and
 	// desired children, accounting for any rollout in progress.
-	syncResult, err := pc.syncRevisions(parent, observedChildren)
+	syncResult, err := pc.syncRevisions(parent, observedChildren, relatedObjects)
 	if err != nil {
+		// report structured hook errors in parent's status
+		if condErr := common.UpdateHookErrorCondition(pc.parentClient, parent, err); condErr != nil {
+			glog.Errorf(
+				"CompositeController %s: can't set hook error condition for %s/%s: %+v",
+				pc,
+				parent.GetNamespace(),
+				parent.GetName(),
+				condErr,
+			)
+		}
 		return err
 	}
 	desiredChildren :=
@@ -672,10 +810,15 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 	// or if it's pending deletion and we have a `finalize` hook.
 	var manageErr error
 	if parent.GetDeletionTimestamp() == nil || pc.finalizer.ShouldFinalize(parent) {
+		// preUpdateChild hook gets re-evaluated during this
+		// reconciliation
+		pc.childUpdateHooks.clearBlocked(parentKeyFromObj(parent))
+
 		// Reconcile children.
 		if err := common.ManageChildren(
 			pc.dynClientSet,
 			pc.updateStrategy,
+			childUpdateHooks,
 			parent,
 			observedChildren,
 			desiredChildren,
@@ -690,10 +833,24 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 		}
 	}
 
+	// Enqueue a delayed resync, if requested by child update hooks.
+	if childUpdateHookExec != nil && childUpdateHookExec.ResyncAfterSeconds > 0 {
+		pc.enqueueParentObjectAfter(
+			parent,
+			time.Duration(childUpdateHookExec.ResyncAfterSeconds*float64(time.Second)),
+		)
+	}
+
 	// Update parent status.
 	// We'll want to make sure this happens after manageChildren once
 	// we support observedGeneration.
-	if _, err := pc.updateParentStatus(parent, syncResult.Status); err != nil {
+	//
+	// NOTE:
+	//	Hook succeeded; hence any earlier hook error is resolved
+	if _, err := pc.updateParentStatus(
+		parent,
+		common.ResolveHookErrorCondition(syncResult.Status),
+	); err != nil {
 		return errors.Wrapf(
 			err,
 			"CompositeController %s: can't update status for %s/%s",
@@ -85,6 +85,7 @@ func (pc *parentController) claimRevisions(
 func (pc *parentController) syncRevisions(
 	parent *unstructured.Unstructured,
 	observedChildren common.AnyUnstructRegistry,
+	relatedObjects common.AnyUnstructRegistry,
 ) (*SyncHookResponse, error) {
 
 	// If no child resources use rolling updates, just sync the latest parent.
@@ -97,8 +98,9 @@ func (pc *parentController) syncRevisions(
 			Controller: pc.api,
 			Parent:     parent,
 			Children:   observedChildren,
+			Related:    relatedObjects,
 		}
-		syncResult, err := callSyncHook(pc.api, syncRequest)
+		syncResult, err := callSyncHook(pc.api, pc.hookScope, syncRequest)
 		if err != nil {
 			return nil, errors.Wrapf(
 				err,
@@ -175,8 +177,9 @@ func (pc *parentController) syncRevisions(
 				Controller: pc.api,
 				Parent:     rev.parent,
 				Children:   observedChildren,
+				Related:    relatedObjects,
 			}
-			syncResult, err := callSyncHook(pc.api, syncRequest)
+			syncResult, err := callSyncHook(pc.api, pc.hookScope, syncRequest)
 			if err != nil {
 				rev.syncError = err
 				return
@@ -191,7 +194,7 @@ func (pc *parentController) syncRevisions(
 	// If any of the sync calls failed, abort.
 	for _, pr := range parentRevisions {
 		if pr.syncError != nil {
-			return nil, fmt.Errorf("sync hook failed for %v %v/%v: %v", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), pr.syncError)
+			return nil, errors.Wrapf(pr.syncError, "sync hook failed for %v %v/%v", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName())
 		}
 	}
 
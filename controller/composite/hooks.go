@@ -18,12 +18,14 @@ package composite
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/customize"
 )
 
 // SyncHookRequest is the object sent as JSON to the sync hook.
@@ -31,9 +33,17 @@ type SyncHookRequest struct {
 	Controller *v1alpha1.CompositeController `json:"controller"`
 	Parent     *unstructured.Unstructured    `json:"parent"`
 	Children   common.AnyUnstructRegistry    `json:"children"`
+	Related    common.AnyUnstructRegistry    `json:"related,omitempty"`
 	Finalizing bool                          `json:"finalizing"`
 }
 
+// CustomizeHookRequest is the object sent as JSON to the
+// customize hook
+type CustomizeHookRequest struct {
+	Controller *v1alpha1.CompositeController `json:"controller"`
+	Parent     *unstructured.Unstructured    `json:"parent"`
+}
+
 // String implements Stringer interface
 func (r *SyncHookRequest) String() string {
 	if r.Parent == nil {
@@ -72,6 +82,9 @@ type SyncHookResponse struct {
 // HookExecutor can execute a hook
 type HookExecutor struct {
 	Controller *v1alpha1.CompositeController
+
+	// Scope of the controller that invokes the hooks
+	Scope common.HookScope
 }
 
 // String implements Stringer interface
@@ -101,7 +114,13 @@ func (e *HookExecutor) Execute(req *SyncHookRequest) (*SyncHookResponse, error)
 		e.Controller.Spec.Hooks.Finalize != nil {
 		// Finalize
 		req.Finalizing = true
-		err := common.InvokeHook(e.Controller.Spec.Hooks.Finalize, req, &resp)
+		err := invokeHook(
+			e.Controller,
+			e.Scope,
+			e.Controller.Spec.Hooks.Finalize,
+			req,
+			&resp,
+		)
 		if err != nil {
 			return nil, errors.Wrapf(err, "%s: Finalize hook failed for %s", e, req)
 		}
@@ -113,7 +132,13 @@ func (e *HookExecutor) Execute(req *SyncHookRequest) (*SyncHookResponse, error)
 				errors.Errorf("%s: Sync hook not defined for %s", e, req)
 		}
 
-		err := common.InvokeHook(e.Controller.Spec.Hooks.Sync, req, &resp)
+		err := invokeHook(
+			e.Controller,
+			e.Scope,
+			e.Controller.Spec.Hooks.Sync,
+			req,
+			&resp,
+		)
 		if err != nil {
 			return nil,
 				errors.Wrapf(err, "%s: Sync hook failed for %s", e, req)
@@ -123,10 +148,71 @@ func (e *HookExecutor) Execute(req *SyncHookRequest) (*SyncHookResponse, error)
 	return &resp, nil
 }
 
+// invokeHook invokes the provided sync or finalize hook. Inline
+// hooks are looked up from CompositeController's inline hook
+// registry.
+func invokeHook(
+	controller *v1alpha1.CompositeController,
+	scope common.HookScope,
+	schema *v1alpha1.Hook,
+	req *SyncHookRequest,
+	resp *SyncHookResponse,
+) (err error) {
+	if schema.Inline == nil || schema.Inline.FuncName == nil {
+		// this is one of the commonly supported hooks
+		return common.InvokeHookWithMetrics(
+			metricsControllerOf(controller),
+			scope,
+			schema,
+			req,
+			resp,
+		)
+	}
+	start := time.Now()
+	defer func() {
+		common.RecordHook(
+			metricsControllerOf(controller),
+			schema,
+			start,
+			req,
+			resp,
+			err,
+		)
+	}()
+	ihi, err := NewInlineHookInvoker(*schema.Inline.FuncName)
+	if err != nil {
+		return err
+	}
+	return ihi.Invoke(req, resp)
+}
+
 func callSyncHook(
 	controller *v1alpha1.CompositeController,
+	scope common.HookScope,
 	request *SyncHookRequest,
 ) (*SyncHookResponse, error) {
-	e := HookExecutor{Controller: controller}
+	e := HookExecutor{Controller: controller, Scope: scope}
 	return e.Execute(request)
 }
+
+// callCustomizeHook invokes the customize hook to get the
+// resources related to the provided parent
+func (pc *parentController) callCustomizeHook(
+	parent *unstructured.Unstructured,
+) (*customize.HookResponse, error) {
+	var resp customize.HookResponse
+	err := common.InvokeHookWithMetrics(
+		metricsControllerOf(pc.api),
+		pc.hookScope,
+		pc.api.Spec.Hooks.Customize,
+		&CustomizeHookRequest{
+			Controller: pc.api,
+			Parent:     parent,
+		},
+		&resp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+func TestHookExecutorExecuteInlineHook(t *testing.T) {
+	AddToInlineRegistry(
+		"test/composite-sync",
+		func(req *SyncHookRequest, resp *SyncHookResponse) error {
+			resp.Status = map[string]interface{}{
+				"parent":     req.Parent.GetName(),
+				"finalizing": req.Finalizing,
+			}
+			return nil
+		},
+	)
+	AddToInlineRegistry(
+		"test/composite-finalize",
+		func(req *SyncHookRequest, resp *SyncHookResponse) error {
+			resp.Finalized = req.Finalizing
+			return nil
+		},
+	)
+
+	var tests = map[string]struct {
+		hooks           *v1alpha1.CompositeControllerHooks
+		isDeleted       bool
+		isErr           bool
+		expectFinalized bool
+	}{
+		"inline sync": {
+			hooks: &v1alpha1.CompositeControllerHooks{
+				Sync: &v1alpha1.Hook{
+					Inline: &v1alpha1.Inline{
+						FuncName: k8s.StringPtr("test/composite-sync"),
+					},
+				},
+			},
+		},
+		"inline finalize": {
+			hooks: &v1alpha1.CompositeControllerHooks{
+				Sync: &v1alpha1.Hook{
+					Inline: &v1alpha1.Inline{
+						FuncName: k8s.StringPtr("test/composite-sync"),
+					},
+				},
+				Finalize: &v1alpha1.Hook{
+					Inline: &v1alpha1.Inline{
+						FuncName: k8s.StringPtr("test/composite-finalize"),
+					},
+				},
+			},
+			isDeleted:       true,
+			expectFinalized: true,
+		},
+		"inline function not registered": {
+			hooks: &v1alpha1.CompositeControllerHooks{
+				Sync: &v1alpha1.Hook{
+					Inline: &v1alpha1.Inline{
+						FuncName: k8s.StringPtr("test/composite-none"),
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			parent := &unstructured.Unstructured{}
+			parent.SetName("test")
+			if mock.isDeleted {
+				now := metav1.Now()
+				parent.SetDeletionTimestamp(&now)
+			}
+			e := &HookExecutor{
+				Controller: &v1alpha1.CompositeController{
+					Spec: v1alpha1.CompositeControllerSpec{
+						Hooks: mock.hooks,
+					},
+				},
+			}
+			resp, err := e.Execute(NewSyncHookRequest(parent, nil))
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if resp.Finalized != mock.expectFinalized {
+				t.Fatalf(
+					"Expected finalized %t got %t",
+					mock.expectFinalized,
+					resp.Finalized,
+				)
+			}
+			if !mock.isDeleted && resp.Status["parent"] != "test" {
+				t.Fatalf("Expected status parent %q got %v", "test", resp.Status)
+			}
+		})
+	}
+}
@@ -185,7 +185,7 @@ func (mc *Metacontroller) syncCompositeController(cc *v1alpha1.CompositeControll
 		delete(mc.parentControllers, cc.Name)
 	}
 
-	pc, err := newParentController(mc.resourceManager, mc.dynamicClientset, mc.dynamicInformerFactory, mc.metaClientset, mc.revisionLister, cc)
+	pc, err := newParentController(mc.resourceManager, mc.dynamicClientset, mc.dynamicInformerFactory, mc.metaClientset, mc.revisionLister, cc, common.HookScope{})
 	if err != nil {
 		return err
 	}
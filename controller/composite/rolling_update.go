@@ -20,8 +20,6 @@ import (
 	"fmt"
 	"reflect"
 
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
 	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
@@ -169,6 +167,22 @@ func (pc *parentController) shouldContinueRolling(
 		}
 
 		for _, name := range ck.Names {
+			// Child update hooks can pause the rollout
+			if state, found := pc.childUpdateHooks.get(
+				parentKeyFromObj(latest.parent),
+				childUpdateHookKey(ck.APIGroup, ck.Kind, name),
+			); found {
+				if state.blockedMessage != "" {
+					return fmt.Errorf(
+						"child %v %v update is blocked: %s", ck.Kind, name, state.blockedMessage,
+					)
+				}
+				if state.pendingPostUpdate != nil {
+					return fmt.Errorf(
+						"child %v %v is waiting for postUpdateChild hook to succeed", ck.Kind, name,
+					)
+				}
+			}
 			child := observedChildren.FindByGroupKindName(ck.APIGroup, ck.Kind, name)
 			if child == nil {
 				// We didn't observe this child at all, so it's not happy.
@@ -201,7 +215,7 @@ func (pc *parentController) shouldContinueRolling(
 				}
 			}
 			// Check the child status according to the updateStrategy.
-			if err := childStatusCheck(&strategy.StatusChecks, child); err != nil {
+			if err := common.CheckStatusConditions(&strategy.StatusChecks, child); err != nil {
 				// If any child already on the latest revision fails the status check,
 				// pause the rollout.
 				return fmt.Errorf("child %v %v failed status check: %v", ck.Kind, name, err)
@@ -268,46 +282,6 @@ func (pc *parentController) syncRevisionClaims(parentRevisions []*parentRevision
 	return claimed
 }
 
-func childStatusCheck(
-	checks *v1alpha1.ChildUpdateStatusChecks,
-	child *unstructured.Unstructured,
-) error {
-	if checks == nil {
-		// Nothing to check.
-		return nil
-	}
-
-	for _, condCheck := range checks.Conditions {
-		cond := dynamicobject.GetStatusCondition(child.UnstructuredContent(), condCheck.Type)
-		if cond == nil {
-			return fmt.Errorf(
-				"required condition type missing: %q", condCheck.Type,
-			)
-		}
-		if condCheck.Status != nil {
-			if cond.Status != *condCheck.Status {
-				return fmt.Errorf(
-					"%q condition status is %q (want %q)",
-					condCheck.Type,
-					cond.Status,
-					*condCheck.Status,
-				)
-			}
-		}
-		if condCheck.Reason != nil {
-			if cond.Reason != *condCheck.Reason {
-				return fmt.Errorf(
-					"%q condition reason is %q (want %q)",
-					condCheck.Type,
-					cond.Reason,
-					*condCheck.Reason,
-				)
-			}
-		}
-	}
-	return nil
-}
-
 type updateStrategyMap map[string]*v1alpha1.CompositeControllerChildUpdateStrategy
 
 func (m updateStrategyMap) Get(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
@@ -322,6 +296,25 @@ func (m updateStrategyMap) get(apiGroup, kind string) *v1alpha1.CompositeControl
 	return m[claimMapKey(apiGroup, kind)]
 }
 
+// childUpdateStrategies holds the update strategies of children
+// along with the field manager used to server side apply them
+type childUpdateStrategies struct {
+	updateStrategyMap
+
+	// fieldManager is used if a strategy does not set one
+	fieldManager string
+}
+
+// GetServerSideApplyOptions returns the server side apply options
+// based on the given api group & kind
+func (s childUpdateStrategies) GetServerSideApplyOptions(apiGroup, kind string) common.ServerSideApplyOptions {
+	var opts *v1alpha1.ServerSideApplyOptions
+	if strategy := s.get(apiGroup, kind); strategy != nil {
+		opts = strategy.ServerSideApply
+	}
+	return common.MakeServerSideApplyOptions(opts, s.fieldManager)
+}
+
 func (m updateStrategyMap) isRolling(apiGroup, kind string) bool {
 	return isRollingStrategy(m.get(apiGroup, kind))
 }
@@ -340,11 +333,7 @@ func isRollingStrategy(strategy *v1alpha1.CompositeControllerChildUpdateStrategy
 		// This child kind uses OnDelete (don't update at all).
 		return false
 	}
-	switch strategy.Method {
-	case v1alpha1.ChildUpdateRollingInPlace, v1alpha1.ChildUpdateRollingRecreate:
-		return true
-	}
-	return false
+	return common.IsRollingUpdate(strategy.Method)
 }
 
 // makeUpdateStrategyMap builds a map of update strategies
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"testing"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+func TestChildUpdateStrategiesGetServerSideApplyOptions(t *testing.T) {
+	strategies := childUpdateStrategies{
+		updateStrategyMap: updateStrategyMap{
+			claimMapKey("apps", "Deployment"): &v1alpha1.CompositeControllerChildUpdateStrategy{
+				Method: v1alpha1.ChildUpdateServerSideApply,
+			},
+			claimMapKey("", "ConfigMap"): &v1alpha1.CompositeControllerChildUpdateStrategy{
+				Method: v1alpha1.ChildUpdateServerSideApply,
+				ServerSideApply: &v1alpha1.ServerSideApplyOptions{
+					FieldManager: "my-manager",
+				},
+			},
+		},
+		fieldManager: "metac/compositecontroller/test",
+	}
+	var tests = map[string]struct {
+		apiGroup      string
+		kind          string
+		expectManager string
+	}{
+		"strategy without field manager": {
+			apiGroup:      "apps",
+			kind:          "Deployment",
+			expectManager: "metac/compositecontroller/test",
+		},
+		"strategy with field manager": {
+			kind:          "ConfigMap",
+			expectManager: "my-manager",
+		},
+		"no strategy": {
+			kind:          "Secret",
+			expectManager: "metac/compositecontroller/test",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := strategies.GetServerSideApplyOptions(mock.apiGroup, mock.kind)
+			if got.FieldManager != mock.expectManager {
+				t.Fatalf(
+					"Expected field manager %q got %q",
+					mock.expectManager,
+					got.FieldManager,
+				)
+			}
+			if !got.Force {
+				t.Fatalf("Expected forced apply got %+v", got)
+			}
+		})
+	}
+}
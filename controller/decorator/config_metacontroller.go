@@ -0,0 +1,308 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decorator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/config"
+	"openebs.io/metac/controller/common"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+// ConfigMetacontroller represents a MetaController that is
+// based on config files. This config schema is based on
+// DecoratorController api. Configs are provided to this binary
+// at some configured location.
+type ConfigMetacontroller struct {
+	ResourceManager    *dynamicdiscovery.APIResourceDiscovery
+	DynClientset       *dynamicclientset.Clientset
+	DynInformerFactory *dynamicinformer.SharedInformerFactory
+
+	DecoratorControllers map[string]*decoratorController
+	WorkerCount          int
+
+	// Path from which metac configs will be loaded
+	ConfigPath string
+
+	// Function that fetches all decorator controller instances
+	// required to run Metac
+	//
+	// NOTE:
+	//	One can use either ConfigPath or this function. ConfigPath
+	// option has higher priority.
+	ConfigLoadFn func() ([]*v1alpha1.DecoratorController, error)
+
+	// Config instances of type DecoratorController required to
+	// run decorator controllers
+	Configs []*v1alpha1.DecoratorController
+
+	// This will allow executing start logic to be retried
+	// indefinitely till all the decorator controllers are started
+	RetryIndefinitelyUntilSucceed *bool
+
+	// Maximum time to wait to start all decorator controllers
+	WaitTimeoutForStartAttempt time.Duration
+
+	// Interval between retries to start all decorator controllers
+	WaitIntervalBetweenRestarts time.Duration
+
+	doneCh chan struct{}
+
+	opts []ConfigMetacontrollerOption
+	err  error
+}
+
+// ConfigMetacontrollerOption is a functional option to
+// mutate ConfigMetacontroller instance
+//
+// This follows **functional options** pattern
+type ConfigMetacontrollerOption func(*ConfigMetacontroller) error
+
+// SetMetacConfigToRetryIndefinitelyForStart will let this
+// controller to retry indefinitely till all its decorator controllers
+// are started
+//
+// NOTE:
+//	Indefinite retry is set only when the provided flag is true
+func SetMetacConfigToRetryIndefinitelyForStart(enabled *bool) ConfigMetacontrollerOption {
+	return func(c *ConfigMetacontroller) error {
+		// indefinite retry is set only if enabled is true
+		if enabled == nil || !*enabled {
+			return nil
+		}
+		c.WaitIntervalBetweenRestarts = 1 * time.Minute
+		c.RetryIndefinitelyUntilSucceed = k8s.BoolPtr(true)
+		return nil
+	}
+}
+
+// SetMetacConfigLoadFn sets the config loader function
+func SetMetacConfigLoadFn(
+	fn func() ([]*v1alpha1.DecoratorController, error),
+) ConfigMetacontrollerOption {
+	return func(c *ConfigMetacontroller) error {
+		c.ConfigLoadFn = fn
+		return nil
+	}
+}
+
+// SetMetacConfigPath sets the config path
+func SetMetacConfigPath(path string) ConfigMetacontrollerOption {
+	return func(c *ConfigMetacontroller) error {
+		c.ConfigPath = path
+		return nil
+	}
+}
+
+// NewConfigMetacontroller returns a new instance of ConfigMetacontroller
+func NewConfigMetacontroller(
+	resourceMgr *dynamicdiscovery.APIResourceDiscovery,
+	dynClientset *dynamicclientset.Clientset,
+	dynInformerFactory *dynamicinformer.SharedInformerFactory,
+	workerCount int,
+	opts ...ConfigMetacontrollerOption,
+) (*ConfigMetacontroller, error) {
+	// initialize with defaults & the provided values
+	ctl := &ConfigMetacontroller{
+		// Default setting for retry
+		// - Retry times out in 30 minutes
+		WaitTimeoutForStartAttempt: 30 * time.Minute,
+		// - Interval between retries is 1 second
+		WaitIntervalBetweenRestarts: 1 * time.Second,
+		ResourceManager:             resourceMgr,
+		DynClientset:                dynClientset,
+		DynInformerFactory:          dynInformerFactory,
+		WorkerCount:                 workerCount,
+		DecoratorControllers:        make(map[string]*decoratorController),
+		opts:                        opts,
+	}
+	var fns = []func(){
+		ctl.runOptions,
+		ctl.loadConfigs,
+		ctl.validateConfigs,
+	}
+	for _, fn := range fns {
+		fn()
+		if ctl.err != nil {
+			return nil, ctl.err
+		}
+	}
+	return ctl, nil
+}
+
+// String implements Stringer interface
+func (mc *ConfigMetacontroller) String() string {
+	return "Local DecoratorController"
+}
+
+func (mc *ConfigMetacontroller) runOptions() {
+	for _, o := range mc.opts {
+		err := o(mc)
+		if err != nil {
+			mc.err = err
+			return
+		}
+	}
+}
+
+func (mc *ConfigMetacontroller) loadConfigs() {
+	// validate
+	if mc.ConfigPath == "" && mc.ConfigLoadFn == nil {
+		mc.err = errors.Errorf(
+			"Can't load config: Either ConfigPath or ConfigLoadFn is required: %s",
+			mc,
+		)
+		return
+	}
+	// NOTE:
+	// 	ConfigPath has **higher priority** to load DecoratorController
+	// instance(s) as config(s) to run Metac
+	if mc.ConfigPath != "" {
+		mc.Configs, mc.err = mc.loadConfigsByPath()
+	} else {
+		mc.Configs, mc.err = mc.ConfigLoadFn()
+	}
+}
+
+func (mc *ConfigMetacontroller) loadConfigsByPath() ([]*v1alpha1.DecoratorController, error) {
+	configs, err := config.New(mc.ConfigPath).Load()
+	if err != nil {
+		return nil, err
+	}
+	return configs.ListDecoratorControllers()
+}
+
+// validateConfigs returns error if any duplicate config
+// is found
+func (mc *ConfigMetacontroller) validateConfigs() {
+	var allconfigs = map[string]bool{}
+	for _, conf := range mc.Configs {
+		if allconfigs[conf.Name] {
+			mc.err = errors.Errorf(
+				"Duplicate %s was found: %s",
+				conf.Name,
+				mc,
+			)
+			return
+		}
+		// add it to check for possible duplicates in
+		// next iterations
+		allconfigs[conf.Name] = true
+	}
+}
+
+// Start starts all the decorator controllers corresponding to
+// the provided configs
+func (mc *ConfigMetacontroller) Start() {
+	mc.doneCh = make(chan struct{})
+
+	go func() {
+		defer close(mc.doneCh)
+		defer utilruntime.HandleCrash()
+
+		glog.Infof("Starting %s", mc)
+
+		// Run this with retries until all the configs are
+		// started. In other words, this starts all the decorator
+		// controllers configured in config file eventually.
+		err := common.StartRetrier{
+			Caller:            mc,
+			WaitTimeout:       mc.WaitTimeoutForStartAttempt,
+			WaitInterval:      mc.WaitIntervalBetweenRestarts,
+			RetryIndefinitely: mc.RetryIndefinitelyUntilSucceed,
+		}.Run(mc.startAllDecoratorControllers)
+		if err != nil {
+			glog.Fatalf("Failed to start %s: %+v", mc, err)
+		}
+	}()
+}
+
+// startAllDecoratorControllers starts all the decorator
+// controllers that were provided as config to this binary
+//
+// NOTE:
+//	This method is used as a condition and is repeatedly executed
+// under a loop till this condition is not met.
+func (mc *ConfigMetacontroller) startAllDecoratorControllers() (bool, error) {
+	var errs []string
+	for _, conf := range mc.Configs {
+		if _, ok := mc.DecoratorControllers[conf.Name]; ok {
+			// Already added; perhaps during earlier condition
+			// checks
+			continue
+		}
+		c, err := newDecoratorController(
+			mc.ResourceManager,
+			mc.DynClientset,
+			mc.DynInformerFactory,
+			conf,
+			common.LocalHookScope,
+		)
+		if err != nil {
+			errs = append(
+				errs,
+				fmt.Sprintf("Failed to init dctl %s: %s", conf.Name, err.Error()),
+			)
+			// continue to initialise & start remaining controllers
+			continue
+		}
+		c.Start(mc.WorkerCount)
+		mc.DecoratorControllers[conf.Name] = c
+	}
+	if len(errs) != 0 {
+		return false, errors.Errorf(
+			"Failed to start all dctl controllers: %d errors found: %s: %s",
+			len(errs),
+			strings.Join(errs, ": "),
+			mc,
+		)
+	}
+	return true, nil
+}
+
+// Stop stops this MetaController
+func (mc *ConfigMetacontroller) Stop() {
+	glog.Infof("Shutting down %s", mc)
+
+	// Stop metacontroller first so there's no more changes
+	// to decorator controllers.
+	<-mc.doneCh
+
+	// Stop all its decorator controllers
+	var wg sync.WaitGroup
+	for _, c := range mc.DecoratorControllers {
+		wg.Add(1)
+		go func(c *decoratorController) {
+			defer wg.Done()
+			c.Stop()
+		}(c)
+	}
+	// wait till all decorator controllers are stopped
+	wg.Wait()
+}
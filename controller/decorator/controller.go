@@ -37,11 +37,13 @@ import (
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/customize"
 	"openebs.io/metac/controller/common/finalizer"
 	dynamicclientset "openebs.io/metac/dynamic/clientset"
 	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
 	dynamicinformer "openebs.io/metac/dynamic/informer"
 	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/metrics"
 	k8s "openebs.io/metac/third_party/kubernetes"
 )
 
@@ -77,7 +79,7 @@ type decoratorController struct {
 	queue workqueue.RateLimitingInterface
 
 	// the strategy to follow during reconcile
-	updateStrategy updateStrategyMap
+	updateStrategy attachmentUpdateStrategies
 
 	// informers are needed to capture the changes against
 	// the watched resource i.e. parent & to list the attachments
@@ -89,6 +91,13 @@ type decoratorController struct {
 	// instance that deals with this controller's finalizer
 	// if any
 	finalizer *finalizer.Finalizer
+
+	// fetches the resources related to a parent if customize
+	// hook is set
+	customize *customize.Manager
+
+	// decides what the hooks of this controller are allowed to do
+	hookScope common.HookScope
 }
 
 // newDecoratorController returns a new instance of decorator
@@ -99,7 +108,22 @@ func newDecoratorController(
 	dynCliSet *dynamicclientset.Clientset,
 	informerFactory *dynamicinformer.SharedInformerFactory,
 	schema *v1alpha1.DecoratorController,
+	hookScope common.HookScope,
 ) (controller *decoratorController, newErr error) {
+	// reject the hooks that are not allowed in this scope
+	if schema.Spec.Hooks != nil {
+		err := common.ValidateHooks(
+			hookScope,
+			schema.Spec.Hooks.Sync,
+			schema.Spec.Hooks.Finalize,
+			schema.Spec.Hooks.Customize,
+		)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "Invalid hooks: DecoratorController %s", schema.Name,
+			)
+		}
+	}
 
 	c := &decoratorController{
 		schema:          schema,
@@ -112,7 +136,10 @@ func newDecoratorController(
 
 		queue: workqueue.NewNamedRateLimitingQueue(
 			workqueue.DefaultControllerRateLimiter(),
-			"DecoratorController-"+schema.Name,
+			metrics.QueueName(metrics.Controller{
+				Kind: metrics.KindDecoratorController,
+				Name: schema.Name,
+			}),
 		),
 
 		finalizer: &finalizer.Finalizer{
@@ -121,6 +148,8 @@ func newDecoratorController(
 			// gets enabled if Finalize property is set
 			Enabled: schema.Spec.Hooks.Finalize != nil,
 		},
+
+		hookScope: hookScope,
 	}
 
 	var err error
@@ -144,10 +173,14 @@ func newDecoratorController(
 	}
 
 	// Remember the update strategy for each child type.
-	c.updateStrategy, err = makeUpdateStrategyMap(resourceMgr, schema)
+	strategies, err := makeUpdateStrategyMap(resourceMgr, schema)
 	if err != nil {
 		return nil, err
 	}
+	c.updateStrategy = attachmentUpdateStrategies{
+		updateStrategyMap: strategies,
+		fieldManager:      common.MakeFieldManager("DecoratorController", schema.GetName()),
+	}
 
 	// close the successfully created informers for parent
 	// and child resources in-case of any errors during
@@ -187,9 +220,36 @@ func newDecoratorController(
 		c.childInformers.Set(child.APIVersion, child.Resource, informer)
 	}
 
+	if schema.Spec.Hooks != nil && schema.Spec.Hooks.Customize != nil {
+		if schema.Spec.Hooks.Customize.Inline != nil {
+			return nil, errors.Errorf(
+				"DecoratorController %s: inline customize hook is not supported",
+				schema.Name,
+			)
+		}
+		// related resources are fetched via informers that
+		// are created on demand
+		c.customize = customize.NewManager(
+			"DecoratorController "+schema.Name,
+			hookScope,
+			c.callCustomizeHook,
+			dynCliSet,
+			informerFactory,
+			c.enqueueParentObject,
+		)
+	}
+
 	return c, nil
 }
 
+// metricsController returns this controller as a metrics label
+func (c *decoratorController) metricsController() metrics.Controller {
+	return metrics.Controller{
+		Kind: metrics.KindDecoratorController,
+		Name: c.schema.Name,
+	}
+}
+
 // Start starts the decorator controller based on its fields
 // that were initialised earlier (mostly via its constructor)
 func (c *decoratorController) Start(workerCount int) {
@@ -203,7 +263,7 @@ func (c *decoratorController) Start(workerCount int) {
 	parentHandlers := cache.ResourceEventHandlerFuncs{
 		AddFunc:    c.enqueueParentObject,
 		UpdateFunc: c.updateParentObject,
-		DeleteFunc: c.enqueueParentObject,
+		DeleteFunc: c.deleteParentObject,
 	}
 	var resyncPeriod time.Duration
 	if c.schema.Spec.ResyncPeriodSeconds != nil {
@@ -291,6 +351,10 @@ func (c *decoratorController) Stop() {
 	// stopped via above close(c.stopCh) invocation
 	<-c.doneCh
 
+	// Release the informers of related resources if any
+	if c.customize != nil {
+		c.customize.Stop()
+	}
 	// Remove event handlers and close informers for all child resources.
 	for _, informer := range c.childInformers {
 		informer.Informer().RemoveEventHandlers()
@@ -329,12 +393,15 @@ func (c *decoratorController) processNextWorkItem() bool {
 	defer c.queue.Done(key)
 
 	// real reconcile logic happens here
+	start := time.Now()
 	err := c.sync(key.(string))
+	metrics.RecordSync(c.metricsController(), start, err)
 	if err != nil {
 		utilruntime.HandleError(
 			errors.Errorf("failed to sync %v %q: %v", c.schema.Name, key, err),
 		)
-		c.queue.AddRateLimited(key)
+		// structured hook errors may defer or skip the requeue
+		common.RequeueAfterSyncError(c.queue, key, err)
 		return true
 	}
 
@@ -377,8 +444,23 @@ func (c *decoratorController) enqueueParentObjectAfter(obj interface{}, delay ti
 	c.queue.AddAfter(key, delay)
 }
 
+// deleteParentObject enqueues the deleted parent after
+// forgetting its related resources
+func (c *decoratorController) deleteParentObject(obj interface{}) {
+	if c.customize != nil {
+		c.customize.ForgetParent(obj)
+	}
+	c.enqueueParentObject(obj)
+}
+
 func (c *decoratorController) updateParentObject(old, cur interface{}) {
 	// TODO(enisoc): Is there any way to avoid resyncing after our own updates?
+	//
+	// Hook error condition set by this controller is ignored since a
+	// failed hook is retried as per its error.
+	if common.IsHookErrorConditionUpdate(old, cur) {
+		return
+	}
 	c.enqueueParentObject(cur)
 }
 
@@ -613,14 +695,41 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 		return err
 	}
 
+	// List the resources related to this parent if any
+	var relatedObjects common.AnyUnstructRegistry
+	if c.customize != nil {
+		relatedObjects, err = c.customize.GetRelatedObjects(parent)
+		if err != nil {
+			return errors.Wrapf(
+				err,
+				"DecoratorController %s: can't get related objects for %s/%s",
+				c.schema.Name,
+				parent.GetNamespace(),
+				parent.GetName(),
+			)
+		}
+	}
+
 	// Call the sync hook to get the desired annotations and children.
 	syncRequest := &SyncHookRequest{
 		Controller:  c.schema,
 		Object:      parent,
 		Attachments: observedChildren,
+		Related:     relatedObjects,
 	}
 	syncResult, err := c.callSyncHook(syncRequest)
 	if err != nil {
+		// report structured hook errors in parent's status
+		if condErr := common.UpdateHookErrorCondition(parentClient, parent, err); condErr != nil {
+			glog.Errorf(
+				"DecoratorController %v: can't set hook error condition for %v %v/%v: %v",
+				c.schema.Name,
+				parent.GetKind(),
+				parent.GetNamespace(),
+				parent.GetName(),
+				condErr,
+			)
+		}
 		return err
 	}
 	desiredChildren :=
@@ -650,6 +759,8 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 		// A null .status in the sync response means leave it unchanged.
 		syncResult.Status = parentStatus
 	}
+	// Hook succeeded; hence resolve any earlier hook error.
+	syncResult.Status = common.ResolveHookErrorCondition(syncResult.Status)
 
 	labelsChanged := updateStringMap(parentLabels, syncResult.Labels)
 	annotationsChanged := updateStringMap(parentAnnotations, syncResult.Annotations)
@@ -714,7 +825,7 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 	if parent.GetDeletionTimestamp() == nil || c.finalizer.ShouldFinalize(parent) {
 		// Reconcile children.
 		err := common.ManageChildren(
-			c.dynCliSet, c.updateStrategy, parent, observedChildren, desiredChildren,
+			c.dynCliSet, c.updateStrategy, nil, parent, observedChildren, desiredChildren,
 		)
 		if err != nil {
 			manageErr = errors.Wrapf(
@@ -812,6 +923,26 @@ func (m updateStrategyMap) get(apiGroup, kind string) *v1alpha1.DecoratorControl
 	return m[updateStrategyMapKey(apiGroup, kind)]
 }
 
+// attachmentUpdateStrategies holds the update strategies of
+// attachments along with the field manager used to server side
+// apply them
+type attachmentUpdateStrategies struct {
+	updateStrategyMap
+
+	// fieldManager is used if a strategy does not set one
+	fieldManager string
+}
+
+// GetServerSideApplyOptions returns the server side apply options
+// based on the given api group & kind
+func (s attachmentUpdateStrategies) GetServerSideApplyOptions(apiGroup, kind string) common.ServerSideApplyOptions {
+	var opts *v1alpha1.ServerSideApplyOptions
+	if strategy := s.get(apiGroup, kind); strategy != nil {
+		opts = strategy.ServerSideApply
+	}
+	return common.MakeServerSideApplyOptions(opts, s.fieldManager)
+}
+
 func updateStrategyMapKey(apiGroup, kind string) string {
 	return fmt.Sprintf("%s.%s", kind, apiGroup)
 }
@@ -17,11 +17,14 @@ limitations under the License.
 package decorator
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/customize"
 )
 
 // SyncHookRequest is the object sent as JSON to the sync hook.
@@ -29,9 +32,17 @@ type SyncHookRequest struct {
 	Controller  *v1alpha1.DecoratorController `json:"controller"`
 	Object      *unstructured.Unstructured    `json:"object"`
 	Attachments common.AnyUnstructRegistry    `json:"attachments"`
+	Related     common.AnyUnstructRegistry    `json:"related,omitempty"`
 	Finalizing  bool                          `json:"finalizing"`
 }
 
+// CustomizeHookRequest is the object sent as JSON to the
+// customize hook
+type CustomizeHookRequest struct {
+	Controller *v1alpha1.DecoratorController `json:"controller"`
+	Object     *unstructured.Unstructured    `json:"object"`
+}
+
 // SyncHookResponse is the expected format of the JSON response from the sync hook.
 type SyncHookResponse struct {
 	Labels      map[string]*string           `json:"labels"`
@@ -67,7 +78,11 @@ func (c *decoratorController) callSyncHook(
 			!c.parentSelector.Matches(request.Object)) {
 		// Finalize
 		request.Finalizing = true
-		err := common.InvokeHook(c.schema.Spec.Hooks.Finalize, request, &response)
+		err := c.invokeHook(
+			c.schema.Spec.Hooks.Finalize,
+			request,
+			&response,
+		)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Finalize hook failed")
 		}
@@ -78,7 +93,11 @@ func (c *decoratorController) callSyncHook(
 			return nil, errors.Errorf("Sync hook not defined")
 		}
 
-		err := common.InvokeHook(c.schema.Spec.Hooks.Sync, request, &response)
+		err := c.invokeHook(
+			c.schema.Spec.Hooks.Sync,
+			request,
+			&response,
+		)
 		if err != nil {
 			return nil, errors.Wrapf(err, "Sync hook failed")
 		}
@@ -86,3 +105,61 @@ func (c *decoratorController) callSyncHook(
 
 	return &response, nil
 }
+
+// invokeHook invokes the provided sync or finalize hook. Inline
+// hooks are looked up from DecoratorController's inline hook
+// registry.
+func (c *decoratorController) invokeHook(
+	schema *v1alpha1.Hook,
+	request *SyncHookRequest,
+	response *SyncHookResponse,
+) (err error) {
+	if schema.Inline == nil || schema.Inline.FuncName == nil {
+		// this is one of the commonly supported hooks
+		return common.InvokeHookWithMetrics(
+			c.metricsController(),
+			c.hookScope,
+			schema,
+			request,
+			response,
+		)
+	}
+	start := time.Now()
+	defer func() {
+		common.RecordHook(
+			c.metricsController(),
+			schema,
+			start,
+			request,
+			response,
+			err,
+		)
+	}()
+	ihi, err := NewInlineHookInvoker(*schema.Inline.FuncName)
+	if err != nil {
+		return err
+	}
+	return ihi.Invoke(request, response)
+}
+
+// callCustomizeHook invokes the customize hook to get the
+// resources related to the provided object
+func (c *decoratorController) callCustomizeHook(
+	obj *unstructured.Unstructured,
+) (*customize.HookResponse, error) {
+	var response customize.HookResponse
+	err := common.InvokeHookWithMetrics(
+		c.metricsController(),
+		c.hookScope,
+		c.schema.Spec.Hooks.Customize,
+		&CustomizeHookRequest{
+			Controller: c.schema,
+			Object:     obj,
+		},
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
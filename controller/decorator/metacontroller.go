@@ -180,7 +180,7 @@ func (mc *Metacontroller) syncDecoratorController(dc *v1alpha1.DecoratorControll
 		delete(mc.decoratorControllers, dc.Name)
 	}
 
-	c, err := newDecoratorController(mc.resourceManager, mc.clientset, mc.informerFactory, dc)
+	c, err := newDecoratorController(mc.resourceManager, mc.clientset, mc.informerFactory, dc, common.HookScope{})
 	if err != nil {
 		return err
 	}
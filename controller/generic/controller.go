@@ -37,14 +37,21 @@ import (
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/customize"
 	"openebs.io/metac/controller/common/finalizer"
 	dynamicclientset "openebs.io/metac/dynamic/clientset"
 	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
 	dynamicinformer "openebs.io/metac/dynamic/informer"
 	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/metrics"
 	k8s "openebs.io/metac/third_party/kubernetes"
 )
 
+// applyWaitRequeueDelay is the delay after which a watch is synced
+// again if some of its attachments could not be applied since they
+// wait on attachments of earlier apply phases
+const applyWaitRequeueDelay = 5 * time.Second
+
 // WatchController reconciles the watch specified in GenericController
 // custom resource
 type WatchController struct {
@@ -83,9 +90,27 @@ type WatchController struct {
 	watchInformers      common.ResourceInformerRegistrar
 	attachmentInformers common.ResourceInformerRegistrar
 
+	// indexes used to lookup the attachments of a watch
+	// instead of listing all the attachments
+	attachmentIndexes attachmentIndexRegistrar
+
 	// instance that deals with this controller's finalizer
 	// if any
 	finalizer *finalizer.Finalizer
+
+	// records the runtime state of this controller that
+	// gets reported as GenericController status
+	status *watchStatusRecorder
+
+	// fetches the resources related to a watch if customize
+	// hook is set
+	customize *customize.Manager
+
+	// caches sync hook responses if enabled
+	syncCache *syncHookCache
+
+	// decides what the hooks of this controller are allowed to do
+	hookScope common.HookScope
 }
 
 // String implements Stringer interface
@@ -102,12 +127,14 @@ func (mgr *WatchController) String() string {
 
 // NewWatchController returns a new instance of watch controller
 // with required watch & child informers, selectors, update
-// strategy & so on.
+// strategy & so on. Hooks of this controller are restricted to
+// the provided scope.
 func NewWatchController(
 	dynDiscovery *dynamicdiscovery.APIResourceDiscovery,
 	dynClientset *dynamicclientset.Clientset,
 	dynInformerFactory *dynamicinformer.SharedInformerFactory,
 	config *v1alpha1.GenericController,
+	hookScope common.HookScope,
 ) (wCtl *WatchController, newErr error) {
 
 	ctl := &WatchController{
@@ -119,10 +146,11 @@ func NewWatchController(
 
 		watchInformers:      make(common.ResourceInformerRegistrar),
 		attachmentInformers: make(common.ResourceInformerRegistrar),
+		attachmentIndexes:   make(attachmentIndexRegistrar),
 
 		watchQ: workqueue.NewNamedRateLimitingQueue(
 			workqueue.DefaultControllerRateLimiter(),
-			"WatchGCtl-"+config.Namespace+"-"+config.Name,
+			metrics.QueueName(metricsControllerOf(config)),
 		),
 
 		finalizer: &finalizer.Finalizer{
@@ -136,9 +164,24 @@ func NewWatchController(
 			// Enable if Finalize field is set in the generic controller
 			Enabled: config.Spec.Hooks.Finalize != nil,
 		},
+
+		status: newWatchStatusRecorder(),
+
+		syncCache: newSyncHookCache(config.Spec.SyncHookCache),
+
+		hookScope: hookScope,
 	}
 
-	var err error
+	// reject the hooks that are not allowed in this scope
+	err := common.ValidateHooks(
+		hookScope,
+		config.Spec.Hooks.Sync,
+		config.Spec.Hooks.Finalize,
+		config.Spec.Hooks.Customize,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid hooks: %s", ctl)
+	}
 
 	// build watch & attachment selectors
 	ctl.watchSelector, ctl.attachmentSelector, err = makeAllSelectors(
@@ -192,10 +235,21 @@ func NewWatchController(
 			}
 		}
 	}()
+	// informers are restricted to the watch namespaces if any
+	globalNamespaces := getWatchNamespaces()
 	// init watch informer
-	informer, err := dynInformerFactory.GetOrCreate(
+	watchScope, err := makeInformerScope(
+		globalNamespaces,
+		config.Spec.InformerScope,
+		config.Spec.Watch.InformerScope,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid watch: %s", ctl)
+	}
+	informer, err := dynInformerFactory.GetOrCreateWithScope(
 		config.Spec.Watch.APIVersion,
 		config.Spec.Watch.Resource,
+		watchScope,
 	)
 	if err != nil {
 		return nil,
@@ -219,10 +273,46 @@ func NewWatchController(
 		informer,
 	)
 	// initialise the informers for attachments
+	//
+	// NOTE:
+	//	Attachments of the same resource share a single informer.
+	// Hence they should not differ in their informer scopes.
+	attachmentScopes := map[string]string{}
 	for _, a := range config.Spec.Attachments {
-		informer, err := dynInformerFactory.GetOrCreate(
+		scope, err := makeInformerScope(
+			globalNamespaces,
+			config.Spec.InformerScope,
+			a.InformerScope,
+		)
+		if err != nil {
+			return nil,
+				errors.Wrapf(
+					err,
+					"Invalid attachment %q with version %q: %s",
+					a.Resource,
+					a.APIVersion,
+					ctl,
+				)
+		}
+		resourceKey := a.APIVersion + "/" + a.Resource
+		if existing, found := attachmentScopes[resourceKey]; found {
+			if existing != scope.String() {
+				return nil,
+					errors.Errorf(
+						"Invalid attachment %q with version %q: Conflicting informer scopes: %s",
+						a.Resource,
+						a.APIVersion,
+						ctl,
+					)
+			}
+			// informer is already registered for this resource
+			continue
+		}
+		attachmentScopes[resourceKey] = scope.String()
+		informer, err := dynInformerFactory.GetOrCreateWithScope(
 			a.APIVersion,
 			a.Resource,
+			scope,
 		)
 		if err != nil {
 			return nil,
@@ -240,10 +330,72 @@ func NewWatchController(
 			a.Resource,
 			informer,
 		)
+		// attachments are looked up from indexes if every select
+		// term has an equality requirement against the watch
+		attachmentAPI := dynDiscovery.GetAPIForAPIVersionAndResource(
+			a.APIVersion,
+			a.Resource,
+		)
+		if attachmentAPI == nil {
+			continue
+		}
+		indexes, ok, err := makeAttachmentIndexes(
+			informer,
+			ctl.attachmentSelector.advancedSelectorReg.Get(
+				a.APIVersion,
+				attachmentAPI.Kind,
+			).Terms,
+		)
+		if err != nil {
+			return nil,
+				errors.Wrapf(
+					err,
+					"Can't index attachment %q with version %q: %s",
+					a.Resource,
+					a.APIVersion,
+					ctl,
+				)
+		}
+		if ok {
+			ctl.attachmentIndexes[makeAttachmentIndexKey(a.APIVersion, a.Resource)] =
+				indexes
+		}
+	}
+	if config.Spec.Hooks != nil && config.Spec.Hooks.Customize != nil {
+		if config.Spec.Hooks.Customize.Inline != nil {
+			return nil, errors.Errorf(
+				"Invalid customize hook: Inline hook is not supported: %s",
+				ctl,
+			)
+		}
+		// related resources are fetched via informers that
+		// are created on demand
+		ctl.customize = customize.NewManager(
+			ctl.String(),
+			hookScope,
+			ctl.callCustomizeHook,
+			dynClientset,
+			dynInformerFactory,
+			ctl.enqueueWatch,
+		)
 	}
 	return ctl, nil
 }
 
+// SetStatusChangeHandler sets the function that gets invoked
+// whenever the runtime state of this controller changes
+//
+// NOTE:
+//	This should be set before starting this controller
+func (mgr *WatchController) SetStatusChangeHandler(fn func()) {
+	mgr.status.onChange = fn
+}
+
+// GetStatus returns the current state of this controller
+func (mgr *WatchController) GetStatus() v1alpha1.GenericControllerStatus {
+	return mgr.status.GetStatus()
+}
+
 // Start starts the generic controller based on its fields
 // that were initialised earlier (mostly via its constructor)
 func (mgr *WatchController) Start(workerCount int) {
@@ -257,7 +409,7 @@ func (mgr *WatchController) Start(workerCount int) {
 	watchHandlers := cache.ResourceEventHandlerFuncs{
 		AddFunc:    mgr.enqueueWatch,
 		UpdateFunc: mgr.updateWatch,
-		DeleteFunc: mgr.enqueueWatch,
+		DeleteFunc: mgr.deleteWatch,
 	}
 	var resyncPeriod time.Duration
 	if mgr.GCtlConfig.Spec.ResyncPeriodSeconds != nil {
@@ -280,6 +432,18 @@ func (mgr *WatchController) Start(workerCount int) {
 			informer.Informer().AddEventHandler(watchHandlers)
 		}
 	}
+	// attachment events are mapped back to their watch(es) so that
+	// any drift in attachments gets corrected without waiting for
+	// the next resync of watch
+	for _, informer := range mgr.attachmentInformers {
+		informer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc:    mgr.onAttachmentAdd,
+				UpdateFunc: mgr.onAttachmentUpdate,
+				DeleteFunc: mgr.onAttachmentDelete,
+			},
+		)
+	}
 	if workerCount <= 0 {
 		// set a reasonable worker count value
 		workerCount = 5
@@ -315,6 +479,7 @@ func (mgr *WatchController) Start(workerCount int) {
 			glog.Warningf("Cache sync never finished: %s", mgr)
 			return
 		}
+		mgr.status.SetInformersSynced(true)
 		glog.V(5).Infof("Starting %d workers: %s", workerCount, mgr)
 		var wg sync.WaitGroup
 		for i := 0; i < workerCount; i++ {
@@ -343,6 +508,10 @@ func (mgr *WatchController) Stop() {
 	// stopped via above close(c.stopCh) invocation
 	<-mgr.doneCh
 
+	// Release the informers of related resources if any
+	if mgr.customize != nil {
+		mgr.customize.Stop()
+	}
 	// Remove event handlers and close informers for all attachment
 	// resources.
 	for _, attInformer := range mgr.attachmentInformers {
@@ -393,7 +562,8 @@ func (mgr *WatchController) processNextWorkItem() bool {
 				mgr,
 			),
 		)
-		mgr.watchQ.AddRateLimited(key)
+		// structured hook errors may defer or skip the requeue
+		common.RequeueAfterSyncError(mgr.watchQ, key, err)
 		return true
 	}
 	// reconcile was successful
@@ -473,11 +643,204 @@ func (mgr *WatchController) enqueueWatchAfter(obj interface{}, delay time.Durati
 	mgr.watchQ.AddAfter(key, delay)
 }
 
-// updateWatch enqueues the watch object without any checks
+// updateWatch enqueues the updated watch object unless the update
+// only sets the hook error condition
+//
+// NOTE:
+//	Hook error condition is set by this controller after a failed
+// hook. A failed hook is retried as per its error & not due to
+// this update.
 func (mgr *WatchController) updateWatch(old, cur interface{}) {
+	if common.IsHookErrorConditionUpdate(old, cur) {
+		glog.V(6).Infof(
+			"Will not enqueue watch: Hook error condition update: %s",
+			mgr,
+		)
+		return
+	}
 	mgr.enqueueWatch(cur)
 }
 
+// deleteWatch enqueues the deleted watch object after
+// forgetting its related resources & cached sync response
+func (mgr *WatchController) deleteWatch(obj interface{}) {
+	if mgr.customize != nil {
+		mgr.customize.ForgetParent(obj)
+	}
+	if mgr.syncCache != nil {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		if watch, ok := obj.(*unstructured.Unstructured); ok {
+			mgr.syncCache.forget(watch.GetUID())
+		}
+	}
+	mgr.enqueueWatch(obj)
+}
+
+// onAttachmentAdd enqueues the watch(es) related to the added
+// attachment
+func (mgr *WatchController) onAttachmentAdd(obj interface{}) {
+	attachment, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		utilruntime.HandleError(
+			errors.Errorf(
+				"Can't handle attachment add: Want *unstructured.Unstructured got %T: %s",
+				obj,
+				mgr,
+			),
+		)
+		return
+	}
+	mgr.enqueueWatchesForAttachments(attachment)
+}
+
+// onAttachmentUpdate enqueues the watch(es) related to the updated
+// attachment
+func (mgr *WatchController) onAttachmentUpdate(old, cur interface{}) {
+	oldAttachment, ok := old.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	curAttachment, ok := cur.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	// Don't sync if it's a no-op update (probably a relist/resync).
+	// We don't care about resyncs for attachments; we rely on the
+	// watch resync.
+	if oldAttachment.GetResourceVersion() == curAttachment.GetResourceVersion() {
+		return
+	}
+	// Old attachment is evaluated as well since the watch(es) that
+	// used to select this attachment need to be reconciled as well
+	mgr.enqueueWatchesForAttachments(oldAttachment, curAttachment)
+}
+
+// onAttachmentDelete enqueues the watch(es) related to the deleted
+// attachment
+func (mgr *WatchController) onAttachmentDelete(obj interface{}) {
+	attachment, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(
+				errors.Errorf(
+					"Can't get attachment from tombstone %+v: %s",
+					obj,
+					mgr,
+				),
+			)
+			return
+		}
+		attachment, ok = tombstone.Obj.(*unstructured.Unstructured)
+		if !ok {
+			utilruntime.HandleError(
+				errors.Errorf(
+					"Tombstone is not *unstructured.Unstructured %#v: %s",
+					obj,
+					mgr,
+				),
+			)
+			return
+		}
+	}
+	mgr.enqueueWatchesForAttachments(attachment)
+}
+
+// enqueueWatchesForAttachments enqueues all the watches that are
+// related to any of the provided attachments
+func (mgr *WatchController) enqueueWatchesForAttachments(
+	attachments ...*unstructured.Unstructured,
+) {
+	for _, informer := range mgr.watchInformers {
+		watches, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			utilruntime.HandleError(
+				errors.Wrapf(
+					err,
+					"Can't list watches to map attachments: %s",
+					mgr,
+				),
+			)
+			continue
+		}
+		for _, watch := range mgr.filterWatchesForAttachments(watches, attachments...) {
+			glog.V(6).Infof(
+				"Will enqueue watch %s due to attachment event: %s",
+				common.DescObjectAsKey(watch),
+				mgr,
+			)
+			mgr.enqueueWatch(watch)
+		}
+	}
+}
+
+// filterWatchesForAttachments returns the watches that are related
+// to any of the provided attachments. A watch is related to an
+// attachment if either of the following is true:
+//
+//	1. attachment was created due to this watch,
+//	2. attachment has this watch as one of its owner references,
+//	3. attachment matches the attachment selectors against this watch
+func (mgr *WatchController) filterWatchesForAttachments(
+	watches []*unstructured.Unstructured,
+	attachments ...*unstructured.Unstructured,
+) []*unstructured.Unstructured {
+	var related []*unstructured.Unstructured
+	for _, watch := range watches {
+		for _, attachment := range attachments {
+			if mgr.isWatchRelatedToAttachment(watch, attachment) {
+				related = append(related, watch)
+				// no need to evaluate remaining attachments
+				// since this watch is already selected
+				break
+			}
+		}
+	}
+	return related
+}
+
+// isWatchRelatedToAttachment returns true if the provided attachment
+// is related to the provided watch
+func (mgr *WatchController) isWatchRelatedToAttachment(
+	watch *unstructured.Unstructured,
+	attachment *unstructured.Unstructured,
+) bool {
+	watchUID := watch.GetUID()
+	if watchUID != "" {
+		// check if attachment was created due to this watch
+		if attachment.GetAnnotations()[common.AttachmentCreateAnnotationKey] ==
+			string(watchUID) {
+			return true
+		}
+		// check if this watch is one of the owners of attachment
+		for _, ownerRef := range attachment.GetOwnerReferences() {
+			if ownerRef.UID == watchUID {
+				return true
+			}
+		}
+	}
+	if mgr.attachmentSelector == nil {
+		return false
+	}
+	isMatch, err := mgr.attachmentSelector.MatchAttachmentAgainstWatch(
+		attachment,
+		watch,
+	)
+	if err != nil {
+		glog.V(5).Infof(
+			"Can't match attachment %s against watch %s: %s: %+v",
+			common.DescObjectAsKey(attachment),
+			common.DescObjectAsKey(watch),
+			mgr,
+			err,
+		)
+		return false
+	}
+	return isMatch
+}
+
 // syncWatch reconciles the watch resource represented by this provided
 // key
 //
@@ -487,9 +850,12 @@ func (mgr *WatchController) updateWatch(old, cur interface{}) {
 //
 // TODO (@amitkumardas):
 // - Unit Tests
-func (mgr *WatchController) syncWatch(key string) error {
-	var err error
+func (mgr *WatchController) syncWatch(key string) (err error) {
+	start := time.Now()
 	defer func() {
+		metrics.RecordSync(metricsControllerOf(mgr.GCtlConfig), start, err)
+		// record the sync result to be reported as status
+		mgr.status.RecordWatchSync(key, err)
 		if err != nil {
 			glog.Warningf("Can't sync: %s", err.Error())
 			return
@@ -642,16 +1008,34 @@ func (mgr *WatchController) syncWatchObj(watch *unstructured.Unstructured) error
 	if err != nil {
 		return err
 	}
+	// List the resources related to this watch if any
+	var relatedObjects common.AnyUnstructRegistry
+	if mgr.customize != nil {
+		relatedObjects, err = mgr.customize.GetRelatedObjects(watch)
+		if err != nil {
+			return withWatchSyncReason(WatchSyncReasonHookFailed, err)
+		}
+	}
 	// Call the sync hook since we have the watch as well as
 	// required attachments
 	syncRequest := &SyncHookRequest{
 		Controller:  mgr.GCtlConfig,
 		Watch:       watch,
 		Attachments: observedAttachments,
+		Related:     relatedObjects,
 	}
 	syncResponse, err := mgr.callSyncHook(syncRequest)
 	if err != nil {
-		return err
+		// report structured hook errors in watch's status
+		if condErr := common.UpdateHookErrorCondition(watchClient, watch, err); condErr != nil {
+			glog.Errorf(
+				"Can't set hook error condition for watch %s: %s: %+v",
+				common.DescObjectAsKey(watch),
+				mgr,
+				condErr,
+			)
+		}
+		return withWatchSyncReason(WatchSyncReasonHookFailed, err)
 	}
 	if syncResponse == nil {
 		glog.V(6).Infof(
@@ -711,6 +1095,15 @@ func (mgr *WatchController) syncWatchObj(watch *unstructured.Unstructured) error
 		// i.e. use the existing status
 		syncResponse.Status = finalWatchStatus
 	}
+	// hook succeeded; hence resolve any earlier hook error
+	syncResponse.Status = common.ResolveHookErrorCondition(syncResponse.Status)
+	if mgr.updateStrategies.isAnyRolling() {
+		// rollout progress is recorded after attachments are applied
+		syncResponse.Status = common.RetainRolloutCondition(
+			finalWatchStatus,
+			syncResponse.Status,
+		)
+	}
 	glog.V(6).Infof(
 		"Desired labels=[%v], annotations=[%v], status=[%v]: Watch %s: %s",
 		syncResponse.Labels,
@@ -871,6 +1264,11 @@ func (mgr *WatchController) syncWatchObj(watch *unstructured.Unstructured) error
 	updateStrategyMgr, err := newAttachmentUpdateStrategyManager(
 		mgr.DynamicDiscovery,
 		mgr.GCtlConfig.Spec.Attachments,
+		common.MakeFieldManager(
+			"GenericController",
+			mgr.GCtlConfig.GetNamespace(),
+			mgr.GCtlConfig.GetName(),
+		),
 	)
 	if err != nil {
 		return err
@@ -881,6 +1279,8 @@ func (mgr *WatchController) syncWatchObj(watch *unstructured.Unstructured) error
 		desiredAttachments,
 		mgr,
 	)
+	// progress of rolling updates if any
+	var rollouts []common.RolloutStatus
 	// Reconcile attachments via attachment manager
 	clusterStatesCtrl := &common.ClusterStatesController{
 		ClusterStatesControllerBase: common.ClusterStatesControllerBase{
@@ -897,7 +1297,13 @@ func (mgr *WatchController) syncWatchObj(watch *unstructured.Unstructured) error
 			// This is currently set to true if this request is being
 			// processed by finalize hook. In other words, this is set
 			// to true during finalize hook invocation.
-			UpdateDuringPendingDelete: k8s.BoolPtr(syncRequest.Finalizing),
+			UpdateDuringPendingDelete:     k8s.BoolPtr(syncRequest.Finalizing),
+			MetricsController:             metricsControllerOf(mgr.GCtlConfig),
+			GetRollingUpdateStrategyByGK:  updateStrategyMgr.GetRollingStrategyByGK,
+			GetServerSideApplyOptionsByGK: updateStrategyMgr.GetServerSideApplyOptionsByGK,
+			RecordRollout: func(rollout common.RolloutStatus) {
+				rollouts = append(rollouts, rollout)
+			},
 		},
 		DynamicClientSet: mgr.DynamicClientSet,
 		Observed:         observedAttachments,
@@ -905,7 +1311,32 @@ func (mgr *WatchController) syncWatchObj(watch *unstructured.Unstructured) error
 		ExplicitUpdates:  explicitUpdates,
 		ExplicitDeletes:  explicitDeletes,
 	}
-	return clusterStatesCtrl.Apply()
+	applyErr := clusterStatesCtrl.Apply()
+	if waiting := clusterStatesCtrl.Waiting(); waiting != "" {
+		// remaining attachments are applied in a later sync
+		glog.V(4).Infof(
+			"Will requeue after %s: %s: Watch %s: %s",
+			applyWaitRequeueDelay,
+			waiting,
+			common.DescObjectAsKey(watch),
+			mgr,
+		)
+		mgr.enqueueWatchAfter(watch, applyWaitRequeueDelay)
+	}
+	// record the progress of rolling updates if any in the watch
+	err = common.UpdateRolloutCondition(watchClient, watch, rollouts)
+	if err != nil {
+		glog.Errorf(
+			"Failed to update rollout condition: Watch %s: %s: %v",
+			common.DescObjectAsKey(watch),
+			mgr,
+			err,
+		)
+	}
+	return withWatchSyncReason(
+		WatchSyncReasonApplyFailed,
+		applyErr,
+	)
 }
 
 // isReconcileAttachments returns true if controller should
@@ -964,11 +1395,12 @@ func (mgr *WatchController) getObservedAttachments(
 					mgr,
 				)
 		}
-		var attachmentObjs []*unstructured.Unstructured
-		var err error
-		// all possible attachment object for the given attachment kind
-		attachmentObjs, err =
-			attachmentInformer.Lister().List(labels.Everything())
+		// attachment objects that may match this watch
+		attachmentObjs, err := mgr.listAttachmentCandidates(
+			attachmentKind,
+			attachmentInformer,
+			watch,
+		)
 		if err != nil {
 			return nil, errors.Wrapf(
 				err,
@@ -1087,6 +1519,7 @@ func (mgr *WatchController) callSyncHook(
 		request.Finalizing = true
 		hi := &HookInvoker{
 			Schema: mgr.GCtlConfig.Spec.Hooks.Finalize,
+			Scope:  mgr.hookScope,
 		}
 		err := hi.Invoke(request, &response)
 		if err != nil {
@@ -1114,18 +1547,63 @@ func (mgr *WatchController) callSyncHook(
 		)
 		// set finalizing to false since this is sync hook invocation
 		request.Finalizing = false
-		hi := &HookInvoker{
-			Schema: mgr.GCtlConfig.Spec.Hooks.Sync,
-		}
-		err := hi.Invoke(request, &response)
+		return mgr.callSyncHookWithCache(request)
+	}
+	return &response, nil
+}
+
+// callSyncHookWithCache invokes the sync hook unless the cache
+// has a response for an unchanged request
+func (mgr *WatchController) callSyncHookWithCache(
+	request *SyncHookRequest,
+) (*SyncHookResponse, error) {
+	var hash string
+	if mgr.syncCache != nil {
+		var err error
+		hash, err = hashSyncHookRequest(request)
 		if err != nil {
-			return nil, errors.Wrapf(err, "Sync hook failed")
+			return nil, err
 		}
-		glog.V(7).Infof(
-			"Sync hook completed for watch %s: %s",
-			common.DescObjectAsKey(request.Watch),
-			mgr,
+		cached, hit := mgr.syncCache.get(request.Watch.GetUID(), hash)
+		metrics.RecordHookCache(
+			metricsControllerOf(mgr.GCtlConfig),
+			common.DescHook(mgr.GCtlConfig.Spec.Hooks.Sync),
+			hit,
 		)
+		if hit {
+			glog.V(7).Infof(
+				"Using cached sync response for watch %s: %s",
+				common.DescObjectAsKey(request.Watch),
+				mgr,
+			)
+			return cached, nil
+		}
+	}
+	var response SyncHookResponse
+	hi := &HookInvoker{
+		Schema: mgr.GCtlConfig.Spec.Hooks.Sync,
+		Scope:  mgr.hookScope,
+	}
+	err := hi.Invoke(request, &response)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Sync hook failed")
+	}
+	glog.V(7).Infof(
+		"Sync hook completed for watch %s: %s",
+		common.DescObjectAsKey(request.Watch),
+		mgr,
+	)
+	if mgr.syncCache != nil {
+		err = mgr.syncCache.set(request.Watch.GetUID(), hash, &response)
+		if err != nil {
+			// caching must not fail the sync
+			glog.V(4).Infof(
+				"Can't cache sync response for watch %s: %s: %v",
+				common.DescObjectAsKey(request.Watch),
+				mgr,
+				err,
+			)
+		}
 	}
 	return &response, nil
 }
@@ -1149,6 +1627,17 @@ func (m attachmentUpdateStrategies) GetOrDefault(
 	return strategy.Method
 }
 
+// isAnyRolling returns true if any of the attachments is
+// updated in a rolling manner
+func (m attachmentUpdateStrategies) isAnyRolling() bool {
+	for _, strategy := range m {
+		if common.IsRollingUpdate(strategy.Method) {
+			return true
+		}
+	}
+	return false
+}
+
 // get returns the attachment's upgrade strategy
 // based on the provided api group & kind
 func (m attachmentUpdateStrategies) get(
@@ -19,6 +19,15 @@ package generic
 import (
 	"testing"
 
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/workqueue"
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/hooks"
 	k8s "openebs.io/metac/third_party/kubernetes"
 )
 
@@ -144,3 +153,311 @@ func TestUpdateStringMap(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchControllerFilterWatchesForAttachments(t *testing.T) {
+	newWatch := func(name, uid string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "test.io/v1",
+				"kind":       "Watch",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": "default",
+					"uid":       uid,
+				},
+			},
+		}
+	}
+	var tests = map[string]struct {
+		attachmentSelector *v1alpha1.GenericControllerResource
+		watches            []*unstructured.Unstructured
+		attachment         *unstructured.Unstructured
+		expectWatches      []string
+	}{
+		"no watches": {
+			attachment: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name": "my-pod",
+					},
+				},
+			},
+		},
+		"attachment created due to watch": {
+			watches: []*unstructured.Unstructured{
+				newWatch("w1", "uid-1"),
+				newWatch("w2", "uid-2"),
+			},
+			attachment: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name": "my-pod",
+						"annotations": map[string]interface{}{
+							common.AttachmentCreateAnnotationKey: "uid-2",
+						},
+					},
+				},
+			},
+			expectWatches: []string{"w2"},
+		},
+		"attachment owned by watch": {
+			watches: []*unstructured.Unstructured{
+				newWatch("w1", "uid-1"),
+				newWatch("w2", "uid-2"),
+			},
+			attachment: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name": "my-pod",
+						"ownerReferences": []interface{}{
+							map[string]interface{}{
+								"apiVersion": "test.io/v1",
+								"kind":       "Watch",
+								"name":       "w1",
+								"uid":        "uid-1",
+							},
+						},
+					},
+				},
+			},
+			expectWatches: []string{"w1"},
+		},
+		"attachment not related to any watch": {
+			watches: []*unstructured.Unstructured{
+				newWatch("w1", "uid-1"),
+			},
+			attachment: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name": "my-pod",
+						"annotations": map[string]interface{}{
+							common.AttachmentCreateAnnotationKey: "uid-100",
+						},
+					},
+				},
+			},
+		},
+		"attachment matches selector": {
+			attachmentSelector: &v1alpha1.GenericControllerResource{
+				ResourceRule: v1alpha1.ResourceRule{
+					APIVersion: "v1",
+					Resource:   "pods",
+				},
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "metac",
+					},
+				},
+			},
+			watches: []*unstructured.Unstructured{
+				newWatch("w1", "uid-1"),
+				newWatch("w2", "uid-2"),
+			},
+			attachment: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name": "my-pod",
+						"labels": map[string]interface{}{
+							"app": "metac",
+						},
+					},
+				},
+			},
+			expectWatches: []string{"w1", "w2"},
+		},
+		"attachment does not match selector": {
+			attachmentSelector: &v1alpha1.GenericControllerResource{
+				ResourceRule: v1alpha1.ResourceRule{
+					APIVersion: "v1",
+					Resource:   "pods",
+				},
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "metac",
+					},
+				},
+			},
+			watches: []*unstructured.Unstructured{
+				newWatch("w1", "uid-1"),
+			},
+			attachment: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata": map[string]interface{}{
+						"name": "my-pod",
+						"labels": map[string]interface{}{
+							"app": "none",
+						},
+					},
+				},
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			mgr := &WatchController{}
+			if mock.attachmentSelector != nil {
+				discovery := &dynamicdiscovery.APIResourceDiscovery{
+					GetAPIForAPIVersionAndResourceFn: func(
+						apiVer, resource string,
+					) *dynamicdiscovery.APIResource {
+						return &dynamicdiscovery.APIResource{
+							APIVersion: "v1",
+							APIResource: metav1.APIResource{
+								Kind: "Pod",
+							},
+						}
+					},
+				}
+				sel, err := NewSelectorForAttachments(
+					discovery,
+					[]v1alpha1.GenericControllerAttachment{
+						v1alpha1.GenericControllerAttachment{
+							GenericControllerResource: *mock.attachmentSelector,
+						},
+					},
+				)
+				if err != nil {
+					t.Fatalf("Expected no error got [%+v]", err)
+				}
+				mgr.attachmentSelector = sel
+			}
+			got := mgr.filterWatchesForAttachments(mock.watches, mock.attachment)
+			if len(got) != len(mock.expectWatches) {
+				t.Fatalf(
+					"Expected watch count %d got %d",
+					len(mock.expectWatches),
+					len(got),
+				)
+			}
+			for i, w := range got {
+				if w.GetName() != mock.expectWatches[i] {
+					t.Fatalf(
+						"Expected watch %s got %s",
+						mock.expectWatches[i],
+						w.GetName(),
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestNewWatchControllerHookScope(t *testing.T) {
+	exec := &v1alpha1.Hook{
+		Exec: &v1alpha1.Exec{Command: "cat"},
+	}
+	var tests = map[string]struct {
+		hooks *v1alpha1.GenericControllerHooks
+	}{
+		"exec sync hook": {
+			hooks: &v1alpha1.GenericControllerHooks{
+				Sync: exec,
+			},
+		},
+		"exec finalize hook": {
+			hooks: &v1alpha1.GenericControllerHooks{
+				Finalize: exec,
+			},
+		},
+		"exec customize hook": {
+			hooks: &v1alpha1.GenericControllerHooks{
+				Customize: exec,
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			gctl := &v1alpha1.GenericController{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: v1alpha1.GenericControllerSpec{
+					Hooks: mock.hooks,
+				},
+			}
+			// controller set as a custom resource
+			_, err := NewWatchController(
+				nil, nil, nil, gctl, common.HookScope{Namespace: "ns"},
+			)
+			if err == nil {
+				t.Fatalf("Expected error got none")
+			}
+		})
+	}
+}
+
+func TestHookInvokerRejectsExecOfCustomResource(t *testing.T) {
+	hi := &HookInvoker{
+		Schema: &v1alpha1.Hook{
+			Exec: &v1alpha1.Exec{Command: "cat"},
+		},
+		Scope: common.HookScope{Namespace: "ns"},
+	}
+	err := hi.Invoke(&SyncHookRequest{}, &SyncHookResponse{})
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
+
+func TestNonRetryableHookErrorIsNotResyncedImmediately(t *testing.T) {
+	notRetryable := false
+	hookErr := &hooks.Error{
+		Reason:    "InvalidSpec",
+		Message:   "Bad replicas",
+		Retryable: &notRetryable,
+	}
+	watch := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "test.io/v1",
+			"kind":       "Watch",
+			"metadata": map[string]interface{}{
+				"name":            "test",
+				"namespace":       "ns",
+				"resourceVersion": "1",
+			},
+		},
+	}
+	mgr := &WatchController{
+		watchQ: workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(),
+			"test",
+		),
+	}
+	defer mgr.watchQ.ShutDown()
+
+	// failed sync of the watch
+	common.RequeueAfterSyncError(
+		mgr.watchQ,
+		"ns/test",
+		errors.Wrapf(hookErr, "Sync hook failed"),
+	)
+	// informer observes the hook error condition set by the sync
+	updated := watch.DeepCopy()
+	updated.SetResourceVersion("2")
+	dynamicobject.SetStatusCondition(
+		updated.UnstructuredContent(),
+		common.NewHookErrorCondition(hookErr),
+	)
+	mgr.updateWatch(watch, updated)
+
+	if mgr.watchQ.Len() != 0 {
+		t.Fatalf("Expected no resync got %d queued watches", mgr.watchQ.Len())
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+)
+
+// defaultSyncHookCacheTTL is the expiry of a cached sync hook
+// response if not set in GenericController
+const defaultSyncHookCacheTTL = 300 * time.Second
+
+// syncHookCacheEntry is the cached sync hook response of a
+// watch
+type syncHookCacheEntry struct {
+	// hash of the request that resulted in this response
+	hash string
+
+	// entry is not used at or after this time
+	expiry time.Time
+
+	// marshalled response; a fresh copy is unmarshalled for
+	// every hit since the response gets mutated during
+	// reconciliation
+	response []byte
+}
+
+// syncHookCache stores the last successful sync hook response
+// per watch
+type syncHookCache struct {
+	ttl time.Duration
+
+	mutex   sync.Mutex
+	entries map[types.UID]*syncHookCacheEntry
+
+	// now is used to check the expiry of entries
+	now func() time.Time
+}
+
+// newSyncHookCache returns a new instance of syncHookCache if
+// the provided config enables it
+func newSyncHookCache(config *v1alpha1.SyncHookCache) *syncHookCache {
+	if config == nil {
+		return nil
+	}
+	ttl := defaultSyncHookCacheTTL
+	if config.TTLSeconds != nil {
+		ttl = time.Duration(*config.TTLSeconds) * time.Second
+	}
+	return &syncHookCache{
+		ttl:     ttl,
+		entries: make(map[types.UID]*syncHookCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached response of the provided
+// watch if the provided hash matches the cached one
+func (c *syncHookCache) get(watch types.UID, hash string) (*SyncHookResponse, bool) {
+	c.mutex.Lock()
+	entry := c.entries[watch]
+	c.mutex.Unlock()
+
+	if entry == nil || entry.hash != hash || !c.now().Before(entry.expiry) {
+		return nil, false
+	}
+	var response SyncHookResponse
+	err := json.Unmarshal(entry.response, &response)
+	if err != nil {
+		// this is not expected since the entry was marshalled
+		// from the same type
+		return nil, false
+	}
+	return &response, true
+}
+
+// set caches the provided response of the provided watch
+//
+// NOTE:
+//	The entry expires earlier than the TTL if the response asks
+// for a resync. This lets the resync invoke the hook.
+func (c *syncHookCache) set(
+	watch types.UID,
+	hash string,
+	response *SyncHookResponse,
+) error {
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return errors.Wrapf(err, "Can't marshal sync hook response")
+	}
+	ttl := c.ttl
+	if response.ResyncAfterSeconds > 0 {
+		resyncAfter := time.Duration(response.ResyncAfterSeconds * float64(time.Second))
+		if resyncAfter < ttl {
+			ttl = resyncAfter
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[watch] = &syncHookCacheEntry{
+		hash:     hash,
+		expiry:   c.now().Add(ttl),
+		response: raw,
+	}
+	return nil
+}
+
+// forget removes the cached response of the provided watch
+func (c *syncHookCache) forget(watch types.UID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, watch)
+}
+
+// hashSyncHookRequest returns the hash of the provided request
+// after excluding the fields that change without any change to
+// the object's content
+func hashSyncHookRequest(request *SyncHookRequest) (string, error) {
+	var controller *v1alpha1.GenericController
+	if request.Controller != nil {
+		controller = request.Controller.DeepCopy()
+		controller.ResourceVersion = ""
+		controller.ManagedFields = nil
+		controller.Status = v1alpha1.GenericControllerStatus{}
+	}
+	stable := &SyncHookRequest{
+		Controller:  controller,
+		Watch:       withoutVolatileFields(request.Watch),
+		Attachments: registryWithoutVolatileFields(request.Attachments),
+		Related:     registryWithoutVolatileFields(request.Related),
+		Finalizing:  request.Finalizing,
+	}
+	// NOTE:
+	//	json marshals map keys in sorted order which makes this
+	// hash deterministic
+	raw, err := json.Marshal(stable)
+	if err != nil {
+		return "", errors.Wrapf(err, "Can't marshal sync hook request")
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// withoutVolatileFields returns a copy of the provided object
+// without its resource version & managed fields
+func withoutVolatileFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil {
+		return nil
+	}
+	copied := obj.DeepCopy()
+	unstructured.RemoveNestedField(copied.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(copied.Object, "metadata", "managedFields")
+	return copied
+}
+
+// registryWithoutVolatileFields returns a copy of the provided
+// registry whose objects are without volatile fields
+func registryWithoutVolatileFields(
+	registry common.AnyUnstructRegistry,
+) common.AnyUnstructRegistry {
+	if registry == nil {
+		return nil
+	}
+	copied := make(common.AnyUnstructRegistry, len(registry))
+	for vk, objs := range registry {
+		copied[vk] = make(map[string]*unstructured.Unstructured, len(objs))
+		for name, obj := range objs {
+			copied[vk][name] = withoutVolatileFields(obj)
+		}
+	}
+	return copied
+}
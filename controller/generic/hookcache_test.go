@@ -0,0 +1,234 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+func TestNewSyncHookCache(t *testing.T) {
+	var tests = map[string]struct {
+		config    *v1alpha1.SyncHookCache
+		isNil     bool
+		expectTTL time.Duration
+	}{
+		"not enabled": {
+			isNil: true,
+		},
+		"default ttl": {
+			config:    &v1alpha1.SyncHookCache{},
+			expectTTL: defaultSyncHookCacheTTL,
+		},
+		"custom ttl": {
+			config: &v1alpha1.SyncHookCache{
+				TTLSeconds: k8s.Int32Ptr(30),
+			},
+			expectTTL: 30 * time.Second,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := newSyncHookCache(mock.config)
+			if mock.isNil {
+				if got != nil {
+					t.Fatalf("Expected nil cache got %+v", got)
+				}
+				return
+			}
+			if got.ttl != mock.expectTTL {
+				t.Fatalf("Expected ttl %s got %s", mock.expectTTL, got.ttl)
+			}
+		})
+	}
+}
+
+func TestSyncHookCacheGet(t *testing.T) {
+	start := time.Now()
+	var tests = map[string]struct {
+		hash        string
+		resyncAfter float64
+		elapsed     time.Duration
+		isHit       bool
+	}{
+		"same hash": {
+			hash:  "h-1",
+			isHit: true,
+		},
+		"different hash": {
+			hash:  "h-2",
+			isHit: false,
+		},
+		"expired": {
+			hash:    "h-1",
+			elapsed: 60 * time.Second,
+			isHit:   false,
+		},
+		"before resync": {
+			hash:        "h-1",
+			resyncAfter: 10,
+			elapsed:     5 * time.Second,
+			isHit:       true,
+		},
+		"after resync": {
+			hash:        "h-1",
+			resyncAfter: 10,
+			elapsed:     10 * time.Second,
+			isHit:       false,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			now := start
+			cache := newSyncHookCache(&v1alpha1.SyncHookCache{
+				TTLSeconds: k8s.Int32Ptr(60),
+			})
+			cache.now = func() time.Time { return now }
+
+			err := cache.set("w-101", "h-1", &SyncHookResponse{
+				Labels:             map[string]*string{"app": k8s.StringPtr("db")},
+				ResyncAfterSeconds: mock.resyncAfter,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+
+			now = start.Add(mock.elapsed)
+			got, hit := cache.get("w-101", mock.hash)
+			if hit != mock.isHit {
+				t.Fatalf("Expected hit %t got %t", mock.isHit, hit)
+			}
+			if !hit {
+				return
+			}
+			if got.Labels["app"] == nil || *got.Labels["app"] != "db" {
+				t.Fatalf("Expected cached label app=db got %+v", got.Labels)
+			}
+			// a hit must return a fresh copy
+			*got.Labels["app"] = "mutated"
+			again, _ := cache.get("w-101", mock.hash)
+			if *again.Labels["app"] != "db" {
+				t.Fatalf("Expected cached response to be unchanged")
+			}
+		})
+	}
+}
+
+func TestSyncHookCacheForget(t *testing.T) {
+	cache := newSyncHookCache(&v1alpha1.SyncHookCache{})
+	err := cache.set("w-101", "h-1", &SyncHookResponse{})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	cache.forget("w-101")
+	if _, hit := cache.get("w-101", "h-1"); hit {
+		t.Fatalf("Expected miss after forget")
+	}
+}
+
+func TestHashSyncHookRequest(t *testing.T) {
+	newRequest := func(rv string, replicas int64) *SyncHookRequest {
+		watch := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"replicas": replicas,
+				},
+			},
+		}
+		watch.SetAPIVersion("test.io/v1")
+		watch.SetKind("Cool")
+		watch.SetName("my-cool")
+		watch.SetUID("w-101")
+		watch.SetResourceVersion(rv)
+		watch.SetManagedFields([]metav1.ManagedFieldsEntry{
+			{Manager: "kubectl-" + rv},
+		})
+
+		attachment := &unstructured.Unstructured{}
+		attachment.SetAPIVersion("v1")
+		attachment.SetKind("Pod")
+		attachment.SetName("my-pod")
+		attachment.SetResourceVersion(rv)
+		attachments := make(common.AnyUnstructRegistry)
+		attachments.Insert(attachment)
+
+		return &SyncHookRequest{
+			Controller: &v1alpha1.GenericController{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "my-gctl",
+					ResourceVersion: rv,
+				},
+			},
+			Watch:       watch,
+			Attachments: attachments,
+		}
+	}
+	base, err := hashSyncHookRequest(newRequest("1", 1))
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	var tests = map[string]struct {
+		request      *SyncHookRequest
+		isSameAsBase bool
+	}{
+		"unchanged": {
+			request:      newRequest("1", 1),
+			isSameAsBase: true,
+		},
+		"only volatile fields changed": {
+			request:      newRequest("2", 1),
+			isSameAsBase: true,
+		},
+		"watch spec changed": {
+			request: newRequest("2", 2),
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got, err := hashSyncHookRequest(mock.request)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if (got == base) != mock.isSameAsBase {
+				t.Fatalf(
+					"Expected same as base %t: base %q got %q",
+					mock.isSameAsBase,
+					base,
+					got,
+				)
+			}
+			// volatile fields of the request must be intact
+			if mock.request.Watch.GetResourceVersion() == "" {
+				t.Fatalf("Expected request to be unchanged")
+			}
+		})
+	}
+}
@@ -17,10 +17,14 @@ limitations under the License.
 package generic
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/customize"
+	"openebs.io/metac/metrics"
 )
 
 // SyncHookRequest is the object sent as JSON to the sync hook.
@@ -36,6 +40,10 @@ type SyncHookRequest struct {
 	// declaration at the generic controller specs
 	Attachments common.AnyUnstructRegistry `json:"attachments"`
 
+	// refers to the related objects returned by the customize
+	// hook. These are read-only & are not reconciled.
+	Related common.AnyUnstructRegistry `json:"related,omitempty"`
+
 	// Flag indicating if this request is for delete reconcile
 	// and not create/update reconcile. This flag helps in
 	// having single reconcile hook for create/update & delete.
@@ -44,6 +52,16 @@ type SyncHookRequest struct {
 	Finalizing bool `json:"finalizing"`
 }
 
+// CustomizeHookRequest is the request sent to the customize
+// hook
+type CustomizeHookRequest struct {
+	// refers to this generic controller schema
+	Controller *v1alpha1.GenericController `json:"controller"`
+
+	// refers to the watch whose related resources are needed
+	Watch *unstructured.Unstructured `json:"watch"`
+}
+
 // SyncHookResponse is the expected format of the JSON response
 // from the sync hook.
 type SyncHookResponse struct {
@@ -105,11 +123,27 @@ type SyncHookResponse struct {
 // hook invocation that is supported by generic controller
 type HookInvoker struct {
 	Schema *v1alpha1.Hook
+
+	// Scope of the controller that invokes this hook
+	Scope common.HookScope
 }
 
 // Invoke invokes the hook based on the given request & fills the
 // response post successful invocation
-func (i *HookInvoker) Invoke(req *SyncHookRequest, resp *SyncHookResponse) error {
+func (i *HookInvoker) Invoke(req *SyncHookRequest, resp *SyncHookResponse) (err error) {
+	if req.Controller != nil {
+		start := time.Now()
+		defer func() {
+			common.RecordHook(
+				metricsControllerOf(req.Controller),
+				i.Schema,
+				start,
+				req,
+				resp,
+				err,
+			)
+		}()
+	}
 	// if inline call then set appropriate call func
 	if i.Schema.Inline != nil && i.Schema.Inline.FuncName != nil {
 		// create a new instance of generic controller based inline hook invoker
@@ -120,5 +154,36 @@ func (i *HookInvoker) Invoke(req *SyncHookRequest, resp *SyncHookResponse) error
 		return ihi.Invoke(req, resp)
 	}
 	// this is one of the commonly supported hooks
-	return common.InvokeHook(i.Schema, req, resp)
+	return common.InvokeHook(i.Scope, i.Schema, req, resp)
+}
+
+// metricsControllerOf returns the provided generic controller
+// as a metrics label
+func metricsControllerOf(gctl *v1alpha1.GenericController) metrics.Controller {
+	return metrics.Controller{
+		Kind: metrics.KindGenericController,
+		Name: gctl.Namespace + "/" + gctl.Name,
+	}
+}
+
+// callCustomizeHook invokes the customize hook to get the
+// resources related to the provided watch
+func (mgr *WatchController) callCustomizeHook(
+	watch *unstructured.Unstructured,
+) (*customize.HookResponse, error) {
+	var response customize.HookResponse
+	err := common.InvokeHookWithMetrics(
+		metricsControllerOf(mgr.GCtlConfig),
+		mgr.hookScope,
+		mgr.GCtlConfig.Spec.Hooks.Customize,
+		&CustomizeHookRequest{
+			Controller: mgr.GCtlConfig,
+			Watch:      watch,
+		},
+		&response,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
 }
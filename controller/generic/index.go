@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/common/selector"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+)
+
+// attachmentIndex looks up the attachments that may match a
+// watch by a single equality requirement of a select term
+type attachmentIndex struct {
+	name        string
+	requirement selector.IndexableRequirement
+}
+
+// attachmentIndexRegistrar acts as the registrar of attachment
+// indexes anchored by api version & resource. An attachment
+// resource without any entry is not indexable.
+type attachmentIndexRegistrar map[string][]attachmentIndex
+
+func makeAttachmentIndexKey(apiVersion, resource string) string {
+	return fmt.Sprintf("%s.%s", resource, apiVersion)
+}
+
+// indexOf returns the index name & function for the provided target
+// field path. Index function is nil if the index is built-in.
+func indexOf(fields []string) (string, cache.IndexFunc) {
+	if len(fields) == 2 && fields[0] == "metadata" && fields[1] == "namespace" {
+		return dynamicinformer.NamespaceIndex, nil
+	}
+	if len(fields) == 3 && fields[0] == "metadata" && fields[1] == "annotations" &&
+		fields[2] == common.AttachmentCreateAnnotationKey {
+		return dynamicinformer.AnnotationIndexName(fields[2]),
+			dynamicinformer.AnnotationIndexFunc(fields[2])
+	}
+	return dynamicinformer.FieldPathIndexName(fields),
+		dynamicinformer.FieldPathIndexFunc(fields)
+}
+
+// makeAttachmentIndexes adds the indexes required to lookup the
+// attachments matching the provided select terms to the provided
+// informer. It returns false if these attachments can't be looked
+// up from indexes.
+func makeAttachmentIndexes(
+	informer *dynamicinformer.ResourceInformer,
+	terms []*v1alpha1.SelectorTerm,
+) ([]attachmentIndex, bool, error) {
+	requirements, ok := selector.IndexableRequirements(terms)
+	if !ok {
+		return nil, false, nil
+	}
+	var indexes []attachmentIndex
+	indexers := cache.Indexers{}
+	for _, r := range requirements {
+		name, fn := indexOf(r.TargetFields)
+		if fn != nil {
+			indexers[name] = fn
+		}
+		indexes = append(indexes, attachmentIndex{
+			name:        name,
+			requirement: r,
+		})
+	}
+	err := informer.AddIndexers(indexers)
+	if err != nil {
+		return nil, false, err
+	}
+	return indexes, true, nil
+}
+
+// listAttachmentCandidates returns the attachments that may match
+// the provided watch. All the attachments are returned if they are
+// not indexable.
+//
+// NOTE:
+//	Candidates need to be matched against the watch to arrive at
+// the actual attachments
+func (mgr *WatchController) listAttachmentCandidates(
+	attachmentKind v1alpha1.GenericControllerAttachment,
+	informer *dynamicinformer.ResourceInformer,
+	watch *unstructured.Unstructured,
+) ([]*unstructured.Unstructured, error) {
+	indexes, found := mgr.attachmentIndexes[makeAttachmentIndexKey(
+		attachmentKind.APIVersion,
+		attachmentKind.Resource,
+	)]
+	if !found {
+		return informer.Lister().List(labels.Everything())
+	}
+	// candidates of every select term are united since terms
+	// are ORed
+	var candidates []*unstructured.Unstructured
+	seen := map[string]bool{}
+	for _, index := range indexes {
+		value, err := index.requirement.ReferenceValue(watch)
+		if err != nil {
+			return nil, err
+		}
+		objs, err := informer.Lister().ByIndex(index.name, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't list by index %q", index.name)
+		}
+		for _, obj := range objs {
+			key := obj.GetNamespace() + "/" + obj.GetName()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			candidates = append(candidates, obj)
+		}
+	}
+	return candidates, nil
+}
@@ -0,0 +1,397 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+)
+
+func TestIndexOf(t *testing.T) {
+	var tests = map[string]struct {
+		fields     []string
+		expectName string
+		isBuiltIn  bool
+	}{
+		"namespace": {
+			fields:     []string{"metadata", "namespace"},
+			expectName: dynamicinformer.NamespaceIndex,
+			isBuiltIn:  true,
+		},
+		"created due to watch annotation": {
+			fields: []string{
+				"metadata", "annotations", common.AttachmentCreateAnnotationKey,
+			},
+			expectName: dynamicinformer.AnnotationIndexName(
+				common.AttachmentCreateAnnotationKey,
+			),
+		},
+		"field path": {
+			fields:     []string{"spec", "nodeName"},
+			expectName: dynamicinformer.FieldPathIndexName([]string{"spec", "nodeName"}),
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			gotName, gotFn := indexOf(mock.fields)
+			if gotName != mock.expectName {
+				t.Fatalf("Expected index %q got %q", mock.expectName, gotName)
+			}
+			if mock.isBuiltIn != (gotFn == nil) {
+				t.Fatalf("Expected built-in %t got %t", mock.isBuiltIn, gotFn == nil)
+			}
+		})
+	}
+}
+
+// newIndexTestAttachments returns config maps that are spread
+// across namespaces, labels & annotations referring to watches
+func newIndexTestAttachments(count int) []*unstructured.Unstructured {
+	var attachments []*unstructured.Unstructured
+	for i := 0; i < count; i++ {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace(fmt.Sprintf("ns%d", i%4))
+		obj.SetName(fmt.Sprintf("cm%d", i))
+		obj.SetLabels(map[string]string{
+			"app": fmt.Sprintf("app%d", i%5),
+		})
+		obj.SetAnnotations(map[string]string{
+			"test.io/watch-name": fmt.Sprintf("w%d", i%7),
+			"test.io/watch-uid":  fmt.Sprintf("uid-%d", i%3),
+		})
+		attachments = append(attachments, obj)
+	}
+	return attachments
+}
+
+// newIndexTestWatches returns the watches whose attachments are
+// looked up
+func newIndexTestWatches() []*unstructured.Unstructured {
+	var watches []*unstructured.Unstructured
+	for i := 0; i < 8; i++ {
+		watch := &unstructured.Unstructured{}
+		watch.SetAPIVersion("test.io/v1")
+		watch.SetKind("Watch")
+		watch.SetNamespace(fmt.Sprintf("ns%d", i%4))
+		watch.SetName(fmt.Sprintf("w%d", i))
+		watch.SetUID(types.UID(fmt.Sprintf("uid-%d", i)))
+		watch.SetLabels(map[string]string{
+			"app": fmt.Sprintf("app%d", i),
+		})
+		watches = append(watches, watch)
+	}
+	return watches
+}
+
+// indexTestAPIServer serves the provided config maps & watches
+// that never change
+type indexTestAPIServer struct {
+	configmaps []*unstructured.Unstructured
+}
+
+func (s *indexTestAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("watch") == "true" {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+	items := []interface{}{}
+	if r.URL.Path == "/api/v1/configmaps" {
+		for _, obj := range s.configmaps {
+			items = append(items, obj.UnstructuredContent())
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"metadata":   map[string]interface{}{"resourceVersion": "1"},
+		"items":      items,
+	})
+}
+
+// newIndexTestController returns a watch controller whose config
+// map attachments are selected by the provided terms. Attachment
+// informer of this controller has synced the provided config maps.
+// The returned function stops this controller.
+func newIndexTestController(
+	t testing.TB,
+	terms []*v1alpha1.SelectorTerm,
+	configmaps []*unstructured.Unstructured,
+) (*WatchController, func()) {
+	srv := httptest.NewServer(&indexTestAPIServer{configmaps: configmaps})
+	discovery := dynamicdiscovery.NewAPIResourceDiscoverer(
+		&fakediscovery.FakeDiscovery{
+			Fake: &clienttesting.Fake{
+				Resources: []*metav1.APIResourceList{
+					{
+						GroupVersion: "v1",
+						APIResources: []metav1.APIResource{
+							{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+						},
+					},
+					{
+						GroupVersion: "test.io/v1",
+						APIResources: []metav1.APIResource{
+							{Name: "watches", Kind: "Watch", Namespaced: true},
+						},
+					},
+				},
+			},
+		},
+	)
+	discovery.Start(time.Hour)
+	for !discovery.HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	clientset, err := dynamicclientset.New(&rest.Config{Host: srv.URL}, discovery)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	gctl := &v1alpha1.GenericController{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.GenericControllerSpec{
+			Watch: v1alpha1.GenericControllerResource{
+				ResourceRule: v1alpha1.ResourceRule{
+					APIVersion: "test.io/v1",
+					Resource:   "watches",
+				},
+			},
+			Attachments: []v1alpha1.GenericControllerAttachment{
+				{
+					GenericControllerResource: v1alpha1.GenericControllerResource{
+						ResourceRule: v1alpha1.ResourceRule{
+							APIVersion: "v1",
+							Resource:   "configmaps",
+						},
+						AdvancedSelector: &v1alpha1.ResourceSelector{
+							SelectorTerms: terms,
+						},
+					},
+				},
+			},
+			Hooks: &v1alpha1.GenericControllerHooks{},
+		},
+	}
+	mgr, err := NewWatchController(
+		discovery,
+		clientset,
+		dynamicinformer.NewSharedInformerFactory(clientset, time.Hour),
+		gctl,
+		common.LocalHookScope,
+	)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	stop := func() {
+		for _, informer := range mgr.attachmentInformers {
+			informer.Close()
+		}
+		for _, informer := range mgr.watchInformers {
+			informer.Close()
+		}
+		discovery.Stop()
+		srv.CloseClientConnections()
+		srv.Close()
+	}
+	informer := mgr.attachmentInformers.Get("v1", "configmaps")
+	for !informer.Informer().HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return mgr, stop
+}
+
+// observedAttachmentNames returns the sorted keys of the provided
+// watch's observed attachments
+func observedAttachmentNames(
+	t testing.TB,
+	mgr *WatchController,
+	watch *unstructured.Unstructured,
+) []string {
+	registry, err := mgr.getObservedAttachments(watch)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	var names []string
+	for _, obj := range registry.List() {
+		names = append(names, obj.GetNamespace()+"/"+obj.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestGetObservedAttachmentsByIndexMatchesListAll(t *testing.T) {
+	var tests = map[string]struct {
+		terms []*v1alpha1.SelectorTerm
+	}{
+		"match reference": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchReference: []string{"metadata.labels.app"}},
+			},
+		},
+		"match reference with namespace": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchReference: []string{"metadata.namespace", "metadata.labels.app"}},
+			},
+		},
+		"match reference expression with ref key": {
+			terms: []*v1alpha1.SelectorTerm{
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:    "metadata.annotations.test\\.io/watch-name",
+							RefKey: "metadata.name",
+						},
+					},
+				},
+			},
+		},
+		"match reference expression against watch uid": {
+			terms: []*v1alpha1.SelectorTerm{
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:      "metadata.annotations.test\\.io/watch-uid",
+							Operator: v1alpha1.ReferenceSelectorOpEqualsUID,
+						},
+					},
+				},
+			},
+		},
+		"match reference expression against watch namespace": {
+			terms: []*v1alpha1.SelectorTerm{
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:      "metadata.namespace",
+							Operator: v1alpha1.ReferenceSelectorOpEqualsNamespace,
+						},
+						{
+							Key:      "metadata.labels.app",
+							Operator: v1alpha1.ReferenceSelectorOpNotEquals,
+						},
+					},
+				},
+			},
+		},
+		"ORed terms": {
+			terms: []*v1alpha1.SelectorTerm{
+				{MatchReference: []string{"metadata.labels.app"}},
+				{
+					MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+						{
+							Key:    "metadata.annotations.test\\.io/watch-name",
+							RefKey: "metadata.name",
+						},
+					},
+				},
+			},
+		},
+	}
+	attachments := newIndexTestAttachments(100)
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			mgr, stop := newIndexTestController(t, mock.terms, attachments)
+			defer stop()
+			if len(mgr.attachmentIndexes) != 1 {
+				t.Fatalf(
+					"Expected indexed attachments got %d indexes",
+					len(mgr.attachmentIndexes),
+				)
+			}
+			indexes := mgr.attachmentIndexes
+			var matched int
+			for _, watch := range newIndexTestWatches() {
+				mgr.attachmentIndexes = indexes
+				byIndex := observedAttachmentNames(t, mgr, watch)
+				// attachments are listed & then filtered without indexes
+				mgr.attachmentIndexes = attachmentIndexRegistrar{}
+				byListAll := observedAttachmentNames(t, mgr, watch)
+				if !reflect.DeepEqual(byIndex, byListAll) {
+					t.Fatalf(
+						"Expected attachments %v got %v: Watch %s",
+						byListAll,
+						byIndex,
+						watch.GetName(),
+					)
+				}
+				matched += len(byListAll)
+			}
+			if matched == 0 {
+				t.Fatalf("Expected matched attachments got none")
+			}
+		})
+	}
+}
+
+func BenchmarkGetObservedAttachments(b *testing.B) {
+	terms := []*v1alpha1.SelectorTerm{
+		{
+			MatchReferenceExpressions: []v1alpha1.ReferenceSelectorRequirement{
+				{
+					Key:    "metadata.annotations.test\\.io/watch-name",
+					RefKey: "metadata.name",
+				},
+			},
+		},
+	}
+	mgr, stop := newIndexTestController(b, terms, newIndexTestAttachments(10000))
+	defer stop()
+	indexes := mgr.attachmentIndexes
+	watches := newIndexTestWatches()
+	var benchmarks = map[string]attachmentIndexRegistrar{
+		"by index": indexes,
+		"list all": {},
+	}
+	for name, registrar := range benchmarks {
+		mgr.attachmentIndexes = registrar
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := mgr.getObservedAttachments(watches[i%len(watches)])
+				if err != nil {
+					b.Fatalf("Expected no error got [%+v]", err)
+				}
+			}
+		})
+	}
+}
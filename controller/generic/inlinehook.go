@@ -17,29 +17,20 @@ limitations under the License.
 package generic
 
 import (
-	"sync"
-
 	"github.com/pkg/errors"
+
+	"openebs.io/metac/controller/common"
 )
 
 // InlineInvokeFn is the signature for all inline hook invocation functions
 type InlineInvokeFn func(req *SyncHookRequest, resp *SyncHookResponse) error
 
-type inlineHookRegistry struct {
-	sync.Mutex
-	invokeFuncs map[string]InlineInvokeFn
-}
-
-var inlineHookRegistryInstance = &inlineHookRegistry{
-	invokeFuncs: make(map[string]InlineInvokeFn),
-}
+var inlineHookRegistry = common.NewInlineHookRegistry()
 
 // AddToInlineRegistry will add function name and correponding
 // function to inline hook registry
 func AddToInlineRegistry(funcName string, fn InlineInvokeFn) {
-	inlineHookRegistryInstance.Lock()
-	defer inlineHookRegistryInstance.Unlock()
-	inlineHookRegistryInstance.invokeFuncs[funcName] = fn
+	inlineHookRegistry.Add(funcName, fn)
 }
 
 // InlineHookInvoker manages invocation of inline hook
@@ -59,11 +50,9 @@ func NewInlineHookInvoker(funcName string) (*InlineHookInvoker, error) {
 // Invoke this inline hook by passing the given request
 // and fill up the given response with the hook's response
 func (i *InlineHookInvoker) Invoke(req *SyncHookRequest, resp *SyncHookResponse) error {
-	fn := inlineHookRegistryInstance.invokeFuncs[i.FuncName]
-	if fn == nil {
-		return errors.Errorf(
-			"Inline hook function not found for %s", i.FuncName,
-		)
+	fn, err := inlineHookRegistry.Get(i.FuncName)
+	if err != nil {
+		return err
 	}
-	return fn(req, resp)
+	return fn.(InlineInvokeFn)(req, resp)
 }
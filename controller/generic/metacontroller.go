@@ -18,6 +18,7 @@ package generic
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,11 +28,14 @@ import (
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	metaclientset "openebs.io/metac/client/generated/clientset/versioned"
 	metainformers "openebs.io/metac/client/generated/informers/externalversions"
 	metalisters "openebs.io/metac/client/generated/listers/metacontroller/v1alpha1"
 	"openebs.io/metac/config"
@@ -94,6 +98,16 @@ type ConfigMetaController struct {
 	// Interval between retries to start all watch controllers
 	WaitIntervalBetweenRestarts time.Duration
 
+	// Interval to reload the configs. Watch controllers are
+	// started, stopped or restarted based on the changes in
+	// reloaded configs.
+	//
+	// NOTE:
+	//	Configs are not reloaded if this is not set
+	ConfigReloadInterval time.Duration
+
+	stopCh chan struct{}
+
 	opts []ConfigMetaControllerOption
 	err  error
 }
@@ -125,6 +139,21 @@ func SetMetacConfigToRetryIndefinitelyForStart(enabled *bool) ConfigMetaControll
 	}
 }
 
+// SetMetacConfigReloadInterval sets the interval to reload
+// the configs
+func SetMetacConfigReloadInterval(interval time.Duration) ConfigMetaControllerOption {
+	return func(c *ConfigMetaController) error {
+		if interval < 0 {
+			return errors.Errorf(
+				"Invalid config reload interval %s: Must not be negative",
+				interval,
+			)
+		}
+		c.ConfigReloadInterval = interval
+		return nil
+	}
+}
+
 // SetMetacConfigLoadFn sets the config loader function
 func SetMetacConfigLoadFn(
 	fn func() ([]*v1alpha1.GenericController, error),
@@ -203,14 +232,19 @@ func (mc *ConfigMetaController) loadConfigs() {
 		)
 		return
 	}
+	mc.Configs, mc.err = mc.fetchConfigs()
+}
+
+// fetchConfigs returns the GenericController instances from
+// either ConfigPath or ConfigLoadFn
+func (mc *ConfigMetaController) fetchConfigs() ([]*v1alpha1.GenericController, error) {
 	// NOTE:
 	// 	ConfigPath has **higher priority** to load GenericController
 	// instance(s) as config(s) to run Metac
 	if mc.ConfigPath != "" {
-		mc.Configs, mc.err = mc.loadConfigsByPath()
-	} else {
-		mc.Configs, mc.err = mc.ConfigLoadFn()
+		return mc.loadConfigsByPath()
 	}
+	return mc.ConfigLoadFn()
 }
 
 func (mc *ConfigMetaController) loadConfigsByPath() ([]*v1alpha1.GenericController, error) {
@@ -244,6 +278,7 @@ func (mc *ConfigMetaController) isDuplicateConfig() {
 // Start generic meta controller by starting watch controllers
 // corresponding to the provided config
 func (mc *ConfigMetaController) Start() {
+	mc.stopCh = make(chan struct{})
 	mc.doneCh = make(chan struct{})
 
 	go func() {
@@ -259,62 +294,31 @@ func (mc *ConfigMetaController) Start() {
 		if err != nil {
 			glog.Fatalf("Failed to start %s: %+v", mc, err)
 		}
+
+		if mc.ConfigReloadInterval == 0 {
+			return
+		}
+		glog.Infof(
+			"Will reload configs every %s: %s",
+			mc.ConfigReloadInterval,
+			mc,
+		)
+		// reload the configs till this controller is stopped
+		wait.Until(mc.reloadConfigs, mc.ConfigReloadInterval, mc.stopCh)
 	}()
 }
 
 // startWithRetries polls the condition until it's true, with
 // a configured interval and timeout.
-//
-// The condition function returns a bool indicating whether it
-// is satisfied, as well as an error which should be non-nil if
-// and only if the function was unable to determine whether or
-// not the condition is satisfied (for example if the check
-// involves calling a remote server and the request failed).
 func (mc *ConfigMetaController) startWithRetries(
 	condition func() (bool, error),
 ) error {
-	// mark the start time
-	start := time.Now()
-	for {
-		// check the condition
-		done, err := condition()
-		if err == nil && done {
-			// returning nil implies the condition has completed
-			return nil
-		}
-		if time.Since(start) > mc.WaitTimeoutForStartAttempt &&
-			(mc.RetryIndefinitelyUntilSucceed == nil ||
-				!*mc.RetryIndefinitelyUntilSucceed) {
-			{
-				return errors.Errorf(
-					"Condition timed out after %s: %+v: %s",
-					mc.WaitTimeoutForStartAttempt,
-					err,
-					mc,
-				)
-			}
-		}
-		if err != nil {
-			// condition resulted in error
-			// keep trying until timeout
-			glog.V(7).Infof(
-				"Condition failed: Will retry after %s: %+v: %s",
-				mc.WaitIntervalBetweenRestarts,
-				err,
-				mc,
-			)
-		} else {
-			// condition did not pass
-			// keep trying until timeout
-			glog.V(7).Infof(
-				"Waiting for condition to succeed: Will retry after %s: %s",
-				mc.WaitIntervalBetweenRestarts,
-				mc,
-			)
-		}
-		// wait & then continue retrying
-		time.Sleep(mc.WaitIntervalBetweenRestarts)
-	}
+	return common.StartRetrier{
+		Caller:            mc,
+		WaitTimeout:       mc.WaitTimeoutForStartAttempt,
+		WaitInterval:      mc.WaitIntervalBetweenRestarts,
+		RetryIndefinitely: mc.RetryIndefinitelyUntilSucceed,
+	}.Run(condition)
 }
 
 // startAllWatchControllers starts all the watches configured
@@ -341,6 +345,7 @@ func (mc *ConfigMetaController) startAllWatchControllers() (bool, error) {
 			mc.DynClientset,
 			mc.DynInformerFactory,
 			conf,
+			common.LocalHookScope,
 		)
 		if err != nil {
 			errs = append(
@@ -375,12 +380,138 @@ func (mc *ConfigMetaController) startAllWatchControllers() (bool, error) {
 	return true, nil
 }
 
+// configDiff is the difference between the running generic
+// controllers & the desired generic controllers
+type configDiff struct {
+	// configs that are not running
+	added []*v1alpha1.GenericController
+
+	// configs that are running with a different spec
+	changed []*v1alpha1.GenericController
+
+	// keys of running configs that are no longer desired
+	removed []string
+
+	// keys of desired configs that are rejected
+	rejected map[string]error
+}
+
+// diffConfigs returns the difference between the running & the
+// desired generic controllers
+func diffConfigs(
+	running map[string]*v1alpha1.GenericController,
+	desired []*v1alpha1.GenericController,
+) configDiff {
+	diff := configDiff{rejected: make(map[string]error)}
+	desiredKeys := make(map[string]bool)
+	for _, conf := range desired {
+		key := conf.AsNamespaceNameKey()
+		if desiredKeys[key] {
+			// duplicates are rejected while the first one is
+			// considered as desired
+			diff.rejected[key] = errors.Errorf("Duplicate %s was found", key)
+			continue
+		}
+		desiredKeys[key] = true
+		old, found := running[key]
+		if !found {
+			diff.added = append(diff.added, conf)
+			continue
+		}
+		if !apiequality.Semantic.DeepEqual(old.Spec, conf.Spec) {
+			diff.changed = append(diff.changed, conf)
+		}
+	}
+	for key := range running {
+		if !desiredKeys[key] {
+			diff.removed = append(diff.removed, key)
+		}
+	}
+	// sort for predictable operations
+	sort.Strings(diff.removed)
+	return diff
+}
+
+// reloadConfigs reloads the configs & starts, stops or restarts
+// only those watch controllers whose configs have changed. Configs
+// that fail to load or start are rejected without affecting the
+// running watch controllers.
+func (mc *ConfigMetaController) reloadConfigs() {
+	desired, err := mc.fetchConfigs()
+	if err != nil {
+		glog.Errorf(
+			"Failed to reload configs: Will keep running existing configs: %+v: %s",
+			err,
+			mc,
+		)
+		return
+	}
+	running := make(map[string]*v1alpha1.GenericController)
+	for key, wc := range mc.WatchControllers {
+		running[key] = wc.GCtlConfig
+	}
+	diff := diffConfigs(running, desired)
+	for key, err := range diff.rejected {
+		glog.Errorf("Rejected config %s: %+v: %s", key, err, mc)
+	}
+
+	for _, key := range diff.removed {
+		glog.Infof("Will stop gctl %s: Config was removed: %s", key, mc)
+		mc.WatchControllers[key].Stop()
+		delete(mc.WatchControllers, key)
+	}
+	for _, conf := range diff.changed {
+		key := conf.AsNamespaceNameKey()
+		// init the new controller before stopping the old one
+		// to keep the old one running if the new config is bad
+		wc, err := NewWatchController(
+			mc.ResourceManager,
+			mc.DynClientset,
+			mc.DynInformerFactory,
+			conf,
+			common.LocalHookScope,
+		)
+		if err != nil {
+			glog.Errorf(
+				"Rejected changed config %s: Will keep running old config: %+v: %s",
+				key,
+				err,
+				mc,
+			)
+			continue
+		}
+		glog.Infof("Will restart gctl %s: Config was changed: %s", key, mc)
+		mc.WatchControllers[key].Stop()
+		wc.Start(mc.WorkerCount)
+		mc.WatchControllers[key] = wc
+	}
+	for _, conf := range diff.added {
+		key := conf.AsNamespaceNameKey()
+		wc, err := NewWatchController(
+			mc.ResourceManager,
+			mc.DynClientset,
+			mc.DynInformerFactory,
+			conf,
+			common.LocalHookScope,
+		)
+		if err != nil {
+			// this will be retried during next reload
+			glog.Errorf("Rejected added config %s: %+v: %s", key, err, mc)
+			continue
+		}
+		glog.Infof("Will start gctl %s: Config was added: %s", key, mc)
+		wc.Start(mc.WorkerCount)
+		mc.WatchControllers[key] = wc
+	}
+}
+
 // Stop stops this MetaController
 func (mc *ConfigMetaController) Stop() {
 	glog.Infof("Shutting down %s", mc)
 
 	// Stop metacontroller first so there's no more changes
 	// to watch controllers.
+	close(mc.stopCh)
 	<-mc.doneCh
 
 	// Stop all its watch controllers
@@ -405,6 +536,9 @@ type CRDMetaController struct {
 	// To list GenericController CRs
 	Lister metalisters.GenericControllerLister
 
+	// To update status of GenericController CRs
+	Clientset metaclientset.Interface
+
 	// To watch GenericController CR events
 	Informer cache.SharedIndexInformer
 
@@ -421,6 +555,7 @@ func NewCRDMetaController(
 	dynClientset *dynamicclientset.Clientset,
 	dynInformerFactory *dynamicinformer.SharedInformerFactory,
 	metaInformerFactory metainformers.SharedInformerFactory,
+	metaClientset metaclientset.Interface,
 	workerCount int,
 ) *CRDMetaController {
 	// initialize
@@ -432,8 +567,9 @@ func NewCRDMetaController(
 			WorkerCount:        workerCount,
 			WatchControllers:   make(map[string]*WatchController),
 		},
-		Lister:   metaInformerFactory.Metacontroller().V1alpha1().GenericControllers().Lister(),
-		Informer: metaInformerFactory.Metacontroller().V1alpha1().GenericControllers().Informer(),
+		Lister:    metaInformerFactory.Metacontroller().V1alpha1().GenericControllers().Lister(),
+		Informer:  metaInformerFactory.Metacontroller().V1alpha1().GenericControllers().Informer(),
+		Clientset: metaClientset,
 		Queue: workqueue.NewNamedRateLimitingQueue(
 			workqueue.DefaultControllerRateLimiter(), "CRD GenericController",
 		),
@@ -557,7 +693,59 @@ func (mc *CRDMetaController) sync(key string) error {
 		return err
 	}
 	// run reconciliation on the found GenericController instance
-	return mc.syncGenericController(ctrl)
+	syncErr := mc.syncGenericController(ctrl)
+
+	// status is updated irrespective of sync errors
+	var desiredStatus v1alpha1.GenericControllerStatus
+	if syncErr != nil {
+		desiredStatus = NewWatchControllerInitErrorStatus(syncErr)
+	} else {
+		desiredStatus = mc.WatchControllers[ctrl.AsNamespaceNameKey()].GetStatus()
+	}
+	statusErr := mc.updateStatus(ctrl, desiredStatus)
+	if syncErr != nil {
+		return syncErr
+	}
+	return statusErr
+}
+
+// updateStatus updates the status of the provided GenericController
+// if the desired status is different from its observed status
+func (mc *CRDMetaController) updateStatus(
+	gctl *v1alpha1.GenericController,
+	desired v1alpha1.GenericControllerStatus,
+) error {
+	if mc.Clientset == nil {
+		// status updates are not possible
+		return nil
+	}
+	desired = MergeGenericControllerStatus(gctl.Status, desired, metav1.Now())
+	if apiequality.Semantic.DeepEqual(gctl.Status, desired) {
+		// Nothing has changed.
+		return nil
+	}
+	glog.V(5).Infof(
+		"Will update status of %s: Phase %q: %s",
+		gctl.AsNamespaceNameKey(),
+		desired.Phase,
+		mc,
+	)
+	// Make a copy since gctl is from the cache
+	gctlCopy := gctl.DeepCopy()
+	gctlCopy.Status = desired
+	_, err := mc.Clientset.
+		MetacontrollerV1alpha1().
+		GenericControllers(gctl.Namespace).
+		UpdateStatus(gctlCopy)
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"Failed to update status of %s: %s",
+			gctl.AsNamespaceNameKey(),
+			mc,
+		)
+	}
+	return nil
 }
 
 // syncGenericController is all about starting individual
@@ -582,10 +770,18 @@ func (mc *CRDMetaController) syncGenericController(gctl *v1alpha1.GenericControl
 		mc.DynClientset,
 		mc.DynInformerFactory,
 		gctl,
+		// GenericController custom resources are namespaced
+		common.HookScope{Namespace: gctl.Namespace},
 	)
 	if err != nil {
 		return err
 	}
+	// reconcile the status of GenericController whenever this
+	// watch controller's state changes
+	key := gctl.AsNamespaceNameKey()
+	wc.SetStatusChangeHandler(func() {
+		mc.Queue.Add(key)
+	})
 	// start this watch based controller
 	wc.Start(mc.WorkerCount)
 	// add to the registry of watch based controllers
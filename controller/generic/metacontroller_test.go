@@ -17,6 +17,7 @@ limitations under the License.
 package generic
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -211,3 +212,108 @@ func TestConfigMetaControllerStartWithRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffConfigs(t *testing.T) {
+	newGCtl := func(name, resource string) *v1alpha1.GenericController {
+		return &v1alpha1.GenericController{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "metac",
+			},
+			Spec: v1alpha1.GenericControllerSpec{
+				Watch: v1alpha1.GenericControllerResource{
+					ResourceRule: v1alpha1.ResourceRule{
+						APIVersion: "v1",
+						Resource:   resource,
+					},
+				},
+			},
+		}
+	}
+	var tests = map[string]struct {
+		running       map[string]*v1alpha1.GenericController
+		desired       []*v1alpha1.GenericController
+		expectAdded   []string
+		expectChanged []string
+		expectRemoved []string
+		expectReject  []string
+	}{
+		"nothing changed": {
+			running: map[string]*v1alpha1.GenericController{
+				"metac/one": newGCtl("one", "pods"),
+			},
+			desired: []*v1alpha1.GenericController{
+				newGCtl("one", "pods"),
+			},
+		},
+		"added & removed": {
+			running: map[string]*v1alpha1.GenericController{
+				"metac/one": newGCtl("one", "pods"),
+			},
+			desired: []*v1alpha1.GenericController{
+				newGCtl("two", "pods"),
+			},
+			expectAdded:   []string{"metac/two"},
+			expectRemoved: []string{"metac/one"},
+		},
+		"changed": {
+			running: map[string]*v1alpha1.GenericController{
+				"metac/one": newGCtl("one", "pods"),
+				"metac/two": newGCtl("two", "pods"),
+			},
+			desired: []*v1alpha1.GenericController{
+				newGCtl("one", "services"),
+				newGCtl("two", "pods"),
+			},
+			expectChanged: []string{"metac/one"},
+		},
+		"duplicate is rejected": {
+			running: map[string]*v1alpha1.GenericController{
+				"metac/one": newGCtl("one", "pods"),
+			},
+			desired: []*v1alpha1.GenericController{
+				newGCtl("one", "pods"),
+				newGCtl("one", "services"),
+			},
+			expectReject: []string{"metac/one"},
+		},
+		"all removed": {
+			running: map[string]*v1alpha1.GenericController{
+				"metac/one": newGCtl("one", "pods"),
+				"metac/two": newGCtl("two", "pods"),
+			},
+			expectRemoved: []string{"metac/one", "metac/two"},
+		},
+	}
+	keysOf := func(confs []*v1alpha1.GenericController) []string {
+		var keys []string
+		for _, conf := range confs {
+			keys = append(keys, conf.AsNamespaceNameKey())
+		}
+		return keys
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := diffConfigs(mock.running, mock.desired)
+			if !reflect.DeepEqual(keysOf(got.added), mock.expectAdded) {
+				t.Fatalf("Expected added %v got %v", mock.expectAdded, keysOf(got.added))
+			}
+			if !reflect.DeepEqual(keysOf(got.changed), mock.expectChanged) {
+				t.Fatalf("Expected changed %v got %v", mock.expectChanged, keysOf(got.changed))
+			}
+			if !reflect.DeepEqual(got.removed, mock.expectRemoved) {
+				t.Fatalf("Expected removed %v got %v", mock.expectRemoved, got.removed)
+			}
+			if len(got.rejected) != len(mock.expectReject) {
+				t.Fatalf("Expected rejected %v got %v", mock.expectReject, got.rejected)
+			}
+			for _, key := range mock.expectReject {
+				if got.rejected[key] == nil {
+					t.Fatalf("Expected %q to be rejected got %v", key, got.rejected)
+				}
+			}
+		})
+	}
+}
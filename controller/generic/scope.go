@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+)
+
+// watchNamespaces restricts the informers of all GenericControllers
+// if set
+var watchNamespaces struct {
+	mutex      sync.RWMutex
+	namespaces []string
+}
+
+// SetWatchNamespaces restricts the watch & attachment informers of
+// all GenericControllers to the provided namespaces. Informers are
+// not restricted if this is empty.
+//
+// NOTE:
+//	Metac binary sets these namespaces from its flags
+func SetWatchNamespaces(namespaces []string) {
+	watchNamespaces.mutex.Lock()
+	defer watchNamespaces.mutex.Unlock()
+	watchNamespaces.namespaces = namespaces
+}
+
+func getWatchNamespaces() []string {
+	watchNamespaces.mutex.RLock()
+	defer watchNamespaces.mutex.RUnlock()
+	return watchNamespaces.namespaces
+}
+
+// makeInformerScope returns the scope of the informer of a watch or
+// attachment. The resource's own scope takes precedence over the
+// controller's scope. The global namespaces are used if neither of
+// these scopes set any namespaces.
+func makeInformerScope(
+	global []string,
+	specScope *v1alpha1.InformerScope,
+	resourceScope *v1alpha1.InformerScope,
+) (dynamicinformer.Scope, error) {
+	scope := resourceScope
+	if scope == nil {
+		scope = specScope
+	}
+	var result dynamicinformer.Scope
+	if scope != nil {
+		result.Namespaces = scope.Namespaces
+		if scope.LabelSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(scope.LabelSelector)
+			if err != nil {
+				return dynamicinformer.Scope{},
+					errors.Wrapf(err, "Invalid informer scope label selector")
+			}
+			result.LabelSelector = selector.String()
+		}
+	}
+	if len(global) == 0 {
+		return result, nil
+	}
+	if len(result.Namespaces) == 0 {
+		result.Namespaces = global
+		return result, nil
+	}
+	allowed := map[string]bool{}
+	for _, ns := range global {
+		allowed[ns] = true
+	}
+	for _, ns := range result.Namespaces {
+		if !allowed[ns] {
+			return dynamicinformer.Scope{},
+				errors.Errorf(
+					"Invalid informer scope: Namespace %q is not in watch namespaces %v",
+					ns,
+					global,
+				)
+		}
+	}
+	return result, nil
+}
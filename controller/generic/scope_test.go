@@ -0,0 +1,129 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+)
+
+func TestMakeInformerScope(t *testing.T) {
+	var tests = map[string]struct {
+		global        []string
+		specScope     *v1alpha1.InformerScope
+		resourceScope *v1alpha1.InformerScope
+		expect        dynamicinformer.Scope
+		isErr         bool
+	}{
+		"no scope": {
+			expect: dynamicinformer.Scope{},
+		},
+		"global namespaces only": {
+			global: []string{"ns1", "ns2"},
+			expect: dynamicinformer.Scope{Namespaces: []string{"ns1", "ns2"}},
+		},
+		"spec scope": {
+			specScope: &v1alpha1.InformerScope{
+				Namespaces: []string{"ns1"},
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "test"},
+				},
+			},
+			expect: dynamicinformer.Scope{
+				Namespaces:    []string{"ns1"},
+				LabelSelector: "app=test",
+			},
+		},
+		"resource scope overrides spec scope": {
+			specScope: &v1alpha1.InformerScope{
+				Namespaces: []string{"ns1"},
+			},
+			resourceScope: &v1alpha1.InformerScope{
+				Namespaces: []string{"ns2"},
+			},
+			expect: dynamicinformer.Scope{Namespaces: []string{"ns2"}},
+		},
+		"selector only scope uses global namespaces": {
+			global: []string{"ns1"},
+			resourceScope: &v1alpha1.InformerScope{
+				LabelSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      "app",
+							Operator: metav1.LabelSelectorOpExists,
+						},
+					},
+				},
+			},
+			expect: dynamicinformer.Scope{
+				Namespaces:    []string{"ns1"},
+				LabelSelector: "app",
+			},
+		},
+		"scope within global namespaces": {
+			global: []string{"ns1", "ns2"},
+			specScope: &v1alpha1.InformerScope{
+				Namespaces: []string{"ns2"},
+			},
+			expect: dynamicinformer.Scope{Namespaces: []string{"ns2"}},
+		},
+		"scope outside global namespaces": {
+			global: []string{"ns1"},
+			specScope: &v1alpha1.InformerScope{
+				Namespaces: []string{"ns1", "ns2"},
+			},
+			isErr: true,
+		},
+		"invalid label selector": {
+			resourceScope: &v1alpha1.InformerScope{
+				LabelSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      "app",
+							Operator: "junk",
+						},
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got, err := makeInformerScope(mock.global, mock.specScope, mock.resourceScope)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr {
+				return
+			}
+			if !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected scope %+v got %+v", mock.expect, got)
+			}
+		})
+	}
+}
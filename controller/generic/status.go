@@ -0,0 +1,315 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+// WatchSyncReason classifies the failure that happened during
+// a watch sync
+type WatchSyncReason string
+
+const (
+	// WatchSyncReasonSyncFailed is used when sync failed due to
+	// reasons other than hook invocation or apply
+	WatchSyncReasonSyncFailed WatchSyncReason = "SyncFailed"
+
+	// WatchSyncReasonHookFailed is used when sync or finalize
+	// hook invocation failed
+	WatchSyncReasonHookFailed WatchSyncReason = "HookFailed"
+
+	// WatchSyncReasonApplyFailed is used when desired attachments
+	// could not be applied against the cluster
+	WatchSyncReasonApplyFailed WatchSyncReason = "ApplyFailed"
+)
+
+const (
+	// ConditionIDInformersSynced is the condition that reports
+	// if informers of watch & attachments have synced
+	ConditionIDInformersSynced string = "InformersSynced"
+
+	// ConditionIDWatchControllerInit is the condition that reports
+	// failure to initialize the watch controller
+	ConditionIDWatchControllerInit string = "WatchControllerInit"
+
+	// ConditionIDMoreWatchErrors is the condition that reports
+	// the number of watch errors that were not listed as conditions
+	ConditionIDMoreWatchErrors string = "MoreWatchErrors"
+)
+
+// maxWatchErrorConditions limits the number of watch errors
+// that get reported as conditions. This keeps the size of
+// GenericController's status in check when a large number of
+// watches fail.
+const maxWatchErrorConditions = 10
+
+// watchSyncError is an error that is tagged with the reason
+// of failure
+type watchSyncError struct {
+	reason WatchSyncReason
+	err    error
+}
+
+// Error implements error interface
+func (e *watchSyncError) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the underlying error. This lets the error be
+// inspected via errors.Cause.
+func (e *watchSyncError) Cause() error {
+	return e.err
+}
+
+// withWatchSyncReason tags the provided error with the provided
+// reason
+func withWatchSyncReason(reason WatchSyncReason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &watchSyncError{reason: reason, err: err}
+}
+
+// watchSyncErrorReason returns the reason tagged against the
+// provided error. It defaults to WatchSyncReasonSyncFailed.
+func watchSyncErrorReason(err error) WatchSyncReason {
+	if wErr, ok := err.(*watchSyncError); ok {
+		return wErr.reason
+	}
+	return WatchSyncReasonSyncFailed
+}
+
+// watchErrorMessages maps a reason to its condition message
+// & help
+var watchErrorMessages = map[WatchSyncReason]struct {
+	message string
+	help    string
+}{
+	WatchSyncReasonSyncFailed: {
+		message: "Failed to sync watch",
+	},
+	WatchSyncReasonHookFailed: {
+		message: "Failed to invoke hook",
+		help:    "Verify if the hook is reachable & returns a valid response",
+	},
+	WatchSyncReasonApplyFailed: {
+		message: "Failed to apply attachments",
+		help:    "Verify if metac has permissions to manage the attachments",
+	},
+}
+
+// watchStatusRecorder records the runtime state of a watch
+// controller that gets reported as GenericController status
+type watchStatusRecorder struct {
+	// guards the fields below since this is accessed by
+	// multiple workers
+	mutex sync.Mutex
+
+	// true if all informers have synced
+	informersSynced bool
+
+	// last error per watch; keyed by watch queue key
+	watchErrors map[string]watchSyncError
+
+	// invoked whenever the recorded state changes
+	onChange func()
+}
+
+// newWatchStatusRecorder returns a new instance of watchStatusRecorder
+func newWatchStatusRecorder() *watchStatusRecorder {
+	return &watchStatusRecorder{
+		watchErrors: make(map[string]watchSyncError),
+	}
+}
+
+// notify invokes the change handler if set
+func (r *watchStatusRecorder) notify(isChange bool) {
+	if !isChange || r.onChange == nil {
+		return
+	}
+	r.onChange()
+}
+
+// SetInformersSynced records if informers have synced
+func (r *watchStatusRecorder) SetInformersSynced(synced bool) {
+	r.mutex.Lock()
+	isChange := r.informersSynced != synced
+	r.informersSynced = synced
+	r.mutex.Unlock()
+
+	r.notify(isChange)
+}
+
+// RecordWatchSync records the result of sync against the provided
+// watch. A nil error clears any previously recorded error.
+func (r *watchStatusRecorder) RecordWatchSync(watchKey string, err error) {
+	r.mutex.Lock()
+	old, found := r.watchErrors[watchKey]
+	var isChange bool
+	if err == nil {
+		isChange = found
+		delete(r.watchErrors, watchKey)
+	} else {
+		reason := watchSyncErrorReason(err)
+		isChange = !found ||
+			old.reason != reason ||
+			old.err.Error() != err.Error()
+		r.watchErrors[watchKey] = watchSyncError{reason: reason, err: err}
+	}
+	r.mutex.Unlock()
+
+	r.notify(isChange)
+}
+
+// GetStatus returns the GenericController status based on the
+// recorded state
+//
+// NOTE:
+//	Condition timestamps are not set here. These are expected to
+// be set by the caller when conditions actually change.
+func (r *watchStatusRecorder) GetStatus() v1alpha1.GenericControllerStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var conditions []v1alpha1.GenericControllerCondition
+	if r.informersSynced {
+		conditions = append(conditions, v1alpha1.GenericControllerCondition{
+			ID:      ConditionIDInformersSynced,
+			Assert:  assertPtr(v1alpha1.GenericControllerConditionAssertPassed),
+			Message: "Informers of watch & attachments have synced",
+		})
+	} else {
+		conditions = append(conditions, v1alpha1.GenericControllerCondition{
+			ID:      ConditionIDInformersSynced,
+			State:   statePtr(v1alpha1.GenericControllerConditionStateInProgress),
+			Message: "Waiting for informers of watch & attachments to sync",
+		})
+	}
+
+	// sort the keys to report conditions in a deterministic order
+	var watchKeys []string
+	for key := range r.watchErrors {
+		watchKeys = append(watchKeys, key)
+	}
+	sort.Strings(watchKeys)
+	for i, key := range watchKeys {
+		if i == maxWatchErrorConditions {
+			conditions = append(conditions, v1alpha1.GenericControllerCondition{
+				ID:    ConditionIDMoreWatchErrors,
+				State: statePtr(v1alpha1.GenericControllerConditionStateError),
+				Message: fmt.Sprintf(
+					"%d more watch(es) have errors",
+					len(watchKeys)-maxWatchErrorConditions,
+				),
+			})
+			break
+		}
+		wErr := r.watchErrors[key]
+		conditions = append(conditions, v1alpha1.GenericControllerCondition{
+			ID:      fmt.Sprintf("%s:%s", wErr.reason, key),
+			State:   statePtr(v1alpha1.GenericControllerConditionStateError),
+			Assert:  assertPtr(v1alpha1.GenericControllerConditionAssertFailed),
+			Message: watchErrorMessages[wErr.reason].message,
+			Error:   wErr.err.Error(),
+			Help:    watchErrorMessages[wErr.reason].help,
+		})
+	}
+
+	phase := v1alpha1.GenericControllerStatusPhaseCompleted
+	if len(r.watchErrors) != 0 {
+		phase = v1alpha1.GenericControllerStatusPhaseError
+	}
+	return v1alpha1.GenericControllerStatus{
+		Phase:      phase,
+		Conditions: conditions,
+	}
+}
+
+// NewWatchControllerInitErrorStatus returns the GenericController
+// status corresponding to the provided initialization error
+func NewWatchControllerInitErrorStatus(err error) v1alpha1.GenericControllerStatus {
+	return v1alpha1.GenericControllerStatus{
+		Phase: v1alpha1.GenericControllerStatusPhaseError,
+		Conditions: []v1alpha1.GenericControllerCondition{
+			{
+				ID:      ConditionIDWatchControllerInit,
+				State:   statePtr(v1alpha1.GenericControllerConditionStateError),
+				Assert:  assertPtr(v1alpha1.GenericControllerConditionAssertFailed),
+				Message: "Failed to initialize watch controller",
+				Error:   err.Error(),
+				Help:    "Verify if watch & attachment resources are served by the cluster",
+			},
+		},
+	}
+}
+
+// MergeGenericControllerStatus returns the desired status after
+// retaining the timestamps of unchanged conditions from the observed
+// status. Conditions that are new or have changed are set with the
+// provided timestamp.
+func MergeGenericControllerStatus(
+	observed v1alpha1.GenericControllerStatus,
+	desired v1alpha1.GenericControllerStatus,
+	now metav1.Time,
+) v1alpha1.GenericControllerStatus {
+	observedConds := make(map[string]v1alpha1.GenericControllerCondition)
+	for _, cond := range observed.Conditions {
+		observedConds[cond.ID] = cond
+	}
+	merged := v1alpha1.GenericControllerStatus{
+		Phase: desired.Phase,
+	}
+	for _, cond := range desired.Conditions {
+		old, found := observedConds[cond.ID]
+		if found && isConditionEqualIgnoreTime(old, cond) {
+			cond.LastUpdatedTimestamp = old.LastUpdatedTimestamp
+		} else {
+			ts := now
+			cond.LastUpdatedTimestamp = &ts
+		}
+		merged.Conditions = append(merged.Conditions, cond)
+	}
+	return merged
+}
+
+// isConditionEqualIgnoreTime returns true if the provided conditions
+// are equal without considering their timestamps
+func isConditionEqualIgnoreTime(a, b v1alpha1.GenericControllerCondition) bool {
+	a.LastUpdatedTimestamp = nil
+	b.LastUpdatedTimestamp = nil
+	return apiequality.Semantic.DeepEqual(a, b)
+}
+
+func statePtr(
+	state v1alpha1.GenericControllerConditionState,
+) *v1alpha1.GenericControllerConditionState {
+	return &state
+}
+
+func assertPtr(
+	assert v1alpha1.GenericControllerConditionAssert,
+) *v1alpha1.GenericControllerConditionAssert {
+	return &assert
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generic
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+func TestWatchStatusRecorderGetStatus(t *testing.T) {
+	var tests = map[string]struct {
+		informersSynced bool
+		watchErrors     map[string]error
+		expectPhase     v1alpha1.GenericControllerStatusPhase
+		expectCondIDs   []string
+	}{
+		"informers not synced": {
+			expectPhase:   v1alpha1.GenericControllerStatusPhaseCompleted,
+			expectCondIDs: []string{ConditionIDInformersSynced},
+		},
+		"informers synced & no errors": {
+			informersSynced: true,
+			expectPhase:     v1alpha1.GenericControllerStatusPhaseCompleted,
+			expectCondIDs:   []string{ConditionIDInformersSynced},
+		},
+		"informers synced & hook error & apply error": {
+			informersSynced: true,
+			watchErrors: map[string]error{
+				"v1:Pod:ns:p2": withWatchSyncReason(
+					WatchSyncReasonApplyFailed, errors.Errorf("apply"),
+				),
+				"v1:Pod:ns:p1": withWatchSyncReason(
+					WatchSyncReasonHookFailed, errors.Errorf("hook"),
+				),
+			},
+			expectPhase: v1alpha1.GenericControllerStatusPhaseError,
+			expectCondIDs: []string{
+				ConditionIDInformersSynced,
+				"HookFailed:v1:Pod:ns:p1",
+				"ApplyFailed:v1:Pod:ns:p2",
+			},
+		},
+		"untagged error": {
+			informersSynced: true,
+			watchErrors: map[string]error{
+				"v1:Pod:ns:p1": errors.Errorf("oops"),
+			},
+			expectPhase: v1alpha1.GenericControllerStatusPhaseError,
+			expectCondIDs: []string{
+				ConditionIDInformersSynced,
+				"SyncFailed:v1:Pod:ns:p1",
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			r := newWatchStatusRecorder()
+			r.SetInformersSynced(mock.informersSynced)
+			for key, err := range mock.watchErrors {
+				r.RecordWatchSync(key, err)
+			}
+			got := r.GetStatus()
+			if got.Phase != mock.expectPhase {
+				t.Fatalf("Expected phase %q got %q", mock.expectPhase, got.Phase)
+			}
+			if len(got.Conditions) != len(mock.expectCondIDs) {
+				t.Fatalf(
+					"Expected conditions %d got %d: %+v",
+					len(mock.expectCondIDs),
+					len(got.Conditions),
+					got.Conditions,
+				)
+			}
+			for i, id := range mock.expectCondIDs {
+				if got.Conditions[i].ID != id {
+					t.Fatalf(
+						"Expected condition %q at %d got %q",
+						id,
+						i,
+						got.Conditions[i].ID,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestWatchStatusRecorderRecordWatchSync(t *testing.T) {
+	var changes int
+	r := newWatchStatusRecorder()
+	r.onChange = func() {
+		changes++
+	}
+	r.RecordWatchSync("k1", nil)
+	if changes != 0 {
+		t.Fatalf("Expected no change on success without prior error")
+	}
+	r.RecordWatchSync("k1", errors.Errorf("err"))
+	r.RecordWatchSync("k1", errors.Errorf("err"))
+	if changes != 1 {
+		t.Fatalf("Expected 1 change for same error got %d", changes)
+	}
+	r.RecordWatchSync("k1", nil)
+	if changes != 2 {
+		t.Fatalf("Expected 2 changes after error is cleared got %d", changes)
+	}
+	for i := 0; i < maxWatchErrorConditions+5; i++ {
+		r.RecordWatchSync(fmt.Sprintf("k%02d", i), errors.Errorf("err"))
+	}
+	got := r.GetStatus()
+	// informers condition + max error conditions + summary condition
+	if len(got.Conditions) != maxWatchErrorConditions+2 {
+		t.Fatalf(
+			"Expected %d conditions got %d",
+			maxWatchErrorConditions+2,
+			len(got.Conditions),
+		)
+	}
+	last := got.Conditions[len(got.Conditions)-1]
+	if last.ID != ConditionIDMoreWatchErrors {
+		t.Fatalf("Expected last condition %q got %q", ConditionIDMoreWatchErrors, last.ID)
+	}
+}
+
+func TestMergeGenericControllerStatus(t *testing.T) {
+	old := metav1.NewTime(time.Now().Add(-1 * time.Hour))
+	now := metav1.Now()
+	observed := v1alpha1.GenericControllerStatus{
+		Phase: v1alpha1.GenericControllerStatusPhaseError,
+		Conditions: []v1alpha1.GenericControllerCondition{
+			{
+				ID:                   "same",
+				Message:              "hi",
+				LastUpdatedTimestamp: &old,
+			},
+			{
+				ID:                   "changed",
+				Message:              "hi",
+				LastUpdatedTimestamp: &old,
+			},
+			{
+				ID:                   "removed",
+				LastUpdatedTimestamp: &old,
+			},
+		},
+	}
+	desired := v1alpha1.GenericControllerStatus{
+		Phase: v1alpha1.GenericControllerStatusPhaseCompleted,
+		Conditions: []v1alpha1.GenericControllerCondition{
+			{ID: "same", Message: "hi"},
+			{ID: "changed", Message: "there"},
+			{ID: "new"},
+		},
+	}
+	got := MergeGenericControllerStatus(observed, desired, now)
+	if got.Phase != desired.Phase {
+		t.Fatalf("Expected phase %q got %q", desired.Phase, got.Phase)
+	}
+	var expect = map[string]metav1.Time{
+		"same":    old,
+		"changed": now,
+		"new":     now,
+	}
+	if len(got.Conditions) != len(expect) {
+		t.Fatalf("Expected conditions %d got %d", len(expect), len(got.Conditions))
+	}
+	for _, cond := range got.Conditions {
+		want := expect[cond.ID]
+		if !cond.LastUpdatedTimestamp.Equal(&want) {
+			t.Fatalf(
+				"Expected timestamp %s for %q got %s",
+				want,
+				cond.ID,
+				cond.LastUpdatedTimestamp,
+			)
+		}
+	}
+}
@@ -34,6 +34,10 @@ type attachmentUpdateStrategyManager struct {
 	// defaultMethod if any attachment does not have
 	// an update strategy set
 	defaultMethod v1alpha1.ChildUpdateMethod
+
+	// fieldManager is the default field manager of attachments
+	// that are server side applied
+	fieldManager string
 }
 
 // String implements Stringer interface
@@ -53,6 +57,7 @@ func makeUpdateStrategyKeyFromGK(apiGroup, kind string) string {
 func newAttachmentUpdateStrategyManager(
 	resourceMgr *dynamicdiscovery.APIResourceDiscovery,
 	attachments []v1alpha1.GenericControllerAttachment,
+	fieldManager string,
 ) (*attachmentUpdateStrategyManager, error) {
 	// create a new instance of attachmentUpdateStrategyManager
 	mgr := &attachmentUpdateStrategyManager{
@@ -60,6 +65,7 @@ func newAttachmentUpdateStrategyManager(
 			map[string]*v1alpha1.GenericControllerAttachmentUpdateStrategy,
 		),
 		defaultMethod: v1alpha1.ChildUpdateOnDelete,
+		fieldManager:  fieldManager,
 	}
 	// init the strategies
 	for _, attachment := range attachments {
@@ -120,3 +126,36 @@ func (mgr attachmentUpdateStrategyManager) IsPatchByGK(apiGroup, kind string) bo
 	}
 	return *strategy.Patch
 }
+
+// GetRollingStrategyByGK returns the rolling update strategy of
+// attachments based on the given api group & kind
+func (mgr attachmentUpdateStrategyManager) GetRollingStrategyByGK(
+	apiGroup, kind string,
+) common.RollingUpdateStrategy {
+	strategy := mgr.getStrategyByGK(apiGroup, kind)
+	if strategy == nil {
+		return common.RollingUpdateStrategy{}
+	}
+	rolling := common.RollingUpdateStrategy{
+		StatusChecks: &strategy.StatusChecks,
+	}
+	if strategy.MaxUnavailable != nil {
+		rolling.MaxUnavailable = int(*strategy.MaxUnavailable)
+	}
+	return rolling
+}
+
+// GetServerSideApplyOptionsByGK returns the server side apply
+// options of attachments based on the given api group & kind
+func (mgr attachmentUpdateStrategyManager) GetServerSideApplyOptionsByGK(
+	apiGroup, kind string,
+) common.ServerSideApplyOptions {
+	strategy := mgr.getStrategyByGK(apiGroup, kind)
+	if strategy == nil {
+		return common.MakeServerSideApplyOptions(nil, mgr.fieldManager)
+	}
+	return common.MakeServerSideApplyOptions(
+		strategy.ServerSideApply,
+		mgr.fieldManager,
+	)
+}
@@ -0,0 +1,903 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/hooks"
+	"openebs.io/metac/metrics"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+const (
+	// mapKeyLabel is set against every output to remember the
+	// input that resulted in this output
+	mapKeyLabel = "metac.openebs.io/map-key"
+)
+
+// mapController maps the inputs selected by a parent to the
+// outputs owned by this parent
+type mapController struct {
+	schema *v1alpha1.MapController
+
+	resourceManager *dynamicdiscovery.APIResourceDiscovery
+	parentResource  *dynamicdiscovery.APIResource
+
+	dynCliSet    *dynamicclientset.Clientset
+	parentClient *dynamicclientset.ResourceClient
+
+	stopCh, doneCh chan struct{}
+	queue          workqueue.RateLimitingInterface
+
+	// the strategy to follow to update the outputs
+	updateStrategy updateStrategyMap
+
+	// resource names of inputs & outputs
+	resourceNames resourceNames
+
+	// informers of parent, input & output resources
+	parentInformer  *dynamicinformer.ResourceInformer
+	inputInformers  common.ResourceInformerRegistrar
+	outputInformers common.ResourceInformerRegistrar
+
+	// last known map hook responses
+	mapHooks *mapHookCache
+
+	// decides what the hooks of this controller are allowed to do
+	hookScope common.HookScope
+}
+
+// newMapController returns a new instance of map controller
+// with required parent, input & output informers
+func newMapController(
+	resourceMgr *dynamicdiscovery.APIResourceDiscovery,
+	dynCliSet *dynamicclientset.Clientset,
+	informerFactory *dynamicinformer.SharedInformerFactory,
+	schema *v1alpha1.MapController,
+	hookScope common.HookScope,
+) (controller *mapController, newErr error) {
+	if schema.Spec.Hooks == nil || schema.Spec.Hooks.Map == nil {
+		return nil, errors.Errorf(
+			"MapController %s: Map hook is required",
+			schema.Name,
+		)
+	}
+	// reject the hooks that are not allowed in this scope
+	err := common.ValidateHooks(
+		hookScope,
+		schema.Spec.Hooks.Map,
+		schema.Spec.Hooks.Tombstone,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err, "Invalid hooks: MapController %s", schema.Name,
+		)
+	}
+
+	parentClient, err := dynCliSet.GetClientForAPIVersionAndResource(
+		schema.Spec.ParentResource.APIVersion,
+		schema.Spec.ParentResource.Resource,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	updateStrategy, err := makeUpdateStrategyMap(resourceMgr, schema)
+	if err != nil {
+		return nil, err
+	}
+	names, err := makeResourceNames(resourceMgr, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &mapController{
+		schema:          schema,
+		resourceManager: resourceMgr,
+		parentResource:  parentClient.APIResource,
+		dynCliSet:       dynCliSet,
+		parentClient:    parentClient,
+		updateStrategy:  updateStrategy,
+		resourceNames:   names,
+		inputInformers:  make(common.ResourceInformerRegistrar),
+		outputInformers: make(common.ResourceInformerRegistrar),
+		mapHooks:        newMapHookCache(),
+		hookScope:       hookScope,
+		queue: workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(),
+			metrics.QueueName(metrics.Controller{
+				Kind: metrics.KindMapController,
+				Name: schema.Name,
+			}),
+		),
+	}
+
+	// close the successfully created informers in-case of
+	// any errors during initialization
+	defer func() {
+		if newErr != nil {
+			// Stop() will never be called
+			for _, informer := range c.outputInformers {
+				informer.Close()
+			}
+			for _, informer := range c.inputInformers {
+				informer.Close()
+			}
+			if c.parentInformer != nil {
+				c.parentInformer.Close()
+			}
+		}
+	}()
+
+	c.parentInformer, err = informerFactory.GetOrCreate(
+		schema.Spec.ParentResource.APIVersion,
+		schema.Spec.ParentResource.Resource,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"can't create informer for parent resource %q %q",
+			schema.Spec.ParentResource.APIVersion,
+			schema.Spec.ParentResource.Resource,
+		)
+	}
+	for _, input := range schema.Spec.InputResources {
+		informer, err := informerFactory.GetOrCreate(input.APIVersion, input.Resource)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"can't create informer for input resource %q %q",
+				input.APIVersion,
+				input.Resource,
+			)
+		}
+		c.inputInformers.Set(input.APIVersion, input.Resource, informer)
+	}
+	for _, output := range schema.Spec.OutputResources {
+		informer, err := informerFactory.GetOrCreate(output.APIVersion, output.Resource)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"can't create informer for output resource %q %q",
+				output.APIVersion,
+				output.Resource,
+			)
+		}
+		c.outputInformers.Set(output.APIVersion, output.Resource, informer)
+	}
+
+	return c, nil
+}
+
+// String implements Stringer interface
+func (c *mapController) String() string {
+	return fmt.Sprintf("MapController %s for %s", c.schema.Name, c.parentResource.Kind)
+}
+
+// metricsController returns this controller as a metrics label
+func (c *mapController) metricsController() metrics.Controller {
+	return metrics.Controller{
+		Kind: metrics.KindMapController,
+		Name: c.schema.Name,
+	}
+}
+
+// Start starts the map controller with the provided number
+// of workers
+func (c *mapController) Start(workerCount int) {
+	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+
+	// Install event handlers. MapControllers can be created at
+	// any time, so we have to assume the shared informers are
+	// already running.
+	parentHandlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueParentObject,
+		UpdateFunc: c.updateParentObject,
+		DeleteFunc: c.deleteParentObject,
+	}
+	if c.schema.Spec.ResyncPeriodSeconds != nil {
+		// Use a custom resync period if requested. This only
+		// applies to the parent.
+		resyncPeriod := time.Duration(*c.schema.Spec.ResyncPeriodSeconds) * time.Second
+		// Put a reasonable limit on it.
+		if resyncPeriod < time.Second {
+			resyncPeriod = time.Second
+		}
+		c.parentInformer.Informer().AddEventHandlerWithResyncPeriod(
+			parentHandlers,
+			resyncPeriod,
+		)
+	} else {
+		c.parentInformer.Informer().AddEventHandler(parentHandlers)
+	}
+	for _, informer := range c.inputInformers {
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onInputAdd,
+			UpdateFunc: c.onInputUpdate,
+			DeleteFunc: c.onInputDelete,
+		})
+	}
+	for _, informer := range c.outputInformers {
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onOutputAdd,
+			UpdateFunc: c.onOutputUpdate,
+			DeleteFunc: c.onOutputDelete,
+		})
+	}
+
+	if workerCount <= 0 {
+		workerCount = 5
+	}
+
+	go func() {
+		defer close(c.doneCh)
+		defer utilruntime.HandleCrash()
+
+		glog.Infof("Starting %s", c)
+		defer glog.Infof("Shutting down %s", c)
+
+		syncFuncs := []cache.InformerSynced{
+			c.dynCliSet.HasSynced,
+			c.parentInformer.Informer().HasSynced,
+		}
+		for _, informer := range c.inputInformers {
+			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
+		}
+		for _, informer := range c.outputInformers {
+			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
+		}
+		if !k8s.WaitForCacheSync(c.String(), c.stopCh, syncFuncs...) {
+			// We wait forever unless Stop() is called, so this
+			// isn't an error.
+			glog.Warningf("%s cache sync never finished", c)
+			return
+		}
+
+		glog.V(5).Infof("Starting %d workers for %s", workerCount, c)
+		var wg sync.WaitGroup
+		for i := 0; i < workerCount; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				wait.Until(c.worker, time.Second, c.stopCh)
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// Stop stops the map controller & releases its informers
+func (c *mapController) Stop() {
+	close(c.stopCh)
+	c.queue.ShutDown()
+	<-c.doneCh
+
+	for _, informer := range c.outputInformers {
+		informer.Informer().RemoveEventHandlers()
+		informer.Close()
+	}
+	for _, informer := range c.inputInformers {
+		informer.Informer().RemoveEventHandlers()
+		informer.Close()
+	}
+	c.parentInformer.Informer().RemoveEventHandlers()
+	c.parentInformer.Close()
+}
+
+func (c *mapController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+// processNextWorkItem reconciles the current queue item i.e.
+// the parent resource
+func (c *mapController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	start := time.Now()
+	err := c.sync(key.(string))
+	metrics.RecordSync(c.metricsController(), start, err)
+	if err != nil {
+		utilruntime.HandleError(
+			errors.Wrapf(err, "%s: failed to sync %q", c, key),
+		)
+		// NOTE:
+		//	structured hook errors may defer or skip the requeue
+		common.RequeueAfterSyncError(c.queue, key, err)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *mapController) enqueueParentObject(obj interface{}) {
+	key, err := common.KeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(
+			errors.Errorf("couldn't get key for object %+v: %v", obj, err),
+		)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *mapController) enqueueParentObjectAfter(obj interface{}, delay time.Duration) {
+	key, err := common.KeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(
+			errors.Errorf("couldn't get key for object %+v: %v", obj, err),
+		)
+		return
+	}
+	c.queue.AddAfter(key, delay)
+}
+
+func (c *mapController) updateParentObject(old, cur interface{}) {
+	c.enqueueParentObject(cur)
+}
+
+// deleteParentObject enqueues the deleted parent after
+// forgetting its map hook responses
+func (c *mapController) deleteParentObject(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if parent, ok := obj.(*unstructured.Unstructured); ok {
+		c.mapHooks.forget(parent.GetUID())
+	}
+	c.enqueueParentObject(obj)
+}
+
+// onInputAdd enqueues all the parents that select the provided
+// input
+func (c *mapController) onInputAdd(obj interface{}) {
+	input, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	var parents []*unstructured.Unstructured
+	var err error
+	if c.parentResource.Namespaced && input.GetNamespace() != "" {
+		parents, err = c.parentInformer.Lister().ListNamespace(
+			input.GetNamespace(),
+			labels.Everything(),
+		)
+	} else {
+		parents, err = c.parentInformer.Lister().List(labels.Everything())
+	}
+	if err != nil {
+		utilruntime.HandleError(
+			errors.Wrapf(err, "%s: can't list parents", c),
+		)
+		return
+	}
+	for _, parent := range parents {
+		selected, err := selectsInput(parent, input)
+		if err != nil {
+			glog.V(4).Infof("%s: %v", c, err)
+			continue
+		}
+		if !selected {
+			continue
+		}
+		glog.V(4).Infof(
+			"%s: parent %s/%s: input %s %s/%s changed",
+			c,
+			parent.GetNamespace(),
+			parent.GetName(),
+			input.GetKind(),
+			input.GetNamespace(),
+			input.GetName(),
+		)
+		c.enqueueParentObject(parent)
+	}
+}
+
+func (c *mapController) onInputUpdate(old, cur interface{}) {
+	oldInput := old.(*unstructured.Unstructured)
+	curInput := cur.(*unstructured.Unstructured)
+
+	// We don't care about resyncs for inputs; we rely on the
+	// parent resync.
+	if oldInput.GetResourceVersion() == curInput.GetResourceVersion() {
+		return
+	}
+	// Parents that no longer select this input need to drop
+	// the outputs of this input
+	c.onInputAdd(old)
+	c.onInputAdd(cur)
+}
+
+func (c *mapController) onInputDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c.onInputAdd(obj)
+}
+
+// onOutputAdd enqueues the parent of the provided output
+func (c *mapController) onOutputAdd(obj interface{}) {
+	output, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	controllerRef := metav1.GetControllerOf(output)
+	if controllerRef == nil {
+		return
+	}
+	parent := c.resolveControllerRef(output.GetNamespace(), controllerRef)
+	if parent == nil {
+		// The controllerRef isn't a parent we know about.
+		return
+	}
+	c.enqueueParentObject(parent)
+}
+
+func (c *mapController) onOutputUpdate(old, cur interface{}) {
+	oldOutput := old.(*unstructured.Unstructured)
+	curOutput := cur.(*unstructured.Unstructured)
+
+	// We don't care about resyncs for outputs; we rely on the
+	// parent resync.
+	if oldOutput.GetResourceVersion() == curOutput.GetResourceVersion() {
+		return
+	}
+	c.onOutputAdd(cur)
+}
+
+func (c *mapController) onOutputDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c.onOutputAdd(obj)
+}
+
+// resolveControllerRef returns the parent referenced by the
+// provided controller reference. It returns nil if the reference
+// could not be resolved to a parent of this controller.
+func (c *mapController) resolveControllerRef(
+	outputNamespace string,
+	controllerRef *metav1.OwnerReference,
+) *unstructured.Unstructured {
+	// We can't look up by UID, so look up by Name and then
+	// verify UID. Don't even try to look up by Name if it's
+	// the wrong APIGroup or Kind.
+	apiGroup, _ := common.ParseAPIVersionToGroupVersion(controllerRef.APIVersion)
+	if apiGroup != c.parentResource.Group {
+		return nil
+	}
+	if controllerRef.Kind != c.parentResource.Kind {
+		return nil
+	}
+	parentNamespace := ""
+	if c.parentResource.Namespaced {
+		// controllerRef does not support cross-namespace references
+		parentNamespace = outputNamespace
+	}
+	parent, err := c.parentInformer.Lister().Get(parentNamespace, controllerRef.Name)
+	if err != nil {
+		return nil
+	}
+	if parent.GetUID() != controllerRef.UID {
+		return nil
+	}
+	return parent
+}
+
+func (c *mapController) sync(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	glog.V(4).Infof("%s: sync %s/%s", c, namespace, name)
+
+	parent, err := c.parentInformer.Lister().Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		// Swallow the error since there's no point retrying if
+		// parent is gone. Its outputs are garbage collected.
+		glog.V(4).Infof("%s: parent %s/%s has been deleted", c, namespace, name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.syncParentObject(parent)
+}
+
+// syncParentObject maps each input selected by the provided
+// parent to its outputs & reconciles these outputs
+func (c *mapController) syncParentObject(parent *unstructured.Unstructured) error {
+	if parent.GetDeletionTimestamp() != nil {
+		// outputs are garbage collected along with the parent
+		return nil
+	}
+
+	inputs, err := c.getInputs(parent)
+	if err != nil {
+		return err
+	}
+	observedOutputs, err := c.getOutputs(parent)
+	if err != nil {
+		return err
+	}
+	observedByMapKey := groupByMapKey(parent, observedOutputs)
+
+	// Only the observed outputs that are managed in this sync
+	// are updated or deleted. Outputs of a failed map or
+	// tombstone hook are left as is.
+	managedOutputs := make(common.AnyUnstructRegistry)
+	desiredOutputs := make(common.AnyUnstructRegistry)
+	mapKeys := make(map[string]bool)
+
+	var hookErrs []error
+	for _, input := range inputs {
+		mapKey := string(input.GetUID())
+		mapKeys[mapKey] = true
+
+		outputs := observedByMapKey[mapKey]
+		if outputs == nil {
+			outputs = make(common.AnyUnstructRegistry)
+		}
+		resp, err := c.mapInput(parent, mapKey, input, outputs)
+		if err != nil {
+			hookErrs = append(hookErrs, errors.Wrapf(
+				err,
+				"%s: input %s %s/%s",
+				c,
+				input.GetKind(),
+				input.GetNamespace(),
+				input.GetName(),
+			))
+			continue
+		}
+		for _, out := range outputs.List() {
+			managedOutputs.InsertByReference(parent, out)
+		}
+		for _, out := range resp.Outputs {
+			lbls := out.GetLabels()
+			if lbls == nil {
+				lbls = make(map[string]string)
+			}
+			lbls[mapKeyLabel] = mapKey
+			out.SetLabels(lbls)
+			desiredOutputs.InsertByReference(parent, out)
+		}
+		if resp.ResyncAfterSeconds > 0 {
+			c.enqueueParentObjectAfter(
+				parent,
+				time.Duration(resp.ResyncAfterSeconds*float64(time.Second)),
+			)
+		}
+	}
+	c.mapHooks.retain(parent.GetUID(), mapKeys)
+
+	// Outputs whose input is gone are detached. These are deleted
+	// unless the tombstone hook wants to keep them.
+	for mapKey, outputs := range observedByMapKey {
+		if mapKeys[mapKey] {
+			continue
+		}
+		keep, err := c.keepDetachedOutputs(parent, mapKey, outputs)
+		if err != nil {
+			hookErrs = append(hookErrs, errors.Wrapf(
+				err,
+				"%s: map key %q",
+				c,
+				mapKey,
+			))
+			continue
+		}
+		for _, out := range outputs.List() {
+			if keep[out.GetUID()] {
+				continue
+			}
+			managedOutputs.InsertByReference(parent, out)
+		}
+	}
+
+	// Reconcile the outputs. Remember the manage error, but
+	// continue to update status regardless.
+	var syncErr error
+	manageErr := common.ManageChildren(
+		c.dynCliSet,
+		c.updateStrategy,
+		nil,
+		parent,
+		managedOutputs,
+		desiredOutputs,
+	)
+	if manageErr != nil {
+		syncErr = errors.Wrapf(
+			manageErr,
+			"%s: can't reconcile outputs of %s/%s",
+			c,
+			parent.GetNamespace(),
+			parent.GetName(),
+		)
+	}
+	if len(hookErrs) != 0 {
+		for _, err := range hookErrs[1:] {
+			utilruntime.HandleError(err)
+		}
+		// first hook error decides the requeue of this parent
+		syncErr = hookErrs[0]
+	}
+
+	status := aggregateStatus(c.schema, c.resourceNames, inputs, observedOutputs)
+	if err := c.updateParentStatus(parent, status, syncErr); err != nil {
+		if syncErr != nil {
+			glog.Errorf("%s: can't update status of %s/%s: %v",
+				c,
+				parent.GetNamespace(),
+				parent.GetName(),
+				err,
+			)
+			return syncErr
+		}
+		return errors.Wrapf(
+			err,
+			"%s: can't update status of %s/%s",
+			c,
+			parent.GetNamespace(),
+			parent.GetName(),
+		)
+	}
+	return syncErr
+}
+
+// mapInput returns the desired outputs of the provided input.
+// Map hook is invoked only if the cached response if any is
+// stale.
+func (c *mapController) mapInput(
+	parent *unstructured.Unstructured,
+	mapKey string,
+	input *unstructured.Unstructured,
+	outputs common.AnyUnstructRegistry,
+) (*MapHookResponse, error) {
+	fp := fingerprint(parent, input, outputs)
+	if resp, found := c.mapHooks.get(parent.GetUID(), mapKey, fp); found {
+		glog.V(6).Infof(
+			"%s: using cached map hook response for input %s %s/%s",
+			c,
+			input.GetKind(),
+			input.GetNamespace(),
+			input.GetName(),
+		)
+		return resp, nil
+	}
+	resp, err := c.callMapHook(&MapHookRequest{
+		Controller: c.schema,
+		Parent:     parent,
+		MapKey:     mapKey,
+		Input:      input,
+		Outputs:    outputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.mapHooks.set(parent.GetUID(), mapKey, fp, resp)
+	return resp, nil
+}
+
+// keepDetachedOutputs returns the detached outputs that should
+// not be deleted
+func (c *mapController) keepDetachedOutputs(
+	parent *unstructured.Unstructured,
+	mapKey string,
+	outputs common.AnyUnstructRegistry,
+) (map[types.UID]bool, error) {
+	if c.schema.Spec.Hooks.Tombstone == nil {
+		// delete all the detached outputs
+		return nil, nil
+	}
+	resp, err := c.callTombstoneHook(&TombstoneHookRequest{
+		Controller: c.schema,
+		Parent:     parent,
+		MapKey:     mapKey,
+		Outputs:    outputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[types.UID]bool)
+	for _, out := range resp.Outputs {
+		// outputs are identified by their relative names since
+		// hook may not return the uid
+		kept := make(common.AnyUnstructRegistry)
+		kept.InsertByReference(parent, out)
+		for key, group := range kept {
+			for name := range group {
+				if observed := outputs[key][name]; observed != nil {
+					keep[observed.GetUID()] = true
+				}
+			}
+		}
+	}
+	return keep, nil
+}
+
+// getInputs returns the inputs selected by the provided parent
+func (c *mapController) getInputs(
+	parent *unstructured.Unstructured,
+) ([]*unstructured.Unstructured, error) {
+	var inputs []*unstructured.Unstructured
+	for _, rule := range c.schema.Spec.InputResources {
+		informer := c.inputInformers.Get(rule.APIVersion, rule.Resource)
+		if informer == nil {
+			return nil, errors.Errorf(
+				"%s: no informer for input resource %q %q",
+				c,
+				rule.APIVersion,
+				rule.Resource,
+			)
+		}
+		var all []*unstructured.Unstructured
+		var err error
+		resource := c.resourceManager.GetAPIForAPIVersionAndResource(
+			rule.APIVersion,
+			rule.Resource,
+		)
+		if resource == nil {
+			return nil, errors.Errorf(
+				"%s: can't find input resource %q %q",
+				c,
+				rule.APIVersion,
+				rule.Resource,
+			)
+		}
+		if parent.GetNamespace() != "" && resource.Namespaced {
+			all, err = informer.Lister().ListNamespace(
+				parent.GetNamespace(),
+				labels.Everything(),
+			)
+		} else {
+			all, err = informer.Lister().List(labels.Everything())
+		}
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"%s: can't list input resource %q %q",
+				c,
+				rule.APIVersion,
+				rule.Resource,
+			)
+		}
+		for _, input := range all {
+			selected, err := selectsInput(parent, input)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s", c)
+			}
+			if selected {
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	return inputs, nil
+}
+
+// getOutputs returns the outputs owned by the provided parent
+func (c *mapController) getOutputs(
+	parent *unstructured.Unstructured,
+) (common.AnyUnstructRegistry, error) {
+	outputs := make(common.AnyUnstructRegistry)
+	for _, rule := range c.schema.Spec.OutputResources {
+		informer := c.outputInformers.Get(rule.APIVersion, rule.Resource)
+		if informer == nil {
+			return nil, errors.Errorf(
+				"%s: no informer for output resource %q %q",
+				c,
+				rule.APIVersion,
+				rule.Resource,
+			)
+		}
+		var all []*unstructured.Unstructured
+		var err error
+		if parent.GetNamespace() != "" {
+			all, err = informer.Lister().ListNamespace(
+				parent.GetNamespace(),
+				labels.Everything(),
+			)
+		} else {
+			all, err = informer.Lister().List(labels.Everything())
+		}
+		if err != nil {
+			return nil, errors.Wrapf(
+				err,
+				"%s: can't list output resource %q %q",
+				c,
+				rule.APIVersion,
+				rule.Resource,
+			)
+		}
+		for _, obj := range all {
+			controllerRef := metav1.GetControllerOf(obj)
+			if controllerRef == nil || controllerRef.UID != parent.GetUID() {
+				continue
+			}
+			outputs.InsertByReference(parent, obj)
+		}
+	}
+	return outputs, nil
+}
+
+// updateParentStatus sets the aggregated status against the
+// provided parent without touching other status fields
+func (c *mapController) updateParentStatus(
+	parent *unstructured.Unstructured,
+	aggregated map[string]interface{},
+	syncErr error,
+) error {
+	hookErr, isHookErr := hooks.AsError(syncErr)
+	_, err := c.parentClient.Namespace(parent.GetNamespace()).AtomicStatusUpdate(
+		parent,
+		func(obj *unstructured.Unstructured) bool {
+			old, _, _ := unstructured.NestedMap(obj.UnstructuredContent(), "status")
+			var status map[string]interface{}
+			if isHookErr {
+				status = runtime.DeepCopyJSON(old)
+				if status == nil {
+					status = make(map[string]interface{})
+				}
+				dynamicobject.SetCondition(status, common.NewHookErrorCondition(hookErr))
+			} else if old != nil {
+				status = runtime.DeepCopyJSON(common.ResolveHookErrorCondition(old))
+			} else {
+				status = make(map[string]interface{})
+			}
+			for key, value := range aggregated {
+				status[key] = value
+			}
+			if reflect.DeepEqual(old, status) {
+				// Nothing to do.
+				return false
+			}
+			obj.UnstructuredContent()["status"] = status
+			return true
+		},
+	)
+	return err
+}
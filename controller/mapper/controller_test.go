@@ -0,0 +1,667 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+	dynamicinformer "openebs.io/metac/dynamic/informer"
+	dynamicobject "openebs.io/metac/dynamic/object"
+	"openebs.io/metac/hooks"
+	k8s "openebs.io/metac/third_party/kubernetes"
+)
+
+var (
+	parentGVR = schema.GroupVersionResource{Group: "test.io", Version: "v1", Resource: "parents"}
+	inputGVR  = schema.GroupVersionResource{Group: "test.io", Version: "v1", Resource: "inputs"}
+	outputGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+)
+
+// mapTestHooks serves the map & tombstone webhooks & records
+// their invocations
+//
+// NOTE:
+//	Map hook returns a config map per input with the input's
+// 'spec.value'. It fails for the inputs labelled 'fail=true'.
+// Tombstone hook keeps all the outputs if keep is true.
+type mapTestHooks struct {
+	keep bool
+
+	mutex          sync.Mutex
+	mapCalls       map[string]int
+	tombstoneCalls map[string]int
+}
+
+func (h *mapTestHooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/map":
+		var req MapHookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		h.mutex.Lock()
+		h.mapCalls[req.Input.GetName()]++
+		h.mutex.Unlock()
+
+		if req.Input.GetLabels()["fail"] == "true" {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(hooks.Error{
+				Reason:  "MapFailed",
+				Message: "Can't map " + req.Input.GetName(),
+			})
+			return
+		}
+		value, _, _ := unstructured.NestedString(req.Input.Object, "spec", "value")
+		out := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata": map[string]interface{}{
+					"name":      req.Input.GetName() + "-out",
+					"namespace": req.Parent.GetNamespace(),
+				},
+				"data": map[string]interface{}{
+					"value": value,
+				},
+			},
+		}
+		json.NewEncoder(w).Encode(MapHookResponse{
+			Outputs: []*unstructured.Unstructured{out},
+		})
+	case "/tombstone":
+		var req TombstoneHookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		h.mutex.Lock()
+		h.tombstoneCalls[req.MapKey]++
+		h.mutex.Unlock()
+
+		var resp TombstoneHookResponse
+		if h.keep {
+			resp.Outputs = req.Outputs.List()
+		}
+		json.NewEncoder(w).Encode(resp)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// calls returns the map & tombstone hook invocations since the
+// last call
+func (h *mapTestHooks) calls() (mapCalls, tombstoneCalls map[string]int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	mapCalls, tombstoneCalls = h.mapCalls, h.tombstoneCalls
+	h.mapCalls, h.tombstoneCalls = map[string]int{}, map[string]int{}
+	return mapCalls, tombstoneCalls
+}
+
+// mapTestFixture runs a map controller whose parent, inputs &
+// outputs are served by a fake dynamic client
+type mapTestFixture struct {
+	t *testing.T
+
+	hooks      *mapTestHooks
+	tracker    clienttesting.ObjectTracker
+	dynClient  *fakedynamic.FakeDynamicClient
+	controller *mapController
+
+	mutex   sync.Mutex
+	version int
+	watches map[schema.GroupVersionResource]bool
+}
+
+// newMapTestFixture returns a fixture with the provided parent &
+// inputs whose informers have synced. Returned function stops the
+// controller's informers & hooks.
+func newMapTestFixture(
+	t *testing.T,
+	withTombstone bool,
+	keep bool,
+	parent *unstructured.Unstructured,
+	inputs ...*unstructured.Unstructured,
+) (*mapTestFixture, func()) {
+	f := &mapTestFixture{
+		t: t,
+		hooks: &mapTestHooks{
+			keep:           keep,
+			mapCalls:       map[string]int{},
+			tombstoneCalls: map[string]int{},
+		},
+		watches: map[schema.GroupVersionResource]bool{},
+	}
+	srv := httptest.NewServer(f.hooks)
+
+	// fake client's own tracker is not accessible; hence objects
+	// are tracked here to know when the informers start watching
+	scheme := runtime.NewScheme()
+	f.dynClient = fakedynamic.NewSimpleDynamicClient(scheme)
+	f.tracker = clienttesting.NewObjectTracker(
+		scheme,
+		serializer.NewCodecFactory(scheme).UniversalDecoder(),
+	)
+	for _, obj := range append([]*unstructured.Unstructured{parent}, inputs...) {
+		if err := f.tracker.Add(obj); err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	f.dynClient.PrependReactor("*", "*", clienttesting.ObjectReaction(f.tracker))
+	f.dynClient.PrependReactor("*", "*", f.setMeta)
+	f.dynClient.PrependWatchReactor(
+		"*",
+		func(action clienttesting.Action) (bool, watch.Interface, error) {
+			w, err := f.tracker.Watch(action.GetResource(), action.GetNamespace())
+			if err != nil {
+				return true, nil, err
+			}
+			f.mutex.Lock()
+			f.watches[action.GetResource()] = true
+			f.mutex.Unlock()
+			return true, w, nil
+		},
+	)
+
+	discovery := dynamicdiscovery.NewAPIResourceDiscoverer(
+		&fakediscovery.FakeDiscovery{
+			Fake: &clienttesting.Fake{
+				Resources: []*metav1.APIResourceList{
+					{
+						GroupVersion: "test.io/v1",
+						APIResources: []metav1.APIResource{
+							{Name: "parents", Kind: "Parent", Namespaced: true},
+							{Name: "parents/status", Kind: "Parent", Namespaced: true},
+							{Name: "inputs", Kind: "Input", Namespaced: true},
+						},
+					},
+					{
+						GroupVersion: "v1",
+						APIResources: []metav1.APIResource{
+							{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+						},
+					},
+				},
+			},
+		},
+	)
+	discovery.Start(time.Hour)
+	for !discovery.HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	clientset := dynamicclientset.NewForDynamicClient(f.dynClient, discovery)
+
+	api := &v1alpha1.MapController{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.MapControllerSpec{
+			ParentResource: v1alpha1.MapControllerParentResourceRule{
+				ResourceRule: v1alpha1.ResourceRule{APIVersion: "test.io/v1", Resource: "parents"},
+			},
+			InputResources: []v1alpha1.MapControllerInputResourceRule{
+				{ResourceRule: v1alpha1.ResourceRule{APIVersion: "test.io/v1", Resource: "inputs"}},
+			},
+			OutputResources: []v1alpha1.MapControllerOutputResourceRule{
+				{
+					ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"},
+					UpdateStrategy: &v1alpha1.MapControllerOutputUpdateStrategy{
+						Method: v1alpha1.ChildUpdateInPlace,
+					},
+				},
+			},
+			Hooks: &v1alpha1.MapControllerHooks{
+				Map: &v1alpha1.Hook{
+					Webhook: &v1alpha1.Webhook{URL: k8s.StringPtr(srv.URL + "/map")},
+				},
+			},
+		},
+	}
+	if withTombstone {
+		api.Spec.Hooks.Tombstone = &v1alpha1.Hook{
+			Webhook: &v1alpha1.Webhook{URL: k8s.StringPtr(srv.URL + "/tombstone")},
+		}
+	}
+	var err error
+	f.controller, err = newMapController(
+		discovery,
+		clientset,
+		dynamicinformer.NewSharedInformerFactory(clientset, time.Hour),
+		api,
+		common.LocalHookScope,
+	)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	stop := func() {
+		f.controller.parentInformer.Close()
+		for _, informer := range f.controller.inputInformers {
+			informer.Close()
+		}
+		for _, informer := range f.controller.outputInformers {
+			informer.Close()
+		}
+		discovery.Stop()
+		srv.Close()
+	}
+
+	// changes made before the informers watch would be missed
+	f.waitFor("informers to sync", func() bool {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		return f.controller.parentInformer.Informer().HasSynced() &&
+			f.controller.inputInformers.Get("test.io/v1", "inputs").Informer().HasSynced() &&
+			f.controller.outputInformers.Get("v1", "configmaps").Informer().HasSynced() &&
+			f.watches[parentGVR] && f.watches[inputGVR] && f.watches[outputGVR]
+	})
+	return f, stop
+}
+
+// setMeta sets the uid of the created objects & the resource
+// version of the created & updated objects like an api server
+func (f *mapTestFixture) setMeta(
+	action clienttesting.Action,
+) (bool, runtime.Object, error) {
+	var obj runtime.Object
+	switch action := action.(type) {
+	case clienttesting.CreateAction:
+		obj = action.GetObject()
+	case clienttesting.UpdateAction:
+		obj = action.GetObject()
+	default:
+		return false, nil, nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return true, nil, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.version++
+	if action.GetVerb() == "create" {
+		accessor.SetUID(types.UID(fmt.Sprintf("uid-%d", f.version)))
+	}
+	accessor.SetResourceVersion(fmt.Sprintf("%d", f.version))
+	return false, nil, nil
+}
+
+// waitFor waits till the provided condition is true
+func (f *mapTestFixture) waitFor(desc string, cond func() bool) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			f.t.Fatalf("Timed out waiting for %s", desc)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sync syncs the test parent & waits till the informers observe
+// the resulting outputs & parent
+func (f *mapTestFixture) sync() error {
+	err := f.controller.sync("ns/test")
+	f.waitFor("informers to observe the sync", func() bool {
+		return reflect.DeepEqual(
+			f.list(outputGVR, "ns"),
+			f.listCached(f.controller.outputInformers.Get("v1", "configmaps")),
+		) && reflect.DeepEqual(
+			f.list(parentGVR, "ns"),
+			f.listCached(f.controller.parentInformer),
+		)
+	})
+	return err
+}
+
+// list returns the resource versions of the tracked objects
+// anchored by their names
+func (f *mapTestFixture) list(gvr schema.GroupVersionResource, ns string) map[string]string {
+	objs, err := f.dynClient.Resource(gvr).Namespace(ns).List(metav1.ListOptions{})
+	if err != nil {
+		f.t.Fatalf("Expected no error got [%+v]", err)
+	}
+	versions := map[string]string{}
+	for _, obj := range objs.Items {
+		versions[obj.GetName()] = obj.GetResourceVersion()
+	}
+	return versions
+}
+
+// listCached returns the resource versions of the provided
+// informer's objects anchored by their names
+func (f *mapTestFixture) listCached(informer *dynamicinformer.ResourceInformer) map[string]string {
+	versions := map[string]string{}
+	for _, obj := range informer.Informer().GetStore().List() {
+		u := obj.(*unstructured.Unstructured)
+		versions[u.GetName()] = u.GetResourceVersion()
+	}
+	return versions
+}
+
+// update changes the 'spec.value' of the provided input & waits
+// till the input informer observes it
+func (f *mapTestFixture) update(name, value string) {
+	client := f.dynClient.Resource(inputGVR).Namespace("ns")
+	input, err := client.Get(name, metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatalf("Expected no error got [%+v]", err)
+	}
+	unstructured.SetNestedField(input.Object, value, "spec", "value")
+	updated, err := client.Update(input, metav1.UpdateOptions{})
+	if err != nil {
+		f.t.Fatalf("Expected no error got [%+v]", err)
+	}
+	f.waitForInputs(func(versions map[string]string) bool {
+		return versions[name] == updated.GetResourceVersion()
+	})
+}
+
+// delete deletes the provided input & waits till the input
+// informer observes it
+func (f *mapTestFixture) delete(name string) {
+	err := f.dynClient.Resource(inputGVR).Namespace("ns").Delete(name, nil)
+	if err != nil {
+		f.t.Fatalf("Expected no error got [%+v]", err)
+	}
+	f.waitForInputs(func(versions map[string]string) bool {
+		_, found := versions[name]
+		return !found
+	})
+}
+
+func (f *mapTestFixture) waitForInputs(cond func(map[string]string) bool) {
+	informer := f.controller.inputInformers.Get("test.io/v1", "inputs")
+	f.waitFor("input informer", func() bool {
+		return cond(f.listCached(informer))
+	})
+}
+
+// getOutput returns the tracked output config map of the provided
+// name or nil if not found
+func (f *mapTestFixture) getOutput(name string) *unstructured.Unstructured {
+	obj, err := f.tracker.Get(outputGVR, "ns", name)
+	if err != nil {
+		return nil
+	}
+	return obj.(*unstructured.Unstructured)
+}
+
+// getParent returns the tracked parent
+func (f *mapTestFixture) getParent() *unstructured.Unstructured {
+	obj, err := f.tracker.Get(parentGVR, "ns", "test")
+	if err != nil {
+		f.t.Fatalf("Expected no error got [%+v]", err)
+	}
+	return obj.(*unstructured.Unstructured)
+}
+
+func newMapTestParent() *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "test.io/v1",
+			"kind":       "Parent",
+			"metadata": map[string]interface{}{
+				"name":            "test",
+				"namespace":       "ns",
+				"uid":             "parent-uid",
+				"generation":      int64(1),
+				"resourceVersion": "1",
+			},
+		},
+	}
+}
+
+func newMapTestInput(name, value string, lbls map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "test.io/v1",
+			"kind":       "Input",
+			"metadata": map[string]interface{}{
+				"name":            name,
+				"namespace":       "ns",
+				"uid":             name + "-uid",
+				"resourceVersion": "1",
+			},
+			"spec": map[string]interface{}{
+				"value": value,
+			},
+		},
+	}
+	obj.SetLabels(lbls)
+	return obj
+}
+
+func TestMapControllerSyncCreatesOutputs(t *testing.T) {
+	f, stop := newMapTestFixture(
+		t,
+		false,
+		false,
+		newMapTestParent(),
+		newMapTestInput("in-1", "one", nil),
+		newMapTestInput("in-2", "two", nil),
+	)
+	defer stop()
+
+	if err := f.sync(); err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	mapCalls, _ := f.hooks.calls()
+	if !reflect.DeepEqual(mapCalls, map[string]int{"in-1": 1, "in-2": 1}) {
+		t.Fatalf("Expected map hook calls for in-1 & in-2 got %v", mapCalls)
+	}
+	for input, value := range map[string]string{"in-1": "one", "in-2": "two"} {
+		out := f.getOutput(input + "-out")
+		if out == nil {
+			t.Fatalf("Expected output of %s got none", input)
+		}
+		got, _, _ := unstructured.NestedString(out.Object, "data", "value")
+		if got != value {
+			t.Fatalf("Expected output value %q got %q", value, got)
+		}
+		if out.GetLabels()[mapKeyLabel] != input+"-uid" {
+			t.Fatalf(
+				"Expected map key %q got %q",
+				input+"-uid",
+				out.GetLabels()[mapKeyLabel],
+			)
+		}
+		controllerRef := metav1.GetControllerOf(out)
+		if controllerRef == nil || controllerRef.UID != "parent-uid" {
+			t.Fatalf("Expected output controlled by parent got %v", controllerRef)
+		}
+	}
+	total, _, _ := unstructured.NestedInt64(
+		f.getParent().Object, "status", "inputs", "inputs", "total",
+	)
+	if total != 2 {
+		t.Fatalf("Expected 2 inputs in status got %d", total)
+	}
+}
+
+func TestMapControllerSyncRemapsChangedInputOnly(t *testing.T) {
+	f, stop := newMapTestFixture(
+		t,
+		false,
+		false,
+		newMapTestParent(),
+		newMapTestInput("in-1", "one", nil),
+		newMapTestInput("in-2", "two", nil),
+		newMapTestInput("in-3", "three", nil),
+	)
+	defer stop()
+
+	// first sync creates the outputs & second one maps the
+	// inputs along with their observed outputs
+	for i := 0; i < 2; i++ {
+		if err := f.sync(); err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	f.hooks.calls()
+
+	if err := f.sync(); err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	mapCalls, _ := f.hooks.calls()
+	if len(mapCalls) != 0 {
+		t.Fatalf("Expected no map hook calls for unchanged inputs got %v", mapCalls)
+	}
+
+	f.update("in-2", "two-updated")
+	if err := f.sync(); err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	mapCalls, _ = f.hooks.calls()
+	if !reflect.DeepEqual(mapCalls, map[string]int{"in-2": 1}) {
+		t.Fatalf("Expected map hook call for in-2 only got %v", mapCalls)
+	}
+	got, _, _ := unstructured.NestedString(
+		f.getOutput("in-2-out").Object, "data", "value",
+	)
+	if got != "two-updated" {
+		t.Fatalf("Expected output value %q got %q", "two-updated", got)
+	}
+}
+
+func TestMapControllerSyncDetachedOutputs(t *testing.T) {
+	var tests = map[string]struct {
+		withTombstone  bool
+		keep           bool
+		isOutputKept   bool
+		tombstoneCalls map[string]int
+	}{
+		"no tombstone hook": {
+			isOutputKept:   false,
+			tombstoneCalls: map[string]int{},
+		},
+		"tombstone hook deletes": {
+			withTombstone:  true,
+			isOutputKept:   false,
+			tombstoneCalls: map[string]int{"in-2-uid": 1},
+		},
+		"tombstone hook keeps": {
+			withTombstone:  true,
+			keep:           true,
+			isOutputKept:   true,
+			tombstoneCalls: map[string]int{"in-2-uid": 1},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			f, stop := newMapTestFixture(
+				t,
+				mock.withTombstone,
+				mock.keep,
+				newMapTestParent(),
+				newMapTestInput("in-1", "one", nil),
+				newMapTestInput("in-2", "two", nil),
+			)
+			defer stop()
+
+			if err := f.sync(); err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			f.hooks.calls()
+
+			f.delete("in-2")
+			if err := f.sync(); err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			_, tombstoneCalls := f.hooks.calls()
+			if !reflect.DeepEqual(tombstoneCalls, mock.tombstoneCalls) {
+				t.Fatalf(
+					"Expected tombstone hook calls %v got %v",
+					mock.tombstoneCalls,
+					tombstoneCalls,
+				)
+			}
+			if f.getOutput("in-1-out") == nil {
+				t.Fatalf("Expected output of in-1 got none")
+			}
+			isKept := f.getOutput("in-2-out") != nil
+			if isKept != mock.isOutputKept {
+				t.Fatalf(
+					"Expected output of in-2 kept %t got %t",
+					mock.isOutputKept,
+					isKept,
+				)
+			}
+		})
+	}
+}
+
+func TestMapControllerSyncHookError(t *testing.T) {
+	f, stop := newMapTestFixture(
+		t,
+		false,
+		false,
+		newMapTestParent(),
+		newMapTestInput("in-1", "one", nil),
+		newMapTestInput("bad", "bad", map[string]string{"fail": "true"}),
+	)
+	defer stop()
+
+	err := f.sync()
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+	hookErr, ok := hooks.AsError(err)
+	if !ok || hookErr.Reason != "MapFailed" {
+		t.Fatalf("Expected hook error with reason MapFailed got [%+v]", err)
+	}
+	// other inputs are mapped regardless
+	if f.getOutput("in-1-out") == nil {
+		t.Fatalf("Expected output of in-1 got none")
+	}
+	if f.getOutput("bad-out") != nil {
+		t.Fatalf("Expected no output of failed input got one")
+	}
+	cond := dynamicobject.GetStatusCondition(
+		f.getParent().Object,
+		common.ConditionTypeHookError,
+	)
+	if cond == nil {
+		t.Fatalf("Expected hook error condition got none")
+	}
+	if cond.Status != "True" || cond.Reason != "MapFailed" {
+		t.Fatalf("Expected true condition with reason MapFailed got %+v", cond)
+	}
+}
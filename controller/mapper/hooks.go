@@ -0,0 +1,258 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+)
+
+// MapHookRequest is the object sent as JSON to the map hook
+type MapHookRequest struct {
+	Controller *v1alpha1.MapController    `json:"controller"`
+	Parent     *unstructured.Unstructured `json:"parent"`
+
+	// MapKey uniquely identifies the outputs of the input
+	MapKey string                     `json:"mapKey"`
+	Input  *unstructured.Unstructured `json:"input"`
+
+	// Outputs that were created earlier for this input
+	Outputs common.AnyUnstructRegistry `json:"outputs"`
+}
+
+// MapHookResponse is the expected format of the JSON response
+// from the map hook
+type MapHookResponse struct {
+	Outputs []*unstructured.Unstructured `json:"outputs"`
+
+	// ResyncAfterSeconds invokes the map hook again after these
+	// many seconds even if nothing has changed
+	ResyncAfterSeconds float64 `json:"resyncAfterSeconds,omitempty"`
+}
+
+// TombstoneHookRequest is the object sent as JSON to the
+// tombstone hook
+type TombstoneHookRequest struct {
+	Controller *v1alpha1.MapController    `json:"controller"`
+	Parent     *unstructured.Unstructured `json:"parent"`
+	MapKey     string                     `json:"mapKey"`
+
+	// Outputs whose input is gone
+	Outputs common.AnyUnstructRegistry `json:"outputs"`
+}
+
+// TombstoneHookResponse is the expected format of the JSON
+// response from the tombstone hook
+type TombstoneHookResponse struct {
+	// Outputs to be kept. Remaining outputs are deleted.
+	Outputs []*unstructured.Unstructured `json:"outputs"`
+}
+
+// callMapHook invokes the map hook for the provided input
+func (c *mapController) callMapHook(
+	request *MapHookRequest,
+) (*MapHookResponse, error) {
+	if c.schema.Spec.Hooks == nil || c.schema.Spec.Hooks.Map == nil {
+		return nil, errors.Errorf("Map hook not defined")
+	}
+	var response MapHookResponse
+	err := common.InvokeHookWithMetrics(
+		c.metricsController(),
+		c.hookScope,
+		c.schema.Spec.Hooks.Map,
+		request,
+		&response,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Map hook failed")
+	}
+	return &response, nil
+}
+
+// callTombstoneHook invokes the tombstone hook for the outputs
+// whose input is gone
+func (c *mapController) callTombstoneHook(
+	request *TombstoneHookRequest,
+) (*TombstoneHookResponse, error) {
+	var response TombstoneHookResponse
+	err := common.InvokeHookWithMetrics(
+		c.metricsController(),
+		c.hookScope,
+		c.schema.Spec.Hooks.Tombstone,
+		request,
+		&response,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Tombstone hook failed")
+	}
+	return &response, nil
+}
+
+// mapHookEntry is the cached map hook response of an input
+type mapHookEntry struct {
+	// fingerprint of the map hook request that resulted in
+	// this response
+	fingerprint string
+
+	// entry expires at this time if the response asked for a
+	// resync; zero value implies no expiry
+	expiry time.Time
+
+	response *MapHookResponse
+}
+
+// mapHookCache stores the map hook responses per parent & map
+// key. Map hook is invoked again only when the parent's
+// generation, the input or its outputs change.
+type mapHookCache struct {
+	mutex   sync.Mutex
+	entries map[types.UID]map[string]*mapHookEntry
+
+	// now is used to check the expiry of entries
+	now func() time.Time
+}
+
+// newMapHookCache returns a new instance of mapHookCache
+func newMapHookCache() *mapHookCache {
+	return &mapHookCache{
+		entries: make(map[types.UID]map[string]*mapHookEntry),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached response if the provided
+// fingerprint matches the cached one
+func (s *mapHookCache) get(
+	parent types.UID,
+	mapKey string,
+	fingerprint string,
+) (*MapHookResponse, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry := s.entries[parent][mapKey]
+	if entry == nil || entry.fingerprint != fingerprint {
+		return nil, false
+	}
+	if !entry.expiry.IsZero() && !s.now().Before(entry.expiry) {
+		return nil, false
+	}
+	// callers set the map key label against the outputs
+	response := &MapHookResponse{
+		ResyncAfterSeconds: entry.response.ResyncAfterSeconds,
+	}
+	for _, out := range entry.response.Outputs {
+		response.Outputs = append(response.Outputs, out.DeepCopy())
+	}
+	return response, true
+}
+
+// set caches a copy of the provided response
+func (s *mapHookCache) set(
+	parent types.UID,
+	mapKey string,
+	fingerprint string,
+	response *MapHookResponse,
+) {
+	entry := &mapHookEntry{
+		fingerprint: fingerprint,
+		response: &MapHookResponse{
+			ResyncAfterSeconds: response.ResyncAfterSeconds,
+		},
+	}
+	for _, out := range response.Outputs {
+		entry.response.Outputs = append(entry.response.Outputs, out.DeepCopy())
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if response.ResyncAfterSeconds > 0 {
+		entry.expiry = s.now().Add(
+			time.Duration(response.ResyncAfterSeconds * float64(time.Second)),
+		)
+	}
+	if s.entries[parent] == nil {
+		s.entries[parent] = make(map[string]*mapHookEntry)
+	}
+	s.entries[parent][mapKey] = entry
+}
+
+// retain removes the cached responses of the provided parent
+// whose map keys are not present in the provided keys
+func (s *mapHookCache) retain(parent types.UID, mapKeys map[string]bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for mapKey := range s.entries[parent] {
+		if !mapKeys[mapKey] {
+			delete(s.entries[parent], mapKey)
+		}
+	}
+	if len(s.entries[parent]) == 0 {
+		delete(s.entries, parent)
+	}
+}
+
+// forget removes all the cached responses of the provided
+// parent
+func (s *mapHookCache) forget(parent types.UID) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.entries, parent)
+}
+
+// fingerprint returns a string that changes whenever the map
+// hook request of the provided input may change
+//
+// NOTE:
+//	Parent's generation is used instead of its resource version
+// since parent's status is updated on every sync
+func fingerprint(
+	parent *unstructured.Unstructured,
+	input *unstructured.Unstructured,
+	outputs common.AnyUnstructRegistry,
+) string {
+	parentVersion := fmt.Sprintf("%d", parent.GetGeneration())
+	if parent.GetGeneration() == 0 {
+		parentVersion = parent.GetResourceVersion()
+	}
+	parts := []string{
+		parentVersion,
+		string(input.GetUID()) + "@" + input.GetResourceVersion(),
+	}
+	var outs []string
+	for _, out := range outputs.List() {
+		outs = append(
+			outs,
+			common.DescObjectAsKey(out)+"@"+out.GetResourceVersion(),
+		)
+	}
+	sort.Strings(outs)
+	return strings.Join(append(parts, outs...), ";")
+}
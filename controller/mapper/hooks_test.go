@@ -0,0 +1,195 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"openebs.io/metac/controller/common"
+)
+
+func TestMapHookCacheGet(t *testing.T) {
+	start := time.Now()
+	var tests = map[string]struct {
+		fingerprint string
+		resync      float64
+		elapsed     time.Duration
+		isFound     bool
+	}{
+		"same fingerprint": {
+			fingerprint: "fp-1",
+			isFound:     true,
+		},
+		"different fingerprint": {
+			fingerprint: "fp-2",
+			isFound:     false,
+		},
+		"before expiry": {
+			fingerprint: "fp-1",
+			resync:      10,
+			elapsed:     5 * time.Second,
+			isFound:     true,
+		},
+		"after expiry": {
+			fingerprint: "fp-1",
+			resync:      10,
+			elapsed:     10 * time.Second,
+			isFound:     false,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			now := start
+			cache := newMapHookCache()
+			cache.now = func() time.Time { return now }
+
+			out := &unstructured.Unstructured{}
+			out.SetName("my-output")
+			cache.set("p-101", "in-101", "fp-1", &MapHookResponse{
+				Outputs:            []*unstructured.Unstructured{out},
+				ResyncAfterSeconds: mock.resync,
+			})
+			// mutating the response after set must not affect
+			// the cached copy
+			out.SetName("mutated")
+
+			now = start.Add(mock.elapsed)
+			got, found := cache.get("p-101", "in-101", mock.fingerprint)
+			if found != mock.isFound {
+				t.Fatalf("Expected found %t got %t", mock.isFound, found)
+			}
+			if !found {
+				return
+			}
+			if len(got.Outputs) != 1 || got.Outputs[0].GetName() != "my-output" {
+				t.Fatalf("Expected cached output my-output got %+v", got.Outputs)
+			}
+		})
+	}
+}
+
+func TestMapHookCacheRetainAndForget(t *testing.T) {
+	cache := newMapHookCache()
+	for _, key := range []string{"in-1", "in-2"} {
+		cache.set("p-101", key, "fp", &MapHookResponse{})
+	}
+	cache.set("p-202", "in-3", "fp", &MapHookResponse{})
+
+	cache.retain("p-101", map[string]bool{"in-1": true})
+	if _, found := cache.get("p-101", "in-1", "fp"); !found {
+		t.Fatalf("Expected in-1 to be retained")
+	}
+	if _, found := cache.get("p-101", "in-2", "fp"); found {
+		t.Fatalf("Expected in-2 to be removed")
+	}
+
+	cache.forget("p-202")
+	if _, found := cache.get("p-202", "in-3", "fp"); found {
+		t.Fatalf("Expected in-3 to be removed")
+	}
+	if len(cache.entries) != 1 {
+		t.Fatalf("Expected 1 parent entry got %d", len(cache.entries))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	newObj := func(name, uid, rv string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace("ns")
+		obj.SetName(name)
+		obj.SetUID(types.UID(uid))
+		obj.SetResourceVersion(rv)
+		return obj
+	}
+	parent := newObj("parent", "p-101", "1")
+	parent.SetGeneration(1)
+	input := newObj("input", "in-101", "10")
+
+	outputs := make(common.AnyUnstructRegistry)
+	outputs.InsertByReference(parent, newObj("out-1", "o-1", "100"))
+	base := fingerprint(parent, input, outputs)
+
+	var tests = map[string]struct {
+		parentGen    int64
+		parentRV     string
+		inputRV      string
+		outputRV     string
+		isSameAsBase bool
+	}{
+		"nothing changed": {
+			parentGen:    1,
+			parentRV:     "1",
+			inputRV:      "10",
+			outputRV:     "100",
+			isSameAsBase: true,
+		},
+		"parent status changed": {
+			parentGen:    1,
+			parentRV:     "2",
+			inputRV:      "10",
+			outputRV:     "100",
+			isSameAsBase: true,
+		},
+		"parent spec changed": {
+			parentGen: 2,
+			parentRV:  "2",
+			inputRV:   "10",
+			outputRV:  "100",
+		},
+		"input changed": {
+			parentGen: 1,
+			parentRV:  "1",
+			inputRV:   "11",
+			outputRV:  "100",
+		},
+		"output changed": {
+			parentGen: 1,
+			parentRV:  "1",
+			inputRV:   "10",
+			outputRV:  "101",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			p := newObj("parent", "p-101", mock.parentRV)
+			p.SetGeneration(mock.parentGen)
+			in := newObj("input", "in-101", mock.inputRV)
+			outs := make(common.AnyUnstructRegistry)
+			outs.InsertByReference(p, newObj("out-1", "o-1", mock.outputRV))
+
+			got := fingerprint(p, in, outs)
+			if (got == base) != mock.isSameAsBase {
+				t.Fatalf(
+					"Expected same as base %t: base %q got %q",
+					mock.isSameAsBase,
+					base,
+					got,
+				)
+			}
+		})
+	}
+}
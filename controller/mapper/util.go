@@ -0,0 +1,287 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+)
+
+// selectsInput returns true if the provided parent selects the
+// provided input
+//
+// NOTE:
+//	Parent's duck typed 'spec.selector' is used to select the
+// inputs. All inputs in the parent's namespace are selected if
+// this selector is not set.
+func selectsInput(parent, input *unstructured.Unstructured) (bool, error) {
+	if parent.GetNamespace() != "" &&
+		input.GetNamespace() != "" &&
+		input.GetNamespace() != parent.GetNamespace() {
+		return false, nil
+	}
+	if controllerRef := metav1.GetControllerOf(input); controllerRef != nil &&
+		controllerRef.UID == parent.GetUID() {
+		// avoid recursion when a resource is both an input
+		// & an output
+		return false, nil
+	}
+	obj, found, err := unstructured.NestedMap(parent.UnstructuredContent(), "spec", "selector")
+	if err != nil {
+		return false, errors.Wrapf(
+			err,
+			"Invalid selector of parent %s/%s",
+			parent.GetNamespace(),
+			parent.GetName(),
+		)
+	}
+	if !found {
+		return true, nil
+	}
+	var labelSelector metav1.LabelSelector
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &labelSelector)
+	if err != nil {
+		return false, errors.Wrapf(
+			err,
+			"Invalid selector of parent %s/%s",
+			parent.GetNamespace(),
+			parent.GetName(),
+		)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&labelSelector)
+	if err != nil {
+		return false, errors.Wrapf(
+			err,
+			"Invalid selector of parent %s/%s",
+			parent.GetNamespace(),
+			parent.GetName(),
+		)
+	}
+	return selector.Matches(labels.Set(input.GetLabels())), nil
+}
+
+// groupByMapKey groups the provided outputs by their map keys
+func groupByMapKey(
+	parent *unstructured.Unstructured,
+	outputs common.AnyUnstructRegistry,
+) map[string]common.AnyUnstructRegistry {
+	grouped := make(map[string]common.AnyUnstructRegistry)
+	for _, out := range outputs.List() {
+		mapKey := out.GetLabels()[mapKeyLabel]
+		if grouped[mapKey] == nil {
+			grouped[mapKey] = make(common.AnyUnstructRegistry)
+		}
+		grouped[mapKey].InsertByReference(parent, out)
+	}
+	return grouped
+}
+
+// resourceNames maps the api version & kind of an object to
+// its resource name
+type resourceNames map[string]string
+
+func resourceNamesKey(apiVersion, kind string) string {
+	return apiVersion + ":" + kind
+}
+
+// of returns the resource name of the provided object
+func (m resourceNames) of(obj *unstructured.Unstructured) string {
+	return m[resourceNamesKey(obj.GetAPIVersion(), obj.GetKind())]
+}
+
+// makeResourceNames returns the resource names of the input
+// & output resources of the provided schema
+func makeResourceNames(
+	resourceMgr *dynamicdiscovery.APIResourceDiscovery,
+	schema *v1alpha1.MapController,
+) (resourceNames, error) {
+	var rules []v1alpha1.ResourceRule
+	for _, input := range schema.Spec.InputResources {
+		rules = append(rules, input.ResourceRule)
+	}
+	for _, output := range schema.Spec.OutputResources {
+		rules = append(rules, output.ResourceRule)
+	}
+	m := make(resourceNames)
+	for _, rule := range rules {
+		resource := resourceMgr.GetAPIForAPIVersionAndResource(
+			rule.APIVersion,
+			rule.Resource,
+		)
+		if resource == nil {
+			return nil, errors.Errorf(
+				"can't find resource %q in apiVersion %q",
+				rule.Resource,
+				rule.APIVersion,
+			)
+		}
+		m[resourceNamesKey(rule.APIVersion, resource.Kind)] = rule.Resource
+	}
+	return m, nil
+}
+
+// aggregateStatus returns the status fields computed from the
+// provided inputs & outputs. Outputs are counted per resource
+// along with the number of outputs per condition that is True.
+//
+// For example:
+//
+//	inputs:
+//	  persistentvolumeclaims:
+//	    total: 20
+//	outputs:
+//	  volumesnapshots:
+//	    total: 100
+//	    ready: 97
+func aggregateStatus(
+	schema *v1alpha1.MapController,
+	names resourceNames,
+	inputs []*unstructured.Unstructured,
+	outputs common.AnyUnstructRegistry,
+) map[string]interface{} {
+	inputStatus := make(map[string]interface{})
+	for _, rule := range schema.Spec.InputResources {
+		inputStatus[rule.Resource] = map[string]interface{}{"total": int64(0)}
+	}
+	for _, input := range inputs {
+		counts, ok := inputStatus[names.of(input)].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		counts["total"] = counts["total"].(int64) + 1
+	}
+
+	outputStatus := make(map[string]interface{})
+	for _, rule := range schema.Spec.OutputResources {
+		outputStatus[rule.Resource] = map[string]interface{}{"total": int64(0)}
+	}
+	for _, out := range outputs.List() {
+		counts, ok := outputStatus[names.of(out)].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		counts["total"] = counts["total"].(int64) + 1
+		for _, condType := range trueConditions(out) {
+			key := lowerFirst(condType)
+			if key == "total" {
+				continue
+			}
+			count, _ := counts[key].(int64)
+			counts[key] = count + 1
+		}
+	}
+
+	return map[string]interface{}{
+		"inputs":  inputStatus,
+		"outputs": outputStatus,
+	}
+}
+
+// trueConditions returns the types of status conditions of the
+// provided object that are True
+func trueConditions(obj *unstructured.Unstructured) []string {
+	var types []string
+	conds, _, _ := unstructured.NestedSlice(obj.UnstructuredContent(), "status", "conditions")
+	for _, c := range conds {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condType, _ := cond["type"].(string)
+		status, _ := cond["status"].(string)
+		if condType != "" && status == "True" {
+			types = append(types, condType)
+		}
+	}
+	return types
+}
+
+// lowerFirst returns the provided string with its first letter
+// in lower case e.g. Ready becomes ready
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// holds update strategies of the output resources
+type updateStrategyMap map[string]*v1alpha1.MapControllerOutputUpdateStrategy
+
+// Get returns the update method of the provided api group &
+// kind
+func (m updateStrategyMap) Get(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
+	strategy := m[updateStrategyMapKey(apiGroup, kind)]
+	if strategy == nil || strategy.Method == "" {
+		// default
+		return v1alpha1.ChildUpdateOnDelete
+	}
+	return strategy.Method
+}
+
+func updateStrategyMapKey(apiGroup, kind string) string {
+	return fmt.Sprintf("%s.%s", kind, apiGroup)
+}
+
+func makeUpdateStrategyMap(
+	resourceMgr *dynamicdiscovery.APIResourceDiscovery,
+	schema *v1alpha1.MapController,
+) (updateStrategyMap, error) {
+	m := make(updateStrategyMap)
+	for _, output := range schema.Spec.OutputResources {
+		if output.UpdateStrategy == nil {
+			continue
+		}
+		switch output.UpdateStrategy.Method {
+		case "",
+			v1alpha1.ChildUpdateOnDelete,
+			v1alpha1.ChildUpdateRecreate,
+			v1alpha1.ChildUpdateInPlace:
+		default:
+			return nil, errors.Errorf(
+				"Invalid update strategy %q for output %q %q: Rolling updates are not supported",
+				output.UpdateStrategy.Method,
+				output.APIVersion,
+				output.Resource,
+			)
+		}
+		resource := resourceMgr.GetAPIForAPIVersionAndResource(
+			output.APIVersion,
+			output.Resource,
+		)
+		if resource == nil {
+			return nil, errors.Errorf(
+				"can't find output resource %q in apiVersion %q",
+				output.Resource,
+				output.APIVersion,
+			)
+		}
+		apiGroup, _ := common.ParseAPIVersionToGroupVersion(output.APIVersion)
+		m[updateStrategyMapKey(apiGroup, resource.Kind)] = output.UpdateStrategy
+	}
+	return m, nil
+}
@@ -0,0 +1,362 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+	"openebs.io/metac/controller/common"
+)
+
+func TestSelectsInput(t *testing.T) {
+	var tests = map[string]struct {
+		parent  *unstructured.Unstructured
+		input   *unstructured.Unstructured
+		isMatch bool
+		isErr   bool
+	}{
+		"no selector selects all": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-parent",
+						"namespace": "ns",
+						"uid":       "p-101",
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "ns",
+					},
+				},
+			},
+			isMatch: true,
+		},
+		"different namespace": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-parent",
+						"namespace": "ns",
+						"uid":       "p-101",
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "other",
+					},
+				},
+			},
+			isMatch: false,
+		},
+		"cluster scoped parent selects any namespace": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name": "my-parent",
+						"uid":  "p-101",
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "other",
+					},
+				},
+			},
+			isMatch: true,
+		},
+		"input owned by parent": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-parent",
+						"namespace": "ns",
+						"uid":       "p-101",
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "ns",
+						"ownerReferences": []interface{}{
+							map[string]interface{}{
+								"apiVersion": "test.io/v1",
+								"kind":       "Parent",
+								"name":       "my-parent",
+								"uid":        "p-101",
+								"controller": true,
+							},
+						},
+					},
+				},
+			},
+			isMatch: false,
+		},
+		"matching labels": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-parent",
+						"namespace": "ns",
+						"uid":       "p-101",
+					},
+					"spec": map[string]interface{}{
+						"selector": map[string]interface{}{
+							"matchLabels": map[string]interface{}{
+								"app": "db",
+							},
+						},
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "ns",
+						"labels": map[string]interface{}{
+							"app": "db",
+						},
+					},
+				},
+			},
+			isMatch: true,
+		},
+		"non matching labels": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-parent",
+						"namespace": "ns",
+						"uid":       "p-101",
+					},
+					"spec": map[string]interface{}{
+						"selector": map[string]interface{}{
+							"matchLabels": map[string]interface{}{
+								"app": "db",
+							},
+						},
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "ns",
+						"labels": map[string]interface{}{
+							"app": "web",
+						},
+					},
+				},
+			},
+			isMatch: false,
+		},
+		"invalid selector": {
+			parent: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-parent",
+						"namespace": "ns",
+						"uid":       "p-101",
+					},
+					"spec": map[string]interface{}{
+						"selector": "app=db",
+					},
+				},
+			},
+			input: &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"name":      "my-pvc",
+						"namespace": "ns",
+					},
+				},
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got, err := selectsInput(mock.parent, mock.input)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if got != mock.isMatch {
+				t.Fatalf("Expected match %t got %t", mock.isMatch, got)
+			}
+		})
+	}
+}
+
+func TestAggregateStatus(t *testing.T) {
+	schema := &v1alpha1.MapController{
+		Spec: v1alpha1.MapControllerSpec{
+			InputResources: []v1alpha1.MapControllerInputResourceRule{
+				{
+					ResourceRule: v1alpha1.ResourceRule{
+						APIVersion: "v1",
+						Resource:   "persistentvolumeclaims",
+					},
+				},
+			},
+			OutputResources: []v1alpha1.MapControllerOutputResourceRule{
+				{
+					ResourceRule: v1alpha1.ResourceRule{
+						APIVersion: "snapshot.storage.k8s.io/v1beta1",
+						Resource:   "volumesnapshots",
+					},
+				},
+			},
+		},
+	}
+	names := resourceNames{
+		resourceNamesKey("v1", "PersistentVolumeClaim"):                       "persistentvolumeclaims",
+		resourceNamesKey("snapshot.storage.k8s.io/v1beta1", "VolumeSnapshot"): "volumesnapshots",
+	}
+	parent := &unstructured.Unstructured{}
+	parent.SetAPIVersion("test.io/v1")
+	parent.SetKind("SnapshotSchedule")
+	parent.SetName("my-schedule")
+
+	newOutput := func(name string, ready string) *unstructured.Unstructured {
+		out := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{
+							"type":   "Ready",
+							"status": ready,
+						},
+					},
+				},
+			},
+		}
+		out.SetAPIVersion("snapshot.storage.k8s.io/v1beta1")
+		out.SetKind("VolumeSnapshot")
+		out.SetName(name)
+		return out
+	}
+	newInput := func(name string) *unstructured.Unstructured {
+		in := &unstructured.Unstructured{}
+		in.SetAPIVersion("v1")
+		in.SetKind("PersistentVolumeClaim")
+		in.SetName(name)
+		return in
+	}
+
+	var tests = map[string]struct {
+		inputs  []*unstructured.Unstructured
+		outputs []*unstructured.Unstructured
+		expect  map[string]interface{}
+	}{
+		"no inputs & no outputs": {
+			expect: map[string]interface{}{
+				"inputs": map[string]interface{}{
+					"persistentvolumeclaims": map[string]interface{}{
+						"total": int64(0),
+					},
+				},
+				"outputs": map[string]interface{}{
+					"volumesnapshots": map[string]interface{}{
+						"total": int64(0),
+					},
+				},
+			},
+		},
+		"inputs & outputs with conditions": {
+			inputs: []*unstructured.Unstructured{
+				newInput("pvc-1"),
+				newInput("pvc-2"),
+			},
+			outputs: []*unstructured.Unstructured{
+				newOutput("snap-1", "True"),
+				newOutput("snap-2", "True"),
+				newOutput("snap-3", "False"),
+			},
+			expect: map[string]interface{}{
+				"inputs": map[string]interface{}{
+					"persistentvolumeclaims": map[string]interface{}{
+						"total": int64(2),
+					},
+				},
+				"outputs": map[string]interface{}{
+					"volumesnapshots": map[string]interface{}{
+						"total": int64(3),
+						"ready": int64(2),
+					},
+				},
+			},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			outputs := make(common.AnyUnstructRegistry)
+			for _, out := range mock.outputs {
+				outputs.InsertByReference(parent, out)
+			}
+			got := aggregateStatus(schema, names, mock.inputs, outputs)
+			if !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected %+v got %+v", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestLowerFirst(t *testing.T) {
+	var tests = map[string]struct {
+		given  string
+		expect string
+	}{
+		"empty":         {given: "", expect: ""},
+		"single":        {given: "R", expect: "r"},
+		"camel case":    {given: "SnapshotReady", expect: "snapshotReady"},
+		"already lower": {given: "ready", expect: "ready"},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := lowerFirst(mock.given)
+			if got != mock.expect {
+				t.Fatalf("Expected %q got %q", mock.expect, got)
+			}
+		})
+	}
+}
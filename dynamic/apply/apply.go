@@ -38,6 +38,10 @@ import (
 
 const (
 	lastAppliedAnnotation = "metac.openebs.io/last-applied-configuration"
+
+	// LastAppliedAnnotationKey is the predefined annotation key
+	// that holds the last applied state
+	LastAppliedAnnotationKey = lastAppliedAnnotation
 )
 
 // SetLastApplied sets the last applied state against a
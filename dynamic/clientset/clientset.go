@@ -59,6 +59,19 @@ func New(
 	}, nil
 }
 
+// NewForDynamicClient returns a new instance of Clientset that
+// invokes the API server via the provided dynamic client e.g. a
+// fake dynamic client in unit tests
+func NewForDynamicClient(
+	dynamicClient dynamic.Interface,
+	resourceMgr *dynamicdiscovery.APIResourceDiscovery,
+) *Clientset {
+	return &Clientset{
+		discoveryManager: resourceMgr,
+		dynamicClient:    dynamicClient,
+	}
+}
+
 // HasSynced returns true if all the discovered resources
 // are synced _i.e. are refreshed from the API server_
 func (cs *Clientset) HasSynced() bool {
@@ -61,30 +61,52 @@ func NewSharedInformerFactory(
 // Shared informers that become unused will be stopped to minimize our load on
 // the API server.
 func (f *SharedInformerFactory) GetOrCreate(apiVersion, resource string) (*ResourceInformer, error) {
+	return f.GetOrCreateWithScope(apiVersion, resource, Scope{})
+}
+
+// GetOrCreateWithScope returns a dynamic informer and lister for the given
+// resource that lists & watches only the objects within the given scope.
+// These are shared with any other controllers in the same process that
+// request the same resource with the same scope.
+//
+// NOTE:
+//	Informers of different scopes are independent of each other even if
+// their objects overlap. Hence a cluster wide informer is not reused to
+// serve a namespace scoped request.
+func (f *SharedInformerFactory) GetOrCreateWithScope(
+	apiVersion, resource string,
+	scope Scope,
+) (*ResourceInformer, error) {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
+	client, err := f.clientset.GetClientForAPIVersionAndResource(apiVersion, resource)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Failed to subscribe shared informer %v: %v",
+			resourceKey(apiVersion, resource, scope),
+			err,
+		)
+	}
+	// namespaces are irrelevant for cluster scoped resources
+	scope = scope.normalize(client.Namespaced)
+
 	// Return existing informer if there is one.
-	key := resourceKey(apiVersion, resource)
+	key := resourceKey(apiVersion, resource, scope)
 	if sharedInformer, ok := f.sharedInformers[key]; ok {
 		count := f.refCount[key] + 1
 		f.refCount[key] = count
 		glog.V(4).Infof(
-			"Subscribed to shared informer for %v in %v (total subscribers now %v)",
+			"Subscribed to shared informer for %v in %v %v (total subscribers now %v)",
 			resource,
 			apiVersion,
+			scope,
 			count,
 		)
 		return newResourceInformer(sharedInformer), nil
 	}
 
 	// Create one if it doesn't exist.
-	client, err := f.clientset.GetClientForAPIVersionAndResource(apiVersion, resource)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"Failed to subscribe shared informer %v: %v", key, err,
-		)
-	}
 	stopCh := make(chan struct{})
 
 	// closeFn is called by users of the shared informer (via Close())
@@ -97,9 +119,10 @@ func (f *SharedInformerFactory) GetOrCreate(apiVersion, resource string) (*Resou
 
 		count := f.refCount[key] - 1
 		glog.V(4).Infof(
-			"Unsubscribed from shared informer %v in %v (total subscribers now %v)",
+			"Unsubscribed from shared informer %v in %v %v (total subscribers now %v)",
 			resource,
 			apiVersion,
+			scope,
 			count,
 		)
 
@@ -111,17 +134,18 @@ func (f *SharedInformerFactory) GetOrCreate(apiVersion, resource string) (*Resou
 
 		// We're the last ones using it.
 		glog.V(4).Infof(
-			"Stopping shared informer for %v in %v (no more subscribers)",
+			"Stopping shared informer for %v in %v %v (no more subscribers)",
 			resource,
 			apiVersion,
+			scope,
 		)
 		close(stopCh)
 		delete(f.refCount, key)
 		delete(f.sharedInformers, key)
 	}
 
-	glog.V(4).Infof("Starting shared informer for %v in %v", resource, apiVersion)
-	sharedInformer := newSharedResourceInformer(client, f.defaultResync, closeFn)
+	glog.V(4).Infof("Starting shared informer for %v in %v %v", resource, apiVersion, scope)
+	sharedInformer := newSharedResourceInformer(client, scope, f.defaultResync, closeFn)
 	f.sharedInformers[key] = sharedInformer
 	f.refCount[key] = 1
 
@@ -132,6 +156,10 @@ func (f *SharedInformerFactory) GetOrCreate(apiVersion, resource string) (*Resou
 	return newResourceInformer(sharedInformer), nil
 }
 
-func resourceKey(apiVersion, resource string) string {
-	return fmt.Sprintf("%s.%s", resource, apiVersion)
+func resourceKey(apiVersion, resource string, scope Scope) string {
+	key := fmt.Sprintf("%s.%s", resource, apiVersion)
+	if s := scope.String(); s != "" {
+		key = key + "[" + s + "]"
+	}
+	return key
 }
@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResourceKey(t *testing.T) {
+	var tests = map[string]struct {
+		scope  Scope
+		expect string
+	}{
+		"zero scope": {
+			expect: "configmaps.v1",
+		},
+		"namespaces": {
+			scope:  Scope{Namespaces: []string{"ns1", "ns2"}},
+			expect: "configmaps.v1[namespaces=ns1,ns2;selector=]",
+		},
+		"label selector": {
+			scope:  Scope{LabelSelector: "app=test"},
+			expect: "configmaps.v1[namespaces=;selector=app=test]",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := resourceKey("v1", "configmaps", mock.scope)
+			if got != mock.expect {
+				t.Fatalf("Expected %q got %q", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestSharedInformerFactoryGetOrCreateWithScope(t *testing.T) {
+	server := &listWatchTestServer{
+		resourceVersions: map[string]string{},
+	}
+	factory := NewSharedInformerFactory(
+		newListWatchTestClientset(t, server),
+		time.Hour,
+	)
+	get := func(resource string, scope Scope) *ResourceInformer {
+		informer, err := factory.GetOrCreateWithScope("v1", resource, scope)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		return informer
+	}
+
+	shared := get("configmaps", Scope{Namespaces: []string{"ns2", "ns1"}})
+	// same namespaces in a different order
+	sameScope := get("configmaps", Scope{Namespaces: []string{"ns1", "ns2", "ns1"}})
+	others := []*ResourceInformer{
+		get("configmaps", Scope{Namespaces: []string{"ns1"}}),
+		get("configmaps", Scope{
+			Namespaces:    []string{"ns1", "ns2"},
+			LabelSelector: "app=test",
+		}),
+		get("configmaps", Scope{}),
+	}
+	// namespaces are ignored for cluster scoped resources
+	cluster := get("namespaces", Scope{Namespaces: []string{"ns1"}})
+	clusterAll := get("namespaces", Scope{})
+
+	if shared.sharedResourceInformer != sameScope.sharedResourceInformer {
+		t.Fatalf("Expected informers of same scope to be shared")
+	}
+	if cluster.sharedResourceInformer != clusterAll.sharedResourceInformer {
+		t.Fatalf("Expected informers of cluster scoped resource to be shared")
+	}
+	for i, other := range others {
+		if other.sharedResourceInformer == shared.sharedResourceInformer {
+			t.Fatalf("Expected informer %d of different scope not to be shared", i)
+		}
+	}
+	factory.mutex.Lock()
+	count := len(factory.sharedInformers)
+	factory.mutex.Unlock()
+	if count != 5 {
+		t.Fatalf("Expected 5 shared informers got %d", count)
+	}
+
+	// shared informer is stopped only after its last user closes it
+	shared.Close()
+	factory.mutex.Lock()
+	count = len(factory.sharedInformers)
+	factory.mutex.Unlock()
+	if count != 5 {
+		t.Fatalf("Expected 5 shared informers after first close got %d", count)
+	}
+	sameScope.Close()
+	for _, informer := range append(others, cluster, clusterAll) {
+		informer.Close()
+	}
+	factory.mutex.Lock()
+	count = len(factory.sharedInformers)
+	factory.mutex.Unlock()
+	if count != 0 {
+		t.Fatalf("Expected no shared informers after all close got %d", count)
+	}
+}
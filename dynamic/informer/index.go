@@ -0,0 +1,302 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespaceIndex is the name of the index by namespace that
+// every shared informer has
+const NamespaceIndex = cache.NamespaceIndex
+
+// AnnotationIndexName returns the name of the index by the value
+// of the provided annotation key
+func AnnotationIndexName(key string) string {
+	return "metac/annotation:" + key
+}
+
+// AnnotationIndexFunc returns the function that indexes an object
+// by the value of the provided annotation key. Objects without this
+// annotation are not indexed.
+func AnnotationIndexFunc(key string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil, errors.Errorf("Can't index %T: Want unstructured", obj)
+		}
+		value, found := u.GetAnnotations()[key]
+		if !found {
+			return nil, nil
+		}
+		return []string{value}, nil
+	}
+}
+
+// FieldPathIndexName returns the name of the index by the value
+// found at the provided nested field path
+func FieldPathIndexName(fields []string) string {
+	return "metac/field:" + strings.Join(fields, ".")
+}
+
+// FieldPathIndexFunc returns the function that indexes an object
+// by the string value found at the provided nested field path.
+// Objects without a string value at this path are not indexed.
+func FieldPathIndexFunc(fields []string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil, errors.Errorf("Can't index %T: Want unstructured", obj)
+		}
+		value, found, err := unstructured.NestedString(u.Object, fields...)
+		if err != nil || !found {
+			// values of other types are never matched against
+			return nil, nil
+		}
+		return []string{value}, nil
+	}
+}
+
+// sharedIndexer extends the indexer of a shared informer with the
+// indexes that are added after this informer has started.
+//
+// NOTE:
+//	Indexers can't be added to a client-go informer once it has
+// started. However shared informers start as soon as they are created
+// & are reused by the controllers that get created later. Hence the
+// additional indexes are maintained here from the informer's events.
+//
+// NOTE:
+//	Indexes are kept till the shared informer is stopped even if the
+// controllers that added them no longer need them.
+//
+// NOTE:
+//	Informer's events are delivered after its store is updated. Hence
+// the additional indexes lag behind the store. Refer to hasSynced.
+type sharedIndexer struct {
+	cache.Indexer
+
+	mutex    sync.RWMutex
+	indexers cache.Indexers
+
+	// seen has the keys of the objects whose events were handled
+	seen sets.String
+
+	// synced is set once the objects that were cached when the
+	// informer synced are seen
+	synced bool
+
+	// indices maps the index name to the indexed value to the
+	// keys of objects having this value
+	indices map[string]map[string]sets.String
+
+	// values maps the index name to the object key to the values
+	// this object was indexed with
+	values map[string]map[string][]string
+}
+
+func newSharedIndexer(indexer cache.Indexer) *sharedIndexer {
+	return &sharedIndexer{
+		Indexer:  indexer,
+		indexers: cache.Indexers{},
+		seen:     sets.NewString(),
+		indices:  map[string]map[string]sets.String{},
+		values:   map[string]map[string][]string{},
+	}
+}
+
+// AddIndexers adds the provided indexers & indexes the objects that
+// are already cached. Indexers whose names are already registered
+// are ignored. Hence index names should identify their index
+// functions.
+func (s *sharedIndexer) AddIndexers(indexers cache.Indexers) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	builtIn := s.Indexer.GetIndexers()
+	for name, fn := range indexers {
+		if _, found := builtIn[name]; found {
+			continue
+		}
+		if _, found := s.indexers[name]; found {
+			continue
+		}
+		s.indexers[name] = fn
+		s.indices[name] = map[string]sets.String{}
+		s.values[name] = map[string][]string{}
+		for _, obj := range s.Indexer.List() {
+			key, err := cache.MetaNamespaceKeyFunc(obj)
+			if err != nil {
+				return errors.Wrapf(err, "Can't add index %q", name)
+			}
+			err = s.indexLocked(name, key, obj)
+			if err != nil {
+				return errors.Wrapf(err, "Can't add index %q", name)
+			}
+		}
+	}
+	return nil
+}
+
+// indexLocked indexes the object with the provided key against the
+// provided index. It replaces any values this object was indexed with
+// earlier.
+func (s *sharedIndexer) indexLocked(name, key string, obj interface{}) error {
+	s.unindexLocked(name, key)
+	if obj == nil {
+		return nil
+	}
+	values, err := s.indexers[name](obj)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		keys := s.indices[name][value]
+		if keys == nil {
+			keys = sets.NewString()
+			s.indices[name][value] = keys
+		}
+		keys.Insert(key)
+	}
+	if len(values) != 0 {
+		s.values[name][key] = values
+	}
+	return nil
+}
+
+func (s *sharedIndexer) unindexLocked(name, key string) {
+	for _, value := range s.values[name][key] {
+		keys := s.indices[name][value]
+		keys.Delete(key)
+		if keys.Len() == 0 {
+			delete(s.indices[name], value)
+		}
+	}
+	delete(s.values[name], key)
+}
+
+// update re-indexes the provided object against all the additional
+// indexes. Provided object is removed from these indexes if it is
+// deleted.
+func (s *sharedIndexer) update(obj interface{}, isDelete bool) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		glog.Warningf("Can't index %T: %v", obj, err)
+		return
+	}
+	if isDelete {
+		obj = nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if isDelete {
+		s.seen.Delete(key)
+	} else {
+		s.seen.Insert(key)
+	}
+	for name := range s.indexers {
+		err := s.indexLocked(name, key, obj)
+		if err != nil {
+			glog.Warningf("Can't index %s with %q: %v", key, name, err)
+		}
+	}
+}
+
+// hasSynced returns true once the events of the objects that are
+// cached have been handled i.e. once these objects are indexed. It
+// is meant to be invoked after the informer has synced.
+//
+// NOTE:
+//	This stays true once it is true. Objects that are added later
+// are indexed like any other update.
+func (s *sharedIndexer) hasSynced() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.synced {
+		return true
+	}
+	for _, key := range s.Indexer.ListKeys() {
+		if !s.seen.Has(key) {
+			return false
+		}
+	}
+	s.synced = true
+	return true
+}
+
+// IndexKeys returns the keys of the objects that are indexed with
+// the provided value
+func (s *sharedIndexer) IndexKeys(name, value string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, found := s.indexers[name]; !found {
+		return s.Indexer.IndexKeys(name, value)
+	}
+	return s.indices[name][value].List(), nil
+}
+
+// ByIndex returns the cached objects that are indexed with the
+// provided value
+func (s *sharedIndexer) ByIndex(name, value string) ([]interface{}, error) {
+	s.mutex.RLock()
+	_, found := s.indexers[name]
+	s.mutex.RUnlock()
+	if !found {
+		return s.Indexer.ByIndex(name, value)
+	}
+	keys, err := s.IndexKeys(name, value)
+	if err != nil {
+		return nil, err
+	}
+	var objs []interface{}
+	for _, key := range keys {
+		obj, exists, err := s.Indexer.GetByKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// GetIndexers returns the built-in as well as the additional indexers
+func (s *sharedIndexer) GetIndexers() cache.Indexers {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	all := cache.Indexers{}
+	for name, fn := range s.Indexer.GetIndexers() {
+		all[name] = fn
+	}
+	for name, fn := range s.indexers {
+		all[name] = fn
+	}
+	return all
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newIndexTestObj(namespace, name, watch string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+			},
+		},
+	}
+	if watch != "" {
+		unstructured.SetNestedField(obj.Object, watch, "spec", "watchName")
+	}
+	return obj
+}
+
+func newIndexTestIndexer(objs ...*unstructured.Unstructured) *sharedIndexer {
+	indexer := cache.NewIndexer(
+		cache.MetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	for _, obj := range objs {
+		indexer.Add(obj)
+	}
+	return newSharedIndexer(indexer)
+}
+
+func namesOf(objs []interface{}) []string {
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.(*unstructured.Unstructured).GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestSharedIndexerAddIndexers(t *testing.T) {
+	fields := []string{"spec", "watchName"}
+	name := FieldPathIndexName(fields)
+	s := newIndexTestIndexer(
+		newIndexTestObj("ns1", "a1", "w1"),
+		newIndexTestObj("ns1", "a2", "w1"),
+		newIndexTestObj("ns2", "a3", "w2"),
+		newIndexTestObj("ns2", "a4", ""),
+	)
+	// indexes are built from the objects that are already cached
+	err := s.AddIndexers(cache.Indexers{
+		name:                 FieldPathIndexFunc(fields),
+		cache.NamespaceIndex: FieldPathIndexFunc(fields),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	var tests = map[string]struct {
+		index  string
+		value  string
+		expect []string
+	}{
+		"by field path": {
+			index:  name,
+			value:  "w1",
+			expect: []string{"a1", "a2"},
+		},
+		"by missing value": {
+			index: name,
+			value: "w3",
+		},
+		"by built-in namespace index": {
+			index:  cache.NamespaceIndex,
+			value:  "ns2",
+			expect: []string{"a3", "a4"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			objs, err := s.ByIndex(mock.index, mock.value)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			got := namesOf(objs)
+			if fmt.Sprint(got) != fmt.Sprint(mock.expect) {
+				t.Fatalf("Expected %v got %v", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestSharedIndexerUpdate(t *testing.T) {
+	fields := []string{"spec", "watchName"}
+	name := FieldPathIndexName(fields)
+	a1 := newIndexTestObj("ns1", "a1", "w1")
+	s := newIndexTestIndexer(a1)
+	err := s.AddIndexers(cache.Indexers{name: FieldPathIndexFunc(fields)})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	// events are processed after the store is updated
+	a2 := newIndexTestObj("ns1", "a2", "w1")
+	s.Indexer.Add(a2)
+	s.update(a2, false)
+	a1Updated := newIndexTestObj("ns1", "a1", "w2")
+	s.Indexer.Update(a1Updated)
+	s.update(a1Updated, false)
+
+	objs, _ := s.ByIndex(name, "w1")
+	if got := namesOf(objs); fmt.Sprint(got) != "[a2]" {
+		t.Fatalf("Expected [a2] for w1 got %v", got)
+	}
+	objs, _ = s.ByIndex(name, "w2")
+	if got := namesOf(objs); fmt.Sprint(got) != "[a1]" {
+		t.Fatalf("Expected [a1] for w2 got %v", got)
+	}
+
+	s.Indexer.Delete(a2)
+	s.update(cache.DeletedFinalStateUnknown{Key: "ns1/a2", Obj: a2}, true)
+	objs, _ = s.ByIndex(name, "w1")
+	if len(objs) != 0 {
+		t.Fatalf("Expected no objects for w1 got %v", namesOf(objs))
+	}
+	if len(s.indices[name]) != 1 {
+		t.Fatalf("Expected 1 indexed value got %d", len(s.indices[name]))
+	}
+}
+
+func TestSharedIndexerHasSynced(t *testing.T) {
+	a1 := newIndexTestObj("ns1", "a1", "w1")
+	a2 := newIndexTestObj("ns1", "a2", "w1")
+	s := newIndexTestIndexer(a1, a2)
+	if s.hasSynced() {
+		t.Fatalf("Expected not synced before events got synced")
+	}
+	s.update(a1, false)
+	if s.hasSynced() {
+		t.Fatalf("Expected not synced with a pending event got synced")
+	}
+	s.update(a2, false)
+	if !s.hasSynced() {
+		t.Fatalf("Expected synced once events are handled got not synced")
+	}
+	// objects added later do not reset the sync
+	s.Indexer.Add(newIndexTestObj("ns1", "a3", "w1"))
+	if !s.hasSynced() {
+		t.Fatalf("Expected synced to stay got not synced")
+	}
+}
+
+// benchmarkIndexer returns an indexer with 10k attachments spread
+// across 1k watches
+func benchmarkIndexer(b *testing.B) *sharedIndexer {
+	var objs []*unstructured.Unstructured
+	for i := 0; i < 10000; i++ {
+		objs = append(
+			objs,
+			newIndexTestObj("ns", fmt.Sprintf("a%d", i), fmt.Sprintf("w%d", i%1000)),
+		)
+	}
+	s := newIndexTestIndexer(objs...)
+	fields := []string{"spec", "watchName"}
+	err := s.AddIndexers(cache.Indexers{FieldPathIndexName(fields): FieldPathIndexFunc(fields)})
+	if err != nil {
+		b.Fatalf("Expected no error got [%+v]", err)
+	}
+	return s
+}
+
+func BenchmarkListAndFilter(b *testing.B) {
+	s := benchmarkIndexer(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		watch := fmt.Sprintf("w%d", i%1000)
+		var matches int
+		for _, obj := range s.List() {
+			value, _, _ := unstructured.NestedString(
+				obj.(*unstructured.Unstructured).Object,
+				"spec", "watchName",
+			)
+			if value == watch {
+				matches++
+			}
+		}
+		if matches != 10 {
+			b.Fatalf("Expected 10 matches got %d", matches)
+		}
+	}
+}
+
+func BenchmarkByIndex(b *testing.B) {
+	s := benchmarkIndexer(b)
+	name := FieldPathIndexName([]string{"spec", "watchName"})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		objs, err := s.ByIndex(name, fmt.Sprintf("w%d", i%1000))
+		if err != nil {
+			b.Fatalf("Expected no error got [%+v]", err)
+		}
+		if len(objs) != 10 {
+			b.Fatalf("Expected 10 matches got %d", len(objs))
+		}
+	}
+}
@@ -21,10 +21,8 @@ import (
 	"sync"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 
@@ -52,7 +50,7 @@ type SharedIndexInformer interface {
 // Users of this package shouldn't create ResourceInformers directly.
 // The SharedInformerFactory returns a new ResourceInformer for each request,
 // but multiple ResourceInformers may share the same underlying informer if they
-// are for the same apiVersion and resource.
+// are for the same apiVersion, resource and scope.
 //
 // When you're done with a ResourceInformer, you should call Close() on it.
 // Once all ResourceInformers for a shared informer are closed, the shared
@@ -86,6 +84,16 @@ func (ri *ResourceInformer) Lister() *dynamiclister.Lister {
 	return ri.sharedResourceInformer.lister
 }
 
+// AddIndexers adds the provided indexers to the shared informer.
+// Objects can then be looked up by these indexes via the lister.
+//
+// NOTE:
+//	Indexers are shared with other users of the same shared informer.
+// Hence an index name should identify its index function.
+func (ri *ResourceInformer) AddIndexers(indexers cache.Indexers) error {
+	return ri.sharedResourceInformer.indexer.AddIndexers(indexers)
+}
+
 // Close marks this ResourceInformer as unused, allowing the underlying
 // shared informer to be stopped when no users are left.
 // You should call this when you no longer need the informer, so the watches
@@ -102,6 +110,9 @@ type sharedResourceInformer struct {
 	informer cache.SharedIndexInformer
 	// lister to the specific API resource
 	lister *dynamiclister.Lister
+	// indexer holds the indexes that are added after the informer
+	// has started
+	indexer *sharedIndexer
 
 	defaultResyncPeriod time.Duration
 
@@ -112,30 +123,28 @@ type sharedResourceInformer struct {
 
 func newSharedResourceInformer(
 	client *dynamicclientset.ResourceClient,
+	scope Scope,
 	defaultResyncPeriod time.Duration,
 	close func(),
 ) *sharedResourceInformer {
 	informer := cache.NewSharedIndexInformer(
-		&cache.ListWatch{
-			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
-				return client.List(opts)
-			},
-			WatchFunc: client.Watch,
-		},
+		newListWatch(client, scope),
 		&unstructured.Unstructured{},
 		defaultResyncPeriod,
 		cache.Indexers{
 			cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
 		},
 	)
+	indexer := newSharedIndexer(informer.GetIndexer())
 	sri := &sharedResourceInformer{
 		close:               close,
 		informer:            informer,
 		defaultResyncPeriod: defaultResyncPeriod,
+		indexer:             indexer,
 
-		lister: dynamiclister.New(client.GetGroupResource(), informer.GetIndexer()),
+		lister: dynamiclister.New(client.GetGroupResource(), indexer),
 	}
-	sri.eventHandlers = newSharedEventHandler(sri.lister, defaultResyncPeriod)
+	sri.eventHandlers = newSharedEventHandler(sri.lister, indexer, defaultResyncPeriod)
 	informer.AddEventHandler(sri.eventHandlers)
 	return sri
 }
@@ -149,6 +158,7 @@ func newSharedResourceInformer(
 // and remove handlers throughout the lifetime of a shared informer.
 type sharedEventHandler struct {
 	lister       *dynamiclister.Lister
+	indexer      *sharedIndexer
 	relistPeriod time.Duration
 
 	mutex    sync.RWMutex
@@ -156,10 +166,13 @@ type sharedEventHandler struct {
 }
 
 func newSharedEventHandler(
-	lister *dynamiclister.Lister, relistPeriod time.Duration,
+	lister *dynamiclister.Lister,
+	indexer *sharedIndexer,
+	relistPeriod time.Duration,
 ) *sharedEventHandler {
 	return &sharedEventHandler{
 		lister:       lister,
+		indexer:      indexer,
 		relistPeriod: relistPeriod,
 		handlers:     make(map[*informerWrapper][]*eventHandler),
 	}
@@ -205,7 +218,11 @@ func (seh *sharedEventHandler) removeHandlers(iw *informerWrapper) {
 	delete(seh.handlers, iw)
 }
 
+// OnAdd indexes the object before broadcasting the event. This lets
+// the handlers lookup this object by the additional indexes.
 func (seh *sharedEventHandler) OnAdd(obj interface{}) {
+	seh.indexer.update(obj, false)
+
 	seh.mutex.RLock()
 	defer seh.mutex.RUnlock()
 
@@ -217,6 +234,8 @@ func (seh *sharedEventHandler) OnAdd(obj interface{}) {
 }
 
 func (seh *sharedEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	seh.indexer.update(newObj, false)
+
 	seh.mutex.RLock()
 	defer seh.mutex.RUnlock()
 
@@ -228,6 +247,8 @@ func (seh *sharedEventHandler) OnUpdate(oldObj, newObj interface{}) {
 }
 
 func (seh *sharedEventHandler) OnDelete(obj interface{}) {
+	seh.indexer.update(obj, true)
+
 	seh.mutex.RLock()
 	defer seh.mutex.RUnlock()
 
@@ -314,6 +335,13 @@ func (iw *informerWrapper) AddEventHandlerWithResyncPeriod(handler cache.Resourc
 	iw.sharedResourceInformer.eventHandlers.addHandler(iw, handler, resyncPeriod)
 }
 
+// HasSynced returns true once the informer has synced & the objects
+// it has cached are indexed by the additional indexes
+func (iw *informerWrapper) HasSynced() bool {
+	return iw.SharedIndexInformer.HasSynced() &&
+		iw.sharedResourceInformer.indexer.hasSynced()
+}
+
 func (iw *informerWrapper) RemoveEventHandlers() {
 	iw.sharedResourceInformer.eventHandlers.removeHandlers(iw)
 }
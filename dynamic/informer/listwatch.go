@@ -0,0 +1,279 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+)
+
+// Scope restricts the objects that are listed & watched by a
+// shared informer. The zero value represents all the objects of
+// the resource.
+type Scope struct {
+	// Namespaces to list & watch. All namespaces are listed &
+	// watched if this is empty. This is ignored for cluster
+	// scoped resources.
+	Namespaces []string
+
+	// LabelSelector is evaluated by the API server while listing
+	// & watching
+	LabelSelector string
+}
+
+// normalize returns a copy of the scope with sorted & unique
+// namespaces. Namespaces are dropped for cluster scoped resources.
+func (s Scope) normalize(namespaced bool) Scope {
+	var namespaces []string
+	if namespaced {
+		seen := map[string]bool{}
+		for _, ns := range s.Namespaces {
+			if ns == "" || seen[ns] {
+				continue
+			}
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+		sort.Strings(namespaces)
+	}
+	return Scope{
+		Namespaces:    namespaces,
+		LabelSelector: s.LabelSelector,
+	}
+}
+
+// String implements Stringer interface
+func (s Scope) String() string {
+	if len(s.Namespaces) == 0 && s.LabelSelector == "" {
+		return ""
+	}
+	return "namespaces=" + strings.Join(s.Namespaces, ",") +
+		";selector=" + s.LabelSelector
+}
+
+// newListWatch returns the list & watch functions of the provided
+// resource restricted to the provided scope
+func newListWatch(
+	client *dynamicclientset.ResourceClient,
+	scope Scope,
+) cache.ListerWatcher {
+	if len(scope.Namespaces) > 1 {
+		return newMultiNamespaceListWatch(client, scope)
+	}
+	var namespace string
+	if len(scope.Namespaces) == 1 {
+		namespace = scope.Namespaces[0]
+	}
+	nsClient := client.Namespace(namespace)
+	return &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = scope.LabelSelector
+			return nsClient.List(opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = scope.LabelSelector
+			return nsClient.Watch(opts)
+		},
+	}
+}
+
+// multiNamespaceListWatch lists & watches a resource across a set
+// of namespaces. Lists are merged & watches are multiplexed.
+//
+// NOTE:
+//	Resource version of the merged list is not meaningful for any
+// single namespace. Hence resource versions are tracked per namespace
+// & used to resume the watch of the corresponding namespace.
+type multiNamespaceListWatch struct {
+	clients       map[string]*dynamicclientset.ResourceClient
+	labelSelector string
+
+	mutex            sync.Mutex
+	resourceVersions map[string]string
+}
+
+func newMultiNamespaceListWatch(
+	client *dynamicclientset.ResourceClient,
+	scope Scope,
+) *multiNamespaceListWatch {
+	clients := map[string]*dynamicclientset.ResourceClient{}
+	for _, ns := range scope.Namespaces {
+		clients[ns] = client.Namespace(ns)
+	}
+	return &multiNamespaceListWatch{
+		clients:          clients,
+		labelSelector:    scope.LabelSelector,
+		resourceVersions: map[string]string{},
+	}
+}
+
+// setResourceVersion remembers the latest resource version seen
+// in the provided namespace
+func (lw *multiNamespaceListWatch) setResourceVersion(namespace, rv string) {
+	if rv == "" {
+		return
+	}
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	lw.resourceVersions[namespace] = rv
+}
+
+func (lw *multiNamespaceListWatch) getResourceVersion(namespace string) string {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+	return lw.resourceVersions[namespace]
+}
+
+// List implements cache.Lister interface
+//
+// NOTE:
+//	Pagination is disabled since continue tokens are specific to
+// a single namespace
+func (lw *multiNamespaceListWatch) List(opts metav1.ListOptions) (runtime.Object, error) {
+	opts.LabelSelector = lw.labelSelector
+	opts.Limit = 0
+	opts.Continue = ""
+
+	merged := &unstructured.UnstructuredList{}
+	for ns, client := range lw.clients {
+		list, err := client.List(opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't list in namespace %q", ns)
+		}
+		lw.setResourceVersion(ns, list.GetResourceVersion())
+		if merged.Object == nil {
+			merged.Object = list.Object
+		}
+		merged.SetResourceVersion(list.GetResourceVersion())
+		merged.Items = append(merged.Items, list.Items...)
+	}
+	return merged, nil
+}
+
+// Watch implements cache.Watcher interface
+func (lw *multiNamespaceListWatch) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	opts.LabelSelector = lw.labelSelector
+
+	mux := newMultiWatch()
+	for ns, client := range lw.clients {
+		nsOpts := opts
+		if rv := lw.getResourceVersion(ns); rv != "" {
+			nsOpts.ResourceVersion = rv
+		}
+		w, err := client.Watch(nsOpts)
+		if err != nil {
+			mux.Stop()
+			return nil, errors.Wrapf(err, "Can't watch in namespace %q", ns)
+		}
+		ns := ns
+		mux.add(w, func(event watch.Event) {
+			if event.Type == watch.Error {
+				return
+			}
+			if obj, err := meta.Accessor(event.Object); err == nil {
+				lw.setResourceVersion(ns, obj.GetResourceVersion())
+			}
+		})
+	}
+	mux.start()
+	return mux, nil
+}
+
+// multiWatch multiplexes the events of several watches into a
+// single result channel. It stops all the watches when any one of
+// them is closed. This lets the informer resume all the watches.
+type multiWatch struct {
+	watchers []watch.Interface
+	onEvents []func(watch.Event)
+
+	result chan watch.Event
+	stopCh chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+func newMultiWatch() *multiWatch {
+	return &multiWatch{
+		result: make(chan watch.Event),
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (mw *multiWatch) add(w watch.Interface, onEvent func(watch.Event)) {
+	mw.watchers = append(mw.watchers, w)
+	mw.onEvents = append(mw.onEvents, onEvent)
+}
+
+func (mw *multiWatch) start() {
+	for i, w := range mw.watchers {
+		mw.wg.Add(1)
+		go mw.forward(w, mw.onEvents[i])
+	}
+	go func() {
+		mw.wg.Wait()
+		close(mw.result)
+	}()
+}
+
+func (mw *multiWatch) forward(w watch.Interface, onEvent func(watch.Event)) {
+	defer mw.wg.Done()
+	// closure of any watch stops all of them
+	defer mw.Stop()
+
+	for {
+		select {
+		case <-mw.stopCh:
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			onEvent(event)
+			select {
+			case mw.result <- event:
+			case <-mw.stopCh:
+				return
+			}
+		}
+	}
+}
+
+// Stop implements watch.Interface
+func (mw *multiWatch) Stop() {
+	mw.once.Do(func() {
+		close(mw.stopCh)
+		for _, w := range mw.watchers {
+			w.Stop()
+		}
+	})
+}
+
+// ResultChan implements watch.Interface
+func (mw *multiWatch) ResultChan() <-chan watch.Event {
+	return mw.result
+}
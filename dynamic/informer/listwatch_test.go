@@ -0,0 +1,427 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	dynamicclientset "openebs.io/metac/dynamic/clientset"
+	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
+)
+
+// listWatchTestServer fakes the list & watch calls of config maps.
+// Lists return the objects of the requested namespace at the
+// namespace's resource version. Watches are recorded per namespace.
+type listWatchTestServer struct {
+	objs             []*unstructured.Unstructured
+	resourceVersions map[string]string
+
+	mutex   sync.Mutex
+	watches map[string][]*listWatchTestWatch
+}
+
+// listWatchTestWatch is a watch started by the fake server
+type listWatchTestWatch struct {
+	*watch.FakeWatcher
+	resourceVersion string
+	labelSelector   string
+}
+
+func newListWatchTestClient(
+	t *testing.T,
+	server *listWatchTestServer,
+) *dynamicclientset.ResourceClient {
+	clientset := newListWatchTestClientset(t, server)
+	rc, err := clientset.GetClientForAPIVersionAndResource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	return rc
+}
+
+// newListWatchTestClientset returns a clientset whose config map
+// lists & watches are served by the provided fake server
+func newListWatchTestClientset(
+	t *testing.T,
+	server *listWatchTestServer,
+) *dynamicclientset.Clientset {
+	server.watches = map[string][]*listWatchTestWatch{}
+	client := fakedynamic.NewSimpleDynamicClient(k8sruntime.NewScheme())
+	client.PrependReactor(
+		"list",
+		"configmaps",
+		func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+			ns := action.GetNamespace()
+			list := &unstructured.UnstructuredList{
+				Object: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "List",
+				},
+			}
+			list.SetResourceVersion(server.resourceVersions[ns])
+			for _, obj := range server.objs {
+				if obj.GetNamespace() == ns {
+					list.Items = append(list.Items, *obj.DeepCopy())
+				}
+			}
+			return true, list, nil
+		},
+	)
+	client.PrependWatchReactor(
+		"configmaps",
+		func(action clienttesting.Action) (bool, watch.Interface, error) {
+			restrictions := action.(clienttesting.WatchAction).GetWatchRestrictions()
+			w := &listWatchTestWatch{
+				FakeWatcher:     watch.NewFakeWithChanSize(1, false),
+				resourceVersion: restrictions.ResourceVersion,
+				labelSelector:   restrictions.Labels.String(),
+			}
+			server.mutex.Lock()
+			defer server.mutex.Unlock()
+			ns := action.GetNamespace()
+			server.watches[ns] = append(server.watches[ns], w)
+			return true, w, nil
+		},
+	)
+	return dynamicclientset.NewForDynamicClient(
+		client,
+		newListWatchTestDiscovery(t),
+	)
+}
+
+// lastWatch returns the latest watch of the provided namespace
+func (s *listWatchTestServer) lastWatch(t *testing.T, ns string) *listWatchTestWatch {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	watches := s.watches[ns]
+	if len(watches) == 0 {
+		t.Fatalf("Expected watch in namespace %q got none", ns)
+	}
+	return watches[len(watches)-1]
+}
+
+// newListWatchTestDiscovery returns a synced discovery of config
+// maps & namespaces
+func newListWatchTestDiscovery(t *testing.T) *dynamicdiscovery.APIResourceDiscovery {
+	discovery := dynamicdiscovery.NewAPIResourceDiscoverer(
+		&fakediscovery.FakeDiscovery{
+			Fake: &clienttesting.Fake{
+				Resources: []*metav1.APIResourceList{
+					{
+						GroupVersion: "v1",
+						APIResources: []metav1.APIResource{
+							{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+							{Name: "namespaces", Kind: "Namespace"},
+						},
+					},
+				},
+			},
+		},
+	)
+	discovery.Start(time.Hour)
+	for !discovery.HasSynced() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return discovery
+}
+
+func newListWatchTestObj(namespace, name, rv string) *unstructured.Unstructured {
+	obj := newIndexTestObj(namespace, name, "")
+	obj.SetLabels(map[string]string{"app": "test"})
+	obj.SetResourceVersion(rv)
+	return obj
+}
+
+// waitForGoroutines waits till the number of goroutines is not
+// more than the provided count & returns the last observed count
+func waitForGoroutines(count int) int {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := runtime.NumGoroutine()
+		if got <= count || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScopeNormalize(t *testing.T) {
+	var tests = map[string]struct {
+		scope      Scope
+		namespaced bool
+		expect     Scope
+	}{
+		"zero scope": {
+			namespaced: true,
+			expect:     Scope{},
+		},
+		"namespaces are sorted & unique": {
+			scope: Scope{
+				Namespaces:    []string{"ns2", "", "ns1", "ns2"},
+				LabelSelector: "app=test",
+			},
+			namespaced: true,
+			expect: Scope{
+				Namespaces:    []string{"ns1", "ns2"},
+				LabelSelector: "app=test",
+			},
+		},
+		"namespaces are dropped for cluster scoped resource": {
+			scope: Scope{
+				Namespaces:    []string{"ns1"},
+				LabelSelector: "app=test",
+			},
+			expect: Scope{LabelSelector: "app=test"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := mock.scope.normalize(mock.namespaced)
+			if !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected %#v got %#v", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestNewListWatchSingleNamespace(t *testing.T) {
+	server := &listWatchTestServer{
+		objs: []*unstructured.Unstructured{
+			newListWatchTestObj("ns1", "a1", "1"),
+			newListWatchTestObj("ns2", "a2", "2"),
+		},
+		resourceVersions: map[string]string{"ns1": "10"},
+	}
+	client := newListWatchTestClient(t, server)
+	lw := newListWatch(client, Scope{
+		Namespaces:    []string{"ns1"},
+		LabelSelector: "app=test",
+	})
+	if _, ok := lw.(*multiNamespaceListWatch); ok {
+		t.Fatalf("Expected single namespace list watch got multi namespace")
+	}
+	obj, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	list := obj.(*unstructured.UnstructuredList)
+	if len(list.Items) != 1 || list.Items[0].GetName() != "a1" {
+		t.Fatalf("Expected only a1 got %v", list.Items)
+	}
+	w, err := lw.Watch(metav1.ListOptions{ResourceVersion: "10"})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer w.Stop()
+	got := server.lastWatch(t, "ns1")
+	if got.resourceVersion != "10" || got.labelSelector != "app=test" {
+		t.Fatalf(
+			"Expected watch from 10 with selector app=test got %q with %q",
+			got.resourceVersion,
+			got.labelSelector,
+		)
+	}
+}
+
+func TestMultiNamespaceListWatchList(t *testing.T) {
+	server := &listWatchTestServer{
+		objs: []*unstructured.Unstructured{
+			newListWatchTestObj("ns1", "a1", "1"),
+			newListWatchTestObj("ns1", "a2", "2"),
+			newListWatchTestObj("ns2", "a3", "3"),
+			newListWatchTestObj("other", "a4", "4"),
+		},
+		resourceVersions: map[string]string{"ns1": "10", "ns2": "20"},
+	}
+	client := newListWatchTestClient(t, server)
+	lw := newListWatch(client, Scope{Namespaces: []string{"ns1", "ns2"}})
+	mlw, ok := lw.(*multiNamespaceListWatch)
+	if !ok {
+		t.Fatalf("Expected multi namespace list watch got %T", lw)
+	}
+
+	// pagination is not sent to the API server
+	obj, err := mlw.List(metav1.ListOptions{Limit: 1, Continue: "token"})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	list := obj.(*unstructured.UnstructuredList)
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"a1", "a2", "a3"}) {
+		t.Fatalf("Expected merged list [a1 a2 a3] got %v", names)
+	}
+	for ns, expect := range server.resourceVersions {
+		if got := mlw.getResourceVersion(ns); got != expect {
+			t.Fatalf(
+				"Expected resource version %q of namespace %q got %q",
+				expect,
+				ns,
+				got,
+			)
+		}
+	}
+}
+
+func TestMultiNamespaceListWatchResume(t *testing.T) {
+	server := &listWatchTestServer{
+		resourceVersions: map[string]string{"ns1": "10", "ns2": "20"},
+	}
+	client := newListWatchTestClient(t, server)
+	lw := newListWatch(client, Scope{
+		Namespaces:    []string{"ns1", "ns2"},
+		LabelSelector: "app=test",
+	})
+	_, err := lw.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	// resource version of the merged list is not used
+	w, err := lw.Watch(metav1.ListOptions{ResourceVersion: "20"})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	for ns, expect := range map[string]string{"ns1": "10", "ns2": "20"} {
+		got := server.lastWatch(t, ns)
+		if got.resourceVersion != expect {
+			t.Fatalf(
+				"Expected watch of namespace %q from %q got %q",
+				ns,
+				expect,
+				got.resourceVersion,
+			)
+		}
+		if got.labelSelector != "app=test" {
+			t.Fatalf("Expected selector app=test got %q", got.labelSelector)
+		}
+	}
+
+	// an event is multiplexed & advances its namespace only
+	server.lastWatch(t, "ns1").Add(newListWatchTestObj("ns1", "a1", "15"))
+	select {
+	case event := <-w.ResultChan():
+		obj := event.Object.(*unstructured.Unstructured)
+		if event.Type != watch.Added || obj.GetName() != "a1" {
+			t.Fatalf("Expected added a1 got %s %s", event.Type, obj.GetName())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected event got none")
+	}
+	// error events do not change resource versions
+	server.lastWatch(t, "ns2").Error(&metav1.Status{
+		ListMeta: metav1.ListMeta{ResourceVersion: "99"},
+	})
+	<-w.ResultChan()
+	w.Stop()
+
+	// restarted watch resumes every namespace from its own version
+	w, err = lw.Watch(metav1.ListOptions{ResourceVersion: "20"})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer w.Stop()
+	for ns, expect := range map[string]string{"ns1": "15", "ns2": "20"} {
+		got := server.lastWatch(t, ns)
+		if got.resourceVersion != expect {
+			t.Fatalf(
+				"Expected resumed watch of namespace %q from %q got %q",
+				ns,
+				expect,
+				got.resourceVersion,
+			)
+		}
+	}
+}
+
+func TestMultiNamespaceListWatchStopsAll(t *testing.T) {
+	server := &listWatchTestServer{
+		resourceVersions: map[string]string{},
+	}
+	client := newListWatchTestClient(t, server)
+	lw := newListWatch(client, Scope{Namespaces: []string{"ns1", "ns2", "ns3"}})
+
+	before := runtime.NumGoroutine()
+	w, err := lw.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	// closure of any one watch closes the multiplexed watch
+	server.lastWatch(t, "ns2").Stop()
+	select {
+	case _, ok := <-w.ResultChan():
+		if ok {
+			t.Fatalf("Expected closed result channel got event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected closed result channel got none")
+	}
+	for _, ns := range []string{"ns1", "ns2", "ns3"} {
+		if !server.lastWatch(t, ns).IsStopped() {
+			t.Fatalf("Expected watch of namespace %q to be stopped", ns)
+		}
+	}
+	// stop is idempotent
+	w.Stop()
+
+	if got := waitForGoroutines(before); got > before {
+		t.Fatalf("Expected at most %d goroutines got %d", before, got)
+	}
+}
+
+func TestMultiNamespaceListWatchStopWithPendingEvent(t *testing.T) {
+	server := &listWatchTestServer{
+		resourceVersions: map[string]string{},
+	}
+	client := newListWatchTestClient(t, server)
+	lw := newListWatch(client, Scope{Namespaces: []string{"ns1", "ns2"}})
+
+	before := runtime.NumGoroutine()
+	w, err := lw.Watch(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	// event that is never received by the consumer
+	server.lastWatch(t, "ns1").Add(newListWatchTestObj("ns1", "a1", "1"))
+	w.Stop()
+
+	if got := waitForGoroutines(before); got > before {
+		t.Fatalf("Expected at most %d goroutines got %d", before, got)
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Fatalf("Expected closed result channel got event")
+	}
+}
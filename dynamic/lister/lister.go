@@ -61,6 +61,26 @@ func (l *Lister) ListNamespace(
 	return ret, err
 }
 
+// ByIndex returns the API resources whose values for the provided
+// index include the provided value
+//
+// NOTE:
+//	Resources may have changed since they were indexed. Hence callers
+// should verify the returned resources if needed.
+func (l *Lister) ByIndex(
+	indexName string,
+	value string,
+) (ret []*unstructured.Unstructured, err error) {
+	objs, err := l.indexer.ByIndex(indexName, value)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		ret = append(ret, obj.(*unstructured.Unstructured))
+	}
+	return ret, nil
+}
+
 // Get returns the specific instance of API resource as an unstructured
 // instance
 func (l *Lister) Get(namespace, name string) (*unstructured.Unstructured, error) {
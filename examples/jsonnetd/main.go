@@ -28,6 +28,8 @@ import (
 	"syscall"
 
 	jsonnet "github.com/google/go-jsonnet"
+
+	"openebs.io/metac/hooks/jsonnet/extensions"
 )
 
 func main() {
@@ -64,8 +66,8 @@ func main() {
 
 			// Evaluate Jsonnet hook, passing request body as a top-level argument.
 			vm := jsonnet.MakeVM()
-			for _, ext := range extensions {
-				vm.NativeFunction(ext)
+			for _, fn := range extensions.NativeFunctions {
+				vm.NativeFunction(fn)
 			}
 			vm.TLACode("request", string(body))
 			result, err := vm.EvaluateSnippet(filename, hookcode)
@@ -0,0 +1,107 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Error is the structured error that a hook can respond with
+// when it fails. A webhook sends this as the body of its non
+// 200 response.
+//
+// For example:
+//
+//	{
+//	  "reason": "QuotaExceeded",
+//	  "message": "Bucket quota of 10 is exhausted",
+//	  "retryable": true,
+//	  "retryAfterSeconds": 30
+//	}
+type Error struct {
+	// Reason is a short CamelCase reason of the failure. This is
+	// mandatory.
+	Reason string `json:"reason"`
+
+	// Message is a human readable description of the failure
+	Message string `json:"message,omitempty"`
+
+	// Retryable tells if the sync should be retried. Defaults
+	// to true.
+	Retryable *bool `json:"retryable,omitempty"`
+
+	// RetryAfterSeconds requeues the sync after this many
+	// seconds instead of the rate limited backoff
+	RetryAfterSeconds float64 `json:"retryAfterSeconds,omitempty"`
+}
+
+// Error implements error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf(
+		"Hook error: Reason %q: Message %q",
+		e.Reason,
+		e.Message,
+	)
+}
+
+// IsRetryable returns true if the sync that resulted in this
+// error should be retried
+func (e *Error) IsRetryable() bool {
+	return e.Retryable == nil || *e.Retryable
+}
+
+// RetryAfter returns the duration after which the sync should
+// be retried. Zero means rate limited backoff is used.
+func (e *Error) RetryAfter() time.Duration {
+	if e.RetryAfterSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(e.RetryAfterSeconds * float64(time.Second))
+}
+
+// ParseError returns the hook error from the provided response
+// body. It returns nil if the body is not a hook error.
+func ParseError(body []byte) *Error {
+	var hookErr Error
+	if err := json.Unmarshal(body, &hookErr); err != nil {
+		return nil
+	}
+	if hookErr.Reason == "" {
+		// reason is mandatory to avoid treating arbitrary
+		// json responses as hook errors
+		return nil
+	}
+	return &hookErr
+}
+
+// AsError returns the hook error that caused the provided
+// error
+//
+// NOTE:
+//	Error is expected to be wrapped via github.com/pkg/errors
+// or by types that implement Cause() error
+func AsError(err error) (*Error, bool) {
+	if err == nil {
+		return nil, false
+	}
+	hookErr, ok := errors.Cause(err).(*Error)
+	return hookErr, ok
+}
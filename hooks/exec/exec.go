@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+// Invoker manages execution of a local command as a hook
+type Invoker struct {
+	// Command to be executed
+	Command string
+
+	// Arguments to the command
+	Args []string
+
+	// Environment variables in KEY=VALUE form that are set
+	// in addition to the ones of the current process
+	Env []string
+
+	// Maximum time the command is allowed to run
+	Timeout time.Duration
+}
+
+// InvokerOption is a typed function that is used
+// to build *Invoker instance
+//
+// NOTE:
+//	This follows "functional options" pattern
+type InvokerOption func(*Invoker) error
+
+// NewInvoker returns a new instance of Invoker
+// based on an optional list of InvokerOptions
+func NewInvoker(opts ...InvokerOption) (*Invoker, error) {
+	i := &Invoker{}
+	for _, o := range opts {
+		err := o(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if i.Command == "" {
+		return nil, errors.Errorf("Invalid exec hook: Empty command: %s", i)
+	}
+	return i, nil
+}
+
+// String implements Stringer interface
+func (i *Invoker) String() string {
+	return fmt.Sprintf(
+		"Exec Invoker: Command=%s: Timeout=%s",
+		strings.Join(append([]string{i.Command}, i.Args...), " "),
+		i.Timeout,
+	)
+}
+
+// Invoke executes the command by writing the given request to
+// its stdin and fills up the given response from its stdout
+//
+// NOTE:
+//	A non-zero exit or any output to stderr is considered
+// as a hook error
+func (i *Invoker) Invoke(request, response interface{}) error {
+	// Encode request.
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrapf(err, "%s: Failed to marshal", i)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := osexec.Command(i.Command, i.Args...)
+	cmd.Env = append(os.Environ(), i.Env...)
+	cmd.Stdin = bytes.NewReader(reqBody)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// NOTE:
+	//	Command runs in its own process group. This lets the
+	// command as well as its child processes to be killed on
+	// timeout.
+	setProcessGroup(cmd)
+
+	err = cmd.Start()
+	if err != nil {
+		return errors.Wrapf(err, "%s: Failed to start", i)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var timeout <-chan time.Time
+	if i.Timeout > 0 {
+		timer := time.NewTimer(i.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err = <-done:
+	case <-timeout:
+		killProcessGroup(cmd)
+		<-done
+		return errors.Errorf("%s: Timed out: Stderr %q", i, stderr.Bytes())
+	}
+	if err != nil {
+		return errors.Wrapf(err, "%s: Failed to execute: Stderr %q", i, stderr.Bytes())
+	}
+	if stderr.Len() != 0 {
+		return errors.Errorf("%s: Got stderr %q", i, stderr.Bytes())
+	}
+	glog.V(8).Infof("%s: Got stdout %q", i, stdout.Bytes())
+
+	// Decode response.
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return errors.Wrapf(err, "%s: Failed to unmarshal stdout", i)
+	}
+
+	glog.V(8).Infof("%s: Invoked successfully", i)
+	return nil
+}
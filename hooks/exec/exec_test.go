@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package exec
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvoke(t *testing.T) {
+	var tests = map[string]struct {
+		script       string
+		env          []string
+		timeout      time.Duration
+		expect       map[string]interface{}
+		isErr        bool
+		expectErrMsg string
+	}{
+		"request is read from stdin": {
+			script: `cat`,
+			expect: map[string]interface{}{"phase": "Ready"},
+		},
+		"env is set": {
+			script: `printf '{"phase": "%s"}' "$PHASE"`,
+			env:    []string{"PHASE=Done"},
+			expect: map[string]interface{}{"phase": "Done"},
+		},
+		"non zero exit": {
+			script:       `echo failed >&2; exit 3`,
+			isErr:        true,
+			expectErrMsg: "failed",
+		},
+		"stderr with zero exit": {
+			script:       `echo warning >&2; cat`,
+			isErr:        true,
+			expectErrMsg: "warning",
+		},
+		"invalid stdout": {
+			script: `echo not-json`,
+			isErr:  true,
+		},
+		"timeout": {
+			script:       `sleep 5`,
+			timeout:      100 * time.Millisecond,
+			isErr:        true,
+			expectErrMsg: "Timed out",
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.Command = "sh"
+				i.Args = []string{"-c", mock.script}
+				i.Env = mock.env
+				i.Timeout = mock.timeout
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var got map[string]interface{}
+			err = invoker.Invoke(map[string]string{"phase": "Ready"}, &got)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr && !strings.Contains(err.Error(), mock.expectErrMsg) {
+				t.Fatalf("Expected error with %q got [%+v]", mock.expectErrMsg, err)
+			}
+			if !mock.isErr && !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected %v got %v", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestNewInvokerWithEmptyCommand(t *testing.T) {
+	_, err := NewInvoker()
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
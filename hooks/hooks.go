@@ -44,7 +44,10 @@ func NewInvoker(must InvokerOption, others ...InvokerOption) (*Invoker, error) {
 
 	i := &Invoker{}
 	for _, o := range options {
-		o(i)
+		err := o(i)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if i.InvokeFn == nil {
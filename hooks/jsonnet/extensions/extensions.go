@@ -1,5 +1,6 @@
 /*
-Copyright 2018 Google Inc.
+Copyright 2017 Google Inc.
+Copyright 2020 The MayaData Authors.
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -14,7 +15,10 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-package main
+// Package extensions provides the native functions that are
+// available to jsonnet hooks evaluated within metac as well as to
+// the ones served by examples/jsonnetd
+package extensions
 
 import (
 	"encoding/json"
@@ -25,7 +29,9 @@ import (
 	"github.com/google/go-jsonnet/ast"
 )
 
-var extensions = []*jsonnet.NativeFunction{
+// NativeFunctions are the native functions available to jsonnet
+// hooks via std.native
+var NativeFunctions = []*jsonnet.NativeFunction{
 	// jsonUnmarshal adds a native function for unmarshaling JSON,
 	// since there doesn't seem to be one in the standard library.
 	{
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonnet
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jsonnet "github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+)
+
+// importDir is the directory that jsonnet hooks may import files
+// from
+var importDir struct {
+	mutex sync.RWMutex
+	dir   string
+}
+
+// SetImportDir lets jsonnet hooks import the files of the provided
+// directory & its sub directories. Imports are not allowed if this
+// is empty.
+//
+// NOTE:
+//	Metac binary sets this directory from its flags
+func SetImportDir(dir string) {
+	importDir.mutex.Lock()
+	defer importDir.mutex.Unlock()
+	importDir.dir = dir
+}
+
+func getImportDir() string {
+	importDir.mutex.RLock()
+	defer importDir.mutex.RUnlock()
+	return importDir.dir
+}
+
+// dirImporter imports the files of a single directory
+//
+// NOTE:
+//	Jsonnet's default importer reads any file that is readable by
+// metac. Hooks can be set by anyone who can create a controller
+// resource & hence may not read metac's files e.g. its service
+// account token.
+type dirImporter struct {
+	// dir to import from. Every import fails if this is empty.
+	dir string
+}
+
+// Import implements jsonnet.Importer interface. Imported path is
+// resolved against the import directory irrespective of the file
+// that imports it. Paths that resolve outside this directory e.g.
+// via '..' or symbolic links are not found.
+func (i *dirImporter) Import(
+	importedFrom, importedPath string,
+) (jsonnet.Contents, string, error) {
+	if i.dir == "" {
+		return jsonnet.Contents{}, "", errors.Errorf(
+			"Can't import %q: Imports are not allowed", importedPath,
+		)
+	}
+	root, err := filepath.EvalSymlinks(i.dir)
+	if err != nil {
+		return jsonnet.Contents{}, "", errors.Wrapf(
+			err, "Can't import %q: Invalid import dir", importedPath,
+		)
+	}
+	// cleaning a rooted path drops the '..' that go above this root
+	path := filepath.Join(root, filepath.Clean("/"+importedPath))
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return jsonnet.Contents{}, "", errors.Errorf(
+			"Can't import %q: Not found", importedPath,
+		)
+	}
+	if !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return jsonnet.Contents{}, "", errors.Errorf(
+			"Can't import %q: Not found", importedPath,
+		)
+	}
+	data, err := ioutil.ReadFile(resolved)
+	if err != nil {
+		return jsonnet.Contents{}, "", errors.Wrapf(
+			err, "Can't import %q", importedPath,
+		)
+	}
+	return jsonnet.MakeContents(string(data)), path, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonnet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirImporterImport(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "jsonnet-import")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "lib")
+	files := map[string]string{
+		filepath.Join(dir, "util.libsonnet"):         `{ name: "util" }`,
+		filepath.Join(dir, "nested", "a.libsonnet"):  `{ name: "a" }`,
+		filepath.Join(tmp, "secret.txt"):             "secret",
+		filepath.Join(tmp, "outside", "b.libsonnet"): `{ name: "b" }`,
+	}
+	for path, data := range files {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		err = ioutil.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	err = os.Symlink(filepath.Join(tmp, "secret.txt"), filepath.Join(dir, "link.txt"))
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	var tests = map[string]struct {
+		dir    string
+		path   string
+		expect string
+		isErr  bool
+	}{
+		"imports are not allowed without dir": {
+			path:  filepath.Join(dir, "util.libsonnet"),
+			isErr: true,
+		},
+		"file of dir": {
+			dir:    dir,
+			path:   "util.libsonnet",
+			expect: `{ name: "util" }`,
+		},
+		"file of sub dir": {
+			dir:    dir,
+			path:   "nested/a.libsonnet",
+			expect: `{ name: "a" }`,
+		},
+		"absolute path is resolved against dir": {
+			dir:    dir,
+			path:   "/nested/a.libsonnet",
+			expect: `{ name: "a" }`,
+		},
+		"absolute path outside dir": {
+			dir:   dir,
+			path:  filepath.Join(tmp, "secret.txt"),
+			isErr: true,
+		},
+		"relative path outside dir": {
+			dir:   dir,
+			path:  "../secret.txt",
+			isErr: true,
+		},
+		"relative path via sub dir outside dir": {
+			dir:   dir,
+			path:  "nested/../../outside/b.libsonnet",
+			isErr: true,
+		},
+		"symbolic link outside dir": {
+			dir:   dir,
+			path:  "link.txt",
+			isErr: true,
+		},
+		"missing file": {
+			dir:   dir,
+			path:  "none.libsonnet",
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			i := &dirImporter{dir: mock.dir}
+			got, _, err := i.Import("hook", mock.path)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !mock.isErr && got.String() != mock.expect {
+				t.Fatalf("Expected %q got %q", mock.expect, got.String())
+			}
+		})
+	}
+}
+
+func TestInvokeImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonnet-import")
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(
+		filepath.Join(dir, "util.libsonnet"), []byte(`{ phase: "Ready" }`), 0644,
+	)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	invoker, err := NewInvoker(func(i *Invoker) error {
+		i.Name = "hook"
+		i.Snippet = `local util = import "util.libsonnet";
+			function(request) { status: { phase: util.phase } }`
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	var got map[string]interface{}
+	err = invoker.Invoke(map[string]interface{}{}, &got)
+	if err == nil {
+		t.Fatalf("Expected error without import dir got none")
+	}
+
+	SetImportDir(dir)
+	defer SetImportDir("")
+	got = nil
+	err = invoker.Invoke(map[string]interface{}{}, &got)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if got["status"].(map[string]interface{})["phase"] != "Ready" {
+		t.Fatalf("Expected phase Ready got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonnet
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	jsonnet "github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"openebs.io/metac/hooks/jsonnet/extensions"
+)
+
+const (
+	// defaultMaxStack is the default maximum depth of the calls
+	// made during an evaluation
+	defaultMaxStack = 500
+
+	// maxRunningEvaluations is the maximum number of evaluations
+	// that may run at a time including the ones that timed out
+	maxRunningEvaluations = 32
+)
+
+// runningEvaluations holds a slot for every evaluation till it
+// completes
+//
+// NOTE:
+//	An evaluation can't be interrupted. One that times out runs
+// in the background till it completes & hence runaway snippets
+// are bounded by these slots.
+var runningEvaluations = make(chan struct{}, maxRunningEvaluations)
+
+// Invoker manages evaluation of a jsonnet snippet as a hook
+type Invoker struct {
+	// Name of the snippet used in evaluation errors
+	Name string
+
+	// Jsonnet snippet to be evaluated
+	Snippet string
+
+	// Maximum time the evaluation is allowed to run. Zero
+	// implies no timeout.
+	Timeout time.Duration
+
+	// Maximum depth of the calls made during the evaluation
+	MaxStack int
+}
+
+// InvokerOption is a typed function that is used
+// to build *Invoker instance
+//
+// NOTE:
+//	This follows "functional options" pattern
+type InvokerOption func(*Invoker) error
+
+// NewInvoker returns a new instance of Invoker
+// based on an optional list of InvokerOptions
+func NewInvoker(opts ...InvokerOption) (*Invoker, error) {
+	i := &Invoker{
+		MaxStack: defaultMaxStack,
+	}
+	for _, o := range opts {
+		err := o(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if i.Snippet == "" {
+		return nil, errors.Errorf("Invalid jsonnet hook: Empty snippet: %s", i)
+	}
+	return i, nil
+}
+
+// String implements Stringer interface
+func (i *Invoker) String() string {
+	return fmt.Sprintf("Jsonnet Invoker: Name=%s: Timeout=%s", i.Name, i.Timeout)
+}
+
+// Invoke evaluates the jsonnet snippet with the given request
+// and fills up the given response with the evaluated result
+//
+// NOTE:
+//	Request is available to the snippet as the top level
+// argument named 'request'. Snippet may import the files of the
+// directory set via SetImportDir only.
+func (i *Invoker) Invoke(request, response interface{}) error {
+	// Encode request.
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrapf(err, "%s: Failed to marshal", i)
+	}
+
+	// NOTE:
+	//	A new VM is used for every evaluation since a VM is
+	// not safe for concurrent use
+	vm := jsonnet.MakeVM()
+	vm.MaxStack = i.MaxStack
+	vm.Importer(&dirImporter{dir: getImportDir()})
+	for _, fn := range extensions.NativeFunctions {
+		vm.NativeFunction(fn)
+	}
+	vm.TLACode("request", string(reqBody))
+	result, err := i.evaluate(vm)
+	if err != nil {
+		return err
+	}
+	glog.V(8).Infof("%s: Got result %q", i, result)
+
+	// Decode response.
+	if err := json.Unmarshal([]byte(result), response); err != nil {
+		return errors.Wrapf(err, "%s: Failed to unmarshal result", i)
+	}
+
+	glog.V(8).Infof("%s: Invoked successfully", i)
+	return nil
+}
+
+// evaluate evaluates the snippet with the provided VM. It returns
+// error if the evaluation does not complete within the timeout.
+func (i *Invoker) evaluate(vm *jsonnet.VM) (string, error) {
+	var timeout <-chan time.Time
+	if i.Timeout > 0 {
+		timer := time.NewTimer(i.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case runningEvaluations <- struct{}{}:
+	case <-timeout:
+		return "", errors.Errorf(
+			"%s: Timed out: Waiting for running evaluations", i,
+		)
+	}
+
+	type evaluation struct {
+		result string
+		err    error
+	}
+	// buffered so that a timed out evaluation does not block
+	done := make(chan evaluation, 1)
+	go func() {
+		defer func() { <-runningEvaluations }()
+		result, err := vm.EvaluateSnippet(i.Name, i.Snippet)
+		done <- evaluation{result: result, err: err}
+	}()
+	select {
+	case e := <-done:
+		if e.err != nil {
+			return "", errors.Wrapf(e.err, "%s: Failed to evaluate", i)
+		}
+		return e.result, nil
+	case <-timeout:
+		return "", errors.Errorf("%s: Timed out", i)
+	}
+}
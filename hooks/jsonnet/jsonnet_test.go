@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonnet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvoke(t *testing.T) {
+	var tests = map[string]struct {
+		snippet string
+		request map[string]interface{}
+		expect  map[string]interface{}
+		isErr   bool
+	}{
+		"request is available as top level argument": {
+			snippet: `function(request) { status: { name: request.watch.name } }`,
+			request: map[string]interface{}{
+				"watch": map[string]interface{}{"name": "test"},
+			},
+			expect: map[string]interface{}{
+				"status": map[string]interface{}{"name": "test"},
+			},
+		},
+		"extensions are available": {
+			snippet: `function(request) {
+				count: std.native("parseInt")(request.hex, 16),
+				parsed: std.native("jsonUnmarshal")(request.raw),
+			}`,
+			request: map[string]interface{}{
+				"hex": "ff",
+				"raw": `{"a": "b"}`,
+			},
+			expect: map[string]interface{}{
+				"count":  int64(255),
+				"parsed": map[string]interface{}{"a": "b"},
+			},
+		},
+		"evaluation error": {
+			snippet: `function(request) { status: error "failed" }`,
+			request: map[string]interface{}{},
+			isErr:   true,
+		},
+		"unbounded recursion": {
+			snippet: `function(request)
+				local f(n) = f(n + 1) + 1;
+				{ count: f(0) }`,
+			request: map[string]interface{}{},
+			isErr:   true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.Name = name
+				i.Snippet = mock.snippet
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var got map[string]interface{}
+			err = invoker.Invoke(mock.request, &got)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if !mock.isErr && !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected %v got %v", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestNewInvokerWithEmptySnippet(t *testing.T) {
+	_, err := NewInvoker()
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
+
+func TestInvokeWithTimeout(t *testing.T) {
+	invoker, err := NewInvoker(func(i *Invoker) error {
+		i.Name = "runaway"
+		// evaluates the function 2^40 times
+		i.Snippet = `function(request)
+			local f(n) = if n == 0 then 0 else f(n - 1) + f(n - 1);
+			{ count: f(40) }`
+		i.Timeout = 100 * time.Millisecond
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	start := time.Now()
+	var got map[string]interface{}
+	err = invoker.Invoke(map[string]interface{}{}, &got)
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+	if !strings.Contains(err.Error(), "Timed out") {
+		t.Fatalf("Expected timed out error got [%+v]", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected invoke to return after timeout got %s", elapsed)
+	}
+}
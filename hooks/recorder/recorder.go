@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package recorder records hook requests & responses so that
+// these can be inspected offline or replayed against a hook to
+// detect changes in its responses.
+package recorder
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Record is a single hook invocation
+type Record struct {
+	// kind & name of the controller that invoked the hook
+	ControllerKind string `json:"controllerKind"`
+	ControllerName string `json:"controllerName"`
+
+	// Hook is the description of the hook e.g. its URL
+	Hook string `json:"hook"`
+
+	// time when the hook was invoked & when it completed
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+
+	// Result is either success or error
+	Result string `json:"result"`
+
+	// Error is the error returned by the hook if any
+	Error string `json:"error,omitempty"`
+
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Sink stores the records
+type Sink interface {
+	Write(record *Record) error
+}
+
+// Recorder writes records to all of its sinks
+type Recorder struct {
+	// RecordAll records invocations of all hooks. Only the hooks
+	// that opt for recording are recorded otherwise.
+	RecordAll bool
+
+	Sinks []Sink
+}
+
+// RecorderOption is a typed function that is used to build
+// *Recorder instance
+//
+// NOTE:
+//	This follows "functional options" pattern
+type RecorderOption func(*Recorder) error
+
+// NewRecorder returns a new instance of Recorder based on the
+// provided options
+func NewRecorder(opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{}
+	for _, o := range opts {
+		err := o(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// RecordAll enables recording of all hooks
+func RecordAll(all bool) RecorderOption {
+	return func(r *Recorder) error {
+		r.RecordAll = all
+		return nil
+	}
+}
+
+// WithDir adds a sink that writes each record as a file in
+// the provided directory
+func WithDir(dir string, opts ...DirSinkOption) RecorderOption {
+	return func(r *Recorder) error {
+		sink, err := NewDirSink(dir, opts...)
+		if err != nil {
+			return err
+		}
+		r.Sinks = append(r.Sinks, sink)
+		return nil
+	}
+}
+
+// WithRing adds a sink that keeps the provided number of most
+// recent records in memory
+func WithRing(ring *RingSink) RecorderOption {
+	return func(r *Recorder) error {
+		if ring == nil {
+			return errors.Errorf("Invalid ring sink: Nil sink")
+		}
+		r.Sinks = append(r.Sinks, ring)
+		return nil
+	}
+}
+
+// IsEnabled returns true if the hook should be recorded based on
+// its opt-in
+func (r *Recorder) IsEnabled(optIn bool) bool {
+	if r == nil || len(r.Sinks) == 0 {
+		return false
+	}
+	return r.RecordAll || optIn
+}
+
+// Write writes the provided record to all the sinks
+func (r *Recorder) Write(record *Record) error {
+	var errs []string
+	for _, sink := range r.Sinks {
+		err := sink.Write(record)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return errors.Errorf(
+			"Can't write hook record: %s",
+			strings.Join(errs, ": "),
+		)
+	}
+	return nil
+}
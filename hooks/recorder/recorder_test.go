@@ -0,0 +1,363 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRecorderIsEnabled(t *testing.T) {
+	ring, _ := NewRingSink(1)
+	var tests = map[string]struct {
+		recorder *Recorder
+		optIn    bool
+		expect   bool
+	}{
+		"nil recorder": {
+			optIn:  true,
+			expect: false,
+		},
+		"no sinks": {
+			recorder: &Recorder{RecordAll: true},
+			optIn:    true,
+			expect:   false,
+		},
+		"opted in": {
+			recorder: &Recorder{Sinks: []Sink{ring}},
+			optIn:    true,
+			expect:   true,
+		},
+		"not opted in": {
+			recorder: &Recorder{Sinks: []Sink{ring}},
+			expect:   false,
+		},
+		"record all": {
+			recorder: &Recorder{RecordAll: true, Sinks: []Sink{ring}},
+			expect:   true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := mock.recorder.IsEnabled(mock.optIn)
+			if got != mock.expect {
+				t.Fatalf("Expected %t got %t", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestRingSink(t *testing.T) {
+	var tests = map[string]struct {
+		size   int
+		writes []string
+		expect []string
+	}{
+		"empty": {
+			size: 2,
+		},
+		"partially filled": {
+			size:   3,
+			writes: []string{"a", "b"},
+			expect: []string{"a", "b"},
+		},
+		"exactly filled": {
+			size:   2,
+			writes: []string{"a", "b"},
+			expect: []string{"a", "b"},
+		},
+		"wrapped around": {
+			size:   2,
+			writes: []string{"a", "b", "c"},
+			expect: []string{"b", "c"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			ring, err := NewRingSink(mock.size)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			for _, hook := range mock.writes {
+				ring.Write(&Record{Hook: hook})
+			}
+			got := ring.List()
+			if len(got) != len(mock.expect) {
+				t.Fatalf("Expected %d records got %d", len(mock.expect), len(got))
+			}
+			for i, record := range got {
+				if record.Hook != mock.expect[i] {
+					t.Fatalf("Expected record %q at %d got %q", mock.expect[i], i, record.Hook)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRingSinkInvalidSize(t *testing.T) {
+	_, err := NewRingSink(0)
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
+
+func TestDirSinkWriteAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hook-records")
+	if err != nil {
+		t.Fatalf("Can't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink, err := NewDirSink(dir)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	start := time.Now()
+	for i, hook := range []string{"first", "second", "third"} {
+		err := sink.Write(&Record{
+			ControllerKind: "gctl",
+			ControllerName: "ns/my-gctl",
+			Hook:           hook,
+			StartTime:      start.Add(time.Duration(i) * time.Millisecond),
+			Request:        json.RawMessage(`{"watch":{}}`),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	records, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records got %d", len(records))
+	}
+	for i, hook := range []string{"first", "second", "third"} {
+		if records[i].Hook != hook {
+			t.Fatalf("Expected record %q at %d got %q", hook, i, records[i].Hook)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Fatalf("Expected file mode 0600 got %o: %s", info.Mode().Perm(), file)
+		}
+	}
+}
+
+func TestDirSinkLimits(t *testing.T) {
+	record := func(hook string, i int) *Record {
+		return &Record{
+			ControllerKind: "gctl",
+			ControllerName: "ns/my-gctl",
+			Hook:           hook,
+			StartTime:      time.Unix(0, 0).Add(time.Duration(i) * time.Second),
+			Request:        json.RawMessage(`{"watch":{}}`),
+		}
+	}
+	// size of each record written by this test
+	raw, _ := json.MarshalIndent(record("h0", 0), "", "  ")
+	size := int64(len(raw))
+
+	var tests = map[string]struct {
+		existing    int
+		opts        []DirSinkOption
+		writes      int
+		expectHooks []string
+	}{
+		"no limits": {
+			opts:        []DirSinkOption{MaxFiles(0), MaxBytes(0)},
+			writes:      3,
+			expectHooks: []string{"h0", "h1", "h2"},
+		},
+		"max files": {
+			opts:        []DirSinkOption{MaxFiles(2)},
+			writes:      5,
+			expectHooks: []string{"h3", "h4"},
+		},
+		"max bytes": {
+			opts:        []DirSinkOption{MaxBytes(3 * size)},
+			writes:      5,
+			expectHooks: []string{"h2", "h3", "h4"},
+		},
+		"max bytes less than a record keeps the newest": {
+			opts:        []DirSinkOption{MaxBytes(1)},
+			writes:      2,
+			expectHooks: []string{"h1"},
+		},
+		"existing records are pruned": {
+			existing:    3,
+			opts:        []DirSinkOption{MaxFiles(2)},
+			expectHooks: []string{"h1", "h2"},
+		},
+		"existing records count towards limits": {
+			existing:    2,
+			opts:        []DirSinkOption{MaxFiles(3)},
+			writes:      2,
+			expectHooks: []string{"h1", "h2", "h3"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hook-records")
+			if err != nil {
+				t.Fatalf("Can't create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			existing, err := NewDirSink(dir, MaxFiles(0))
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			for i := 0; i < mock.existing; i++ {
+				err = existing.Write(record(fmt.Sprintf("h%d", i), i))
+				if err != nil {
+					t.Fatalf("Expected no error got [%+v]", err)
+				}
+			}
+			sink, err := NewDirSink(dir, mock.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			for i := mock.existing; i < mock.existing+mock.writes; i++ {
+				err = sink.Write(record(fmt.Sprintf("h%d", i), i))
+				if err != nil {
+					t.Fatalf("Expected no error got [%+v]", err)
+				}
+			}
+			records, err := LoadDir(dir)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var got []string
+			for _, r := range records {
+				got = append(got, r.Hook)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(mock.expectHooks) {
+				t.Fatalf("Expected records %v got %v", mock.expectHooks, got)
+			}
+		})
+	}
+}
+
+func TestNewDirSinkInvalidLimits(t *testing.T) {
+	_, err := NewDirSink("records", MaxFiles(-1))
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+	_, err = NewDirSink("records", MaxBytes(-1))
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	echo := func(request json.RawMessage) (json.RawMessage, error) {
+		return request, nil
+	}
+	fail := func(request json.RawMessage) (json.RawMessage, error) {
+		return nil, errors.Errorf("oops")
+	}
+	var tests = map[string]struct {
+		record  *Record
+		invoke  InvokeFn
+		isMatch bool
+	}{
+		"same response": {
+			record: &Record{
+				Request:  json.RawMessage(`{"a":1,"b":2}`),
+				Response: json.RawMessage(`{"b":2, "a":1}`),
+			},
+			invoke:  echo,
+			isMatch: true,
+		},
+		"different response": {
+			record: &Record{
+				Request:  json.RawMessage(`{"a":1}`),
+				Response: json.RawMessage(`{"a":2}`),
+			},
+			invoke:  echo,
+			isMatch: false,
+		},
+		"same error": {
+			record: &Record{
+				Request: json.RawMessage(`{}`),
+				Error:   "oops",
+			},
+			invoke:  fail,
+			isMatch: true,
+		},
+		"error instead of response": {
+			record: &Record{
+				Request:  json.RawMessage(`{}`),
+				Response: json.RawMessage(`{}`),
+			},
+			invoke:  fail,
+			isMatch: false,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			results := Replay([]*Record{mock.record}, mock.invoke)
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 result got %d", len(results))
+			}
+			if results[0].IsMatch() != mock.isMatch {
+				t.Fatalf(
+					"Expected match %t got %t: %s",
+					mock.isMatch,
+					results[0].IsMatch(),
+					results[0].Diff,
+				)
+			}
+		})
+	}
+}
+
+func TestReplayDirWithoutRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hook-records")
+	if err != nil {
+		t.Fatalf("Can't create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = ReplayDir(dir, func(request json.RawMessage) (json.RawMessage, error) {
+		return request, nil
+	})
+	if err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+// InvokeFn invokes a hook with the provided request & returns
+// its response
+type InvokeFn func(request json.RawMessage) (json.RawMessage, error)
+
+// ReplayResult is the outcome of replaying a single record
+type ReplayResult struct {
+	Record *Record
+
+	// Response & Error are the outcome of the replay
+	Response json.RawMessage
+	Error    string
+
+	// Diff between the recorded & the replayed outcome. This is
+	// empty if both are same.
+	Diff string
+}
+
+// IsMatch returns true if the replayed outcome is same as the
+// recorded outcome
+func (r ReplayResult) IsMatch() bool {
+	return r.Diff == ""
+}
+
+// Replay invokes the provided hook with each of the recorded
+// requests & compares the responses against the recorded ones
+func Replay(records []*Record, invoke InvokeFn) []ReplayResult {
+	var results []ReplayResult
+	for _, record := range records {
+		result := ReplayResult{Record: record}
+		response, err := invoke(record.Request)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Response = response
+		}
+		result.Diff = diff(record, result)
+		results = append(results, result)
+	}
+	return results
+}
+
+// ReplayDir replays the records found in the provided directory
+// against the provided hook. This is meant to be used by golden
+// tests of a hook whose records were captured from real traffic.
+func ReplayDir(dir string, invoke InvokeFn) ([]ReplayResult, error) {
+	records, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.Errorf("No hook records found in %q", dir)
+	}
+	return Replay(records, invoke), nil
+}
+
+// diff returns the difference between the recorded & replayed
+// outcome
+//
+// NOTE:
+//	Responses are compared as JSON values. Hence differences in
+// formatting or order of fields are ignored.
+func diff(record *Record, result ReplayResult) string {
+	if record.Error != "" || result.Error != "" {
+		if record.Error == result.Error {
+			return ""
+		}
+		return fmt.Sprintf(
+			"error: recorded %q got %q",
+			record.Error,
+			result.Error,
+		)
+	}
+	recorded, err := asJSONValue(record.Response)
+	if err != nil {
+		return fmt.Sprintf("invalid recorded response: %v", err)
+	}
+	replayed, err := asJSONValue(result.Response)
+	if err != nil {
+		return fmt.Sprintf("invalid replayed response: %v", err)
+	}
+	return cmp.Diff(recorded, replayed)
+}
+
+// asJSONValue unmarshals the provided JSON into a generic value
+func asJSONValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var val interface{}
+	err := json.Unmarshal(raw, &val)
+	return val, err
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxFiles is the default maximum number of records
+	// kept in a directory
+	DefaultMaxFiles = 1000
+
+	// DefaultMaxBytes is the default maximum size of the records
+	// kept in a directory i.e. 100MiB
+	DefaultMaxBytes = 100 << 20
+)
+
+// recordFile is a record that was written to a directory
+type recordFile struct {
+	name string
+	size int64
+}
+
+// DirSink writes each record as a JSON file in a directory. The
+// oldest records are deleted once the directory holds more than
+// the maximum number of files or bytes.
+type DirSink struct {
+	Dir string
+
+	// MaxFiles is the maximum number of records kept in Dir. Zero
+	// implies no limit.
+	MaxFiles int
+
+	// MaxBytes is the maximum size of the records kept in Dir.
+	// Zero implies no limit.
+	MaxBytes int64
+
+	// seq makes file names unique for records that start at
+	// the same time
+	seq uint64
+
+	// records in Dir from oldest to newest & their total size
+	mutex sync.Mutex
+	files []recordFile
+	size  int64
+}
+
+// DirSinkOption is a typed function that is used to build
+// *DirSink instance
+//
+// NOTE:
+//	This follows "functional options" pattern
+type DirSinkOption func(*DirSink) error
+
+// MaxFiles limits the number of records kept in the directory
+func MaxFiles(max int) DirSinkOption {
+	return func(s *DirSink) error {
+		if max < 0 {
+			return errors.Errorf("Invalid dir sink: Negative max files %d", max)
+		}
+		s.MaxFiles = max
+		return nil
+	}
+}
+
+// MaxBytes limits the size of the records kept in the directory
+func MaxBytes(max int64) DirSinkOption {
+	return func(s *DirSink) error {
+		if max < 0 {
+			return errors.Errorf("Invalid dir sink: Negative max bytes %d", max)
+		}
+		s.MaxBytes = max
+		return nil
+	}
+}
+
+// NewDirSink returns a new instance of DirSink after creating
+// the provided directory if it does not exist. Records already
+// present in the directory count towards its limits.
+func NewDirSink(dir string, opts ...DirSinkOption) (*DirSink, error) {
+	if dir == "" {
+		return nil, errors.Errorf("Invalid dir sink: Empty dir")
+	}
+	s := &DirSink{
+		Dir:      dir,
+		MaxFiles: DefaultMaxFiles,
+		MaxBytes: DefaultMaxBytes,
+	}
+	for _, o := range opts {
+		err := o(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// records may hold sensitive data of the hooks
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid dir sink: Can't create %q", dir)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid dir sink: Can't list %q", dir)
+	}
+	// file names start with the start time of the record
+	sort.Strings(files)
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid dir sink: Can't stat %q", file)
+		}
+		s.files = append(s.files, recordFile{name: file, size: info.Size()})
+		s.size += info.Size()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.prune()
+	return s, nil
+}
+
+// prune deletes the oldest records till the directory is within
+// its limits. The newest record is always kept.
+//
+// NOTE:
+//	This must be invoked with the lock held
+func (s *DirSink) prune() {
+	for len(s.files) > 1 &&
+		((s.MaxFiles > 0 && len(s.files) > s.MaxFiles) ||
+			(s.MaxBytes > 0 && s.size > s.MaxBytes)) {
+		oldest := s.files[0]
+		err := os.Remove(oldest.name)
+		if err != nil && !os.IsNotExist(err) {
+			// will be retried during next write
+			glog.V(4).Infof("Can't delete hook record %q: %v", oldest.name, err)
+			return
+		}
+		s.files = s.files[1:]
+		s.size -= oldest.size
+	}
+}
+
+// Write writes the provided record as a new file
+//
+// NOTE:
+//	File names start with the start time of the record. Hence
+// listing the files by name lists the records in the order of
+// invocation.
+func (s *DirSink) Write(record *Record) error {
+	raw, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "Can't marshal hook record")
+	}
+	name := fmt.Sprintf(
+		"%s-%06d-%s-%s.json",
+		record.StartTime.UTC().Format("20060102T150405.000000000"),
+		atomic.AddUint64(&s.seq, 1),
+		record.ControllerKind,
+		strings.Replace(record.ControllerName, "/", "_", -1),
+	)
+	file := filepath.Join(s.Dir, name)
+	err = ioutil.WriteFile(file, raw, 0600)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.files = append(s.files, recordFile{name: file, size: int64(len(raw))})
+	s.size += int64(len(raw))
+	s.prune()
+	return nil
+}
+
+// LoadDir returns the records found in the provided directory
+// in the order of invocation
+func LoadDir(dir string) ([]*Record, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't list hook records in %q", dir)
+	}
+	sort.Strings(files)
+	var records []*Record
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Can't read hook record %q", file)
+		}
+		var record Record
+		err = json.Unmarshal(raw, &record)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid hook record %q", file)
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
+
+// RingSink keeps a fixed number of most recent records in
+// memory
+type RingSink struct {
+	mutex   sync.Mutex
+	records []*Record
+
+	// index where the next record gets written
+	next int
+
+	// true once the ring has wrapped around
+	full bool
+}
+
+// NewRingSink returns a new instance of RingSink that holds the
+// provided number of records
+func NewRingSink(size int) (*RingSink, error) {
+	if size < 1 {
+		return nil, errors.Errorf("Invalid ring sink: Size must be >= 1: %d", size)
+	}
+	return &RingSink{records: make([]*Record, size)}, nil
+}
+
+// Write adds the provided record to the ring by replacing the
+// oldest record if the ring is full
+func (s *RingSink) Write(record *Record) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records[s.next] = record
+	s.next = (s.next + 1) % len(s.records)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+// List returns the records in the order of invocation
+func (s *RingSink) List() []*Record {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.full {
+		return append([]*Record(nil), s.records[:s.next]...)
+	}
+	return append(
+		append([]*Record(nil), s.records[s.next:]...),
+		s.records[:s.next]...,
+	)
+}
+
+// ServeHTTP serves the records as a JSON list
+func (s *RingSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	records := s.List()
+	if records == nil {
+		records = []*Record{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(records)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package wasm executes WebAssembly modules as hooks.
+//
+// A module exchanges the hook request & response with metac via
+// the following functions imported from the module named 'metac':
+//
+//	request_size() i32
+//		returns the size of the JSON encoded hook request
+//
+//	request_read(ptr i32)
+//		copies the JSON encoded hook request into the module's
+//		memory starting at ptr
+//
+//	response_write(ptr i32, len i32)
+//		sets the JSON encoded hook response from the module's
+//		memory
+//
+//	error_write(ptr i32, len i32)
+//		sets the error message from the module's memory
+//
+// The module must export a function named 'sync' with the
+// signature 'sync() i32'. A non-zero return value is considered
+// as a hook error.
+package wasm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/perlin-network/life/compiler"
+	"github.com/perlin-network/life/exec"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/json"
+)
+
+const (
+	// importModule is the name of the module whose functions
+	// are imported by the hook module
+	importModule = "metac"
+
+	// entryFunc is the function exported by the hook module
+	entryFunc = "sync"
+
+	// gasSlice is the number of instructions executed between
+	// checks for timeout
+	gasSlice = 1000000
+
+	// maxCompiledModules is the maximum number of compiled
+	// modules that are cached
+	maxCompiledModules = 64
+)
+
+// compiledModules caches the compiled modules anchored by the
+// hash of their content & their memory limit
+//
+// NOTE:
+//	Compiling a module is much costlier than instantiating it.
+// Hooks are invoked on every sync & their modules rarely change.
+var compiledModules struct {
+	mutex sync.Mutex
+	cache map[string]*exec.Module
+
+	// keys in the order they were cached; used for eviction
+	keys []string
+}
+
+// Invoker manages execution of a WebAssembly module as a hook
+type Invoker struct {
+	// Name of the module used in errors
+	Name string
+
+	// WebAssembly module in binary format
+	Module []byte
+
+	// Maximum memory available to the module in units of
+	// 64KiB pages
+	MaxMemoryPages int
+
+	// Maximum time the module is allowed to run
+	Timeout time.Duration
+}
+
+// InvokerOption is a typed function that is used
+// to build *Invoker instance
+//
+// NOTE:
+//	This follows "functional options" pattern
+type InvokerOption func(*Invoker) error
+
+// NewInvoker returns a new instance of Invoker
+// based on an optional list of InvokerOptions
+func NewInvoker(opts ...InvokerOption) (*Invoker, error) {
+	i := &Invoker{}
+	for _, o := range opts {
+		err := o(i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(i.Module) == 0 {
+		return nil, errors.Errorf("Invalid wasm hook: Empty module: %s", i)
+	}
+	return i, nil
+}
+
+// String implements Stringer interface
+func (i *Invoker) String() string {
+	return fmt.Sprintf(
+		"Wasm Invoker: Name=%s: Timeout=%s",
+		i.Name,
+		i.Timeout,
+	)
+}
+
+// abi resolves the functions imported by the hook module & holds
+// the data exchanged with the module
+type abi struct {
+	request  []byte
+	response []byte
+	errMsg   string
+
+	isResponseSet bool
+}
+
+// memory returns the provided range of the module's memory
+func memory(vm *exec.VirtualMachine, ptr, size int64) []byte {
+	start := int64(uint32(ptr))
+	end := start + int64(uint32(size))
+	if end > int64(len(vm.Memory)) {
+		panic(fmt.Sprintf(
+			"Out of bounds memory access: [%d:%d]: Memory size %d",
+			start,
+			end,
+			len(vm.Memory),
+		))
+	}
+	return vm.Memory[start:end]
+}
+
+// ResolveFunc implements exec.ImportResolver interface
+func (a *abi) ResolveFunc(module, field string) exec.FunctionImport {
+	if module != importModule {
+		panic(fmt.Sprintf("Unknown import module %q", module))
+	}
+	switch field {
+	case "request_size":
+		return func(vm *exec.VirtualMachine) int64 {
+			return int64(len(a.request))
+		}
+	case "request_read":
+		return func(vm *exec.VirtualMachine) int64 {
+			locals := vm.GetCurrentFrame().Locals
+			copy(memory(vm, locals[0], int64(len(a.request))), a.request)
+			return 0
+		}
+	case "response_write":
+		return func(vm *exec.VirtualMachine) int64 {
+			locals := vm.GetCurrentFrame().Locals
+			a.response = append([]byte(nil), memory(vm, locals[0], locals[1])...)
+			a.isResponseSet = true
+			return 0
+		}
+	case "error_write":
+		return func(vm *exec.VirtualMachine) int64 {
+			locals := vm.GetCurrentFrame().Locals
+			a.errMsg = string(memory(vm, locals[0], locals[1]))
+			return 0
+		}
+	default:
+		panic(fmt.Sprintf("Unknown import %s.%s", module, field))
+	}
+}
+
+// ResolveGlobal implements exec.ImportResolver interface
+func (a *abi) ResolveGlobal(module, field string) int64 {
+	panic(fmt.Sprintf("Global import %s.%s is not supported", module, field))
+}
+
+// compile returns the compiled module from the cache or compiles
+// the module & caches it
+func (i *Invoker) compile() (*exec.Module, error) {
+	sum := sha256.Sum256(i.Module)
+	key := fmt.Sprintf("%s/%d", hex.EncodeToString(sum[:]), i.MaxMemoryPages)
+
+	compiledModules.mutex.Lock()
+	module, found := compiledModules.cache[key]
+	compiledModules.mutex.Unlock()
+	if found {
+		return module, nil
+	}
+
+	module, err := exec.NewModule(
+		i.Module,
+		exec.VMConfig{
+			MaxMemoryPages: i.MaxMemoryPages,
+			// NOTE:
+			//	Execution returns after every gas slice to let
+			// the timeout be verified
+			GasLimit:                 gasSlice,
+			ReturnOnGasLimitExceeded: true,
+		},
+		// NOTE:
+		//	Imports are resolved by the resolver set against
+		// each instance. This resolver is used to verify the
+		// module's imports while compiling.
+		&abi{},
+		&compiler.SimpleGasPolicy{GasPerInstruction: 1},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledModules.mutex.Lock()
+	defer compiledModules.mutex.Unlock()
+	if compiledModules.cache == nil {
+		compiledModules.cache = make(map[string]*exec.Module)
+	}
+	if _, found := compiledModules.cache[key]; !found {
+		if len(compiledModules.keys) >= maxCompiledModules {
+			// evict the oldest module
+			delete(compiledModules.cache, compiledModules.keys[0])
+			compiledModules.keys = compiledModules.keys[1:]
+		}
+		compiledModules.keys = append(compiledModules.keys, key)
+	}
+	compiledModules.cache[key] = module
+	return module, nil
+}
+
+// Invoke executes the module with the given request and fills
+// up the given response with the module's response
+//
+// NOTE:
+//	Module is compiled once & is instantiated for every invocation.
+// Hence no state is shared between invocations.
+func (i *Invoker) Invoke(request, response interface{}) (err error) {
+	// Encode request.
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrapf(err, "%s: Failed to marshal", i)
+	}
+
+	// NOTE:
+	//	The runtime panics on invalid modules e.g. when entry
+	// function has parameters
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%s: Failed to execute: %v", i, r)
+		}
+	}()
+
+	module, err := i.compile()
+	if err != nil {
+		return errors.Wrapf(err, "%s: Failed to load", i)
+	}
+	// a new instance gets its own memory, globals & table
+	a := &abi{request: reqBody}
+	vm := module.NewVirtualMachine()
+	vm.ImportResolver = a
+
+	entryID, found := vm.GetFunctionExport(entryFunc)
+	if !found {
+		return errors.Errorf("%s: Function %q is not exported", i, entryFunc)
+	}
+
+	var deadline time.Time
+	if i.Timeout > 0 {
+		deadline = time.Now().Add(i.Timeout)
+	}
+	vm.Ignite(entryID)
+	for !vm.Exited {
+		vm.Execute()
+		if vm.Delegate != nil {
+			// invoke the imported function
+			vm.Delegate()
+			vm.Delegate = nil
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return errors.Errorf("%s: Timed out", i)
+		}
+		if vm.GasLimitExceeded {
+			vm.Config.GasLimit += gasSlice
+		}
+	}
+	if vm.ExitError != nil {
+		return errors.Errorf("%s: Failed to execute: %v", i, vm.ExitError)
+	}
+	if vm.ReturnValue != 0 {
+		return errors.Errorf(
+			"%s: Returned %d: %s",
+			i,
+			vm.ReturnValue,
+			a.errMsg,
+		)
+	}
+	if !a.isResponseSet {
+		return errors.Errorf("%s: No response was written", i)
+	}
+	glog.V(8).Infof("%s: Got response %q", i, a.response)
+
+	// Decode response.
+	if err := json.Unmarshal(a.response, response); err != nil {
+		return errors.Wrapf(err, "%s: Failed to unmarshal response", i)
+	}
+
+	glog.V(8).Infof("%s: Invoked successfully", i)
+	return nil
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wasm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// uleb encodes the provided value as unsigned LEB128
+func uleb(v int) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if v == 0 {
+			return out
+		}
+	}
+}
+
+func name(s string) []byte {
+	return append(uleb(len(s)), s...)
+}
+
+func vec(items ...[]byte) []byte {
+	out := uleb(len(items))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func section(id byte, payload []byte) []byte {
+	return append(append([]byte{id}, uleb(len(payload))...), payload...)
+}
+
+func join(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// newTestModule returns a module that imports metac functions,
+// exports 'sync' with the provided body & has the provided
+// initial memory pages & data at offset 0
+func newTestModule(exportName string, memPages int, data string, body []byte) []byte {
+	const (
+		i32      = 0x7f
+		funcType = 0x60
+	)
+	types := section(1, vec(
+		[]byte{funcType, 0x00, 0x01, i32},      // () -> i32
+		[]byte{funcType, 0x01, i32, 0x00},      // (i32) -> ()
+		[]byte{funcType, 0x02, i32, i32, 0x00}, // (i32, i32) -> ()
+	))
+	imports := section(2, vec(
+		join(name("metac"), name("request_size"), []byte{0x00, 0x00}),
+		join(name("metac"), name("request_read"), []byte{0x00, 0x01}),
+		join(name("metac"), name("response_write"), []byte{0x00, 0x02}),
+		join(name("metac"), name("error_write"), []byte{0x00, 0x02}),
+	))
+	funcs := section(3, vec([]byte{0x00}))
+	mem := section(5, vec(append([]byte{0x00}, uleb(memPages)...)))
+	exports := section(7, vec(join(name(exportName), []byte{0x00, 0x04})))
+	code := section(10, vec(append(uleb(len(body)), body...)))
+	var datas []byte
+	if data != "" {
+		datas = section(11, vec(
+			join([]byte{0x00, 0x41, 0x00, 0x0b}, name(data)),
+		))
+	}
+	return join(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+		types, imports, funcs, mem, exports, code, datas,
+	)
+}
+
+var (
+	// echoes the request as the response
+	echoBody = []byte{
+		0x01, 0x01, 0x7f, // 1 local i32
+		0x10, 0x00, 0x21, 0x00, // local = request_size()
+		0x41, 0x00, 0x10, 0x01, // request_read(0)
+		0x41, 0x00, 0x20, 0x00, 0x10, 0x02, // response_write(0, local)
+		0x41, 0x00, 0x0b, // return 0
+	}
+
+	// writes the error message found at offset 0 & returns 1
+	errorBody = []byte{
+		0x00,
+		0x41, 0x00, 0x41, 0x04, 0x10, 0x03, // error_write(0, 4)
+		0x41, 0x01, 0x0b, // return 1
+	}
+
+	// returns 1 if the marker at offset 1024 is set; otherwise
+	// sets the marker & echoes the request as the response
+	markerBody = []byte{
+		0x01, 0x01, 0x7f, // 1 local i32
+		0x41, 0x80, 0x08, 0x2d, 0x00, 0x00, // i32.load8_u(1024)
+		0x04, 0x40, 0x41, 0x01, 0x0f, 0x0b, // if return 1 end
+		0x41, 0x80, 0x08, 0x41, 0x01, 0x3a, 0x00, 0x00, // i32.store8(1024, 1)
+		0x10, 0x00, 0x21, 0x00, // local = request_size()
+		0x41, 0x00, 0x10, 0x01, // request_read(0)
+		0x41, 0x00, 0x20, 0x00, 0x10, 0x02, // response_write(0, local)
+		0x41, 0x00, 0x0b, // return 0
+	}
+
+	// loops forever
+	loopBody = []byte{
+		0x00,
+		0x03, 0x40, 0x0c, 0x00, 0x0b, // loop br 0 end
+		0x41, 0x00, 0x0b,
+	}
+)
+
+func TestInvoke(t *testing.T) {
+	var tests = map[string]struct {
+		module       []byte
+		timeout      time.Duration
+		maxPages     int
+		expect       map[string]interface{}
+		isErr        bool
+		expectErrMsg string
+	}{
+		"echo": {
+			module: newTestModule("sync", 1, "", echoBody),
+			expect: map[string]interface{}{"phase": "Ready"},
+		},
+		"non zero return": {
+			module:       newTestModule("sync", 1, "fail", errorBody),
+			isErr:        true,
+			expectErrMsg: "Returned 1: fail",
+		},
+		"timeout": {
+			module:       newTestModule("sync", 1, "", loopBody),
+			timeout:      200 * time.Millisecond,
+			isErr:        true,
+			expectErrMsg: "Timed out",
+		},
+		"memory more than limit": {
+			module:   newTestModule("sync", 4, "", echoBody),
+			maxPages: 2,
+			isErr:    true,
+		},
+		"missing sync export": {
+			module:       newTestModule("reconcile", 1, "", echoBody),
+			isErr:        true,
+			expectErrMsg: "not exported",
+		},
+		"invalid module": {
+			module: []byte("junk"),
+			isErr:  true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.Name = name
+				i.Module = mock.module
+				i.Timeout = mock.timeout
+				i.MaxMemoryPages = mock.maxPages
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var got map[string]interface{}
+			err = invoker.Invoke(map[string]string{"phase": "Ready"}, &got)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr && !strings.Contains(err.Error(), mock.expectErrMsg) {
+				t.Fatalf("Expected error with %q got [%+v]", mock.expectErrMsg, err)
+			}
+			if !mock.isErr && !reflect.DeepEqual(got, mock.expect) {
+				t.Fatalf("Expected %v got %v", mock.expect, got)
+			}
+		})
+	}
+}
+
+func TestInvokeReusesCompiledModule(t *testing.T) {
+	module := newTestModule("sync", 1, "", markerBody)
+	invoker, err := NewInvoker(func(i *Invoker) error {
+		i.Name = "marker"
+		i.Module = module
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	for _, phase := range []string{"Ready", "Done", "Ready"} {
+		var got map[string]interface{}
+		// NOTE:
+		//	This fails if memory of an earlier invocation is reused
+		err = invoker.Invoke(map[string]string{"phase": phase}, &got)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		if got["phase"] != phase {
+			t.Fatalf("Expected phase %q got %v", phase, got)
+		}
+	}
+	compiled, err := invoker.compile()
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	again, _ := invoker.compile()
+	if compiled != again {
+		t.Fatalf("Expected compiled module to be cached")
+	}
+}
+
+func TestCompiledModulesAreEvicted(t *testing.T) {
+	for n := 0; n < maxCompiledModules+5; n++ {
+		invoker := &Invoker{
+			Name:   "echo",
+			Module: newTestModule("sync", 1, strings.Repeat("a", n+1), echoBody),
+		}
+		_, err := invoker.compile()
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+	}
+	compiledModules.mutex.Lock()
+	defer compiledModules.mutex.Unlock()
+	if len(compiledModules.cache) != maxCompiledModules {
+		t.Fatalf(
+			"Expected %d compiled modules got %d",
+			maxCompiledModules,
+			len(compiledModules.cache),
+		)
+	}
+	if len(compiledModules.keys) != maxCompiledModules {
+		t.Fatalf(
+			"Expected %d keys got %d",
+			maxCompiledModules,
+			len(compiledModules.keys),
+		)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultSignatureHeader is the header that holds the HMAC
+	// signature of the request body
+	DefaultSignatureHeader = "X-Metac-Signature"
+
+	// tokenFileTTL is the duration after which a token file is
+	// read again to pick up the rotated token
+	tokenFileTTL = 1 * time.Minute
+)
+
+// Authenticator sets the credentials against a webhook request
+type Authenticator interface {
+	// Authenticate is invoked with the request & its body that
+	// is sent to the webhook
+	Authenticate(req *http.Request, body []byte) error
+}
+
+// HeaderAuth sets a header whose value is fetched per request
+type HeaderAuth struct {
+	// Name of the header
+	Name string
+
+	// Prefix is prepended to the value along with a space
+	Prefix string
+
+	// Value returns the header value. This is invoked for every
+	// request so that rotated values are picked up.
+	Value func() ([]byte, error)
+}
+
+// Authenticate implements Authenticator interface
+func (a *HeaderAuth) Authenticate(req *http.Request, body []byte) error {
+	value, err := a.Value()
+	if err != nil {
+		return errors.Wrapf(err, "Can't get value of header %q", a.Name)
+	}
+	val := strings.TrimSpace(string(value))
+	if a.Prefix != "" {
+		val = a.Prefix + " " + val
+	}
+	req.Header.Set(a.Name, val)
+	return nil
+}
+
+// TokenFileAuth sets the token read from a file as a bearer token
+// in the Authorization header. The file is read again once its
+// cached content expires.
+type TokenFileAuth struct {
+	Path string
+
+	mutex    sync.Mutex
+	token    string
+	readAt   time.Time
+	ttl      time.Duration
+	now      func() time.Time
+	readFile func(string) ([]byte, error)
+}
+
+// NewTokenFileAuth returns a new instance of TokenFileAuth
+//
+// NOTE:
+//	The token is sent to the webhook as is. Hence this should be
+// a projected service account token whose audience is dedicated to
+// webhooks & not the token that metac uses against kube api server.
+func NewTokenFileAuth(path string) *TokenFileAuth {
+	return &TokenFileAuth{
+		Path:     path,
+		ttl:      tokenFileTTL,
+		now:      time.Now,
+		readFile: ioutil.ReadFile,
+	}
+}
+
+// getToken returns the cached token or reads the token file if
+// the cached token has expired
+func (a *TokenFileAuth) getToken() (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.token != "" && a.now().Sub(a.readAt) < a.ttl {
+		return a.token, nil
+	}
+	raw, err := a.readFile(a.Path)
+	if err != nil {
+		return "", errors.Wrapf(err, "Can't read token file %q", a.Path)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return "", errors.Errorf("Empty token file %q", a.Path)
+	}
+	a.token = token
+	a.readAt = a.now()
+	return token, nil
+}
+
+// Authenticate implements Authenticator interface
+func (a *TokenFileAuth) Authenticate(req *http.Request, body []byte) error {
+	token, err := a.getToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// HMACAuth sets the HMAC-SHA256 signature of the request body
+// as a header
+type HMACAuth struct {
+	// Header that holds the signature
+	Header string
+
+	// Key returns the shared key. This is invoked for every
+	// request so that rotated keys are picked up.
+	Key func() ([]byte, error)
+}
+
+// Authenticate implements Authenticator interface
+//
+// NOTE:
+//	Signature is computed over the body that is sent i.e. after
+// compressing the body if gzip is enabled
+func (a *HMACAuth) Authenticate(req *http.Request, body []byte) error {
+	key, err := a.Key()
+	if err != nil {
+		return errors.Wrapf(err, "Can't get HMAC key")
+	}
+	req.Header.Set(a.Header, Sign(key, body))
+	return nil
+}
+
+// Sign returns the signature of the provided body in the form
+// sha256=<hex encoded digest>. Webhooks may use this to verify
+// the signature.
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
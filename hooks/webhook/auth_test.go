@@ -0,0 +1,177 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// authHandler responds with the provided status if the request
+// is missing the expected header value
+func authHandler(t *testing.T, header string, expect func(body []byte) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("Can't read request: %v", err)
+		}
+		if got := r.Header.Get(header); got != expect(body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"unauthorized"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+}
+
+func TestInvokeWithAuth(t *testing.T) {
+	var tests = map[string]struct {
+		auth   Authenticator
+		header string
+		expect func(body []byte) string
+		isErr  bool
+	}{
+		"static header": {
+			auth: &HeaderAuth{
+				Name:   "Authorization",
+				Prefix: "Bearer",
+				Value:  func() ([]byte, error) { return []byte("secret-token\n"), nil },
+			},
+			header: "Authorization",
+			expect: func([]byte) string { return "Bearer secret-token" },
+		},
+		"header without prefix": {
+			auth: &HeaderAuth{
+				Name:  "X-Api-Key",
+				Value: func() ([]byte, error) { return []byte("my-key"), nil },
+			},
+			header: "X-Api-Key",
+			expect: func([]byte) string { return "my-key" },
+		},
+		"header value error": {
+			auth: &HeaderAuth{
+				Name:  "X-Api-Key",
+				Value: func() ([]byte, error) { return nil, errors.Errorf("missing") },
+			},
+			header: "X-Api-Key",
+			expect: func([]byte) string { return "my-key" },
+			isErr:  true,
+		},
+		"hmac signature": {
+			auth: &HMACAuth{
+				Header: DefaultSignatureHeader,
+				Key:    func() ([]byte, error) { return []byte("shared"), nil },
+			},
+			header: DefaultSignatureHeader,
+			expect: func(body []byte) string { return Sign([]byte("shared"), body) },
+		},
+		"hmac signature with wrong key": {
+			auth: &HMACAuth{
+				Header: DefaultSignatureHeader,
+				Key:    func() ([]byte, error) { return []byte("wrong"), nil },
+			},
+			header: DefaultSignatureHeader,
+			expect: func(body []byte) string { return Sign([]byte("shared"), body) },
+			isErr:  true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(authHandler(t, mock.header, mock.expect))
+			defer srv.Close()
+
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.URL = srv.URL
+				i.Timeout = 5 * time.Second
+				i.Auth = []Authenticator{mock.auth}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var resp map[string]interface{}
+			err = invoker.Invoke(map[string]string{"hello": "world"}, &resp)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+		})
+	}
+}
+
+func TestTokenFileAuthRotation(t *testing.T) {
+	now := time.Now()
+	token := "token-1"
+	reads := 0
+	auth := NewTokenFileAuth("/var/run/secrets/metac/token")
+	auth.now = func() time.Time { return now }
+	auth.readFile = func(path string) ([]byte, error) {
+		if path != "/var/run/secrets/metac/token" {
+			t.Fatalf("Expected path %q got %q", "/var/run/secrets/metac/token", path)
+		}
+		reads++
+		return []byte(token + "\n"), nil
+	}
+
+	var tests = []struct {
+		elapsed     time.Duration
+		rotateTo    string
+		expect      string
+		expectReads int
+	}{
+		{expect: "Bearer token-1", expectReads: 1},
+		// rotated token is not read before ttl
+		{elapsed: 30 * time.Second, rotateTo: "token-2", expect: "Bearer token-1", expectReads: 1},
+		// rotated token is read after ttl
+		{elapsed: 31 * time.Second, expect: "Bearer token-2", expectReads: 2},
+	}
+	for i, step := range tests {
+		now = now.Add(step.elapsed)
+		if step.rotateTo != "" {
+			token = step.rotateTo
+		}
+		req, _ := http.NewRequest(http.MethodPost, "http://hook", nil)
+		err := auth.Authenticate(req, nil)
+		if err != nil {
+			t.Fatalf("Step %d: Expected no error got [%+v]", i, err)
+		}
+		if got := req.Header.Get("Authorization"); got != step.expect {
+			t.Fatalf("Step %d: Expected %q got %q", i, step.expect, got)
+		}
+		if reads != step.expectReads {
+			t.Fatalf("Step %d: Expected %d reads got %d", i, step.expectReads, reads)
+		}
+	}
+}
+
+func TestTokenFileAuthEmptyToken(t *testing.T) {
+	auth := NewTokenFileAuth("/token")
+	auth.readFile = func(string) ([]byte, error) { return []byte(" \n"), nil }
+	req, _ := http.NewRequest(http.MethodPost, "http://hook", nil)
+	if err := auth.Authenticate(req, nil); err == nil {
+		t.Fatalf("Expected error got none")
+	}
+}
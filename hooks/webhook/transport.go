@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportConfig tunes the connection pool of the http
+// transports used to invoke webhooks
+type TransportConfig struct {
+	// Maximum number of idle connections across all hosts.
+	// Zero means no limit.
+	MaxIdleConns int
+
+	// Maximum number of idle connections kept per host
+	MaxIdleConnsPerHost int
+
+	// Duration an idle connection is kept in the pool before
+	// it is closed. Zero means no limit.
+	IdleConnTimeout time.Duration
+
+	// Attempt HTTP/2 for https webhooks. Webhooks served over
+	// plain http always use HTTP/1.1.
+	EnableHTTP2 bool
+}
+
+// DefaultTransportConfig is the transport config used if none
+// is provided
+var DefaultTransportConfig = TransportConfig{
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+	EnableHTTP2:         true,
+}
+
+// NewTransport returns a new http transport that keeps its
+// connections alive as per the provided config. Default TLS
+// settings are used if the provided TLS config is nil.
+//
+// NOTE:
+//	Returned transport is meant to be reused across invocations
+// so that connections are not set up for every sync
+func NewTransport(config TransportConfig, tlsConfig *tls.Config) *http.Transport {
+	if tlsConfig != nil {
+		// transport may modify the config e.g. to set NextProtos
+		tlsConfig = tlsConfig.Clone()
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		ForceAttemptHTTP2:     config.EnableHTTP2,
+	}
+}
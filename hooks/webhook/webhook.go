@@ -19,6 +19,8 @@ package webhook
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/tls"
 	gojson "encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -28,8 +30,40 @@ import (
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/util/json"
+
+	"openebs.io/metac/hooks"
 )
 
+// RetryPolicy determines if & when a failed webhook invocation
+// is retried
+type RetryPolicy struct {
+	// Maximum number of invocations including the first one
+	MaxAttempts int
+
+	// Wait duration before the first retry. This is doubled
+	// after every retry.
+	InitialBackoff time.Duration
+
+	// Maximum wait duration between retries
+	MaxBackoff time.Duration
+
+	// HTTP status codes that are retried
+	RetryableStatusCodes map[int]bool
+}
+
+// backoff returns the wait duration before the provided retry
+// count
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	wait := p.InitialBackoff
+	for count := 1; count < retry && wait < p.MaxBackoff; count++ {
+		wait *= 2
+	}
+	if wait > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return wait
+}
+
 // Invoker manages invocation of webhook
 type Invoker struct {
 	// webhook URL
@@ -37,6 +71,32 @@ type Invoker struct {
 
 	// webhook invocation timeout
 	Timeout time.Duration
+
+	// TLS settings to invoke the webhook over https. Default
+	// TLS settings are used if this is not set.
+	TLSConfig *tls.Config
+
+	// Retry policy used if the invocation fails. Webhook is
+	// invoked only once if this is not set.
+	Retry *RetryPolicy
+
+	// Client used to invoke the webhook. A client is built per
+	// invocation if this is not set.
+	//
+	// NOTE:
+	//	Client with a shared transport lets the connections be
+	// reused across invocations
+	Client *http.Client
+
+	// Gzip compresses the request body. Gzip encoded responses
+	// are decompressed irrespective of this setting.
+	Gzip bool
+
+	// Auth sets the credentials against every request
+	Auth []Authenticator
+
+	// used to mock sleep in unit tests
+	sleep func(time.Duration)
 }
 
 // InvokerOption is a typed function that is used
@@ -49,7 +109,9 @@ type InvokerOption func(*Invoker) error
 // NewInvoker returns a new instance of Invoker
 // based on an optional list of InvokerOptions
 func NewInvoker(opts ...InvokerOption) (*Invoker, error) {
-	i := &Invoker{}
+	i := &Invoker{
+		sleep: time.Sleep,
+	}
 	for _, o := range opts {
 		err := o(i)
 		if err != nil {
@@ -68,8 +130,20 @@ func (i *Invoker) String() string {
 	)
 }
 
+// maxAttempts returns the maximum number of invocations
+func (i *Invoker) maxAttempts() int {
+	if i.Retry == nil || i.Retry.MaxAttempts < 1 {
+		return 1
+	}
+	return i.Retry.MaxAttempts
+}
+
 // Invoke this webhook by passing the given request
 // and fill up the given response with the webhook response
+//
+// NOTE:
+//	Webhook is retried as per the retry policy if the invocation
+// fails due to connection errors or retryable status codes
 func (i *Invoker) Invoke(request, response interface{}) error {
 	// Encode request.
 	reqBody, err := json.Marshal(request)
@@ -89,15 +163,121 @@ func (i *Invoker) Invoke(request, response interface{}) error {
 		)
 	}
 
-	// Send request.
+	if i.Gzip {
+		// request is compressed once & sent in every attempt
+		reqBody, err = gzipBytes(reqBody)
+		if err != nil {
+			return errors.Wrapf(
+				err,
+				"%s: Failed to compress request",
+				i,
+			)
+		}
+	}
+
+	maxAttempts := i.maxAttempts()
+	var respBody []byte
+	for attempt := 1; ; attempt++ {
+		var isRetryable bool
+		respBody, isRetryable, err = i.post(reqBody)
+		if err == nil {
+			break
+		}
+		if !isRetryable || attempt >= maxAttempts {
+			return errors.Wrapf(
+				err,
+				"Attempt %d/%d",
+				attempt,
+				maxAttempts,
+			)
+		}
+		wait := i.Retry.backoff(attempt)
+		glog.V(4).Infof(
+			"Attempt %d/%d failed: Will retry after %s: %v",
+			attempt,
+			maxAttempts,
+			wait,
+			err,
+		)
+		i.sleep(wait)
+	}
+
+	// Decode response.
+	if err := json.Unmarshal(respBody, response); err != nil {
+		return errors.Wrapf(err, "%s: Failed to unmarshal response", i)
+	}
+
+	glog.V(8).Infof("%s: Invoked successfully", i)
+	return nil
+}
+
+// client returns the http client used to invoke this webhook
+func (i *Invoker) client() *http.Client {
+	if i.Client != nil {
+		return i.Client
+	}
 	client := &http.Client{Timeout: i.Timeout}
-	resp, err := client.Post(
-		i.URL,
-		"application/json",
-		bytes.NewReader(reqBody),
-	)
+	if i.TLSConfig != nil {
+		// NOTE:
+		//	Keep alives are disabled since this transport is not
+		// reused across invocations
+		client.Transport = &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   i.TLSConfig,
+			DisableKeepAlives: true,
+		}
+	}
+	return client
+}
+
+// gzipBytes returns the gzip compressed form of the provided
+// bytes
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends the provided request body to this webhook & returns
+// the response body. In case of error it also returns whether
+// the error can be retried.
+func (i *Invoker) post(reqBody []byte) (respBody []byte, isRetryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, i.URL, bytes.NewReader(reqBody))
 	if err != nil {
-		return errors.Wrapf(
+		return nil, false, errors.Wrapf(
+			err,
+			"%s: Failed to build request",
+			i,
+		)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if i.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for _, auth := range i.Auth {
+		err = auth.Authenticate(req, reqBody)
+		if err != nil {
+			// credentials are fetched per request & may
+			// succeed in the next attempt
+			return nil, true, errors.Wrapf(
+				err,
+				"%s: Failed to authenticate request",
+				i,
+			)
+		}
+	}
+
+	// Send request.
+	resp, err := i.client().Do(req)
+	if err != nil {
+		// connection errors & timeouts are retried
+		return nil, true, errors.Wrapf(
 			err,
 			"%s: Failed to invoke",
 			i,
@@ -106,9 +286,9 @@ func (i *Invoker) Invoke(request, response interface{}) error {
 	defer resp.Body.Close()
 
 	// Read response.
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return errors.Wrapf(
+		return nil, true, errors.Wrapf(
 			err,
 			"%s: Failed to read response",
 			i,
@@ -118,19 +298,26 @@ func (i *Invoker) Invoke(request, response interface{}) error {
 
 	// Check status code.
 	if resp.StatusCode != http.StatusOK {
-		return errors.Errorf(
-			"%s: Response status is not OK: Got %d: Response %q",
-			i,
-			resp.StatusCode,
-			respBody,
-		)
-	}
-
-	// Decode response.
-	if err := json.Unmarshal(respBody, response); err != nil {
-		return errors.Wrapf(err, "%s: Failed to unmarshal response", i)
+		isRetryable = i.Retry != nil && i.Retry.RetryableStatusCodes[resp.StatusCode]
+		if hookErr := hooks.ParseError(respBody); hookErr != nil {
+			// webhook responded with a structured error
+			return nil,
+				isRetryable && hookErr.IsRetryable(),
+				errors.Wrapf(
+					hookErr,
+					"%s: Response status is not OK: Got %d",
+					i,
+					resp.StatusCode,
+				)
+		}
+		return nil,
+			isRetryable,
+			errors.Errorf(
+				"%s: Response status is not OK: Got %d: Response %q",
+				i,
+				resp.StatusCode,
+				respBody,
+			)
 	}
-
-	glog.V(8).Infof("%s: Invoked successfully", i)
-	return nil
+	return respBody, false, nil
 }
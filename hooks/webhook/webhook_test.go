@@ -0,0 +1,501 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"openebs.io/metac/hooks"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     5 * time.Second,
+	}
+	var tests = map[int]time.Duration{
+		1: 1 * time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+		9: 5 * time.Second,
+	}
+	for retry, expect := range tests {
+		got := policy.backoff(retry)
+		if got != expect {
+			t.Fatalf("Expected backoff %s for retry %d got %s", expect, retry, got)
+		}
+	}
+}
+
+func TestInvokeWithRetry(t *testing.T) {
+	var tests = map[string]struct {
+		failures     int32
+		failStatus   int
+		retry        *RetryPolicy
+		isErr        bool
+		expectErrMsg string
+		expectCalls  int32
+	}{
+		"no retry policy": {
+			failures:     1,
+			failStatus:   http.StatusServiceUnavailable,
+			isErr:        true,
+			expectErrMsg: "Attempt 1/1",
+			expectCalls:  1,
+		},
+		"retryable status succeeds eventually": {
+			failures:   2,
+			failStatus: http.StatusServiceUnavailable,
+			retry: &RetryPolicy{
+				MaxAttempts:          3,
+				RetryableStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+			},
+			expectCalls: 3,
+		},
+		"retryable status exhausts attempts": {
+			failures:   5,
+			failStatus: http.StatusServiceUnavailable,
+			retry: &RetryPolicy{
+				MaxAttempts:          3,
+				RetryableStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+			},
+			isErr:        true,
+			expectErrMsg: "Attempt 3/3",
+			expectCalls:  3,
+		},
+		"non retryable status": {
+			failures:   1,
+			failStatus: http.StatusBadRequest,
+			retry: &RetryPolicy{
+				MaxAttempts:          3,
+				RetryableStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+			},
+			isErr:        true,
+			expectErrMsg: "Attempt 1/3",
+			expectCalls:  1,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if atomic.AddInt32(&calls, 1) <= mock.failures {
+						w.WriteHeader(mock.failStatus)
+						return
+					}
+					fmt.Fprint(w, `{}`)
+				}),
+			)
+			defer srv.Close()
+
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.URL = srv.URL
+				i.Timeout = 5 * time.Second
+				i.Retry = mock.retry
+				i.sleep = func(time.Duration) {}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var resp map[string]interface{}
+			err = invoker.Invoke(map[string]string{}, &resp)
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if mock.isErr && !strings.Contains(err.Error(), mock.expectErrMsg) {
+				t.Fatalf("Expected error with %q got [%+v]", mock.expectErrMsg, err)
+			}
+			if calls != mock.expectCalls {
+				t.Fatalf("Expected %d calls got %d", mock.expectCalls, calls)
+			}
+		})
+	}
+}
+
+func TestInvokeRetriesConnectionErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	// connections to a closed server are refused
+	srv.Close()
+
+	var sleeps int
+	invoker, err := NewInvoker(func(i *Invoker) error {
+		i.URL = url
+		i.Timeout = 5 * time.Second
+		i.Retry = &RetryPolicy{MaxAttempts: 4}
+		i.sleep = func(time.Duration) { sleeps++ }
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	err = invoker.Invoke(map[string]string{}, &map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), "Attempt 4/4") {
+		t.Fatalf("Expected error with %q got [%+v]", "Attempt 4/4", err)
+	}
+	if sleeps != 3 {
+		t.Fatalf("Expected 3 backoffs got %d", sleeps)
+	}
+}
+
+// gzipHandler responds with the decoded request body & gzip
+// encodes the response if the request was gzip encoded
+func gzipHandler(t testing.TB, isGzip *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			atomic.AddInt32(isGzip, 1)
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("Expected no error got [%+v]", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+			body = zr
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			w.Write(data)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write(data)
+		zw.Close()
+	}
+}
+
+func TestInvokeWithGzip(t *testing.T) {
+	var tests = map[string]struct {
+		gzip bool
+	}{
+		"without gzip": {},
+		"with gzip": {
+			gzip: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			var isGzip int32
+			srv := httptest.NewServer(gzipHandler(t, &isGzip))
+			defer srv.Close()
+
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.URL = srv.URL
+				i.Timeout = 5 * time.Second
+				i.Gzip = mock.gzip
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			var resp map[string]string
+			err = invoker.Invoke(map[string]string{"phase": "Ready"}, &resp)
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			if resp["phase"] != "Ready" {
+				t.Fatalf("Expected phase %q got %v", "Ready", resp)
+			}
+			if mock.gzip != (isGzip == 1) {
+				t.Fatalf("Expected gzip %t got %d gzip requests", mock.gzip, isGzip)
+			}
+		})
+	}
+}
+
+// newTestTLSServer returns a started TLS server that supports
+// HTTP/2 along with the TLS config trusting this server
+// newTestTLSServer starts a TLS server that reports the state of
+// its connections to the provided function if any
+func newTestTLSServer(
+	handler http.Handler,
+	connState func(net.Conn, http.ConnState),
+) (*httptest.Server, *tls.Config) {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.ConnState = connState
+	srv.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	srv.StartTLS()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return srv, &tls.Config{RootCAs: pool}
+}
+
+func TestInvokeWithPooledTransport(t *testing.T) {
+	var tests = map[string]struct {
+		enableHTTP2 bool
+		expectProto int
+	}{
+		"http/1.1": {
+			expectProto: 1,
+		},
+		"http/2": {
+			enableHTTP2: true,
+			expectProto: 2,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			var proto, conns int32
+			srv, tlsConfig := newTestTLSServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					atomic.StoreInt32(&proto, int32(r.ProtoMajor))
+					fmt.Fprint(w, `{}`)
+				}),
+				func(c net.Conn, state http.ConnState) {
+					if state == http.StateNew {
+						atomic.AddInt32(&conns, 1)
+					}
+				},
+			)
+			defer srv.Close()
+
+			config := DefaultTransportConfig
+			config.EnableHTTP2 = mock.enableHTTP2
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.URL = srv.URL
+				i.Timeout = 5 * time.Second
+				i.Client = &http.Client{
+					Timeout:   i.Timeout,
+					Transport: NewTransport(config, tlsConfig),
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			for count := 0; count < 5; count++ {
+				err = invoker.Invoke(map[string]string{}, &map[string]interface{}{})
+				if err != nil {
+					t.Fatalf("Expected no error got [%+v]", err)
+				}
+			}
+			if conns != 1 {
+				t.Fatalf("Expected 1 connection got %d", conns)
+			}
+			if int(proto) != mock.expectProto {
+				t.Fatalf("Expected HTTP/%d got HTTP/%d", mock.expectProto, proto)
+			}
+		})
+	}
+}
+
+// newAttachments returns the provided number of attachments
+// similar to the ones sent to a sync hook
+func newAttachments(count int) []map[string]interface{} {
+	attachments := make([]map[string]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		attachments = append(attachments, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      fmt.Sprintf("cm-%d", i),
+				"namespace": "default",
+				"labels": map[string]interface{}{
+					"app":                         "metac",
+					"metac.openebs.io/controller": "benchmark",
+				},
+				"resourceVersion": fmt.Sprintf("%d", 1000+i),
+				"uid":             fmt.Sprintf("7f3c9e1a-0000-4000-8000-%012d", i),
+			},
+			"data": map[string]interface{}{
+				"config.yaml": strings.Repeat("key: value\n", 20),
+			},
+		})
+	}
+	return attachments
+}
+
+func BenchmarkInvoke(b *testing.B) {
+	var isGzip int32
+	srv, tlsConfig := newTestTLSServer(gzipHandler(b, &isGzip), nil)
+	defer srv.Close()
+
+	var clients = map[string]func() *Invoker{
+		"client per invocation": func() *Invoker {
+			return &Invoker{TLSConfig: tlsConfig}
+		},
+		"pooled client": func() *Invoker {
+			return &Invoker{
+				Client: &http.Client{
+					Transport: NewTransport(DefaultTransportConfig, tlsConfig),
+				},
+			}
+		},
+		"pooled client with gzip": func() *Invoker {
+			return &Invoker{
+				Gzip: true,
+				Client: &http.Client{
+					Transport: NewTransport(DefaultTransportConfig, tlsConfig),
+				},
+			}
+		},
+	}
+	for _, count := range []int{100, 500} {
+		request := map[string]interface{}{
+			"attachments": newAttachments(count),
+		}
+		for name, newInvoker := range clients {
+			newInvoker := newInvoker
+			b.Run(fmt.Sprintf("%d attachments/%s", count, name), func(b *testing.B) {
+				invoker := newInvoker()
+				invoker.URL = srv.URL
+				invoker.Timeout = 30 * time.Second
+				b.ReportAllocs()
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					var resp map[string]interface{}
+					err := invoker.Invoke(request, &resp)
+					if err != nil {
+						b.Fatalf("Expected no error got [%+v]", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestInvokeWithStructuredError(t *testing.T) {
+	var tests = map[string]struct {
+		body              string
+		retryable         bool
+		expectCalls       int32
+		isHookErr         bool
+		expectReason      string
+		expectRetryAfter  time.Duration
+		expectIsRetryable bool
+	}{
+		"structured error": {
+			body:              `{"reason": "QuotaExceeded", "message": "quota exhausted", "retryAfterSeconds": 30}`,
+			expectCalls:       1,
+			isHookErr:         true,
+			expectReason:      "QuotaExceeded",
+			expectRetryAfter:  30 * time.Second,
+			expectIsRetryable: true,
+		},
+		"retryable structured error is retried": {
+			body:              `{"reason": "Busy"}`,
+			retryable:         true,
+			expectCalls:       3,
+			isHookErr:         true,
+			expectReason:      "Busy",
+			expectIsRetryable: true,
+		},
+		"non retryable structured error is not retried": {
+			body:         `{"reason": "InvalidSpec", "retryable": false}`,
+			retryable:    true,
+			expectCalls:  1,
+			isHookErr:    true,
+			expectReason: "InvalidSpec",
+		},
+		"unstructured error": {
+			body:        `{"message": "oops"}`,
+			expectCalls: 1,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt32(&calls, 1)
+					w.WriteHeader(http.StatusServiceUnavailable)
+					fmt.Fprint(w, mock.body)
+				}),
+			)
+			defer srv.Close()
+
+			invoker, err := NewInvoker(func(i *Invoker) error {
+				i.URL = srv.URL
+				i.Timeout = 5 * time.Second
+				if mock.retryable {
+					i.Retry = &RetryPolicy{
+						MaxAttempts:          3,
+						RetryableStatusCodes: map[int]bool{http.StatusServiceUnavailable: true},
+					}
+				}
+				i.sleep = func(time.Duration) {}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			err = invoker.Invoke(map[string]string{}, &map[string]interface{}{})
+			if err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if calls != mock.expectCalls {
+				t.Fatalf("Expected %d calls got %d", mock.expectCalls, calls)
+			}
+			hookErr, isHookErr := hooks.AsError(err)
+			if isHookErr != mock.isHookErr {
+				t.Fatalf("Expected hook error %t got %t: %+v", mock.isHookErr, isHookErr, err)
+			}
+			if !mock.isHookErr {
+				return
+			}
+			if hookErr.Reason != mock.expectReason {
+				t.Fatalf("Expected reason %q got %q", mock.expectReason, hookErr.Reason)
+			}
+			if hookErr.RetryAfter() != mock.expectRetryAfter {
+				t.Fatalf(
+					"Expected retry after %s got %s",
+					mock.expectRetryAfter,
+					hookErr.RetryAfter(),
+				)
+			}
+			if hookErr.IsRetryable() != mock.expectIsRetryable {
+				t.Fatalf(
+					"Expected retryable %t got %t",
+					mock.expectIsRetryable,
+					hookErr.IsRetryable(),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics records the runtime measurements of metac
+// controllers. These measurements are exposed as OpenCensus
+// views & are served in prometheus format by metac binary.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+// ControllerKind is the short name of a metac controller kind
+// used to label the metrics
+type ControllerKind string
+
+const (
+	// KindGenericController labels GenericController metrics
+	KindGenericController ControllerKind = "gctl"
+
+	// KindCompositeController labels CompositeController metrics
+	KindCompositeController ControllerKind = "cctl"
+
+	// KindDecoratorController labels DecoratorController metrics
+	KindDecoratorController ControllerKind = "dctl"
+
+	// KindMapController labels MapController metrics
+	KindMapController ControllerKind = "mctl"
+)
+
+// Controller identifies the controller against which metrics
+// get recorded
+type Controller struct {
+	Kind ControllerKind
+	Name string
+}
+
+// Operation is an operation executed against the kubernetes
+// cluster while applying the desired state
+type Operation string
+
+const (
+	// OperationCreate is used when a resource is created
+	OperationCreate Operation = "create"
+
+	// OperationUpdate is used when a resource is updated
+	OperationUpdate Operation = "update"
+
+	// OperationDelete is used when a resource is deleted
+	OperationDelete Operation = "delete"
+)
+
+// result values used to label the outcome of an action
+const (
+	resultSuccess string = "success"
+	resultError   string = "error"
+	resultHit     string = "hit"
+	resultMiss    string = "miss"
+)
+
+// tag keys used to label the measurements
+var (
+	// KeyControllerKind labels the kind of controller
+	KeyControllerKind = mustNewKey("controller_kind")
+
+	// KeyControllerName labels the name of controller
+	KeyControllerName = mustNewKey("controller_name")
+
+	// KeyResult labels the outcome i.e. success or error
+	KeyResult = mustNewKey("result")
+
+	// KeyHook labels the hook i.e. its URL or function name
+	KeyHook = mustNewKey("hook")
+
+	// KeyOperation labels the operation executed against the
+	// cluster
+	KeyOperation = mustNewKey("operation")
+
+	// KeyResource labels the apiVersion & kind of the resource
+	// under operation
+	KeyResource = mustNewKey("resource")
+)
+
+// measures recorded by metac
+var (
+	// SyncLatency measures the time taken to reconcile a single
+	// watch or parent
+	SyncLatency = stats.Float64(
+		"metac/sync_latency",
+		"Time taken to reconcile a watch or parent",
+		stats.UnitMilliseconds,
+	)
+
+	// HookLatency measures the time taken to invoke a hook
+	HookLatency = stats.Float64(
+		"metac/hook_latency",
+		"Time taken to invoke a hook",
+		stats.UnitMilliseconds,
+	)
+
+	// Operations counts the operations executed against the
+	// cluster
+	Operations = stats.Int64(
+		"metac/operations",
+		"Number of create, update & delete operations",
+		stats.UnitDimensionless,
+	)
+
+	// HookCacheLookups counts the lookups of cached hook
+	// responses
+	HookCacheLookups = stats.Int64(
+		"metac/hook_cache_lookups",
+		"Number of lookups of cached hook responses",
+		stats.UnitDimensionless,
+	)
+
+	// QueueDepth measures the current depth of a workqueue
+	QueueDepth = stats.Int64(
+		"metac/workqueue_depth",
+		"Current depth of workqueue",
+		stats.UnitDimensionless,
+	)
+
+	// QueueAdds counts the items added to a workqueue
+	QueueAdds = stats.Int64(
+		"metac/workqueue_adds",
+		"Number of items added to workqueue",
+		stats.UnitDimensionless,
+	)
+
+	// QueueRetries counts the items re-queued due to errors
+	QueueRetries = stats.Int64(
+		"metac/workqueue_retries",
+		"Number of items re-queued to workqueue",
+		stats.UnitDimensionless,
+	)
+
+	// QueueLatency measures the time an item stays in workqueue
+	// before being processed
+	QueueLatency = stats.Float64(
+		"metac/workqueue_latency",
+		"Time an item stays in workqueue before being processed",
+		stats.UnitMilliseconds,
+	)
+)
+
+// latencyBounds are the histogram buckets in milliseconds
+var latencyBounds = []float64{
+	5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000,
+}
+
+var controllerKeys = []tag.Key{KeyControllerKind, KeyControllerName}
+
+// Views returns all the views supported by metac
+func Views() []*view.View {
+	return []*view.View{
+		{
+			Name:        "metac_sync_latency_milliseconds",
+			Description: SyncLatency.Description(),
+			Measure:     SyncLatency,
+			TagKeys:     append(controllerKeys, KeyResult),
+			Aggregation: view.Distribution(latencyBounds...),
+		},
+		{
+			Name:        "metac_sync_total",
+			Description: "Number of reconciliations",
+			Measure:     SyncLatency,
+			TagKeys:     append(controllerKeys, KeyResult),
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "metac_hook_latency_milliseconds",
+			Description: HookLatency.Description(),
+			Measure:     HookLatency,
+			TagKeys:     append(controllerKeys, KeyHook, KeyResult),
+			Aggregation: view.Distribution(latencyBounds...),
+		},
+		{
+			Name:        "metac_hook_total",
+			Description: "Number of hook invocations",
+			Measure:     HookLatency,
+			TagKeys:     append(controllerKeys, KeyHook, KeyResult),
+			Aggregation: view.Count(),
+		},
+		{
+			Name:        "metac_hook_cache_lookups_total",
+			Description: HookCacheLookups.Description(),
+			Measure:     HookCacheLookups,
+			TagKeys:     append(controllerKeys, KeyHook, KeyResult),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "metac_operations_total",
+			Description: Operations.Description(),
+			Measure:     Operations,
+			TagKeys:     append(controllerKeys, KeyOperation, KeyResource, KeyResult),
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "metac_workqueue_depth",
+			Description: QueueDepth.Description(),
+			Measure:     QueueDepth,
+			TagKeys:     controllerKeys,
+			Aggregation: view.LastValue(),
+		},
+		{
+			Name:        "metac_workqueue_adds_total",
+			Description: QueueAdds.Description(),
+			Measure:     QueueAdds,
+			TagKeys:     controllerKeys,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "metac_workqueue_retries_total",
+			Description: QueueRetries.Description(),
+			Measure:     QueueRetries,
+			TagKeys:     controllerKeys,
+			Aggregation: view.Sum(),
+		},
+		{
+			Name:        "metac_workqueue_latency_milliseconds",
+			Description: QueueLatency.Description(),
+			Measure:     QueueLatency,
+			TagKeys:     controllerKeys,
+			Aggregation: view.Distribution(latencyBounds...),
+		},
+	}
+}
+
+// Register registers all the views supported by metac. Measurements
+// are collected only after the views are registered.
+func Register() error {
+	return view.Register(Views()...)
+}
+
+// Unregister unregisters all the views supported by metac
+func Unregister() {
+	view.Unregister(Views()...)
+}
+
+// mustNewKey returns a new tag key & panics if the key
+// is invalid
+func mustNewKey(name string) tag.Key {
+	key, err := tag.NewKey(name)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// sinceInMillis returns the duration since the provided time
+// in milliseconds
+func sinceInMillis(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+// resultOf returns the result label based on the provided error
+func resultOf(err error) string {
+	if err != nil {
+		return resultError
+	}
+	return resultSuccess
+}
+
+// record records the provided measurements with the controller
+// tags & the provided additional tags
+func record(ctl Controller, mutators []tag.Mutator, ms ...stats.Measurement) {
+	mutators = append(
+		mutators,
+		tag.Upsert(KeyControllerKind, string(ctl.Kind)),
+		tag.Upsert(KeyControllerName, ctl.Name),
+	)
+	err := stats.RecordWithTags(context.Background(), mutators, ms...)
+	if err != nil {
+		// metrics must never interrupt reconciliation
+		glog.V(4).Infof("Failed to record metrics: %s: %v", ctl.Name, err)
+	}
+}
+
+// RecordSync records the latency & outcome of a reconciliation
+// that was started at the provided time
+func RecordSync(ctl Controller, start time.Time, err error) {
+	record(
+		ctl,
+		[]tag.Mutator{tag.Upsert(KeyResult, resultOf(err))},
+		SyncLatency.M(sinceInMillis(start)),
+	)
+}
+
+// RecordHook records the latency & outcome of a hook invocation
+// that was started at the provided time
+func RecordHook(ctl Controller, hook string, start time.Time, err error) {
+	record(
+		ctl,
+		[]tag.Mutator{
+			tag.Upsert(KeyHook, hook),
+			tag.Upsert(KeyResult, resultOf(err)),
+		},
+		HookLatency.M(sinceInMillis(start)),
+	)
+}
+
+// RecordHookCache records the outcome i.e. hit or miss of a
+// lookup of cached hook response
+func RecordHookCache(ctl Controller, hook string, hit bool) {
+	result := resultMiss
+	if hit {
+		result = resultHit
+	}
+	record(
+		ctl,
+		[]tag.Mutator{
+			tag.Upsert(KeyHook, hook),
+			tag.Upsert(KeyResult, result),
+		},
+		HookCacheLookups.M(1),
+	)
+}
+
+// RecordOperation records the outcome of an operation executed
+// against the provided resource type
+func RecordOperation(ctl Controller, op Operation, resource string, err error) {
+	record(
+		ctl,
+		[]tag.Mutator{
+			tag.Upsert(KeyOperation, string(op)),
+			tag.Upsert(KeyResource, resource),
+			tag.Upsert(KeyResult, resultOf(err)),
+		},
+		Operations.M(1),
+	)
+}
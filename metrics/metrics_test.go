@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func TestControllerFromQueueName(t *testing.T) {
+	var tests = map[string]struct {
+		name   string
+		expect Controller
+	}{
+		"generic controller": {
+			name:   QueueName(Controller{Kind: KindGenericController, Name: "ns/my-gctl"}),
+			expect: Controller{Kind: KindGenericController, Name: "ns/my-gctl"},
+		},
+		"composite controller": {
+			name:   QueueName(Controller{Kind: KindCompositeController, Name: "my-cctl"}),
+			expect: Controller{Kind: KindCompositeController, Name: "my-cctl"},
+		},
+		"decorator controller": {
+			name:   QueueName(Controller{Kind: KindDecoratorController, Name: "my-dctl"}),
+			expect: Controller{Kind: KindDecoratorController, Name: "my-dctl"},
+		},
+		"map controller": {
+			name:   QueueName(Controller{Kind: KindMapController, Name: "my-mctl"}),
+			expect: Controller{Kind: KindMapController, Name: "my-mctl"},
+		},
+		"unknown kind": {
+			name:   "CompositeController",
+			expect: Controller{Name: "CompositeController"},
+		},
+		"unknown kind with separator": {
+			name:   "xyz/abc",
+			expect: Controller{Name: "xyz/abc"},
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := controllerFromQueueName(mock.name)
+			if got != mock.expect {
+				t.Fatalf("Expected %+v got %+v", mock.expect, got)
+			}
+		})
+	}
+}
+
+// countOf returns the count of the provided view's row that
+// matches the provided tags
+func countOf(t *testing.T, viewName string, tags map[tag.Key]string) int64 {
+	rows, err := view.RetrieveData(viewName)
+	if err != nil {
+		t.Fatalf("Can't retrieve view %q: %v", viewName, err)
+	}
+	for _, row := range rows {
+		if !hasTags(row.Tags, tags) {
+			continue
+		}
+		switch data := row.Data.(type) {
+		case *view.CountData:
+			return data.Value
+		case *view.SumData:
+			return int64(data.Value)
+		}
+	}
+	return 0
+}
+
+// hasTags returns true if all the provided tags are present
+func hasTags(got []tag.Tag, want map[tag.Key]string) bool {
+	matches := 0
+	for _, tg := range got {
+		if val, found := want[tg.Key]; found && val == tg.Value {
+			matches++
+		}
+	}
+	return matches == len(want)
+}
+
+func TestRecord(t *testing.T) {
+	err := Register()
+	if err != nil {
+		t.Fatalf("Can't register views: %v", err)
+	}
+	defer Unregister()
+
+	ctl := Controller{Kind: KindCompositeController, Name: "test-record"}
+	RecordSync(ctl, time.Now(), nil)
+	RecordSync(ctl, time.Now(), errors.Errorf("oops"))
+	RecordSync(ctl, time.Now(), errors.Errorf("oops"))
+	RecordHook(ctl, "http://hook", time.Now(), nil)
+	RecordHookCache(ctl, "http://hook", true)
+	RecordHookCache(ctl, "http://hook", true)
+	RecordHookCache(ctl, "http://hook", false)
+	RecordOperation(ctl, OperationCreate, "v1/Pod", nil)
+	RecordOperation(ctl, OperationCreate, "v1/Pod", nil)
+
+	var tests = map[string]struct {
+		view   string
+		tags   map[tag.Key]string
+		expect int64
+	}{
+		"sync success": {
+			view: "metac_sync_total",
+			tags: map[tag.Key]string{
+				KeyControllerKind: "cctl",
+				KeyControllerName: "test-record",
+				KeyResult:         "success",
+			},
+			expect: 1,
+		},
+		"sync error": {
+			view: "metac_sync_total",
+			tags: map[tag.Key]string{
+				KeyControllerName: "test-record",
+				KeyResult:         "error",
+			},
+			expect: 2,
+		},
+		"hook success": {
+			view: "metac_hook_total",
+			tags: map[tag.Key]string{
+				KeyControllerName: "test-record",
+				KeyHook:           "http://hook",
+				KeyResult:         "success",
+			},
+			expect: 1,
+		},
+		"hook cache hits": {
+			view: "metac_hook_cache_lookups_total",
+			tags: map[tag.Key]string{
+				KeyControllerName: "test-record",
+				KeyHook:           "http://hook",
+				KeyResult:         "hit",
+			},
+			expect: 2,
+		},
+		"hook cache misses": {
+			view: "metac_hook_cache_lookups_total",
+			tags: map[tag.Key]string{
+				KeyControllerName: "test-record",
+				KeyHook:           "http://hook",
+				KeyResult:         "miss",
+			},
+			expect: 1,
+		},
+		"create operations": {
+			view: "metac_operations_total",
+			tags: map[tag.Key]string{
+				KeyControllerName: "test-record",
+				KeyOperation:      "create",
+				KeyResource:       "v1/Pod",
+			},
+			expect: 2,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			got := countOf(t, mock.view, mock.tags)
+			if got != mock.expect {
+				t.Fatalf("Expected count %d got %d", mock.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+func init() {
+	// workqueue metrics are recorded only for the queues that
+	// get created after the provider is set
+	workqueue.SetProvider(queueMetricsProvider{})
+}
+
+// QueueName returns the workqueue name for the provided controller.
+// Workqueues named this way get their metrics labelled by the
+// controller's kind & name.
+func QueueName(ctl Controller) string {
+	return string(ctl.Kind) + "/" + ctl.Name
+}
+
+// controllerFromQueueName returns the controller from the provided
+// workqueue name. Names that were not built via QueueName are
+// returned as controller name without any kind.
+func controllerFromQueueName(name string) Controller {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 {
+		switch kind := ControllerKind(parts[0]); kind {
+		case KindGenericController,
+			KindCompositeController,
+			KindDecoratorController,
+			KindMapController:
+			return Controller{Kind: kind, Name: parts[1]}
+		}
+	}
+	return Controller{Name: name}
+}
+
+// queueMetricsProvider implements workqueue.MetricsProvider
+type queueMetricsProvider struct{}
+
+// queueDepth implements workqueue.GaugeMetric
+type queueDepth struct {
+	ctl   Controller
+	value int64
+}
+
+func (d *queueDepth) Inc() {
+	record(d.ctl, nil, QueueDepth.M(atomic.AddInt64(&d.value, 1)))
+}
+
+func (d *queueDepth) Dec() {
+	record(d.ctl, nil, QueueDepth.M(atomic.AddInt64(&d.value, -1)))
+}
+
+// queueCounter implements workqueue.CounterMetric
+type queueCounter struct {
+	ctl    Controller
+	record func(Controller)
+}
+
+func (c *queueCounter) Inc() {
+	c.record(c.ctl)
+}
+
+// queueLatency implements workqueue.HistogramMetric
+type queueLatency struct {
+	ctl Controller
+}
+
+// Observe records the provided value that is in seconds
+func (l *queueLatency) Observe(seconds float64) {
+	record(l.ctl, nil, QueueLatency.M(seconds*1000))
+}
+
+// noopMetric implements the workqueue metric interfaces that
+// are not recorded by metac
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
+
+func (queueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return &queueDepth{ctl: controllerFromQueueName(name)}
+}
+
+func (queueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return &queueCounter{
+		ctl: controllerFromQueueName(name),
+		record: func(ctl Controller) {
+			record(ctl, nil, QueueAdds.M(1))
+		},
+	}
+}
+
+func (queueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return &queueLatency{ctl: controllerFromQueueName(name)}
+}
+
+func (queueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	// work duration is recorded as sync latency
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (queueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return &queueCounter{
+		ctl: controllerFromQueueName(name),
+		record: func(ctl Controller) {
+			record(ctl, nil, QueueRetries.M(1))
+		},
+	}
+}
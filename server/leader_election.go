@@ -0,0 +1,330 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Starter abstracts starting a metac server
+//
+// NOTE:
+//	CRDServer & ConfigServer adhere to the contracts exposed
+// by this interface
+type Starter interface {
+	Start(workerCount int) (stop func(), err error)
+}
+
+// LeaderElection holds the settings to run metac server
+// only when this instance is the leader
+type LeaderElection struct {
+	// Name of the lease object used for locking
+	LeaseName string
+
+	// Namespace of the lease object used for locking
+	LeaseNamespace string
+
+	// Identity of this instance. Defaults to hostname with
+	// a unique suffix
+	Identity string
+
+	// Duration that non-leader candidates will wait before
+	// attempting to acquire leadership
+	LeaseDuration time.Duration
+
+	// Duration that the leader will retry refreshing
+	// leadership before giving it up
+	RenewDeadline time.Duration
+
+	// Duration the candidates wait between attempts to
+	// acquire or renew leadership
+	RetryPeriod time.Duration
+}
+
+// String implements Stringer interface
+func (l *LeaderElection) String() string {
+	return fmt.Sprintf(
+		"LeaderElection %s/%s: Identity %q",
+		l.LeaseNamespace,
+		l.LeaseName,
+		l.Identity,
+	)
+}
+
+// setDefaults sets default values against the fields that
+// were not set
+func (l *LeaderElection) setDefaults() error {
+	if l.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return errors.Wrapf(err, "Can't determine identity: %s", l)
+		}
+		l.Identity = hostname + "_" + string(uuid.NewUUID())
+	}
+	if l.LeaseDuration == 0 {
+		l.LeaseDuration = 15 * time.Second
+	}
+	if l.RenewDeadline == 0 {
+		l.RenewDeadline = 10 * time.Second
+	}
+	if l.RetryPeriod == 0 {
+		l.RetryPeriod = 2 * time.Second
+	}
+	return nil
+}
+
+// validate returns error if the settings are not valid
+func (l *LeaderElection) validate() error {
+	if l.LeaseName == "" {
+		return errors.Errorf("Invalid leader election: Missing lease name: %s", l)
+	}
+	if l.LeaseNamespace == "" {
+		return errors.Errorf("Invalid leader election: Missing lease namespace: %s", l)
+	}
+	if l.LeaseDuration <= l.RenewDeadline {
+		return errors.Errorf(
+			"Invalid leader election: Lease duration %s must be greater than renew deadline %s: %s",
+			l.LeaseDuration,
+			l.RenewDeadline,
+			l,
+		)
+	}
+	return nil
+}
+
+// Start starts the provided server only after this instance
+// acquires the leadership. The server is stopped when the
+// leadership is lost & this instance campaigns for leadership
+// again.
+//
+// The returned function stops the server if it was started &
+// releases the leadership.
+func (l *LeaderElection) Start(
+	config *rest.Config,
+	server Starter,
+	workerCount int,
+) (stop func(), err error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't create clientset: %s", l)
+	}
+	return l.startWithClient(client, server, workerCount)
+}
+
+// startWithClient starts the provided server after acquiring the
+// leadership via the provided clientset
+func (l *LeaderElection) startWithClient(
+	client kubernetes.Interface,
+	server Starter,
+	workerCount int,
+) (stop func(), err error) {
+	err = l.setDefaults()
+	if err != nil {
+		return nil, err
+	}
+	err = l.validate()
+	if err != nil {
+		return nil, err
+	}
+	lock, err := l.newLock(client)
+	if err != nil {
+		return nil, err
+	}
+
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			l.campaign(shutdown, lock, server, workerCount)
+			select {
+			case <-shutdown:
+				// stop was invoked
+				return
+			default:
+				glog.Warningf("Lost leadership: Will campaign again: %s", l)
+			}
+			// NOTE:
+			//	A renewal of the lost term may still be using the
+			// previous lock. Hence a new lock is used for every
+			// campaign.
+			newLock, err := l.newLock(client)
+			if err != nil {
+				glog.Errorf("Will campaign with previous lock: %v", err)
+				continue
+			}
+			lock = newLock
+		}
+	}()
+
+	return func() {
+		close(shutdown)
+		// wait till the server is stopped & lease is released
+		<-done
+	}, nil
+}
+
+// newLock returns a new lease lock via the provided clientset
+func (l *LeaderElection) newLock(
+	client kubernetes.Interface,
+) (resourcelock.Interface, error) {
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		l.LeaseNamespace,
+		l.LeaseName,
+		client.CoreV1(),
+		client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{
+			Identity: l.Identity,
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Can't create lease lock: %s", l)
+	}
+	return lock, nil
+}
+
+// term is this instance's tenure as a leader
+type term struct {
+	mutex sync.Mutex
+
+	// set to true once the leader election of this
+	// term is over
+	isOver bool
+
+	// set to true if the server was started during
+	// this term
+	isServing bool
+
+	// closed when the server started during this
+	// term is stopped
+	stopped chan struct{}
+}
+
+// serve marks this term as serving. It returns false if
+// this term is already over.
+func (t *term) serve() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.isOver {
+		return false
+	}
+	t.isServing = true
+	return true
+}
+
+// end marks this term as over & waits for the server, if it
+// was started, to be stopped
+func (t *term) end() {
+	t.mutex.Lock()
+	t.isOver = true
+	isServing := t.isServing
+	t.mutex.Unlock()
+
+	if isServing {
+		<-t.stopped
+	}
+}
+
+// campaign tries to acquire the leadership & runs the provided
+// server till the leadership is lost or shutdown is invoked. This
+// returns only after the server is stopped.
+func (l *LeaderElection) campaign(
+	shutdown <-chan struct{},
+	lock resourcelock.Interface,
+	server Starter,
+	workerCount int,
+) {
+	termCtx, termCancel := context.WithCancel(context.Background())
+	defer termCancel()
+
+	t := &term{stopped: make(chan struct{})}
+	// NOTE:
+	//	RunOrDie invokes OnStartedLeading asynchronously & does
+	// not wait for it to return. Hence the term is used to wait
+	// for the server to stop before campaigning again.
+	defer t.end()
+
+	go func() {
+		select {
+		case <-shutdown:
+			// NOTE:
+			//	Lease is released only after the server is stopped.
+			// This avoids other instances to reconcile while this
+			// instance is still stopping.
+			t.end()
+			termCancel()
+		case <-termCtx.Done():
+		}
+	}()
+
+	leaderelection.RunOrDie(termCtx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   l.LeaseDuration,
+		RenewDeadline:   l.RenewDeadline,
+		RetryPeriod:     l.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            l.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				if !t.serve() {
+					// term got over even before serving
+					return
+				}
+				defer close(t.stopped)
+
+				glog.Infof("Started leading: %s", l)
+				stopServer, err := server.Start(workerCount)
+				if err != nil {
+					glog.Errorf("Failed to start server: %v: %s", err, l)
+					// give up the leadership to let others try
+					termCancel()
+					return
+				}
+				select {
+				case <-leaderCtx.Done():
+					glog.Warningf("Leadership lost: Stopping server: %s", l)
+				case <-shutdown:
+					glog.Infof("Shutting down: Stopping server: %s", l)
+				}
+				stopServer()
+			},
+			OnStoppedLeading: func() {
+				glog.V(4).Infof("Stopped leading or campaigning: %s", l)
+			},
+			OnNewLeader: func(identity string) {
+				if identity == l.Identity {
+					return
+				}
+				glog.Infof("New leader %q elected: %s", identity, l)
+			},
+		},
+	})
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeStarter records the start & stop invocations
+type fakeStarter struct {
+	started chan struct{}
+	stopped chan struct{}
+}
+
+func (s *fakeStarter) Start(workerCount int) (func(), error) {
+	close(s.started)
+	return func() {
+		close(s.stopped)
+	}, nil
+}
+
+func TestLeaderElectionValidate(t *testing.T) {
+	var tests = map[string]struct {
+		election LeaderElection
+		isErr    bool
+	}{
+		"valid": {
+			election: LeaderElection{
+				LeaseName:      "metac",
+				LeaseNamespace: "metac",
+			},
+		},
+		"missing lease name": {
+			election: LeaderElection{
+				LeaseNamespace: "metac",
+			},
+			isErr: true,
+		},
+		"missing lease namespace": {
+			election: LeaderElection{
+				LeaseName: "metac",
+			},
+			isErr: true,
+		},
+		"lease duration not greater than renew deadline": {
+			election: LeaderElection{
+				LeaseName:      "metac",
+				LeaseNamespace: "metac",
+				LeaseDuration:  5 * time.Second,
+				RenewDeadline:  5 * time.Second,
+			},
+			isErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name := name
+		mock := mock
+		t.Run(name, func(t *testing.T) {
+			err := mock.election.setDefaults()
+			if err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+			err = mock.election.validate()
+			if mock.isErr && err == nil {
+				t.Fatalf("Expected error got none")
+			}
+			if !mock.isErr && err != nil {
+				t.Fatalf("Expected no error got [%+v]", err)
+			}
+		})
+	}
+}
+
+func TestLeaderElectionStartStop(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	server := &fakeStarter{
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	election := &LeaderElection{
+		LeaseName:      "metac",
+		LeaseNamespace: "metac",
+		Identity:       "test-1",
+		LeaseDuration:  2 * time.Second,
+		RenewDeadline:  1 * time.Second,
+		RetryPeriod:    100 * time.Millisecond,
+	}
+	stop, err := election.startWithClient(client, server, 1)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+
+	select {
+	case <-server.started:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected server to start after acquiring leadership")
+	}
+
+	stop()
+	select {
+	case <-server.stopped:
+	default:
+		t.Fatalf("Expected server to be stopped when stop returns")
+	}
+
+	lease, err := client.CoordinationV1().Leases("metac").Get(
+		"metac",
+		metav1.GetOptions{},
+	)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		t.Fatalf(
+			"Expected lease to be released got holder %q",
+			*lease.Spec.HolderIdentity,
+		)
+	}
+}
@@ -23,14 +23,17 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
 	"openebs.io/metac/apis/metacontroller/v1alpha1"
 	metaclientset "openebs.io/metac/client/generated/clientset/versioned"
 	metainformers "openebs.io/metac/client/generated/informers/externalversions"
+	"openebs.io/metac/controller/common"
 	"openebs.io/metac/controller/composite"
 	"openebs.io/metac/controller/decorator"
 	"openebs.io/metac/controller/generic"
+	"openebs.io/metac/controller/mapper"
 	dynamicclientset "openebs.io/metac/dynamic/clientset"
 	dynamicdiscovery "openebs.io/metac/dynamic/discovery"
 	dynamicinformer "openebs.io/metac/dynamic/informer"
@@ -109,6 +112,32 @@ func (s *Server) GetKubeDetails() *KubeDetails {
 	}
 }
 
+// setHookKubeClient sets the kubernetes client used by hooks
+// to fetch the Secrets & ConfigMaps they refer to e.g. CA bundles
+// & client certificates of webhooks
+func (s *Server) setHookKubeClient() error {
+	client, err := kubernetes.NewForConfig(s.Config)
+	if err != nil {
+		return err
+	}
+	common.SetHookKubeClient(client)
+	return nil
+}
+
+// stopControllers stops the provided controllers in parallel &
+// waits till all of them are stopped
+func stopControllers(controllers []controller) {
+	var wg sync.WaitGroup
+	for _, ctl := range controllers {
+		wg.Add(1)
+		go func(ctl controller) {
+			defer wg.Done()
+			ctl.Stop()
+		}(ctl)
+	}
+	wg.Wait()
+}
+
 // CRDServer represents metac server based on metac's CRDs.
 // In other words, this is about running Kubernetes controllers
 // against various MetaControllers. MetaControllers
@@ -126,11 +155,28 @@ func (s *CRDServer) Start(workerCount int) (stop func(), err error) {
 	// refresh discovery cache to pick up newly-installed resources.
 	discoveryClient :=
 		discovery.NewDiscoveryClientForConfigOrDie(s.Config)
-	s.apiDiscovery =
+	apiDiscovery :=
 		dynamicdiscovery.NewAPIResourceDiscoverer(discoveryClient)
-	// We don't care about stopping this cleanly since it has no
-	// external effects.
-	s.apiDiscovery.Start(s.DiscoveryInterval)
+	s.apiDiscovery = apiDiscovery
+	// NOTE:
+	//	Server may be started again e.g. on every leadership term.
+	// Hence discovery is stopped when the server is stopped or
+	// fails to start.
+	apiDiscovery.Start(s.DiscoveryInterval)
+	defer func() {
+		if err != nil {
+			apiDiscovery.Stop()
+		}
+	}()
+
+	err = s.setHookKubeClient()
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Failed to start %s: Can't create hook clientset",
+			s,
+		)
+	}
 
 	// init the clientset
 	metaClientset, err := metaclientset.NewForConfig(s.Config)
@@ -185,6 +231,14 @@ func (s *CRDServer) Start(workerCount int) (stop func(), err error) {
 			workerCount,
 		),
 		generic.NewCRDMetaController(
+			s.apiDiscovery,
+			dynamicClientset,
+			dynamicInformerFactory,
+			metaInformerFactory,
+			metaClientset,
+			workerCount,
+		),
+		mapper.NewMetacontroller(
 			s.apiDiscovery,
 			dynamicClientset,
 			dynamicInformerFactory,
@@ -193,9 +247,10 @@ func (s *CRDServer) Start(workerCount int) (stop func(), err error) {
 		),
 	}
 
-	// Start all requested informers.
-	// We don't care about stopping this cleanly since it has no external effects.
-	metaInformerFactory.Start(nil)
+	// Start all requested informers. These are stopped when
+	// the server is stopped.
+	informerStopCh := make(chan struct{})
+	metaInformerFactory.Start(informerStopCh)
 
 	// Start all controllers.
 	for _, c := range metaControllers {
@@ -203,19 +258,12 @@ func (s *CRDServer) Start(workerCount int) (stop func(), err error) {
 	}
 
 	// Return stop function that can be used by the clients
-	// of this method to stop all meta controllers that were
-	// started here
+	// of this method to stop all meta controllers, informers &
+	// api discovery that were started here
 	return func() {
-		var wg sync.WaitGroup
-		for _, mctl := range metaControllers {
-			wg.Add(1)
-			go func(mctl controller) {
-				defer wg.Done()
-				mctl.Stop()
-			}(mctl)
-		}
-		// wait till all meta controllers are stopped
-		wg.Wait()
+		stopControllers(metaControllers)
+		close(informerStopCh)
+		apiDiscovery.Stop()
 	}, nil
 }
 
@@ -240,10 +288,35 @@ type ConfigServer struct {
 	//  ConfigPath has higher priority
 	GenericControllerConfigLoadFn func() ([]*v1alpha1.GenericController, error)
 
+	// Function that fetches CompositeController instances to
+	// be used as configs to run Metac
+	//
+	// NOTE:
+	//	ConfigPath has higher priority
+	CompositeControllerConfigLoadFn func() ([]*v1alpha1.CompositeController, error)
+
+	// Function that fetches DecoratorController instances to
+	// be used as configs to run Metac
+	//
+	// NOTE:
+	//	ConfigPath has higher priority
+	DecoratorControllerConfigLoadFn func() ([]*v1alpha1.DecoratorController, error)
+
+	// Function that fetches MapController instances to
+	// be used as configs to run Metac
+	//
+	// NOTE:
+	//	ConfigPath has higher priority
+	MapControllerConfigLoadFn func() ([]*v1alpha1.MapController, error)
+
 	// This will allow executing start logic to be retried
 	// indefinitely till all the watch controllers are started
 	RetryIndefinitelyForStart *bool
 
+	// Interval to reload the GenericController configs. Configs
+	// are not reloaded if this is not set.
+	ConfigReloadInterval time.Duration
+
 	// Number of workers per watch controller
 	workerCount int
 }
@@ -257,11 +330,28 @@ func (s *ConfigServer) Start(workerCount int) (stop func(), err error) {
 	// refresh discovery cache to pick up newly-installed resources.
 	discoveryClient :=
 		discovery.NewDiscoveryClientForConfigOrDie(s.Config)
-	s.apiDiscovery =
+	apiDiscovery :=
 		dynamicdiscovery.NewAPIResourceDiscoverer(discoveryClient)
-	// We don't care about stopping this cleanly since it
-	// has no external effects
-	s.apiDiscovery.Start(s.DiscoveryInterval)
+	s.apiDiscovery = apiDiscovery
+	// NOTE:
+	//	Server may be started again e.g. on every leadership term.
+	// Hence discovery is stopped when the server is stopped or
+	// fails to start.
+	apiDiscovery.Start(s.DiscoveryInterval)
+	defer func() {
+		if err != nil {
+			apiDiscovery.Stop()
+		}
+	}()
+
+	err = s.setHookKubeClient()
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"Failed to start %s: Can't create hook clientset",
+			s,
+		)
+	}
 
 	// Create dynamic clientset (factory for dynamic clients).
 	dynamicClientset, err := dynamicclientset.New(s.Config, s.apiDiscovery)
@@ -275,33 +365,79 @@ func (s *ConfigServer) Start(workerCount int) (stop func(), err error) {
 		s.InformerRelist,
 	)
 
-	// various generic meta controller options to setup meta controller
-	configOpts := []generic.ConfigMetaControllerOption{
-		generic.SetMetacConfigLoadFn(s.GenericControllerConfigLoadFn),
-		generic.SetMetacConfigPath(s.ConfigPath),
-		generic.SetMetacConfigToRetryIndefinitelyForStart(s.RetryIndefinitelyForStart),
-	}
-
-	genericMetac, err := generic.NewConfigMetaController(
-		s.apiDiscovery,
-		dynamicClientset,
-		dynamicInformerFactory,
-		workerCount,
-		configOpts...,
-	)
-	if err != nil {
-		return nil, err
-	}
-
 	// Start various metacontrollers (controllers that spawn controllers).
 	// Each one requests the informers it needs from the factory.
-	metaControllers := []controller{
-		// TODO (@amitkumardas):
-		//
-		// Currently GenericController is the only meta controller
-		// supported to run using config i.e. runaslocal flag.
-		// Support for other controllers needs be introduced.
-		genericMetac,
+	//
+	// NOTE:
+	//	A meta controller is set only if its configs can be loaded
+	// either from ConfigPath or from its config load function
+	var metaControllers []controller
+	if s.ConfigPath != "" || s.GenericControllerConfigLoadFn != nil {
+		genericMetac, err := generic.NewConfigMetaController(
+			s.apiDiscovery,
+			dynamicClientset,
+			dynamicInformerFactory,
+			workerCount,
+			generic.SetMetacConfigLoadFn(s.GenericControllerConfigLoadFn),
+			generic.SetMetacConfigPath(s.ConfigPath),
+			generic.SetMetacConfigToRetryIndefinitelyForStart(s.RetryIndefinitelyForStart),
+			generic.SetMetacConfigReloadInterval(s.ConfigReloadInterval),
+		)
+		if err != nil {
+			return nil, err
+		}
+		metaControllers = append(metaControllers, genericMetac)
+	}
+	if s.ConfigPath != "" || s.CompositeControllerConfigLoadFn != nil {
+		compositeMetac, err := composite.NewConfigMetacontroller(
+			s.apiDiscovery,
+			dynamicClientset,
+			dynamicInformerFactory,
+			workerCount,
+			composite.SetMetacConfigLoadFn(s.CompositeControllerConfigLoadFn),
+			composite.SetMetacConfigPath(s.ConfigPath),
+			composite.SetMetacConfigToRetryIndefinitelyForStart(s.RetryIndefinitelyForStart),
+		)
+		if err != nil {
+			return nil, err
+		}
+		metaControllers = append(metaControllers, compositeMetac)
+	}
+	if s.ConfigPath != "" || s.DecoratorControllerConfigLoadFn != nil {
+		decoratorMetac, err := decorator.NewConfigMetacontroller(
+			s.apiDiscovery,
+			dynamicClientset,
+			dynamicInformerFactory,
+			workerCount,
+			decorator.SetMetacConfigLoadFn(s.DecoratorControllerConfigLoadFn),
+			decorator.SetMetacConfigPath(s.ConfigPath),
+			decorator.SetMetacConfigToRetryIndefinitelyForStart(s.RetryIndefinitelyForStart),
+		)
+		if err != nil {
+			return nil, err
+		}
+		metaControllers = append(metaControllers, decoratorMetac)
+	}
+	if s.ConfigPath != "" || s.MapControllerConfigLoadFn != nil {
+		mapperMetac, err := mapper.NewConfigMetacontroller(
+			s.apiDiscovery,
+			dynamicClientset,
+			dynamicInformerFactory,
+			workerCount,
+			mapper.SetMetacConfigLoadFn(s.MapControllerConfigLoadFn),
+			mapper.SetMetacConfigPath(s.ConfigPath),
+			mapper.SetMetacConfigToRetryIndefinitelyForStart(s.RetryIndefinitelyForStart),
+		)
+		if err != nil {
+			return nil, err
+		}
+		metaControllers = append(metaControllers, mapperMetac)
+	}
+	if len(metaControllers) == 0 {
+		return nil, errors.Errorf(
+			"Failed to start %s: Either ConfigPath or config load function is required",
+			s,
+		)
 	}
 
 	// Start all controllers.
@@ -309,17 +445,10 @@ func (s *ConfigServer) Start(workerCount int) (stop func(), err error) {
 		c.Start()
 	}
 
-	// Return a function that will stop all controllers.
+	// Return a function that will stop all controllers & api
+	// discovery.
 	return func() {
-		var wg sync.WaitGroup
-		for _, mctl := range metaControllers {
-			wg.Add(1)
-			go func(mctl controller) {
-				defer wg.Done()
-				mctl.Stop()
-			}(mctl)
-		}
-		// wait till all meta controllers are stopped
-		wg.Wait()
+		stopControllers(metaControllers)
+		apiDiscovery.Stop()
 	}, nil
 }
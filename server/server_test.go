@@ -0,0 +1,242 @@
+/*
+Copyright 2020 The MayaData Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"openebs.io/metac/apis/metacontroller/v1alpha1"
+)
+
+// serverTestAPIServer serves the discovery of metac's resources,
+// empty lists of these resources & watches that never change
+type serverTestAPIServer struct{}
+
+func (s *serverTestAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Query().Get("watch") == "true" {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+	groupVersion := v1alpha1.SchemeGroupVersion.String()
+	kinds := map[string]string{
+		"compositecontrollers": "CompositeController",
+		"decoratorcontrollers": "DecoratorController",
+		"genericcontrollers":   "GenericController",
+		"mapcontrollers":       "MapController",
+	}
+	var resp interface{}
+	switch r.URL.Path {
+	case "/api":
+		resp = metav1.APIVersions{Versions: []string{"v1"}}
+	case "/apis":
+		version := metav1.GroupVersionForDiscovery{
+			GroupVersion: groupVersion,
+			Version:      v1alpha1.SchemeGroupVersion.Version,
+		}
+		resp = metav1.APIGroupList{
+			Groups: []metav1.APIGroup{
+				{
+					Name:             v1alpha1.SchemeGroupVersion.Group,
+					Versions:         []metav1.GroupVersionForDiscovery{version},
+					PreferredVersion: version,
+				},
+			},
+		}
+	case "/api/v1":
+		resp = metav1.APIResourceList{GroupVersion: "v1"}
+	case "/apis/" + groupVersion:
+		list := metav1.APIResourceList{GroupVersion: groupVersion}
+		for name, kind := range kinds {
+			list.APIResources = append(list.APIResources, metav1.APIResource{
+				Name:  name,
+				Kind:  kind,
+				Verbs: metav1.Verbs{"list", "watch"},
+			})
+		}
+		resp = list
+	default:
+		name := strings.TrimPrefix(r.URL.Path, "/apis/"+groupVersion+"/")
+		kind, found := kinds[name]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp = map[string]interface{}{
+			"apiVersion": groupVersion,
+			"kind":       kind + "List",
+			"metadata":   map[string]interface{}{"resourceVersion": "1"},
+			"items":      []interface{}{},
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// countingStarter starts the provided server & records the
+// number of times it was started & stopped
+type countingStarter struct {
+	server Starter
+
+	mutex   sync.Mutex
+	started int
+	stopped int
+}
+
+func (s *countingStarter) Start(workerCount int) (func(), error) {
+	stop, err := s.server.Start(workerCount)
+	if err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	s.started++
+	s.mutex.Unlock()
+	return func() {
+		stop()
+		s.mutex.Lock()
+		s.stopped++
+		s.mutex.Unlock()
+	}, nil
+}
+
+// waitFor waits till the server is started & stopped the
+// provided number of times
+func (s *countingStarter) waitFor(t *testing.T, started, stopped int) {
+	deadline := time.Now().Add(20 * time.Second)
+	for {
+		s.mutex.Lock()
+		gotStarted, gotStopped := s.started, s.stopped
+		s.mutex.Unlock()
+		if gotStarted == started && gotStopped == stopped {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf(
+				"Expected %d starts & %d stops got %d starts & %d stops",
+				started,
+				stopped,
+				gotStarted,
+				gotStopped,
+			)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// waitForGoroutines waits till the number of goroutines is not
+// more than the provided count & returns the last observed count
+func waitForGoroutines(count int) int {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		// connections kept alive by the previous terms
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+		got := runtime.NumGoroutine()
+		if got <= count || time.Now().After(deadline) {
+			return got
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestCRDServerStopsOnLeadershipLoss(t *testing.T) {
+	srv := httptest.NewServer(&serverTestAPIServer{})
+	defer func() {
+		// closes the watches that were not stopped
+		srv.CloseClientConnections()
+		srv.Close()
+	}()
+
+	client := fake.NewSimpleClientset()
+	server := &countingStarter{
+		server: &CRDServer{
+			Server: &Server{
+				Config:            &rest.Config{Host: srv.URL},
+				DiscoveryInterval: time.Hour,
+				InformerRelist:    time.Hour,
+			},
+		},
+	}
+	election := &LeaderElection{
+		LeaseName:      "metac",
+		LeaseNamespace: "metac",
+		Identity:       "test-1",
+		LeaseDuration:  1 * time.Second,
+		RenewDeadline:  500 * time.Millisecond,
+		RetryPeriod:    50 * time.Millisecond,
+	}
+
+	before := runtime.NumGoroutine()
+	stop, err := election.startWithClient(client, server, 1)
+	if err != nil {
+		t.Fatalf("Expected no error got [%+v]", err)
+	}
+	server.waitFor(t, 1, 0)
+
+	for term := 1; term <= 3; term++ {
+		// another instance takes over the lease
+		lease, err := client.CoordinationV1().Leases("metac").Get(
+			"metac",
+			metav1.GetOptions{},
+		)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		other := "test-2"
+		now := metav1.NewMicroTime(time.Now())
+		lease.Spec.HolderIdentity = &other
+		lease.Spec.AcquireTime = &now
+		lease.Spec.RenewTime = &now
+		_, err = client.CoordinationV1().Leases("metac").Update(
+			lease.DeepCopy(),
+		)
+		if err != nil {
+			t.Fatalf("Expected no error got [%+v]", err)
+		}
+		// server is stopped on losing the leadership & started
+		// again once the other instance's lease expires
+		server.waitFor(t, term+1, term)
+	}
+
+	stop()
+	server.waitFor(t, 4, 4)
+
+	// goroutines of api discovery, informers & controllers of all
+	// the terms are stopped
+	after := waitForGoroutines(before + 2)
+	if after > before+2 {
+		buf := make([]byte, 1<<20)
+		n := runtime.Stack(buf, true)
+		t.Fatalf(
+			"Expected at most %d goroutines got %d:\n%s",
+			before+2,
+			after,
+			buf[:n],
+		)
+	}
+}
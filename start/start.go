@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -32,6 +33,12 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	"openebs.io/metac/controller/common"
+	"openebs.io/metac/controller/generic"
+	"openebs.io/metac/hooks/jsonnet"
+	"openebs.io/metac/hooks/recorder"
+	"openebs.io/metac/hooks/webhook"
+	"openebs.io/metac/metrics"
 	"openebs.io/metac/server"
 )
 
@@ -96,8 +103,148 @@ var (
 		`When true will let metac to retry continuously till all its controllers are started.
 		 Applicable if run-as-local is set to true`,
 	)
+	metacConfigReloadInterval = flag.Duration(
+		"metac-config-reload-interval",
+		0,
+		`How often to reload GenericController configs from metac-config-path.
+		 Only the controllers whose configs were added, removed or changed get affected.
+		 Configs are not reloaded if this is not set. Applicable if run-as-local is set to true`,
+	)
+	leaderElect = flag.Bool(
+		"leader-elect",
+		false,
+		`When true metac starts its controllers only after acquiring the leadership.
+		 This lets multiple replicas of metac to run in high availability mode`,
+	)
+	leaderElectLeaseName = flag.String(
+		"leader-elect-lease-name",
+		"metac",
+		`Name of the lease object used for leader election.
+		 Applicable if leader-elect is set to true`,
+	)
+	leaderElectLeaseNamespace = flag.String(
+		"leader-elect-lease-namespace",
+		"metac",
+		`Namespace of the lease object used for leader election.
+		 Applicable if leader-elect is set to true`,
+	)
+	leaderElectLeaseDuration = flag.Duration(
+		"leader-elect-lease-duration",
+		15*time.Second,
+		`Duration that non-leader candidates will wait before attempting to acquire leadership.
+		 Applicable if leader-elect is set to true`,
+	)
+	leaderElectRenewDeadline = flag.Duration(
+		"leader-elect-renew-deadline",
+		10*time.Second,
+		`Duration that the leader will retry refreshing leadership before giving it up.
+		 Applicable if leader-elect is set to true`,
+	)
+	leaderElectRetryPeriod = flag.Duration(
+		"leader-elect-retry-period",
+		2*time.Second,
+		`Duration the candidates wait between attempts to acquire or renew leadership.
+		 Applicable if leader-elect is set to true`,
+	)
+	webhookMaxIdleConns = flag.Int(
+		"webhook-max-idle-conns",
+		webhook.DefaultTransportConfig.MaxIdleConns,
+		"Maximum number of idle webhook connections across all hosts. Zero means no limit",
+	)
+	webhookMaxIdleConnsPerHost = flag.Int(
+		"webhook-max-idle-conns-per-host",
+		webhook.DefaultTransportConfig.MaxIdleConnsPerHost,
+		"Maximum number of idle webhook connections kept per host",
+	)
+	webhookIdleConnTimeout = flag.Duration(
+		"webhook-idle-conn-timeout",
+		webhook.DefaultTransportConfig.IdleConnTimeout,
+		"Duration an idle webhook connection is kept before it is closed. Zero means no limit",
+	)
+	webhookHTTP2 = flag.Bool(
+		"webhook-http2",
+		webhook.DefaultTransportConfig.EnableHTTP2,
+		`When true metac attempts HTTP/2 to invoke https webhooks.
+		 Webhooks served over plain http always use HTTP/1.1`,
+	)
+	webhookTokenPath = flag.String(
+		"webhook-token-path",
+		"",
+		`Path of a projected service account token whose audience is dedicated to
+		 webhooks. This token is sent to webhooks whose auth is set to service
+		 account token. These webhooks fail if this is not set`,
+	)
+	hookRecordDir = flag.String(
+		"hook-record-dir",
+		"",
+		`Directory where hook requests & responses are recorded as JSON files.
+		 Only the hooks with 'record' set to true are recorded unless hook-record-all is set`,
+	)
+	hookRecordMaxFiles = flag.Int(
+		"hook-record-max-files",
+		recorder.DefaultMaxFiles,
+		`Maximum number of records kept in hook-record-dir. Oldest records are
+		 deleted once exceeded. Zero implies no limit`,
+	)
+	hookRecordMaxBytes = flag.Int64(
+		"hook-record-max-bytes",
+		recorder.DefaultMaxBytes,
+		`Maximum size in bytes of the records kept in hook-record-dir. Oldest
+		 records are deleted once exceeded. Zero implies no limit`,
+	)
+	hookRecordBufferSize = flag.Int(
+		"hook-record-buffer-size",
+		0,
+		`Number of most recent hook requests & responses kept in memory & served at
+		 /debug/hooks of the debug http server. Zero disables the buffer.
+		 Applicable if enable-debug-hooks is set`,
+	)
+	enableDebugHooks = flag.Bool(
+		"enable-debug-hooks",
+		false,
+		`When true recorded hook requests & responses are served at /debug/hooks
+		 of the debug http server. Anyone who can reach the debug http server
+		 can read these`,
+	)
+	watchNamespaces = flag.String(
+		"watch-namespaces",
+		"",
+		`Comma separated namespaces that GenericController watches & attachments
+		 are listed & watched from. All namespaces are watched if empty`,
+	)
+	hookRecordAll = flag.Bool(
+		"hook-record-all",
+		false,
+		`When true requests & responses of all hooks are recorded.
+		 Applicable if hook-record-dir or hook-record-buffer-size is set`,
+	)
+	hookNamespaces = flag.String(
+		"hook-namespaces",
+		"",
+		`Comma separated namespaces whose Secrets & ConfigMaps may be referred by
+		 hooks of GenericController custom resources of any namespace. These hooks
+		 may refer to Secrets & ConfigMaps of their own namespace only if empty`,
+	)
+	jsonnetImportDir = flag.String(
+		"jsonnet-import-dir",
+		"",
+		`Directory whose files may be imported by Jsonnet hooks. Paths are resolved
+		 against this directory. Jsonnet hooks can't import any file if empty`,
+	)
 )
 
+// splitNamespaces returns the namespaces of the provided comma
+// separated flag value
+func splitNamespaces(value string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(value, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
 // KubeDetails provides kubernetes config & api discovery instance
 // based on the kubernetes cluster that gets connected by metac.
 //
@@ -118,6 +265,7 @@ func Start() {
 	glog.Infof("API server relist interval i.e. cache flush interval: %v", *informerRelist)
 	glog.Infof("Debug http server address: %v", *debugAddr)
 	glog.Infof("Run metac locally: %t", *runAsLocal)
+	glog.Infof("Leader election: %t", *leaderElect)
 
 	var config *rest.Config
 	var err error
@@ -137,6 +285,71 @@ func Start() {
 	config.QPS = float32(*clientGoQPS)
 	config.Burst = *clientGoBurst
 
+	// connections to webhooks are reused across syncs
+	common.SetWebhookTransportConfig(webhook.TransportConfig{
+		MaxIdleConns:        *webhookMaxIdleConns,
+		MaxIdleConnsPerHost: *webhookMaxIdleConnsPerHost,
+		IdleConnTimeout:     *webhookIdleConnTimeout,
+		EnableHTTP2:         *webhookHTTP2,
+	})
+	// projected token & not metac's own token is sent to webhooks
+	common.SetWebhookTokenPath(*webhookTokenPath)
+
+	// hook traffic is recorded for offline debugging
+	var hookRecordBuffer *recorder.RingSink
+	var recorderOpts = []recorder.RecorderOption{
+		recorder.RecordAll(*hookRecordAll),
+	}
+	if *hookRecordDir != "" {
+		glog.Infof("Recording hooks to dir %s", *hookRecordDir)
+		recorderOpts = append(
+			recorderOpts,
+			recorder.WithDir(
+				*hookRecordDir,
+				recorder.MaxFiles(*hookRecordMaxFiles),
+				recorder.MaxBytes(*hookRecordMaxBytes),
+			),
+		)
+	}
+	if *hookRecordBufferSize > 0 && !*enableDebugHooks {
+		glog.Warningf(
+			"Ignoring hook-record-buffer-size: /debug/hooks is not enabled: Set enable-debug-hooks",
+		)
+	}
+	if *hookRecordBufferSize > 0 && *enableDebugHooks {
+		hookRecordBuffer, err = recorder.NewRingSink(*hookRecordBufferSize)
+		if err != nil {
+			glog.Fatal(err)
+		}
+		recorderOpts = append(recorderOpts, recorder.WithRing(hookRecordBuffer))
+	}
+	hookRecorder, err := recorder.NewRecorder(recorderOpts...)
+	if err != nil {
+		glog.Fatal(err)
+	}
+	common.SetHookRecorder(hookRecorder)
+
+	// GenericController informers are restricted to these namespaces
+	if *watchNamespaces != "" {
+		namespaces := splitNamespaces(*watchNamespaces)
+		glog.Infof("Watch namespaces: %v", namespaces)
+		generic.SetWatchNamespaces(namespaces)
+	}
+
+	// hooks of GenericControllers may refer to Secrets & ConfigMaps
+	// of these namespaces in addition to their own namespace
+	if *hookNamespaces != "" {
+		namespaces := splitNamespaces(*hookNamespaces)
+		glog.Infof("Hook namespaces: %v", namespaces)
+		common.SetHookNamespaces(namespaces)
+	}
+
+	// jsonnet hooks may import the files of this directory only
+	if *jsonnetImportDir != "" {
+		glog.Infof("Jsonnet import dir: %s", *jsonnetImportDir)
+		jsonnet.SetImportDir(*jsonnetImportDir)
+	}
+
 	// declare the stop server function
 	var stopServer func()
 	// common server values
@@ -145,22 +358,36 @@ func Start() {
 		DiscoveryInterval: *discoveryInterval,
 		InformerRelist:    *informerRelist,
 	}
-	// start metac either as config based or CRD based
+	// metac runs either as config based or CRD based
+	var starter server.Starter
 	if *runAsLocal {
 		// run as local implies starting this binary by
 		// looking up various MetaController resources as
 		// config files
-		configServer := &server.ConfigServer{
+		starter = &server.ConfigServer{
 			Server:                    mserver,
 			ConfigPath:                *metacConfigPath,
 			RetryIndefinitelyForStart: retryIndefinitelyToStart,
+			ConfigReloadInterval:      *metacConfigReloadInterval,
 		}
-		stopServer, err = configServer.Start(*workerCount)
 	} else {
-		crdServer := &server.CRDServer{
+		starter = &server.CRDServer{
 			Server: mserver,
 		}
-		stopServer, err = crdServer.Start(*workerCount)
+	}
+	if *leaderElect {
+		// controllers are started only after this
+		// instance becomes the leader
+		election := &server.LeaderElection{
+			LeaseName:      *leaderElectLeaseName,
+			LeaseNamespace: *leaderElectLeaseNamespace,
+			LeaseDuration:  *leaderElectLeaseDuration,
+			RenewDeadline:  *leaderElectRenewDeadline,
+			RetryPeriod:    *leaderElectRetryPeriod,
+		}
+		stopServer, err = election.Start(config, starter, *workerCount)
+	} else {
+		stopServer, err = starter.Start(*workerCount)
 	}
 	if err != nil {
 		glog.Fatal(err)
@@ -176,9 +403,17 @@ func Start() {
 		glog.Fatalf("Can't create prometheus exporter: %v", err)
 	}
 	view.RegisterExporter(exporter)
+	err = metrics.Register()
+	if err != nil {
+		glog.Fatalf("Can't register metrics views: %v", err)
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", exporter)
+	if hookRecordBuffer != nil {
+		glog.Infof("Serving recorded hooks at /debug/hooks")
+		mux.Handle("/debug/hooks", hookRecordBuffer)
+	}
 	httpServer := &http.Server{
 		Addr:    *debugAddr,
 		Handler: mux,